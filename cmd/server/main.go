@@ -1,33 +1,96 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 
+	"github.com/Ricky004/watchdata/internals/ingest"
+	"github.com/Ricky004/watchdata/pkg/api"
 	"github.com/Ricky004/watchdata/pkg/api/handlers"
+	"github.com/Ricky004/watchdata/pkg/api/routes"
 	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/factory"
+	"github.com/Ricky004/watchdata/pkg/telemetry"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	errors.MustvalidateCodes()
+
+	// Logging comes first so everything after it is logged consistently.
+	telCfg, err := telemetry.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load telemetry config: %v", err)
+	}
+	tel, err := telemetry.Setup(context.Background(), telCfg)
+	if err != nil {
+		log.Fatalf("Failed to set up telemetry: %v", err)
+	}
+
 	// Load ClickHouse configuration
 	cfg, err := clickhousestore.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("failed to load config", err)
+	}
+
+	apiCfg, err := api.LoadConfig()
+	if err != nil {
+		fatal("failed to load API config", err)
 	}
 
 	// Initialize server with ClickHouse provider
-	server, err := handlers.NewServer(cfg)
+	server, err := handlers.NewServer(cfg, apiCfg)
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+		fatal("failed to create server", err)
 	}
+	a := server.Authenticator()
 
-	// Register routes
-	mux := http.NewServeMux()
-	mux.HandleFunc("/v1/logs", server.GetLogs)
-	mux.HandleFunc("/v1/logs/since", server.GetLogsSince)
-	mux.HandleFunc("/v1/logs/timerange", server.GetLogsInTimeRanges)
-	mux.HandleFunc("/ws", server.WebSocketHandler)
+	// Native OTLP receivers
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(a.UnaryServerInterceptor(authtypes.RoleEditor)))
+	collectorpb.RegisterLogsServiceServer(grpcServer, ingest.NewGRPCLogServer(server, server.Limiter()))
+
+	// Services stop in reverse order: the listeners drain first, then the
+	// background workers and self telemetry, and the store is closed last.
+	services := append(server.Services(tel.Service(server.TelemetrySink())),
+		newGRPCService("ingest-grpc", apiCfg.Ingest.GRPCAddress, grpcServer),
+		newHTTPService("ingest-http", &http.Server{
+			Addr:    apiCfg.Ingest.HTTPAddress,
+			Handler: routes.RegisterIngestRoutes(server),
+		}),
+		newHTTPService("api", &http.Server{
+			Addr:    apiCfg.Address,
+			Handler: routes.RegisterRoutes(apiCfg, server),
+		}),
+	)
+	registry, err := factory.NewRegistry(services...)
+	if err != nil {
+		fatal("failed to create service registry", err)
+	}
+
+	ctx := context.Background()
+	registry.Start(ctx)
+	slog.Info("server started", "address", apiCfg.Address)
+
+	waitErr := registry.Wait(ctx)
+
+	stopCtx, cancel := context.WithTimeout(ctx, apiCfg.ShutdownTimeout)
+	defer cancel()
+	if err := registry.Stop(stopCtx); err != nil {
+		slog.Error("shutdown incomplete", "error", err)
+	}
+	if waitErr != nil {
+		fatal("server stopped", waitErr)
+	}
+	slog.Info("server stopped")
+}
 
-	log.Println("🚀 Server started on :8080")
-	log.Fatal(http.ListenAndServe(":8080", mux))
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+	"google.golang.org/grpc"
+)
+
+// httpService serves srv until it is stopped. Stop drains in-flight
+// requests; hijacked connections such as WebSockets are closed by their
+// owner.
+type httpService struct {
+	id  factory.Id
+	srv *http.Server
+}
+
+func newHTTPService(id string, srv *http.Server) factory.IdxService {
+	return &httpService{id: factory.MustNewId(id), srv: srv}
+}
+
+func (s *httpService) Id() factory.Id {
+	return s.id
+}
+
+func (s *httpService) Start(context.Context) error {
+	slog.Info("listening", "service", s.id.String(), "address", s.srv.Addr)
+	if err := s.srv.ListenAndServe(); !stderrors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *httpService) Stop(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// grpcService serves srv on addr until it is stopped. Stop waits for
+// in-flight calls and cancels them if ctx ends first.
+type grpcService struct {
+	id   factory.Id
+	addr string
+	srv  *grpc.Server
+}
+
+func newGRPCService(id, addr string, srv *grpc.Server) factory.IdxService {
+	return &grpcService{id: factory.MustNewId(id), addr: addr, srv: srv}
+}
+
+func (s *grpcService) Id() factory.Id {
+	return s.id
+}
+
+func (s *grpcService) Start(context.Context) error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("listening", "service", s.id.String(), "address", s.addr)
+	return s.srv.Serve(lis)
+}
+
+func (s *grpcService) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,173 @@
+// Command watchdata is the watchdata command line tool.
+//
+// Usage:
+//
+//	watchdata pipeline generate [flags]
+//	watchdata pipeline validate [config]
+//	watchdata pipeline versions [builder-config]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/builderconfig"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+const usage = `usage: watchdata <command> [arguments]
+
+commands:
+  pipeline generate   generate the collector and builder configs
+  pipeline validate   check a collector config against the component schemas
+  pipeline versions   check that a builder config pins one collector release
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "watchdata:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) >= 2 && args[0] == "pipeline" && args[1] == "generate" {
+		return generate(args[2:])
+	}
+	if len(args) >= 2 && args[0] == "pipeline" && args[1] == "validate" {
+		return validate(args[2:])
+	}
+	if len(args) >= 2 && args[0] == "pipeline" && args[1] == "versions" {
+		return versions(args[2:])
+	}
+	fmt.Fprint(os.Stderr, usage)
+	return errors.New("unknown command")
+}
+
+func generate(args []string) error {
+	flags := flag.NewFlagSet("pipeline generate", flag.ContinueOnError)
+	selectionPath := flags.String("selection", "", "YAML or JSON file with the pipelines, overrides and dist")
+	receivers := flags.String("receivers", "", "comma-separated receivers of a single logs pipeline")
+	processors := flags.String("processors", "", "comma-separated processors of a single logs pipeline")
+	exporters := flags.String("exporters", "", "comma-separated exporters of a single logs pipeline")
+	collectorPath := flags.String("collector-config", "configs/otel-collector-config.yaml", "collector config to edit, or create")
+	builderPath := flags.String("builder-config", "configs/builder-config.yaml", "builder config to write")
+	release := flags.String("release", "", "collector release to pin the modules to (default: the existing builder config's)")
+	dryRun := flags.Bool("dry-run", false, "print the configs instead of writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var sel otelpipelinetypes.Selection
+	switch {
+	case *selectionPath != "" && (*receivers != "" || *processors != "" || *exporters != ""):
+		return errors.New("-selection cannot be combined with -receivers, -processors or -exporters")
+	case *selectionPath != "":
+		var err error
+		if sel, err = otelpipeline.LoadSelection(*selectionPath); err != nil {
+			return err
+		}
+	default:
+		sel.Pipelines = map[string]otelpipelinetypes.Pipeline{
+			"logs": {
+				Receivers:  splitList(*receivers),
+				Processors: splitList(*processors),
+				Exporters:  splitList(*exporters),
+			},
+		}
+	}
+
+	// Keep the distribution and release of an existing builder config
+	// unless they are given.
+	if *release != "" {
+		sel.Release = *release
+	}
+	if err := otelpipeline.KeepBuild(&sel, *builderPath); err != nil {
+		return err
+	}
+
+	res, err := otelpipeline.Generate(sel)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		existing, err := os.ReadFile(*collectorPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		collector, err := res.EditCollectorYAML(existing)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *collectorPath, err)
+		}
+		builder, err := res.BuilderYAML()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s\n%s---\n# %s\n%s", *collectorPath, collector, *builderPath, builder)
+		return nil
+	}
+
+	if err := res.WriteFiles(*collectorPath, *builderPath); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s and %s for collector release %s\n", *collectorPath, *builderPath, res.Release)
+	return nil
+}
+
+func validate(args []string) error {
+	path := "configs/otel-collector-config.yaml"
+	switch len(args) {
+	case 0:
+	case 1:
+		path = args[0]
+	default:
+		return errors.New("usage: watchdata pipeline validate [config]")
+	}
+
+	if err := otelpipeline.ValidateFile(path); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
+
+func versions(args []string) error {
+	path := "configs/builder-config.yaml"
+	switch len(args) {
+	case 0:
+	case 1:
+		path = args[0]
+	default:
+		return errors.New("usage: watchdata pipeline versions [builder-config]")
+	}
+
+	cfg, err := builderconfig.LoadBuilderConfig(path)
+	if err != nil {
+		return err
+	}
+	release, err := builderconfig.DetectRelease(cfg)
+	if err != nil && release != "" {
+		return fmt.Errorf("%s: %w\nrun watchdata pipeline generate -release %s to repin them", path, err, release)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	fmt.Printf("%s pins collector release %s\n", path, release)
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
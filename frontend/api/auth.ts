@@ -0,0 +1,32 @@
+import { authHeaders } from "@/api/logs";
+import { apiError } from "@/api/errors";
+
+const API_URL = "http://localhost:8080";
+
+export type WhoAmI = {
+  subject: string
+  role: "viewer" | "editor" | "admin"
+  method: string
+  email?: string
+  name?: string
+  groups?: string[]
+}
+
+export function loginURL(returnTo: string = "/"): string {
+  return `${API_URL}/v1/auth/oidc/login?return_to=${encodeURIComponent(returnTo)}`
+}
+
+// getWhoAmI returns null when the browser has no valid session.
+export async function getWhoAmI(): Promise<WhoAmI | null> {
+  const res = await fetch(`${API_URL}/v1/auth/whoami`, { headers: authHeaders(), credentials: "include" })
+  if (res.status === 401) return null
+  if (!res.ok) throw await apiError(res, "Failed to fetch current user")
+  return res.json()
+}
+
+export async function logout(): Promise<string | undefined> {
+  const res = await fetch(`${API_URL}/v1/auth/logout`, { method: "POST", credentials: "include" })
+  if (!res.ok) throw await apiError(res, "Failed to log out")
+  const body: { logout_url?: string } = await res.json()
+  return body.logout_url
+}
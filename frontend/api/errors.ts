@@ -0,0 +1,27 @@
+// ApiError is thrown for non-2xx responses. The server answers with
+// { error: { code, message, meta, request_id } }.
+export class ApiError extends Error {
+  constructor(
+    public status: number,
+    public code: string,
+    message: string,
+    public requestId?: string,
+    public meta?: Record<string, unknown>,
+  ) {
+    super(message)
+    this.name = "ApiError"
+  }
+}
+
+export async function apiError(res: Response, fallback: string): Promise<ApiError> {
+  try {
+    const body = await res.json()
+    if (body?.error?.code) {
+      const e = body.error
+      return new ApiError(res.status, e.code, e.message ?? fallback, e.request_id, e.meta)
+    }
+  } catch {
+    // Not an error envelope, e.g. a proxy error page.
+  }
+  return new ApiError(res.status, "unknown", fallback, res.headers.get("X-Request-ID") ?? undefined)
+}
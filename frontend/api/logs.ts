@@ -1,19 +1,31 @@
 import { Log } from "@/components/types/log-type";
+import { apiError } from "@/api/errors";
+
+const API_KEY = process.env.NEXT_PUBLIC_WATCHDATA_API_KEY ?? "";
+
+export function authHeaders(): HeadersInit {
+  return API_KEY ? { Authorization: `Bearer ${API_KEY}` } : {};
+}
+
+export function liveLogsURL(): string {
+  const url = "ws://localhost:8080/ws";
+  return API_KEY ? `${url}?access_token=${encodeURIComponent(API_KEY)}` : url;
+}
 
 export async function getLogs() {
-  const res = await fetch('http://localhost:8080/v1/logs')
-  if (!res.ok) throw new Error('Failed to fetch logs');
+  const res = await fetch('http://localhost:8080/v1/logs', { headers: authHeaders(), credentials: "include" })
+  if (!res.ok) throw await apiError(res, "Failed to fetch logs");
   return res.json();
 }
 
 export async function getLogsSince(timestamp: string): Promise<Log[]> {
-  const res = await fetch(`http://localhost:8080/v1/logs/since?timestamp=${encodeURIComponent(timestamp)}`)
-  if (!res.ok) throw new Error("Failed to fetch logs since")
+  const res = await fetch(`http://localhost:8080/v1/logs/since?timestamp=${encodeURIComponent(timestamp)}`, { headers: authHeaders(), credentials: "include" })
+  if (!res.ok) throw await apiError(res, "Failed to fetch logs since")
   return res.json()
 }
 
 export async function getLogsInTimeRanges(start: number, end: number): Promise<Log[]> {
-  const res = await fetch(`http://localhost:8080/v1/logs/timerange?start=${start}&end=${end}`)
-  if (!res.ok) throw new Error("Failed to fetch logs in time range")
+  const res = await fetch(`http://localhost:8080/v1/logs/timerange?start=${start}&end=${end}`, { headers: authHeaders(), credentials: "include" })
+  if (!res.ok) throw await apiError(res, "Failed to fetch logs in time range")
   return res.json()
 }
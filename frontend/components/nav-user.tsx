@@ -1,5 +1,6 @@
 "use client"
 
+import { useEffect, useState } from "react"
 import {
   IconCreditCard,
   IconDotsVertical,
@@ -28,6 +29,16 @@ import {
   SidebarMenuItem,
   useSidebar,
 } from "@/components/ui/sidebar"
+import { getWhoAmI, loginURL, logout, WhoAmI } from "@/api/auth"
+
+function initials(name: string) {
+  return name
+    .split(/\s+/)
+    .filter(Boolean)
+    .slice(0, 2)
+    .map((part) => part[0].toUpperCase())
+    .join("")
+}
 
 export function NavUser({
   user,
@@ -39,6 +50,30 @@ export function NavUser({
   }
 }) {
   const { isMobile } = useSidebar()
+  const [me, setMe] = useState<WhoAmI | null>(null)
+
+  useEffect(() => {
+    getWhoAmI()
+      .then(setMe)
+      .catch((err) => console.error("Failed to load current user:", err))
+  }, [])
+
+  if (me) {
+    user = {
+      name: me.name || me.subject,
+      email: me.email || me.role,
+      avatar: "",
+    }
+  }
+
+  async function handleLogout() {
+    try {
+      const providerLogout = await logout()
+      window.location.href = providerLogout ?? loginURL(window.location.pathname)
+    } catch (err) {
+      console.error("Failed to log out:", err)
+    }
+  }
 
   return (
     <SidebarMenu>
@@ -51,7 +86,7 @@ export function NavUser({
             >
               <Avatar className="h-8 w-8 rounded-lg grayscale">
                 <AvatarImage src={user.avatar} alt={user.name} />
-                <AvatarFallback className="rounded-lg">CN</AvatarFallback>
+                <AvatarFallback className="rounded-lg">{initials(user.name)}</AvatarFallback>
               </Avatar>
               <div className="grid flex-1 text-left text-sm leading-tight">
                 <span className="truncate font-medium">{user.name}</span>
@@ -72,7 +107,7 @@ export function NavUser({
               <div className="flex items-center gap-2 px-1 py-1.5 text-left text-sm">
                 <Avatar className="h-8 w-8 rounded-lg">
                   <AvatarImage src={user.avatar} alt={user.name} />
-                  <AvatarFallback className="rounded-lg">CN</AvatarFallback>
+                  <AvatarFallback className="rounded-lg">{initials(user.name)}</AvatarFallback>
                 </Avatar>
                 <div className="grid flex-1 text-left text-sm leading-tight">
                   <span className="truncate font-medium">{user.name}</span>
@@ -98,7 +133,7 @@ export function NavUser({
               </DropdownMenuItem>
             </DropdownMenuGroup>
             <DropdownMenuSeparator />
-            <DropdownMenuItem>
+            <DropdownMenuItem onClick={handleLogout}>
               <IconLogout />
               Log out
             </DropdownMenuItem>
@@ -1,5 +1,6 @@
 import { useEffect, useRef, useState } from "react";
 import { Log } from "@/components/types/log-type";
+import { authHeaders, liveLogsURL } from "@/api/logs";
 
 export function useLiveLogs(enabled: boolean) {
   const [logs, setLogs] = useState<Log[]>([]);
@@ -18,7 +19,7 @@ export function useLiveLogs(enabled: boolean) {
 
     // Step 1: Fetch missed logs since last known timestamp
     if (lastTimestampRef.current) {
-      fetch(`/v1/logs/since?timestamp=${encodeURIComponent(lastTimestampRef.current)}`)
+      fetch(`/v1/logs/since?timestamp=${encodeURIComponent(lastTimestampRef.current)}`, { headers: authHeaders(), credentials: "include" })
         .then((res) => res.json())
         .then((fetchedLogs: Log[]) => {
           // Prepend new logs (assumed ascending order from server)
@@ -30,7 +31,7 @@ export function useLiveLogs(enabled: boolean) {
     }
 
     // Step 2: Setup WebSocket
-    const ws = new WebSocket("ws://localhost:8080/ws");
+    const ws = new WebSocket(liveLogsURL());
     socketRef.current = ws;
 
     ws.onmessage = (event) => {
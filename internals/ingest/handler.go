@@ -1,54 +1,88 @@
 package ingest
 
 import (
-    "context"
-	"log"
+	"context"
+	"log/slog"
 
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/telemetry"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
-
+	"google.golang.org/protobuf/proto"
 )
 
+// Sink stores records received by the native receivers.
+type Sink interface {
+	IngestLogs(ctx context.Context, logs []telemetrytypes.LogRecord) error
+}
+
+// GRPCLogServer is an OTLP/gRPC logs receiver that writes to a Sink.
 type GRPCLogServer struct {
 	collectorpb.UnimplementedLogsServiceServer
+	sink    Sink
+	limiter Limiter
+}
+
+// NewGRPCLogServer creates a receiver. limiter may be nil to accept
+// everything.
+func NewGRPCLogServer(sink Sink, limiter Limiter) *GRPCLogServer {
+	return &GRPCLogServer{sink: sink, limiter: limiter}
 }
 
 func (s *GRPCLogServer) Export(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error) {
-    count := 0
-    for _, rl := range req.ResourceLogs {
-        // Print resource attributes if available
-        if rl.Resource != nil && len(rl.Resource.Attributes) > 0 {
-            log.Printf("Resource attributes: %v", rl.Resource.Attributes)
-        }
-        
-        for _, sl := range rl.ScopeLogs {
-            // Print scope name if available
-            if sl.Scope != nil {
-                log.Printf("Scope: %s", sl.Scope.Name)
-            }
-            
-            for i, logRecord := range sl.LogRecords {
-                count++
-                // Print the actual log content
-                log.Printf("Log #%d - Timestamp: %v, Body: %v", 
-                    i, 
-                    logRecord.TimeUnixNano, 
-                    logRecord.Body)
-                
-                // Print attributes if available
-                if len(logRecord.Attributes) > 0 {
-                    log.Printf("  Attributes: %v", logRecord.Attributes)
-                }
-                
-                // Print trace context if available
-                if logRecord.TraceId != nil || logRecord.SpanId != nil {
-                    log.Printf("  Trace ID: %x, Span ID: %x", 
-                        logRecord.TraceId, 
-                        logRecord.SpanId)
-                }
-            }
-        }
-    }
-    
-    log.Printf("Received %d OTLP log records via gRPC\n", count)
-    return &collectorpb.ExportLogsServiceResponse{}, nil
+	records := ConvertRequest(req)
+	if len(records) == 0 {
+		return &collectorpb.ExportLogsServiceResponse{}, nil
+	}
+	assignTenant(ctx, records)
+
+	ctx, span := telemetry.Start(ctx, "LogsService/Export",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.Int("records", len(records))))
+	defer span.End()
+
+	if s.limiter != nil {
+		if err := s.limiter.Admit(ctx, len(records), proto.Size(req)); err != nil {
+			return nil, grpcStatus(err).Err()
+		}
+	}
+
+	if err := s.sink.IngestLogs(ctx, records); err != nil {
+		slog.ErrorContext(ctx, "failed to ingest OTLP logs", "transport", "grpc", "records", len(records), "error", err)
+		span.SetStatus(codes.Error, "failed to store logs")
+		return nil, grpcStatus(errors.Wrap(err, errors.CodeUnavailable, "failed to store logs", errors.SeverityError)).Err()
+	}
+
+	countIngest(ctx, "grpc", len(records), proto.Size(req))
+
+	slog.DebugContext(ctx, "received OTLP logs", "transport", "grpc", "records", len(records))
+	return &collectorpb.ExportLogsServiceResponse{}, nil
+}
+
+// assignTenant stamps records with the tenant of the authenticated caller.
+// Without a caller the sink falls back to the default tenant.
+func assignTenant(ctx context.Context, records []telemetrytypes.LogRecord) {
+	p, ok := authtypes.FromContext(ctx)
+	if !ok {
+		return
+	}
+	for i := range records {
+		records[i].TenantID = p.TenantID
+	}
+}
+
+// countIngest records accepted records in the ingest metrics.
+func countIngest(ctx context.Context, transport string, records, bytes int) {
+	tenant := tenanttypes.DefaultTenant
+	if p, ok := authtypes.FromContext(ctx); ok && p.TenantID != "" {
+		tenant = p.TenantID
+	}
+	metrics.IngestRecords.WithLabelValues(transport, tenant).Add(float64(records))
+	metrics.IngestBytes.WithLabelValues(transport).Add(float64(bytes))
 }
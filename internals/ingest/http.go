@@ -0,0 +1,113 @@
+package ingest
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+
+	// maxBodyBytes caps the decompressed size of a single export request.
+	maxBodyBytes = 16 << 20
+)
+
+// HTTPHandler is an OTLP/HTTP logs receiver (POST /v1/logs) that writes to
+// a Sink. It accepts binary protobuf and JSON encodings, optionally gzipped.
+// limiter may be nil to accept everything.
+func HTTPHandler(sink Sink, limiter Limiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, contentTypeJSON, errors.New(errors.CodeMethodNotAllowed, "method not allowed", errors.SeverityInfo))
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || (mediaType != contentTypeProtobuf && mediaType != contentTypeJSON) {
+			writeError(w, contentTypeJSON, errors.New(errors.CodeUnsupportedMedia, "unsupported content type", errors.SeverityInfo))
+			return
+		}
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				writeError(w, mediaType, errors.New(errors.CodeInvalidRequest, "invalid gzip body", errors.SeverityInfo, err))
+				return
+			}
+			defer gz.Close()
+			body = gz
+		}
+
+		data, err := io.ReadAll(io.LimitReader(body, maxBodyBytes+1))
+		if err != nil {
+			writeError(w, mediaType, errors.New(errors.CodeInvalidRequest, "failed to read body", errors.SeverityInfo, err))
+			return
+		}
+		if len(data) > maxBodyBytes {
+			writeError(w, mediaType, errors.New(errors.CodePayloadTooLarge, "request body too large", errors.SeverityInfo))
+			return
+		}
+
+		req := &collectorpb.ExportLogsServiceRequest{}
+		if mediaType == contentTypeJSON {
+			err = protojson.Unmarshal(data, req)
+		} else {
+			err = proto.Unmarshal(data, req)
+		}
+		if err != nil {
+			writeError(w, mediaType, errors.New(errors.CodeInvalidRequest, "invalid OTLP payload", errors.SeverityInfo, err))
+			return
+		}
+
+		records := ConvertRequest(req)
+		assignTenant(r.Context(), records)
+
+		if limiter != nil {
+			if err := limiter.Admit(r.Context(), len(records), len(data)); err != nil {
+				writeError(w, mediaType, err)
+				return
+			}
+		}
+
+		if err := sink.IngestLogs(r.Context(), records); err != nil {
+			slog.ErrorContext(r.Context(), "failed to ingest OTLP logs", "transport", "http", "records", len(records), "error", err)
+			writeError(w, mediaType, errors.Wrap(err, errors.CodeUnavailable, "failed to store logs", errors.SeverityError))
+			return
+		}
+		countIngest(r.Context(), "http", len(records), len(data))
+
+		writeExportResponse(w, mediaType)
+	}
+}
+
+func writeExportResponse(w http.ResponseWriter, mediaType string) {
+	resp := &collectorpb.ExportLogsServiceResponse{}
+
+	var (
+		out []byte
+		err error
+	)
+	if mediaType == contentTypeJSON {
+		out, err = protojson.Marshal(resp)
+	} else {
+		out, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		writeError(w, mediaType, errors.New(errors.CodeEncodingFailed, "failed to encode response", errors.SeverityError, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
@@ -1 +1,123 @@
-package ingest
\ No newline at end of file
+package ingest
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// ConvertRequest flattens an OTLP export request into log records, the
+// same shape the collector exporter produces.
+func ConvertRequest(req *collectorpb.ExportLogsServiceRequest) []telemetrytypes.LogRecord {
+	var records []telemetrytypes.LogRecord
+
+	for _, rl := range req.GetResourceLogs() {
+		resourceAttrs := convertAttributes(rl.GetResource().GetAttributes())
+
+		for _, sl := range rl.GetScopeLogs() {
+			for _, lr := range sl.GetLogRecords() {
+				timestamp := lr.GetTimeUnixNano()
+				if timestamp == 0 {
+					timestamp = lr.GetObservedTimeUnixNano()
+				}
+
+				traceID := ""
+				if len(lr.GetTraceId()) > 0 {
+					traceID = hex.EncodeToString(lr.GetTraceId())
+				}
+				spanID := ""
+				if len(lr.GetSpanId()) > 0 {
+					spanID = hex.EncodeToString(lr.GetSpanId())
+				}
+
+				records = append(records, telemetrytypes.LogRecord{
+					ID:               telemetrytypes.NewLogID(),
+					Timestamp:        time.Unix(0, int64(timestamp)).UTC(),
+					ObservedTime:     time.Unix(0, int64(lr.GetObservedTimeUnixNano())).UTC(),
+					SeverityNumber:   int8(lr.GetSeverityNumber()),
+					SeverityText:     lr.GetSeverityText(),
+					Body:             anyValueString(lr.GetBody()),
+					Attributes:       convertAttributes(lr.GetAttributes()),
+					Resource:         telemetrytypes.Resource{Attributes: resourceAttrs},
+					TraceID:          traceID,
+					SpanID:           spanID,
+					TraceFlags:       uint8(lr.GetFlags()),
+					Flags:            lr.GetFlags(),
+					DroppedAttrCount: lr.GetDroppedAttributesCount(),
+				})
+			}
+		}
+	}
+
+	return records
+}
+
+func convertAttributes(kvs []*commonpb.KeyValue) []telemetrytypes.KeyValue {
+	attrs := make([]telemetrytypes.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		attrs = append(attrs, telemetrytypes.KeyValue{
+			Key:   kv.GetKey(),
+			Value: anyValueString(kv.GetValue()),
+		})
+	}
+	return attrs
+}
+
+// anyValueString renders a value like pcommon.Value.AsString does: scalars
+// as text, bytes as base64 and arrays and maps as JSON.
+func anyValueString(v *commonpb.AnyValue) string {
+	switch val := v.GetValue().(type) {
+	case nil:
+		return ""
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(val.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(val.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(val.DoubleValue, 'f', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(val.BytesValue)
+	default:
+		b, err := json.Marshal(anyValueRaw(v))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+func anyValueRaw(v *commonpb.AnyValue) any {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(val.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		out := make([]any, 0, len(val.ArrayValue.GetValues()))
+		for _, item := range val.ArrayValue.GetValues() {
+			out = append(out, anyValueRaw(item))
+		}
+		return out
+	case *commonpb.AnyValue_KvlistValue:
+		out := make(map[string]any, len(val.KvlistValue.GetValues()))
+		for _, kv := range val.KvlistValue.GetValues() {
+			out[kv.GetKey()] = anyValueRaw(kv.GetValue())
+		}
+		return out
+	}
+	return nil
+}
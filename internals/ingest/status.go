@@ -0,0 +1,67 @@
+package ingest
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/ratelimit"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Limiter decides whether a request may be ingested. Rejections must be
+// understood by ratelimit.RetryAfter.
+type Limiter interface {
+	Admit(ctx context.Context, records, bytes int) error
+}
+
+// grpcStatus converts err to the status returned to OTLP/gRPC clients.
+// Throttling errors carry RetryInfo, without which clients do not retry
+// RESOURCE_EXHAUSTED. Exceeded quotas have none, so that clients drop the
+// data rather than retry until midnight.
+func grpcStatus(err error) *status.Status {
+	st := errors.GRPCStatus(err)
+	if wait, ok := ratelimit.RetryAfter(err); ok {
+		if detailed, derr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); derr == nil {
+			st = detailed
+		}
+	}
+	return st
+}
+
+// writeError answers an OTLP/HTTP request with the status mapped from the
+// error code and a google.rpc.Status body in the request's encoding.
+// Throttled requests also get a Retry-After header.
+func writeError(w http.ResponseWriter, mediaType string, err error) {
+	if wait, ok := ratelimit.RetryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+	if mediaType != contentTypeProtobuf {
+		mediaType = contentTypeJSON
+	}
+
+	var (
+		out  []byte
+		merr error
+	)
+	st := grpcStatus(err).Proto()
+	if mediaType == contentTypeJSON {
+		out, merr = protojson.Marshal(st)
+	} else {
+		out, merr = proto.Marshal(st)
+	}
+	if merr != nil {
+		http.Error(w, st.GetMessage(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(errors.From(err).Code.HTTPStatus())
+	w.Write(out)
+}
@@ -0,0 +1,209 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+)
+
+// Message is a rendered notification.
+type Message struct {
+	Title string
+	Body  string
+	Data  *TemplateData
+}
+
+// Channel delivers notifications to an external system.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+const sendTimeout = 10 * time.Second
+
+// NewChannel builds a channel from its configuration.
+func NewChannel(cfg ChannelConfig) (Channel, error) {
+	client := &http.Client{Timeout: sendTimeout}
+
+	switch cfg.Type {
+	case ChannelWebhook:
+		return &webhookChannel{cfg: cfg, client: client}, nil
+	case ChannelSlack:
+		return &slackChannel{cfg: cfg, client: client}, nil
+	case ChannelEmail:
+		return &emailChannel{cfg: cfg, send: smtp.SendMail}, nil
+	}
+	return nil, errors.New(errors.CodeInvalidConfig, fmt.Sprintf("unsupported channel type %q", cfg.Type), errors.SeverityError)
+}
+
+// webhookChannel posts the template data as JSON, or the rendered body when
+// a body template is configured.
+type webhookChannel struct {
+	cfg    ChannelConfig
+	client *http.Client
+}
+
+func (c *webhookChannel) Name() string { return c.cfg.Name }
+
+func (c *webhookChannel) Send(ctx context.Context, msg Message) error {
+	var (
+		payload     []byte
+		contentType = "application/json"
+		err         error
+	)
+	if c.cfg.Body != "" {
+		payload = []byte(msg.Body)
+		contentType = "text/plain; charset=utf-8"
+	} else {
+		payload, err = json.Marshal(struct {
+			Title string `json:"title"`
+			*TemplateData
+		}{Title: msg.Title, TemplateData: msg.Data})
+		if err != nil {
+			return errors.New(errors.CodeEncodingFailed, "failed to encode webhook payload", errors.SeverityError, err)
+		}
+	}
+
+	return post(ctx, c.client, c.cfg.URL, contentType, c.cfg.Headers, payload)
+}
+
+// slackChannel posts to a Slack-compatible incoming webhook.
+type slackChannel struct {
+	cfg    ChannelConfig
+	client *http.Client
+}
+
+func (c *slackChannel) Name() string { return c.cfg.Name }
+
+func (c *slackChannel) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"text": "*" + msg.Title + "*\n```\n" + msg.Body + "\n```",
+	})
+	if err != nil {
+		return errors.New(errors.CodeEncodingFailed, "failed to encode slack payload", errors.SeverityError, err)
+	}
+	return post(ctx, c.client, c.cfg.URL, "application/json", c.cfg.Headers, payload)
+}
+
+func post(ctx context.Context, client *http.Client, url, contentType string, headers map[string]string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.New(errors.CodeInvalidConfig, "invalid channel url", errors.SeverityError, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return classifySendError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.NewMeta(errors.CodeExternalBadResponse,
+			fmt.Sprintf("unexpected status %d", resp.StatusCode), errors.SeverityError,
+			map[string]any{"status": resp.StatusCode, "body": string(body)})
+	}
+	return nil
+}
+
+// emailChannel sends notifications over SMTP.
+type emailChannel struct {
+	cfg  ChannelConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func (c *emailChannel) Name() string { return c.cfg.Name }
+
+func (c *emailChannel) Send(ctx context.Context, msg Message) error {
+	s := c.cfg.SMTP
+	port := s.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", strings.ReplaceAll(msg.Title, "\n", " "))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	// net/smtp has no context support, so run it in the background and
+	// give up when the context ends.
+	done := make(chan error, 1)
+	go func() {
+		done <- c.send(addr, auth, s.From, s.To, []byte(body.String()))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return classifySendError(err)
+		}
+		return nil
+	case <-ctx.Done():
+		return classifySendError(ctx.Err())
+	}
+}
+
+// classifySendError maps transport failures to error codes.
+func classifySendError(err error) *errors.Error {
+	var netErr net.Error
+	if stderrors.Is(err, context.DeadlineExceeded) || (stderrors.As(err, &netErr) && netErr.Timeout()) {
+		return errors.New(errors.CodeExternalTimeout, "notification channel timed out", errors.SeverityError, err)
+	}
+	return errors.New(errors.CodeExternalUnreachable, "notification channel unreachable", errors.SeverityError, err)
+}
+
+// renderer renders the title and body templates of one channel.
+type renderer struct {
+	title *template.Template
+	body  *template.Template
+}
+
+func newRenderer(cfg ChannelConfig) (*renderer, error) {
+	title, err := parseTemplate(cfg.Name+"-title", cfg.Title, defaultTitleTemplate)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidConfig, fmt.Sprintf("channel %q: invalid title template", cfg.Name), errors.SeverityError, err)
+	}
+	body, err := parseTemplate(cfg.Name+"-body", cfg.Body, defaultBodyTemplate)
+	if err != nil {
+		return nil, errors.New(errors.CodeInvalidConfig, fmt.Sprintf("channel %q: invalid body template", cfg.Name), errors.SeverityError, err)
+	}
+	return &renderer{title: title, body: body}, nil
+}
+
+func (r *renderer) render(data *TemplateData) (Message, error) {
+	title, err := render(r.title, data)
+	if err != nil {
+		return Message{}, errors.New(errors.CodeInvalidConfig, "failed to render title template", errors.SeverityError, err)
+	}
+	body, err := render(r.body, data)
+	if err != nil {
+		return Message{}, errors.New(errors.CodeInvalidConfig, "failed to render body template", errors.SeverityError, err)
+	}
+	return Message{Title: title, Body: body, Data: data}, nil
+}
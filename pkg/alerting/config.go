@@ -0,0 +1,156 @@
+package alerting
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPathEnv names the environment variable holding the path of the
+// notification config file.
+const ConfigPathEnv = "WATCHDATA_ALERTING_CONFIG"
+
+// Config configures notification channels and routing.
+type Config struct {
+	Channels []ChannelConfig `yaml:"channels"`
+	Route    RouteConfig     `yaml:"route"`
+}
+
+type ChannelType string
+
+const (
+	ChannelWebhook ChannelType = "webhook"
+	ChannelSlack   ChannelType = "slack"
+	ChannelEmail   ChannelType = "email"
+)
+
+type ChannelConfig struct {
+	Name string      `yaml:"name"`
+	Type ChannelType `yaml:"type"`
+
+	// URL is the endpoint for webhook and slack channels.
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+
+	// SMTP settings for email channels.
+	SMTP SMTPConfig `yaml:"smtp"`
+
+	// Title and Body are Go templates rendered with TemplateData. Defaults
+	// are used when they are empty.
+	Title string `yaml:"title"`
+	Body  string `yaml:"body"`
+}
+
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// RouteConfig is a node in the routing tree. An alert follows the first
+// child route whose matchers match, or stays at the parent otherwise.
+type RouteConfig struct {
+	Channel  string                  `yaml:"channel"`
+	Matchers []alertingtypes.Matcher `yaml:"matchers"`
+	GroupBy  []string                `yaml:"group_by"`
+
+	// GroupWait is how long to buffer alerts of a new group before the
+	// first notification.
+	GroupWait time.Duration `yaml:"group_wait"`
+	// GroupInterval is how long to wait before notifying about changes to
+	// a group that was already notified.
+	GroupInterval time.Duration `yaml:"group_interval"`
+	// RepeatInterval is how long to wait before repeating a notification
+	// for alerts that are still firing.
+	RepeatInterval time.Duration `yaml:"repeat_interval"`
+
+	// Continue keeps matching sibling routes after this one matched.
+	Continue bool          `yaml:"continue"`
+	Routes   []RouteConfig `yaml:"routes"`
+}
+
+func newConfig() Config {
+	return Config{
+		Route: RouteConfig{
+			GroupBy:        []string{"alertname"},
+			GroupWait:      30 * time.Second,
+			GroupInterval:  5 * time.Minute,
+			RepeatInterval: 4 * time.Hour,
+		},
+	}
+}
+
+func (c Config) Validate() error {
+	names := make(map[string]bool, len(c.Channels))
+	for _, ch := range c.Channels {
+		if ch.Name == "" {
+			return fmt.Errorf("channel name is required")
+		}
+		if names[ch.Name] {
+			return fmt.Errorf("duplicate channel %q", ch.Name)
+		}
+		names[ch.Name] = true
+
+		switch ch.Type {
+		case ChannelWebhook, ChannelSlack:
+			if ch.URL == "" {
+				return fmt.Errorf("channel %q: url is required", ch.Name)
+			}
+		case ChannelEmail:
+			if ch.SMTP.Host == "" || ch.SMTP.From == "" || len(ch.SMTP.To) == 0 {
+				return fmt.Errorf("channel %q: smtp host, from and to are required", ch.Name)
+			}
+		default:
+			return fmt.Errorf("channel %q: unsupported type %q", ch.Name, ch.Type)
+		}
+	}
+
+	return validateRoute(c.Route, names)
+}
+
+func validateRoute(r RouteConfig, channels map[string]bool) error {
+	if r.Channel != "" && !channels[r.Channel] {
+		return fmt.Errorf("route references unknown channel %q", r.Channel)
+	}
+	for i := range r.Matchers {
+		if err := r.Matchers[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for _, child := range r.Routes {
+		if err := validateRoute(child, channels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads the notification config from the file named by
+// WATCHDATA_ALERTING_CONFIG. Without it, alerts are tracked but not sent
+// anywhere.
+func LoadConfig() (Config, error) {
+	cfg := newConfig()
+
+	path := os.Getenv(ConfigPathEnv)
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read alerting config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse alerting config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid alerting config: %w", err)
+	}
+	return cfg, nil
+}
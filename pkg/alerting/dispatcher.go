@@ -0,0 +1,451 @@
+package alerting
+
+import (
+	"context"
+	stderrors "errors"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// DispatcherStore is the storage the dispatcher needs for sample logs,
+// silences and the notification log.
+type DispatcherStore interface {
+	SearchLogs(ctx context.Context, tenantID string, expr filter.Expr, start, end time.Time, limit int) ([]telemetrytypes.LogRecord, error)
+	ListSilences(ctx context.Context, tenantID string, includeExpired bool) ([]alertingtypes.Silence, error)
+	InsertNotificationAttempts(ctx context.Context, attempts []alertingtypes.NotificationAttempt) error
+}
+
+const (
+	// sampleLimit is the number of log lines attached to a firing alert.
+	sampleLimit = 5
+	// flushTick is how often groups are checked for pending notifications.
+	flushTick = time.Second
+)
+
+// route is a resolved routing tree node; unset fields are inherited from
+// the parent.
+type route struct {
+	id       string
+	cfg      RouteConfig
+	children []*route
+}
+
+func newRoute(id string, cfg RouteConfig, parent *RouteConfig) *route {
+	if parent != nil {
+		if cfg.Channel == "" {
+			cfg.Channel = parent.Channel
+		}
+		if cfg.GroupBy == nil {
+			cfg.GroupBy = parent.GroupBy
+		}
+		if cfg.GroupWait == 0 {
+			cfg.GroupWait = parent.GroupWait
+		}
+		if cfg.GroupInterval == 0 {
+			cfg.GroupInterval = parent.GroupInterval
+		}
+		if cfg.RepeatInterval == 0 {
+			cfg.RepeatInterval = parent.RepeatInterval
+		}
+	} else {
+		// A zero group wait is valid, but zero intervals would notify on
+		// every tick.
+		defaults := newConfig().Route
+		if cfg.GroupInterval == 0 {
+			cfg.GroupInterval = defaults.GroupInterval
+		}
+		if cfg.RepeatInterval == 0 {
+			cfg.RepeatInterval = defaults.RepeatInterval
+		}
+	}
+
+	// The matchers are compiled on a copy, as the config may be shared.
+	cfg.Matchers = slices.Clone(cfg.Matchers)
+	for i := range cfg.Matchers {
+		cfg.Matchers[i].Compile()
+	}
+
+	r := &route{id: id, cfg: cfg}
+	for i, child := range cfg.Routes {
+		r.children = append(r.children, newRoute(id+"."+strconv.Itoa(i), child, &r.cfg))
+	}
+	return r
+}
+
+// match returns the routes an alert with the given labels is delivered through.
+func (r *route) match(labels map[string]string) []*route {
+	if !alertingtypes.MatchAll(r.cfg.Matchers, labels) {
+		return nil
+	}
+
+	var matched []*route
+	for _, child := range r.children {
+		m := child.match(labels)
+		matched = append(matched, m...)
+		if len(m) > 0 && !child.cfg.Continue {
+			break
+		}
+	}
+	if len(matched) == 0 {
+		matched = []*route{r}
+	}
+	return matched
+}
+
+// group collects the alerts that share a route and group-by label values.
+type group struct {
+	key       string
+	tenantID  string
+	route     *route
+	labels    map[string]string
+	alerts    map[string]AlertData
+	createdAt time.Time
+	lastFlush time.Time
+	notified  bool
+	dirty     bool
+}
+
+func (g *group) firing() bool {
+	for _, a := range g.alerts {
+		if a.State == alertingtypes.StateFiring {
+			return true
+		}
+	}
+	return false
+}
+
+// due reports whether the group should be notified at now.
+func (g *group) due(now time.Time) bool {
+	cfg := g.route.cfg
+	switch {
+	case !g.notified:
+		return now.Sub(g.createdAt) >= cfg.GroupWait
+	case g.dirty:
+		return now.Sub(g.lastFlush) >= cfg.GroupInterval
+	case g.firing():
+		return now.Sub(g.lastFlush) >= cfg.RepeatInterval
+	}
+	return false
+}
+
+// Dispatcher groups alerts by route, applies silences and delivers
+// notifications to channels. It implements Notifier.
+type Dispatcher struct {
+	store     DispatcherStore
+	root      *route
+	channels  map[string]Channel
+	renderers map[string]*renderer
+	now       func() time.Time
+
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+// NewDispatcher builds the channels and routing tree described by cfg.
+func NewDispatcher(cfg Config, store DispatcherStore) (*Dispatcher, error) {
+	d := &Dispatcher{
+		store:     store,
+		root:      newRoute("0", cfg.Route, nil),
+		channels:  make(map[string]Channel, len(cfg.Channels)),
+		renderers: make(map[string]*renderer, len(cfg.Channels)),
+		now:       time.Now,
+		groups:    make(map[string]*group),
+	}
+
+	for _, chCfg := range cfg.Channels {
+		ch, err := NewChannel(chCfg)
+		if err != nil {
+			return nil, err
+		}
+		r, err := newRenderer(chCfg)
+		if err != nil {
+			return nil, err
+		}
+		d.channels[chCfg.Name] = ch
+		d.renderers[chCfg.Name] = r
+	}
+
+	return d, nil
+}
+
+// Notify queues alerts that changed state for delivery.
+func (d *Dispatcher) Notify(ctx context.Context, rule alertingtypes.Rule, alerts []alertingtypes.Alert) {
+	now := d.now()
+
+	for _, a := range alerts {
+		data := AlertData{Alert: a}
+		if a.State == alertingtypes.StateFiring {
+			data.Samples = d.samples(ctx, rule, a, now)
+		}
+
+		d.mu.Lock()
+		for _, r := range d.root.match(a.Labels) {
+			if r.cfg.Channel == "" {
+				continue
+			}
+			d.add(r, data, now)
+		}
+		d.mu.Unlock()
+	}
+}
+
+// samples fetches recent log lines that match the rule and the alert's
+// group-by values.
+func (d *Dispatcher) samples(ctx context.Context, rule alertingtypes.Rule, a alertingtypes.Alert, now time.Time) []telemetrytypes.LogRecord {
+	expr, err := filter.Parse(rule.Filter)
+	if err != nil {
+		return nil
+	}
+	for _, key := range rule.GroupBy {
+		field, err := filter.ParseField(key)
+		if err != nil {
+			return nil
+		}
+		cond := &filter.Condition{Field: field, Op: filter.OpEq, Value: a.Labels[key]}
+		if expr == nil {
+			expr = cond
+		} else {
+			expr = &filter.And{Left: expr, Right: cond}
+		}
+	}
+
+	records, err := d.store.SearchLogs(ctx, rule.TenantID, expr, now.Add(-time.Duration(rule.Window)), now, sampleLimit)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to fetch sample logs for alert", "tenant", rule.TenantID, "rule", rule.Name, "error", err)
+		return nil
+	}
+	return records
+}
+
+// add puts an alert into its group. Groups never mix tenants. It must be
+// called with d.mu held.
+func (d *Dispatcher) add(r *route, a AlertData, now time.Time) {
+	labels := make(map[string]string, len(r.cfg.GroupBy)+1)
+	for _, key := range r.cfg.GroupBy {
+		labels[key] = a.Labels[key]
+	}
+	labels[alertingtypes.TenantLabel] = a.TenantID
+	key := groupKey(r.id, labels)
+
+	g, ok := d.groups[key]
+	if !ok {
+		g = &group{
+			key:       key,
+			tenantID:  a.TenantID,
+			route:     r,
+			labels:    labels,
+			alerts:    make(map[string]AlertData),
+			createdAt: now,
+		}
+		d.groups[key] = g
+	}
+	g.alerts[a.Fingerprint] = a
+	g.dirty = true
+}
+
+func groupKey(routeID string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(routeID)
+	sb.WriteString(":{")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(k + "=" + strconv.Quote(labels[k]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// Run delivers due notifications until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Flush(ctx)
+		}
+	}
+}
+
+// Flush delivers notifications for every group that is due.
+func (d *Dispatcher) Flush(ctx context.Context) {
+	now := d.now()
+
+	d.mu.Lock()
+	var due []*group
+	for _, g := range d.groups {
+		if g.due(now) {
+			due = append(due, g)
+		}
+	}
+	d.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	silences, err := d.store.ListSilences(ctx, tenanttypes.AllTenants, false)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load silences", "error", err)
+		return
+	}
+	// Regex matchers are compiled once per flush rather than for every
+	// alert they are checked against.
+	for i := range silences {
+		for j := range silences[i].Matchers {
+			silences[i].Matchers[j].Compile()
+		}
+	}
+
+	var attempts []alertingtypes.NotificationAttempt
+	for _, g := range due {
+		d.mu.Lock()
+		sent := make([]AlertData, 0, len(g.alerts))
+		for _, a := range g.alerts {
+			if !silenced(silences, g.tenantID, a.Labels, now) {
+				sent = append(sent, a)
+			}
+		}
+		d.mu.Unlock()
+
+		if len(sent) == 0 {
+			// Everything is silenced. The group stays due, so that it is
+			// sent, resolutions included, as soon as the silences expire.
+			continue
+		}
+		attempt := d.send(ctx, g, sent, now)
+		attempts = append(attempts, attempt)
+
+		d.mu.Lock()
+		g.lastFlush = now
+		if attempt.Success {
+			g.notified = true
+			g.dirty = false
+			for fp, a := range g.alerts {
+				if a.State == alertingtypes.StateResolved {
+					delete(g.alerts, fp)
+				}
+			}
+			if len(g.alerts) == 0 {
+				delete(d.groups, g.key)
+			}
+		}
+		d.mu.Unlock()
+	}
+
+	if err := d.store.InsertNotificationAttempts(ctx, attempts); err != nil {
+		slog.ErrorContext(ctx, "failed to record notification attempts", "error", err)
+	}
+}
+
+func silenced(silences []alertingtypes.Silence, tenantID string, labels map[string]string, now time.Time) bool {
+	for _, s := range silences {
+		if s.TenantID == tenantID && s.Active(now) && alertingtypes.MatchAll(s.Matchers, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// send renders and delivers one notification for a group.
+func (d *Dispatcher) send(ctx context.Context, g *group, alerts []AlertData, now time.Time) alertingtypes.NotificationAttempt {
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Fingerprint < alerts[j].Fingerprint
+	})
+
+	data := &TemplateData{
+		Status:       alertingtypes.StateResolved,
+		Channel:      g.route.cfg.Channel,
+		GroupKey:     g.key,
+		GroupLabels:  g.labels,
+		CommonLabels: commonLabels(alerts),
+		Alerts:       alerts,
+	}
+	for _, a := range alerts {
+		if a.State == alertingtypes.StateFiring {
+			data.Status = alertingtypes.StateFiring
+			break
+		}
+	}
+
+	attempt := alertingtypes.NotificationAttempt{
+		Timestamp:  now,
+		TenantID:   g.tenantID,
+		Channel:    data.Channel,
+		GroupKey:   g.key,
+		Status:     data.Status,
+		AlertCount: len(alerts),
+	}
+
+	err := d.deliver(ctx, data)
+	if err != nil {
+		attempt.ErrorCode = string(errors.CodeUnknown)
+		var e *errors.Error
+		if stderrors.As(err, &e) {
+			attempt.ErrorCode = string(e.Code)
+		}
+		attempt.Error = err.Error()
+		slog.ErrorContext(ctx, "failed to send notification", "channel", data.Channel, "error", err)
+		return attempt
+	}
+
+	attempt.Success = true
+	return attempt
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, data *TemplateData) error {
+	ch, ok := d.channels[data.Channel]
+	if !ok {
+		return errors.New(errors.CodeInvalidConfig, "unknown channel "+data.Channel, errors.SeverityError)
+	}
+
+	msg, err := d.renderers[data.Channel].render(data)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return ch.Send(ctx, msg)
+}
+
+// commonLabels returns the labels shared by every alert.
+func commonLabels(alerts []AlertData) map[string]string {
+	common := make(map[string]string)
+	if len(alerts) == 0 {
+		return common
+	}
+	for k, v := range alerts[0].Labels {
+		common[k] = v
+	}
+	for _, a := range alerts[1:] {
+		for k, v := range common {
+			if a.Labels[k] != v {
+				delete(common, k)
+			}
+		}
+	}
+	return common
+}
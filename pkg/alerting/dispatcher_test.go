@@ -0,0 +1,252 @@
+package alerting_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/alerting"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDispatcherStore struct {
+	mu       sync.Mutex
+	silences []alertingtypes.Silence
+	attempts []alertingtypes.NotificationAttempt
+}
+
+func (f *fakeDispatcherStore) SearchLogs(context.Context, string, filter.Expr, time.Time, time.Time, int) ([]telemetrytypes.LogRecord, error) {
+	return []telemetrytypes.LogRecord{{Timestamp: time.Now(), SeverityText: "ERROR", Body: "payment gateway timeout"}}, nil
+}
+
+func (f *fakeDispatcherStore) ListSilences(context.Context, string, bool) ([]alertingtypes.Silence, error) {
+	return f.silences, nil
+}
+
+func (f *fakeDispatcherStore) InsertNotificationAttempts(_ context.Context, a []alertingtypes.NotificationAttempt) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts = append(f.attempts, a...)
+	return nil
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	status int
+	bodies []string
+}
+
+func (r *recorder) handler(w http.ResponseWriter, req *http.Request) {
+	b, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, string(b))
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+}
+
+func firingAlert(service string) alertingtypes.Alert {
+	labels := map[string]string{"alertname": "ErrorSpike", "service": service, "team": "payments"}
+	return alertingtypes.Alert{
+		TenantID:    "default",
+		RuleID:      "rule-1",
+		RuleName:    "ErrorSpike",
+		Fingerprint: alertingtypes.Fingerprint("rule-1", labels),
+		Labels:      labels,
+		State:       alertingtypes.StateFiring,
+		Value:       42,
+	}
+}
+
+func newTestDispatcher(t *testing.T, store *fakeDispatcherStore, payments, fallback string) *alerting.Dispatcher {
+	cfg := alerting.Config{
+		Channels: []alerting.ChannelConfig{
+			{Name: "payments", Type: alerting.ChannelSlack, URL: payments},
+			{Name: "default", Type: alerting.ChannelWebhook, URL: fallback},
+		},
+		Route: alerting.RouteConfig{
+			Channel: "default",
+			GroupBy: []string{"alertname"},
+			Routes: []alerting.RouteConfig{
+				{Channel: "payments", Matchers: []alertingtypes.Matcher{{Name: "team", Value: "pay.*", IsRegex: true}}},
+			},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	d, err := alerting.NewDispatcher(cfg, store)
+	require.NoError(t, err)
+	return d
+}
+
+func TestDispatcherRoutesAndGroups(t *testing.T) {
+	payments, fallback := &recorder{}, &recorder{}
+	paySrv := httptest.NewServer(http.HandlerFunc(payments.handler))
+	defer paySrv.Close()
+	defSrv := httptest.NewServer(http.HandlerFunc(fallback.handler))
+	defer defSrv.Close()
+
+	store := &fakeDispatcherStore{}
+	d := newTestDispatcher(t, store, paySrv.URL, defSrv.URL)
+	ctx := context.Background()
+
+	d.Notify(ctx, errorSpikeRule(), []alertingtypes.Alert{firingAlert("checkout"), firingAlert("cart")})
+	d.Flush(ctx)
+
+	require.Len(t, payments.bodies, 1, "both alerts share a group")
+	assert.Empty(t, fallback.bodies)
+
+	var msg map[string]string
+	require.NoError(t, json.Unmarshal([]byte(payments.bodies[0]), &msg))
+	assert.Contains(t, msg["text"], "[FIRING:2] ErrorSpike")
+	assert.Contains(t, msg["text"], "payment gateway timeout")
+
+	require.Len(t, store.attempts, 1)
+	assert.True(t, store.attempts[0].Success)
+	assert.Equal(t, 2, store.attempts[0].AlertCount)
+
+	// Nothing changed and the repeat interval has not passed.
+	d.Flush(ctx)
+	assert.Len(t, payments.bodies, 1)
+}
+
+func TestDispatcherSilences(t *testing.T) {
+	payments := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(payments.handler))
+	defer srv.Close()
+
+	store := &fakeDispatcherStore{
+		silences: []alertingtypes.Silence{{
+			ID:       "s1",
+			TenantID: "default",
+			Matchers: []alertingtypes.Matcher{{Name: "service", Value: "checkout"}},
+			StartsAt: time.Now().Add(-time.Minute),
+			EndsAt:   time.Now().Add(time.Hour),
+		}},
+	}
+	d := newTestDispatcher(t, store, srv.URL, srv.URL)
+	ctx := context.Background()
+
+	d.Notify(ctx, errorSpikeRule(), []alertingtypes.Alert{firingAlert("checkout")})
+	d.Flush(ctx)
+
+	assert.Empty(t, payments.bodies)
+	assert.Empty(t, store.attempts)
+}
+
+func TestDispatcherSendsWhenSilencesExpire(t *testing.T) {
+	payments := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(payments.handler))
+	defer srv.Close()
+
+	cfg := alerting.Config{
+		Channels: []alerting.ChannelConfig{{Name: "payments", Type: alerting.ChannelSlack, URL: srv.URL}},
+		Route: alerting.RouteConfig{
+			Channel:       "payments",
+			GroupBy:       []string{"alertname"},
+			GroupInterval: time.Nanosecond,
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	silence := alertingtypes.Silence{
+		ID:       "s1",
+		TenantID: "default",
+		Matchers: []alertingtypes.Matcher{{Name: "service", Value: "checkout"}},
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   time.Now().Add(time.Hour),
+	}
+	store := &fakeDispatcherStore{silences: []alertingtypes.Silence{silence}}
+	d, err := alerting.NewDispatcher(cfg, store)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	d.Notify(ctx, errorSpikeRule(), []alertingtypes.Alert{firingAlert("checkout")})
+	d.Flush(ctx)
+	assert.Empty(t, payments.bodies)
+
+	silence.EndsAt = time.Now().Add(-time.Second)
+	store.silences = []alertingtypes.Silence{silence}
+	d.Flush(ctx)
+	require.Len(t, store.attempts, 1)
+	assert.Equal(t, alertingtypes.StateFiring, store.attempts[0].Status)
+
+	// A resolution that happens while silenced is sent once the silence
+	// expires rather than forgotten.
+	silence.EndsAt = time.Now().Add(time.Hour)
+	store.silences = []alertingtypes.Silence{silence}
+	resolved := firingAlert("checkout")
+	resolved.State = alertingtypes.StateResolved
+	d.Notify(ctx, errorSpikeRule(), []alertingtypes.Alert{resolved})
+	d.Flush(ctx)
+	assert.Len(t, store.attempts, 1)
+
+	silence.EndsAt = time.Now().Add(-time.Second)
+	store.silences = []alertingtypes.Silence{silence}
+	d.Flush(ctx)
+	require.Len(t, store.attempts, 2)
+	assert.Equal(t, alertingtypes.StateResolved, store.attempts[1].Status)
+
+	d.Flush(ctx)
+	assert.Len(t, store.attempts, 2)
+}
+
+func TestDispatcherSilencesAreTenantScoped(t *testing.T) {
+	payments := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(payments.handler))
+	defer srv.Close()
+
+	store := &fakeDispatcherStore{
+		silences: []alertingtypes.Silence{{
+			ID:       "s1",
+			TenantID: "team-b",
+			Matchers: []alertingtypes.Matcher{{Name: "service", Value: "checkout"}},
+			StartsAt: time.Now().Add(-time.Minute),
+			EndsAt:   time.Now().Add(time.Hour),
+		}},
+	}
+	d := newTestDispatcher(t, store, srv.URL, srv.URL)
+	ctx := context.Background()
+
+	d.Notify(ctx, errorSpikeRule(), []alertingtypes.Alert{firingAlert("checkout")})
+	d.Flush(ctx)
+
+	assert.Len(t, payments.bodies, 1)
+	require.Len(t, store.attempts, 1)
+	assert.Equal(t, "default", store.attempts[0].TenantID)
+}
+
+func TestDispatcherRecordsFailures(t *testing.T) {
+	payments := &recorder{status: http.StatusBadGateway}
+	srv := httptest.NewServer(http.HandlerFunc(payments.handler))
+	defer srv.Close()
+
+	store := &fakeDispatcherStore{}
+	d := newTestDispatcher(t, store, srv.URL, srv.URL)
+	ctx := context.Background()
+
+	d.Notify(ctx, errorSpikeRule(), []alertingtypes.Alert{firingAlert("checkout")})
+	d.Flush(ctx)
+
+	require.Len(t, store.attempts, 1)
+	assert.False(t, store.attempts[0].Success)
+	assert.Equal(t, string(errors.CodeExternalBadResponse), store.attempts[0].ErrorCode)
+
+	srv.Close()
+	d2 := newTestDispatcher(t, store, srv.URL, srv.URL)
+	d2.Notify(ctx, errorSpikeRule(), []alertingtypes.Alert{firingAlert("checkout")})
+	d2.Flush(ctx)
+
+	require.Len(t, store.attempts, 2)
+	assert.Equal(t, string(errors.CodeExternalUnreachable), store.attempts[1].ErrorCode)
+}
@@ -0,0 +1,290 @@
+package alerting
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/anomalytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// Store is the storage the engine evaluates rules against.
+type Store interface {
+	ListAlertRules(ctx context.Context, tenantID string) ([]alertingtypes.Rule, error)
+	CountLogs(ctx context.Context, tenantID string, expr filter.Expr, start, end time.Time, groupBy []string) ([]clickhousestore.AggregateRow, error)
+	CountAnomalies(ctx context.Context, tenantID string, kinds []anomalytypes.Kind, expr filter.Expr, start, end time.Time, groupBy []string) ([]clickhousestore.AggregateRow, error)
+	InsertAlertTransitions(ctx context.Context, transitions []alertingtypes.Transition) error
+}
+
+// Notifier receives alerts that changed state during an evaluation.
+type Notifier interface {
+	Notify(ctx context.Context, rule alertingtypes.Rule, alerts []alertingtypes.Alert)
+}
+
+const (
+	// defaultTick is how often the engine checks for rules that are due.
+	defaultTick = 10 * time.Second
+	// resolvedRetention is how long resolved alerts stay visible.
+	resolvedRetention = 15 * time.Minute
+)
+
+// Engine evaluates alert rules on a schedule and tracks alert state.
+type Engine struct {
+	store    Store
+	notifier Notifier
+	tick     time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	alerts   map[string]map[string]*alertingtypes.Alert // rule id -> fingerprint -> alert
+	nextEval map[string]time.Time
+}
+
+// NewEngine returns an engine that evaluates the rules in store.
+// notifier may be nil.
+func NewEngine(store Store, notifier Notifier) *Engine {
+	return &Engine{
+		store:    store,
+		notifier: notifier,
+		tick:     defaultTick,
+		now:      time.Now,
+		alerts:   make(map[string]map[string]*alertingtypes.Alert),
+		nextEval: make(map[string]time.Time),
+	}
+}
+
+// Run evaluates due rules until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "starting alert rule engine")
+
+	ticker := time.NewTicker(e.tick)
+	defer ticker.Stop()
+
+	for {
+		e.evaluateDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) evaluateDue(ctx context.Context) {
+	rules, err := e.store.ListAlertRules(ctx, tenanttypes.AllTenants)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load alert rules", "error", err)
+		return
+	}
+
+	now := e.now()
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.ID] = true
+		if !rule.Enabled {
+			continue
+		}
+
+		e.mu.Lock()
+		due := !now.Before(e.nextEval[rule.ID])
+		if due {
+			e.nextEval[rule.ID] = now.Add(rule.EvalInterval())
+		}
+		e.mu.Unlock()
+
+		if due {
+			if err := e.Evaluate(ctx, rule, now); err != nil {
+				slog.ErrorContext(ctx, "failed to evaluate alert rule", "tenant", rule.TenantID, "rule", rule.Name, "error", err)
+			}
+		}
+	}
+
+	// Forget rules that were deleted or disabled.
+	e.mu.Lock()
+	for id := range e.alerts {
+		if !known[id] {
+			delete(e.alerts, id)
+			delete(e.nextEval, id)
+		}
+	}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			delete(e.alerts, rule.ID)
+		}
+	}
+	e.mu.Unlock()
+}
+
+// Evaluate runs a single rule at the given time, updates alert state and
+// persists any state transitions.
+func (e *Engine) Evaluate(ctx context.Context, rule alertingtypes.Rule, now time.Time) error {
+	expr, err := filter.Parse(rule.Filter)
+	if err != nil {
+		return err
+	}
+
+	window := time.Duration(rule.Window)
+	var rows []clickhousestore.AggregateRow
+	if rule.Source == alertingtypes.SourceAnomalies {
+		rows, err = e.store.CountAnomalies(ctx, rule.TenantID, rule.AnomalyKinds, expr, now.Add(-window), now, rule.GroupBy)
+	} else {
+		rows, err = e.store.CountLogs(ctx, rule.TenantID, expr, now.Add(-window), now, rule.GroupBy)
+	}
+	if err != nil {
+		return err
+	}
+
+	// A query without group-by returns a single row, which may be a zero
+	// count that still has to be compared (e.g. "fewer than 10 logs").
+	if len(rule.GroupBy) == 0 && len(rows) == 0 {
+		rows = []clickhousestore.AggregateRow{{Group: map[string]string{}}}
+	}
+
+	e.mu.Lock()
+	transitions, changed := e.apply(rule, rows, now)
+	e.mu.Unlock()
+
+	if err := e.store.InsertAlertTransitions(ctx, transitions); err != nil {
+		return err
+	}
+
+	if e.notifier != nil && len(changed) > 0 {
+		e.notifier.Notify(ctx, rule, changed)
+	}
+	return nil
+}
+
+// apply moves the alerts of a rule through the pending, firing and resolved
+// states. It must be called with e.mu held.
+func (e *Engine) apply(rule alertingtypes.Rule, rows []clickhousestore.AggregateRow, now time.Time) ([]alertingtypes.Transition, []alertingtypes.Alert) {
+	current := e.alerts[rule.ID]
+	if current == nil {
+		current = make(map[string]*alertingtypes.Alert)
+		e.alerts[rule.ID] = current
+	}
+
+	var (
+		transitions []alertingtypes.Transition
+		changed     []alertingtypes.Alert
+		seen        = make(map[string]bool, len(rows))
+	)
+
+	transition := func(a *alertingtypes.Alert, to alertingtypes.State) {
+		transitions = append(transitions, alertingtypes.Transition{
+			TenantID:    a.TenantID,
+			RuleID:      a.RuleID,
+			RuleName:    a.RuleName,
+			Fingerprint: a.Fingerprint,
+			Labels:      a.Labels,
+			From:        a.State,
+			To:          to,
+			Value:       a.Value,
+			Timestamp:   now,
+		})
+		a.State = to
+		if to == alertingtypes.StateFiring || to == alertingtypes.StateResolved {
+			changed = append(changed, *a)
+		}
+	}
+
+	for _, row := range rows {
+		value := float64(row.Count)
+		if rule.Aggregation == alertingtypes.AggregationRate {
+			value = value / time.Duration(rule.Window).Seconds()
+		}
+		if !rule.Comparator.Compare(value, rule.Threshold) {
+			continue
+		}
+
+		labels := alertLabels(rule, row.Group)
+		fp := alertingtypes.Fingerprint(rule.ID, labels)
+		seen[fp] = true
+
+		a, ok := current[fp]
+		if !ok || a.State == alertingtypes.StateResolved {
+			a = &alertingtypes.Alert{
+				TenantID:    rule.TenantID,
+				RuleID:      rule.ID,
+				RuleName:    rule.Name,
+				Fingerprint: fp,
+				Labels:      labels,
+				Annotations: rule.Annotations,
+				State:       alertingtypes.StateInactive,
+				ActiveAt:    now,
+			}
+			current[fp] = a
+		}
+		a.Value = value
+		a.LastEvaluatedAt = now
+
+		if a.State == alertingtypes.StateInactive {
+			transition(a, alertingtypes.StatePending)
+		}
+		if a.State == alertingtypes.StatePending && now.Sub(a.ActiveAt) >= time.Duration(rule.For) {
+			a.FiredAt = now
+			transition(a, alertingtypes.StateFiring)
+		}
+	}
+
+	for fp, a := range current {
+		if seen[fp] {
+			continue
+		}
+		switch a.State {
+		case alertingtypes.StatePending:
+			transition(a, alertingtypes.StateInactive)
+			delete(current, fp)
+		case alertingtypes.StateFiring:
+			a.ResolvedAt = now
+			a.LastEvaluatedAt = now
+			transition(a, alertingtypes.StateResolved)
+		case alertingtypes.StateResolved:
+			if now.Sub(a.ResolvedAt) >= resolvedRetention {
+				delete(current, fp)
+			}
+		}
+	}
+
+	return transitions, changed
+}
+
+// alertLabels merges the static rule labels with the group-by values.
+func alertLabels(rule alertingtypes.Rule, group map[string]string) map[string]string {
+	labels := make(map[string]string, len(rule.Labels)+len(group)+2)
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+	for k, v := range group {
+		labels[k] = v
+	}
+	labels["alertname"] = rule.Name
+	labels[alertingtypes.TenantLabel] = rule.TenantID
+	return labels
+}
+
+// Alerts returns the alerts that are pending, firing or recently resolved.
+func (e *Engine) Alerts(tenantID string) []alertingtypes.Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []alertingtypes.Alert
+	for _, byFingerprint := range e.alerts {
+		for _, a := range byFingerprint {
+			if tenantID == tenanttypes.AllTenants || a.TenantID == tenantID {
+				alerts = append(alerts, *a)
+			}
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].ActiveAt.After(alerts[j].ActiveAt)
+	})
+	return alerts
+}
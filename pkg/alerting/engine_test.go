@@ -0,0 +1,171 @@
+package alerting_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/alerting"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/anomalytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	rows        []clickhousestore.AggregateRow
+	anomalies   []clickhousestore.AggregateRow
+	kinds       []anomalytypes.Kind
+	transitions []alertingtypes.Transition
+}
+
+func (f *fakeStore) ListAlertRules(context.Context, string) ([]alertingtypes.Rule, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) CountLogs(context.Context, string, filter.Expr, time.Time, time.Time, []string) ([]clickhousestore.AggregateRow, error) {
+	return f.rows, nil
+}
+
+func (f *fakeStore) CountAnomalies(_ context.Context, _ string, kinds []anomalytypes.Kind, _ filter.Expr, _, _ time.Time, _ []string) ([]clickhousestore.AggregateRow, error) {
+	f.kinds = kinds
+	return f.anomalies, nil
+}
+
+func (f *fakeStore) InsertAlertTransitions(_ context.Context, t []alertingtypes.Transition) error {
+	f.transitions = append(f.transitions, t...)
+	return nil
+}
+
+type fakeNotifier struct {
+	alerts []alertingtypes.Alert
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, _ alertingtypes.Rule, alerts []alertingtypes.Alert) {
+	f.alerts = append(f.alerts, alerts...)
+}
+
+func errorSpikeRule() alertingtypes.Rule {
+	return alertingtypes.Rule{
+		ID:          "rule-1",
+		TenantID:    "default",
+		Name:        "ErrorSpike",
+		Filter:      `severity_number >= 17`,
+		Aggregation: alertingtypes.AggregationCount,
+		Comparator:  alertingtypes.ComparatorGt,
+		Threshold:   10,
+		Window:      alertingtypes.Duration(5 * time.Minute),
+		For:         alertingtypes.Duration(2 * time.Minute),
+		GroupBy:     []string{"service"},
+		Enabled:     true,
+	}
+}
+
+func states(transitions []alertingtypes.Transition) []alertingtypes.State {
+	var out []alertingtypes.State
+	for _, t := range transitions {
+		out = append(out, t.To)
+	}
+	return out
+}
+
+func TestEngineLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{}
+	notifier := &fakeNotifier{}
+	engine := alerting.NewEngine(store, notifier)
+	rule := errorSpikeRule()
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	store.rows = []clickhousestore.AggregateRow{
+		{Group: map[string]string{"service": "checkout"}, Count: 50},
+		{Group: map[string]string{"service": "cart"}, Count: 3},
+	}
+	require.NoError(t, engine.Evaluate(ctx, rule, start))
+	assert.Equal(t, []alertingtypes.State{alertingtypes.StatePending}, states(store.transitions))
+	require.Len(t, engine.Alerts("default"), 1)
+	assert.Equal(t, "checkout", engine.Alerts("default")[0].Labels["service"])
+	assert.Equal(t, "default", engine.Alerts("default")[0].Labels[alertingtypes.TenantLabel])
+	assert.Empty(t, engine.Alerts("team-b"))
+	assert.Empty(t, notifier.alerts)
+
+	// Still breaching, but not for long enough.
+	require.NoError(t, engine.Evaluate(ctx, rule, start.Add(time.Minute)))
+	assert.Len(t, store.transitions, 1)
+
+	require.NoError(t, engine.Evaluate(ctx, rule, start.Add(2*time.Minute)))
+	assert.Equal(t, alertingtypes.StateFiring, store.transitions[len(store.transitions)-1].To)
+	require.Len(t, notifier.alerts, 1)
+	assert.Equal(t, alertingtypes.StateFiring, notifier.alerts[0].State)
+
+	// Condition clears.
+	store.rows = nil
+	require.NoError(t, engine.Evaluate(ctx, rule, start.Add(3*time.Minute)))
+	assert.Equal(t, alertingtypes.StateResolved, store.transitions[len(store.transitions)-1].To)
+	require.Len(t, notifier.alerts, 2)
+	assert.Equal(t, alertingtypes.StateResolved, engine.Alerts("default")[0].State)
+
+	// Resolved alerts are forgotten after the retention period.
+	require.NoError(t, engine.Evaluate(ctx, rule, start.Add(time.Hour)))
+	assert.Empty(t, engine.Alerts("default"))
+}
+
+func TestEnginePendingClears(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{}
+	engine := alerting.NewEngine(store, nil)
+	rule := errorSpikeRule()
+	now := time.Now()
+
+	store.rows = []clickhousestore.AggregateRow{{Group: map[string]string{"service": "checkout"}, Count: 11}}
+	require.NoError(t, engine.Evaluate(ctx, rule, now))
+
+	store.rows = []clickhousestore.AggregateRow{{Group: map[string]string{"service": "checkout"}, Count: 10}}
+	require.NoError(t, engine.Evaluate(ctx, rule, now.Add(time.Minute)))
+
+	assert.Equal(t, []alertingtypes.State{alertingtypes.StatePending, alertingtypes.StateInactive}, states(store.transitions))
+	assert.Empty(t, engine.Alerts("default"))
+}
+
+func TestEngineRateWithoutGroupBy(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{}
+	engine := alerting.NewEngine(store, nil)
+
+	rule := errorSpikeRule()
+	rule.GroupBy = nil
+	rule.For = 0
+	rule.Aggregation = alertingtypes.AggregationRate
+	rule.Comparator = alertingtypes.ComparatorLt
+	rule.Threshold = 0.5
+
+	// No rows at all means a rate of zero, which is below the threshold.
+	require.NoError(t, engine.Evaluate(ctx, rule, time.Now()))
+	assert.Equal(t, []alertingtypes.State{alertingtypes.StatePending, alertingtypes.StateFiring}, states(store.transitions))
+}
+
+func TestEngineAnomalies(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{}
+	engine := alerting.NewEngine(store, nil)
+
+	rule := errorSpikeRule()
+	rule.Source = alertingtypes.SourceAnomalies
+	rule.AnomalyKinds = []anomalytypes.Kind{anomalytypes.KindNewError}
+	rule.Filter = `service = "checkout"`
+	rule.Threshold = 0
+	rule.For = 0
+	require.NoError(t, rule.Validate())
+
+	store.rows = []clickhousestore.AggregateRow{{Group: map[string]string{"service": "checkout"}, Count: 50}}
+	store.anomalies = []clickhousestore.AggregateRow{{Group: map[string]string{"service": "checkout"}, Count: 1}}
+	require.NoError(t, engine.Evaluate(ctx, rule, time.Now()))
+	assert.Equal(t, rule.AnomalyKinds, store.kinds)
+	require.Len(t, engine.Alerts("default"), 1)
+	assert.EqualValues(t, 1, engine.Alerts("default")[0].Value)
+
+	rule.Filter = `body contains "x"`
+	assert.Error(t, rule.Validate())
+}
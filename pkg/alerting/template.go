@@ -0,0 +1,81 @@
+package alerting
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+const (
+	defaultTitleTemplate = `[{{ .Status | upper }}{{ if gt (len .Alerts) 1 }}:{{ len .Alerts }}{{ end }}] {{ index .CommonLabels "alertname" }}`
+
+	defaultBodyTemplate = `{{ range .Alerts -}}
+{{ .State | upper }} {{ .RuleName }} (value {{ printf "%.2f" .Value }})
+{{- range $k, $v := .Labels }}
+  {{ $k }}={{ $v }}
+{{- end }}
+{{- with .Annotations.summary }}
+  {{ . }}
+{{- end }}
+{{- if .Samples }}
+  Sample logs:
+{{- range .Samples }}
+    {{ .Timestamp.Format "2006-01-02T15:04:05Z07:00" }} {{ .SeverityText }} {{ .Body }}
+{{- end }}
+{{- end }}
+
+{{ end }}`
+)
+
+// TemplateData is the value notification templates are rendered with.
+type TemplateData struct {
+	// Status is "firing" if any alert in the group is firing, else "resolved".
+	Status       alertingtypes.State `json:"status"`
+	Channel      string              `json:"channel"`
+	GroupKey     string              `json:"group_key"`
+	GroupLabels  map[string]string   `json:"group_labels"`
+	CommonLabels map[string]string   `json:"common_labels"`
+	Alerts       []AlertData         `json:"alerts"`
+}
+
+// AlertData is an alert together with sample log lines that triggered it.
+type AlertData struct {
+	alertingtypes.Alert
+	Samples []telemetrytypes.LogRecord `json:"samples,omitempty"`
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": func(v any) string {
+		switch s := v.(type) {
+		case alertingtypes.State:
+			return strings.ToUpper(string(s))
+		case string:
+			return strings.ToUpper(s)
+		}
+		return ""
+	},
+	"join": strings.Join,
+	"since": func(t time.Time) string {
+		return time.Since(t).Round(time.Second).String()
+	},
+}
+
+// parseTemplate parses text, falling back to def when text is empty.
+func parseTemplate(name, text, def string) (*template.Template, error) {
+	if text == "" {
+		text = def
+	}
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+func render(t *template.Template, data *TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,105 @@
+package anomaly
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+)
+
+type Config struct {
+	// Enabled turns on the background detector.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Window is the span of logs evaluated at a time. It must divide an
+	// hour.
+	Window time.Duration `mapstructure:"window"`
+
+	// Weeks is how many past weeks the baseline of an hour of the week is
+	// computed from, and MinWeeks how many of them must have records of a
+	// service and severity before its volume is judged.
+	Weeks    int `mapstructure:"weeks"`
+	MinWeeks int `mapstructure:"min_weeks"`
+
+	// Threshold is the z-score, the distance from the baseline in standard
+	// deviations, beyond which the volume of a window is anomalous.
+	Threshold float64 `mapstructure:"threshold"`
+
+	// MinCount ignores volume changes where both the window and its
+	// baseline have fewer records than this.
+	MinCount float64 `mapstructure:"min_count"`
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("anomaly"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	cfg := Config{
+		Enabled:   true,
+		Window:    envDuration("WATCHDATA_ANOMALY_WINDOW", 5*time.Minute),
+		Weeks:     envInt("WATCHDATA_ANOMALY_WEEKS", 4),
+		MinWeeks:  envInt("WATCHDATA_ANOMALY_MIN_WEEKS", 2),
+		Threshold: envFloat("WATCHDATA_ANOMALY_THRESHOLD", 3),
+		MinCount:  envFloat("WATCHDATA_ANOMALY_MIN_COUNT", 10),
+	}
+	if v := os.Getenv("WATCHDATA_ANOMALY_ENABLED"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			cfg.Enabled = parsed
+		}
+	}
+	return cfg
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func envFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func (c Config) Validate() error {
+	if c.Window < time.Minute || time.Hour%c.Window != 0 {
+		return fmt.Errorf("anomaly window must be at least 1m and divide an hour")
+	}
+	if c.Weeks < 1 || c.MinWeeks < 1 || c.MinWeeks > c.Weeks {
+		return fmt.Errorf("anomaly weeks must be at least 1, and min weeks between 1 and weeks")
+	}
+	if c.Threshold <= 0 {
+		return fmt.Errorf("anomaly threshold must be positive")
+	}
+	if c.MinCount < 0 {
+		return fmt.Errorf("anomaly min count must not be negative")
+	}
+	return nil
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,223 @@
+// Package anomaly detects unusual log volume and new log patterns.
+package anomaly
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/types/anomalytypes"
+)
+
+const (
+	week = 7 * 24 * time.Hour
+
+	// settle is how long the detector waits after a window ends before
+	// evaluating it, so that records ingested a little late are counted.
+	settle = time.Minute
+
+	// tick is how often the detector checks for a window to evaluate.
+	tick = 30 * time.Second
+
+	// errorSeverity is the lowest severity number of ERROR.
+	errorSeverity = 17
+)
+
+// Store is the storage the detector reads logs from and writes findings to.
+type Store interface {
+	CountLogVolume(ctx context.Context, start, end time.Time, bucket time.Duration) ([]clickhousestore.VolumeRow, error)
+	NewPatterns(ctx context.Context, start, end time.Time) ([]clickhousestore.PatternActivity, error)
+	InsertAnomalies(ctx context.Context, findings []anomalytypes.Finding) error
+}
+
+// Detector evaluates every window of every tenant's logs as it ends.
+//
+// The volume of each service at each severity is compared with a seasonal
+// baseline: the windows of the same hour of the week in the past weeks.
+// Patterns first seen in the window are reported as new, or as new errors
+// if any of their records is an error.
+type Detector struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+
+	// last is the end of the last window evaluated.
+	last time.Time
+}
+
+func NewDetector(cfg Config, store Store) *Detector {
+	return &Detector{cfg: cfg, store: store, now: time.Now}
+}
+
+// SetClock replaces the clock, for tests.
+func (d *Detector) SetClock(now func() time.Time) {
+	d.now = now
+}
+
+// Run evaluates windows as they end until ctx is cancelled. Windows that
+// ended while the detector was not running are skipped.
+func (d *Detector) Run(ctx context.Context) error {
+	slog.InfoContext(ctx, "starting anomaly detector", "window", d.cfg.Window)
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		end := d.now().Add(-settle).Truncate(d.cfg.Window)
+		if end.After(d.last) {
+			d.last = end
+			findings, err := d.Detect(ctx, end)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to detect anomalies", "window_end", end, "error", err)
+			} else if len(findings) > 0 {
+				slog.InfoContext(ctx, "detected anomalies", "window_end", end, "findings", len(findings))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Detect evaluates the window ending at end and stores its findings.
+// Evaluating a window again replaces its findings.
+func (d *Detector) Detect(ctx context.Context, end time.Time) ([]anomalytypes.Finding, error) {
+	start := end.Add(-d.cfg.Window)
+
+	findings, err := d.volume(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := d.newPatterns(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, patterns...)
+
+	if err := d.store.InsertAnomalies(ctx, findings); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// series identifies the records of one service at one severity.
+type series struct {
+	tenantID, service, severity string
+}
+
+// baseline accumulates the windows of one series in the past weeks.
+type baseline struct {
+	weeks      map[int]bool
+	sum, sumSq float64
+}
+
+func (d *Detector) volume(ctx context.Context, start, end time.Time) ([]anomalytypes.Finding, error) {
+	rows, err := d.store.CountLogVolume(ctx, start, end, d.cfg.Window)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[series]float64)
+	for _, r := range rows {
+		current[series{r.TenantID, r.Service, r.SeverityText}] += float64(r.Count)
+	}
+
+	// The windows of the same hour of the week, in UTC.
+	hour := start.UTC().Truncate(time.Hour)
+	baselines := make(map[series]*baseline)
+	for k := 1; k <= d.cfg.Weeks; k++ {
+		from := hour.Add(-time.Duration(k) * week)
+		rows, err := d.store.CountLogVolume(ctx, from, from.Add(time.Hour), d.cfg.Window)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			key := series{r.TenantID, r.Service, r.SeverityText}
+			b, ok := baselines[key]
+			if !ok {
+				b = &baseline{weeks: make(map[int]bool)}
+				baselines[key] = b
+			}
+			b.weeks[k] = true
+			n := float64(r.Count)
+			b.sum += n
+			b.sumSq += n * n
+		}
+	}
+
+	var findings []anomalytypes.Finding
+	windowsPerHour := float64(time.Hour / d.cfg.Window)
+	for key, b := range baselines {
+		if len(b.weeks) < d.cfg.MinWeeks {
+			continue
+		}
+		// Windows without records count as zero.
+		n := float64(len(b.weeks)) * windowsPerHour
+		mean := b.sum / n
+		variance := math.Max(b.sumSq/n-mean*mean, 0)
+		// Counts vary at least as much as a Poisson process would.
+		stddev := math.Max(math.Sqrt(variance), math.Max(math.Sqrt(mean), 1))
+
+		value := current[key]
+		if math.Max(value, mean) < d.cfg.MinCount {
+			continue
+		}
+		score := (value - mean) / stddev
+		if math.Abs(score) < d.cfg.Threshold {
+			continue
+		}
+
+		kind := anomalytypes.KindVolumeSpike
+		if score < 0 {
+			kind = anomalytypes.KindVolumeDrop
+		}
+		findings = append(findings, anomalytypes.Finding{
+			ID:           anomalytypes.FindingID(key.tenantID, kind, key.service, key.severity, "", start),
+			TenantID:     key.tenantID,
+			Kind:         kind,
+			Service:      key.service,
+			SeverityText: key.severity,
+			Value:        value,
+			Expected:     mean,
+			Score:        score,
+			WindowStart:  start,
+			WindowEnd:    end,
+			DetectedAt:   d.now(),
+		})
+	}
+	return findings, nil
+}
+
+func (d *Detector) newPatterns(ctx context.Context, start, end time.Time) ([]anomalytypes.Finding, error) {
+	activity, err := d.store.NewPatterns(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]anomalytypes.Finding, 0, len(activity))
+	for _, a := range activity {
+		kind := anomalytypes.KindNewPattern
+		if a.SeverityNumber >= errorSeverity {
+			kind = anomalytypes.KindNewError
+		}
+		findings = append(findings, anomalytypes.Finding{
+			ID:           anomalytypes.FindingID(a.Pattern.TenantID, kind, "", "", a.Pattern.ID, start),
+			TenantID:     a.Pattern.TenantID,
+			Kind:         kind,
+			Service:      a.Service,
+			SeverityText: a.SeverityText,
+			PatternID:    a.Pattern.ID,
+			Template:     a.Pattern.Template,
+			Sample:       a.Sample,
+			Value:        float64(a.Count),
+			WindowStart:  start,
+			WindowEnd:    end,
+			DetectedAt:   d.now(),
+		})
+	}
+	return findings, nil
+}
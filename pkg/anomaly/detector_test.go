@@ -0,0 +1,111 @@
+package anomaly_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/anomaly"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/types/anomalytypes"
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var end = time.Date(2025, 3, 10, 14, 30, 0, 0, time.UTC)
+
+// fakeStore serves per-window counts: every window of the past weeks gets
+// past, the evaluated window gets now.
+type fakeStore struct {
+	past, now map[string]uint64
+	weeks     int
+	patterns  []clickhousestore.PatternActivity
+	stored    []anomalytypes.Finding
+}
+
+func (f *fakeStore) CountLogVolume(_ context.Context, start, stop time.Time, bucket time.Duration) ([]clickhousestore.VolumeRow, error) {
+	counts := f.past
+	if !start.Before(end.Add(-bucket)) {
+		counts = f.now
+	} else if start.Before(end.Add(-time.Duration(f.weeks) * 7 * 24 * time.Hour).Truncate(time.Hour)) {
+		return nil, nil
+	}
+
+	var rows []clickhousestore.VolumeRow
+	for b := start; b.Before(stop); b = b.Add(bucket) {
+		for service, n := range counts {
+			rows = append(rows, clickhousestore.VolumeRow{TenantID: "acme", Service: service, SeverityText: "INFO", Bucket: b, Count: n})
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) NewPatterns(context.Context, time.Time, time.Time) ([]clickhousestore.PatternActivity, error) {
+	return f.patterns, nil
+}
+
+func (f *fakeStore) InsertAnomalies(_ context.Context, findings []anomalytypes.Finding) error {
+	f.stored = append(f.stored, findings...)
+	return nil
+}
+
+func config() anomaly.Config {
+	return anomaly.Config{Enabled: true, Window: 5 * time.Minute, Weeks: 4, MinWeeks: 2, Threshold: 3, MinCount: 10}
+}
+
+func kinds(findings []anomalytypes.Finding) map[string]anomalytypes.Kind {
+	out := make(map[string]anomalytypes.Kind)
+	for _, f := range findings {
+		out[f.Service+f.PatternID] = f.Kind
+	}
+	return out
+}
+
+func TestVolume(t *testing.T) {
+	store := &fakeStore{
+		weeks: 4,
+		past:  map[string]uint64{"checkout": 100, "cart": 100, "search": 100, "quiet": 2},
+		now:   map[string]uint64{"checkout": 400, "cart": 105, "quiet": 8, "brand-new": 5000},
+	}
+	findings, err := anomaly.NewDetector(config(), store).Detect(context.Background(), end)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]anomalytypes.Kind{
+		"checkout": anomalytypes.KindVolumeSpike,
+		"search":   anomalytypes.KindVolumeDrop,
+	}, kinds(findings))
+	assert.Equal(t, findings, store.stored)
+	for _, f := range findings {
+		assert.InDelta(t, 100, f.Expected, 0.001)
+		assert.Equal(t, end.Add(-5*time.Minute), f.WindowStart)
+	}
+}
+
+func TestVolumeNeedsHistory(t *testing.T) {
+	store := &fakeStore{
+		weeks: 1,
+		past:  map[string]uint64{"checkout": 100},
+		now:   map[string]uint64{"checkout": 400},
+	}
+	findings, err := anomaly.NewDetector(config(), store).Detect(context.Background(), end)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestNewPatterns(t *testing.T) {
+	store := &fakeStore{patterns: []clickhousestore.PatternActivity{
+		{Pattern: patterntypes.Pattern{TenantID: "acme", ID: "p1", Template: "cache warmed in <*>"}, SeverityNumber: 9, Count: 3},
+		{Pattern: patterntypes.Pattern{TenantID: "acme", ID: "p2", Template: "payment <*> failed"}, SeverityNumber: 17, Count: 1},
+	}}
+	findings, err := anomaly.NewDetector(config(), store).Detect(context.Background(), end)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]anomalytypes.Kind{
+		"p1": anomalytypes.KindNewPattern,
+		"p2": anomalytypes.KindNewError,
+	}, kinds(findings))
+
+	again, err := anomaly.NewDetector(config(), store).Detect(context.Background(), end)
+	require.NoError(t, err)
+	assert.Equal(t, findings[0].ID, again[0].ID)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+)
+
+type Config struct {
+	// Address is the listen address of the query API and WebSocket server.
+	Address string `mapstructure:"address"`
+
+	// Ingest configures the native OTLP receivers.
+	Ingest IngestConfig `mapstructure:"ingest"`
+
+	// CORS configures cross-origin access for browsers.
+	CORS CORSConfig `mapstructure:"cors"`
+
+	// RequestTimeout bounds how long a query API request may run. It does
+	// not apply to WebSocket connections.
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+
+	// ExportTimeout bounds how long a log export may stream. Exports are
+	// not bound by RequestTimeout.
+	ExportTimeout time.Duration `mapstructure:"export_timeout"`
+
+	// MaxRequestBytes limits the size of request bodies.
+	MaxRequestBytes int64 `mapstructure:"max_request_bytes"`
+
+	// ShutdownTimeout bounds the graceful shutdown on SIGTERM. It should
+	// be shorter than the orchestrator's grace period.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+type IngestConfig struct {
+	// GRPCAddress is the listen address of the OTLP/gRPC receiver.
+	GRPCAddress string `mapstructure:"grpc_address"`
+
+	// HTTPAddress is the listen address of the OTLP/HTTP receiver.
+	HTTPAddress string `mapstructure:"http_address"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins lists origins allowed to call the API. "*" allows any
+	// origin, but without credentials, and should only be used for local
+	// development.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("api"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	origins := []string{"http://localhost:3000"}
+	if v := os.Getenv("WATCHDATA_CORS_ORIGINS"); v != "" {
+		origins = nil
+		for _, o := range strings.Split(v, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				origins = append(origins, o)
+			}
+		}
+	}
+
+	return Config{
+		Address: envOr("WATCHDATA_HTTP_ADDR", ":8080"),
+		Ingest: IngestConfig{
+			// The collector from docker-compose already binds 4317/4318.
+			GRPCAddress: envOr("WATCHDATA_INGEST_GRPC_ADDR", ":14317"),
+			HTTPAddress: envOr("WATCHDATA_INGEST_HTTP_ADDR", ":14318"),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: origins,
+		},
+		RequestTimeout:  envDuration("WATCHDATA_REQUEST_TIMEOUT", 30*time.Second),
+		ExportTimeout:   envDuration("WATCHDATA_EXPORT_TIMEOUT", time.Hour),
+		MaxRequestBytes: envInt("WATCHDATA_MAX_REQUEST_BYTES", 1<<20),
+		ShutdownTimeout: envDuration("WATCHDATA_SHUTDOWN_TIMEOUT", 25*time.Second),
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func envInt(key string, def int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func (c Config) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if c.RequestTimeout <= 0 {
+		return fmt.Errorf("request timeout must be positive")
+	}
+	if c.ExportTimeout <= 0 {
+		return fmt.Errorf("export timeout must be positive")
+	}
+	if c.MaxRequestBytes <= 0 {
+		return fmt.Errorf("max request bytes must be positive")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdown timeout must be positive")
+	}
+	for _, o := range c.CORS.AllowedOrigins {
+		if o != "*" && !strings.HasPrefix(o, "http://") && !strings.HasPrefix(o, "https://") {
+			return fmt.Errorf("invalid CORS origin %q", o)
+		}
+	}
+	return nil
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// OriginListed reports whether a browser origin is listed explicitly,
+// rather than allowed by "*", and so may send credentials.
+func (c CORSConfig) OriginListed(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// OriginAllowed reports whether a browser origin may call the API.
+func (c CORSConfig) OriginAllowed(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/opamp"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// OpAMP serves collectors managed over OpAMP, connecting over WebSocket or
+// polling with plain HTTP POSTs.
+func (s *Server) OpAMP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		ws, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			slog.WarnContext(r.Context(), "failed to upgrade OpAMP connection", "error", err)
+			return
+		}
+		go s.opamp.Serve(ws)
+		return
+	}
+	if r.Method != http.MethodPost {
+		render.Error(w, r, errors.New(errors.CodeMethodNotAllowed, "method not allowed", errors.SeverityInfo))
+		return
+	}
+
+	msg, err := s.opamp.ReadHTTP(r)
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid OpAMP message", errors.SeverityInfo, err))
+		return
+	}
+	out, err := proto.Marshal(s.opamp.HandleHTTP(msg))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeEncodingFailed, "failed to encode OpAMP message", errors.SeverityError, err))
+		return
+	}
+	w.Header().Set("Content-Type", opamp.ContentType)
+	w.Write(out)
+}
+
+// ListAgents returns the collectors managed over OpAMP, with their health,
+// effective config and remote config status.
+func (s *Server) ListAgents(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, http.StatusOK, s.opamp.Agents())
+}
+
+// GetAgent returns a single collector managed over OpAMP.
+func (s *Server) GetAgent(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.opamp.Agent(chi.URLParam(r, "id"))
+	if !ok {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "agent not found", errors.SeverityInfo))
+		return
+	}
+	render.JSON(w, http.StatusOK, agent)
+}
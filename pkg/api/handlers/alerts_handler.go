@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ListAlertRules returns the tenant's alert rules.
+func (s *Server) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.provider.ListAlertRules(r.Context(), tenantOf(r))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch alert rules", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusOK, rules)
+}
+
+// CreateAlertRule stores a new rule.
+func (s *Server) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeAlertRule(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	rule.ID = uuid.NewString()
+	rule.TenantID = tenantOf(r)
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	if err := s.provider.UpsertAlertRule(r.Context(), rule); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store alert rule", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusCreated, rule)
+}
+
+// GetAlertRule returns a single rule.
+func (s *Server) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	if rule, ok := s.alertRule(w, r); ok {
+		render.JSON(w, http.StatusOK, rule)
+	}
+}
+
+// UpdateAlertRule replaces a rule.
+func (s *Server) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	existing, ok := s.alertRule(w, r)
+	if !ok {
+		return
+	}
+	rule, ok := decodeAlertRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule.ID = existing.ID
+	rule.TenantID = existing.TenantID
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+
+	if err := s.provider.UpsertAlertRule(r.Context(), rule); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store alert rule", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusOK, rule)
+}
+
+// DeleteAlertRule removes a rule.
+func (s *Server) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	existing, ok := s.alertRule(w, r)
+	if !ok {
+		return
+	}
+	if err := s.provider.DeleteAlertRule(r.Context(), existing.TenantID, existing.ID); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to delete alert rule", errors.SeverityError, err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// alertRule loads the rule named in the path, writing the error response
+// if it cannot.
+func (s *Server) alertRule(w http.ResponseWriter, r *http.Request) (alertingtypes.Rule, bool) {
+	rule, err := s.provider.GetAlertRule(r.Context(), tenantOf(r), chi.URLParam(r, "id"))
+	if stderrors.Is(err, clickhousestore.ErrRuleNotFound) {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "alert rule not found", errors.SeverityInfo))
+		return alertingtypes.Rule{}, false
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch alert rule", errors.SeverityError, err))
+		return alertingtypes.Rule{}, false
+	}
+	return rule, true
+}
+
+func decodeAlertRule(w http.ResponseWriter, r *http.Request) (alertingtypes.Rule, bool) {
+	var rule alertingtypes.Rule
+	if !decodeBody(w, r, &rule, "alert rule") {
+		return rule, false
+	}
+	if err := rule.Validate(); err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid alert rule: "+err.Error(), errors.SeverityInfo))
+		return rule, false
+	}
+	return rule, true
+}
+
+// GetAlerts returns the alerts that are currently pending, firing or recently resolved.
+func (s *Server) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts := s.alerts.Alerts(tenantOf(r))
+	if alerts == nil {
+		alerts = []alertingtypes.Alert{}
+	}
+	render.JSON(w, http.StatusOK, alerts)
+}
+
+// GetAlertHistory returns recent alert state transitions.
+func (s *Server) GetAlertHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'limit' parameter", errors.SeverityInfo))
+			return
+		}
+		limit = parsed
+	}
+
+	history, err := s.provider.GetAlertHistory(r.Context(), tenantOf(r), r.URL.Query().Get("rule_id"), limit)
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch alert history", errors.SeverityError, err))
+		return
+	}
+	if history == nil {
+		history = []alertingtypes.Transition{}
+	}
+	render.JSON(w, http.StatusOK, history)
+}
+
+// ListSilences returns the tenant's silences. Expired silences are only
+// listed with ?expired=true.
+func (s *Server) ListSilences(w http.ResponseWriter, r *http.Request) {
+	silences, err := s.provider.ListSilences(r.Context(), tenantOf(r), r.URL.Query().Get("expired") == "true")
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch silences", errors.SeverityError, err))
+		return
+	}
+	if silences == nil {
+		silences = []alertingtypes.Silence{}
+	}
+	render.JSON(w, http.StatusOK, silences)
+}
+
+// CreateSilence stores a new silence.
+func (s *Server) CreateSilence(w http.ResponseWriter, r *http.Request) {
+	var silence alertingtypes.Silence
+	if !decodeBody(w, r, &silence, "silence") {
+		return
+	}
+
+	now := time.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if err := silence.Validate(); err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid silence: "+err.Error(), errors.SeverityInfo))
+		return
+	}
+
+	silence.ID = uuid.NewString()
+	silence.TenantID = tenantOf(r)
+	silence.CreatedAt = now
+	silence.UpdatedAt = now
+
+	if err := s.provider.UpsertSilence(r.Context(), silence); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store silence", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusCreated, silence)
+}
+
+// GetSilence returns a single silence.
+func (s *Server) GetSilence(w http.ResponseWriter, r *http.Request) {
+	if silence, ok := s.silence(w, r); ok {
+		render.JSON(w, http.StatusOK, silence)
+	}
+}
+
+// ExpireSilence ends a silence now. Silences are expired rather than
+// removed so they remain auditable.
+func (s *Server) ExpireSilence(w http.ResponseWriter, r *http.Request) {
+	silence, ok := s.silence(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if silence.EndsAt.After(now) {
+		silence.EndsAt = now
+	}
+	silence.UpdatedAt = now
+
+	if err := s.provider.UpsertSilence(r.Context(), silence); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to expire silence", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusOK, silence)
+}
+
+func (s *Server) silence(w http.ResponseWriter, r *http.Request) (alertingtypes.Silence, bool) {
+	silence, err := s.provider.GetSilence(r.Context(), tenantOf(r), chi.URLParam(r, "id"))
+	if stderrors.Is(err, clickhousestore.ErrSilenceNotFound) {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "silence not found", errors.SeverityInfo))
+		return alertingtypes.Silence{}, false
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch silence", errors.SeverityError, err))
+		return alertingtypes.Silence{}, false
+	}
+	return silence, true
+}
+
+// GetNotifications returns recent notification attempts, including failures.
+func (s *Server) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	attempts, err := s.provider.GetNotificationAttempts(r.Context(), tenantOf(r), 100)
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch notifications", errors.SeverityError, err))
+		return
+	}
+	if attempts == nil {
+		attempts = []alertingtypes.NotificationAttempt{}
+	}
+	render.JSON(w, http.StatusOK, attempts)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/anomalytypes"
+)
+
+const (
+	defaultAnomalyLimit  = 100
+	maxAnomalyLimit      = 1000
+	defaultAnomalyWindow = 24 * time.Hour
+)
+
+// GetAnomalies returns the tenant's findings for windows starting in
+// [start, end), most recent first. kind takes a comma separated list of
+// kinds and query filters on the service, severity_text and pattern_id.
+func (s *Server) GetAnomalies(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var kinds []anomalytypes.Kind
+	if v := q.Get("kind"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			kind, err := anomalytypes.ParseKind(strings.TrimSpace(name))
+			if err != nil {
+				render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'kind' parameter: "+err.Error(), errors.SeverityInfo))
+				return
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+
+	expr, err := filter.Parse(q.Get("query"))
+	if err == nil {
+		_, err = anomalytypes.MapFilter(expr)
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'query' parameter: "+err.Error(), errors.SeverityInfo))
+		return
+	}
+
+	end := time.Now()
+	if e := q.Get("end"); e != "" {
+		if end, err = time.Parse(time.RFC3339Nano, e); err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'end' parameter", errors.SeverityInfo))
+			return
+		}
+	}
+	start := end.Add(-defaultAnomalyWindow)
+	if v := q.Get("start"); v != "" {
+		if start, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'start' parameter", errors.SeverityInfo))
+			return
+		}
+	}
+	if !start.Before(end) {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "'start' must be before 'end'", errors.SeverityInfo))
+		return
+	}
+
+	limit := defaultAnomalyLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAnomalyLimit {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'limit' parameter, expected 1 to "+strconv.Itoa(maxAnomalyLimit), errors.SeverityInfo))
+			return
+		}
+	}
+
+	findings, err := s.provider.ListAnomalies(r.Context(), tenantOf(r), kinds, expr, start, end, limit)
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch anomalies", errors.SeverityError, err))
+		return
+	}
+	if findings == nil {
+		findings = []anomalytypes.Finding{}
+	}
+	render.JSON(w, http.StatusOK, findings)
+}
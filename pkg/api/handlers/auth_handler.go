@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/auth"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/go-chi/chi/v5"
+)
+
+type createAPIKeyRequest struct {
+	Name string         `json:"name"`
+	Role authtypes.Role `json:"role"`
+	// ExpiresIn is an optional lifetime such as "720h".
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+type createAPIKeyResponse struct {
+	authtypes.APIKey
+	// Token is only returned once, when the key is created.
+	Token string `json:"token"`
+}
+
+// ListAPIKeys returns the keys of the tenant the request acts on.
+func (s *Server) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.provider.ListAPIKeys(r.Context(), tenantOf(r))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch API keys", errors.SeverityError, err))
+		return
+	}
+	if keys == nil {
+		keys = []authtypes.APIKey{}
+	}
+	render.JSON(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey issues a new key for the tenant the request acts on. The
+// token is only returned in this response.
+func (s *Server) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyRequest
+	if !decodeBody(w, r, &req, "API key") {
+		return
+	}
+
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'expires_in' duration", errors.SeverityInfo))
+			return
+		}
+		ttl = parsed
+	}
+
+	token, key, err := auth.NewAPIKey(tenantOf(r), req.Name, req.Role, ttl)
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid API key: "+err.Error(), errors.SeverityInfo))
+		return
+	}
+
+	if err := s.provider.UpsertAPIKey(r.Context(), key); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store API key", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusCreated, createAPIKeyResponse{APIKey: key, Token: token})
+}
+
+// GetAPIKey returns a single key.
+func (s *Server) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	if key, ok := s.apiKey(w, r); ok {
+		render.JSON(w, http.StatusOK, key)
+	}
+}
+
+// RevokeAPIKey revokes a key. Revoked keys are kept so they remain
+// auditable.
+func (s *Server) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.apiKey(w, r)
+	if !ok {
+		return
+	}
+
+	key.Revoked = true
+	key.UpdatedAt = time.Now()
+	if err := s.provider.UpsertAPIKey(r.Context(), key); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to revoke API key", errors.SeverityError, err))
+		return
+	}
+	s.auth.Invalidate(key.ID)
+	render.JSON(w, http.StatusOK, key)
+}
+
+// apiKey loads the key named in the path. Keys of other tenants are
+// reported as not found.
+func (s *Server) apiKey(w http.ResponseWriter, r *http.Request) (authtypes.APIKey, bool) {
+	key, err := s.provider.GetAPIKey(r.Context(), chi.URLParam(r, "id"))
+	if err == nil && key.TenantID != tenantOf(r) {
+		err = authtypes.ErrAPIKeyNotFound
+	}
+	if stderrors.Is(err, authtypes.ErrAPIKeyNotFound) {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "API key not found", errors.SeverityInfo))
+		return authtypes.APIKey{}, false
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch API key", errors.SeverityError, err))
+		return authtypes.APIKey{}, false
+	}
+	return key, true
+}
+
+// WhoAmI returns the authenticated caller.
+func (s *Server) WhoAmI(w http.ResponseWriter, r *http.Request) {
+	p, ok := authtypes.FromContext(r.Context())
+	if !ok {
+		render.Error(w, r, errors.New(errors.CodeUnauthorized, "unauthorized", errors.SeverityInfo))
+		return
+	}
+	render.JSON(w, http.StatusOK, p)
+}
+
+// OIDCLogin starts single sign-on by redirecting to the OpenID provider.
+// The optional return_to parameter is the frontend path to come back to.
+func (s *Server) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		render.Error(w, r, errors.New(errors.CodeFeatureDisabled, "single sign-on is not configured", errors.SeverityInfo))
+		return
+	}
+
+	authURL, flow, err := s.oidc.Begin(r.URL.Query().Get("return_to"))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInternalError, "failed to start login", errors.SeverityError, err))
+		return
+	}
+	if err := s.oidc.SetFlowCookie(w, flow); err != nil {
+		render.Error(w, r, errors.New(errors.CodeInternalError, "failed to start login", errors.SeverityError, err))
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback finishes single sign-on, creates a session and redirects
+// back to the frontend.
+func (s *Server) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		render.Error(w, r, errors.New(errors.CodeFeatureDisabled, "single sign-on is not configured", errors.SeverityInfo))
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		render.Error(w, r, errors.New(errors.CodeUnauthorized, "login failed: "+e, errors.SeverityInfo))
+		return
+	}
+
+	flow, ok := s.oidc.FlowFromRequest(w, r)
+	if !ok {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "login expired, please try again", errors.SeverityInfo))
+		return
+	}
+
+	identity, err := s.oidc.Finish(r.Context(), flow, q.Get("state"), q.Get("code"))
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+
+	role, ok := s.oidc.RoleFor(identity.Groups)
+	if !ok {
+		render.Error(w, r, errors.New(errors.CodeForbidden, "no role is mapped to your groups", errors.SeverityInfo))
+		return
+	}
+
+	cfg := s.oidc.Config()
+	token, session, err := auth.NewSession(identity, role, cfg.SessionTTL)
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInternalError, "failed to create session", errors.SeverityError, err))
+		return
+	}
+	if err := s.provider.UpsertSession(r.Context(), session); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to create session", errors.SeverityError, err))
+		return
+	}
+
+	auth.SetSessionCookie(w, token, session.ExpiresAt, cfg.SecureCookies())
+	http.Redirect(w, r, s.oidc.RedirectAfterLogin(flow), http.StatusFound)
+}
+
+type logoutResponse struct {
+	// LogoutURL ends the session at the OpenID provider, when supported.
+	LogoutURL string `json:"logout_url,omitempty"`
+}
+
+// Logout revokes the caller's session and clears the cookie.
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	var resp logoutResponse
+	secure := false
+	if s.oidc != nil {
+		resp.LogoutURL = s.oidc.LogoutURL()
+		secure = s.oidc.Config().SecureCookies()
+	}
+
+	if c, err := r.Cookie(auth.SessionCookie); err == nil && c.Value != "" {
+		id := auth.SessionID(c.Value)
+		session, err := s.provider.GetSession(r.Context(), id)
+		switch {
+		case stderrors.Is(err, authtypes.ErrSessionNotFound):
+		case err != nil:
+			render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to log out", errors.SeverityError, err))
+			return
+		case !session.Revoked:
+			session.Revoked = true
+			session.UpdatedAt = time.Now()
+			if err := s.provider.UpsertSession(r.Context(), session); err != nil {
+				render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to log out", errors.SeverityError, err))
+				return
+			}
+		}
+		s.auth.InvalidateSession(id)
+	}
+
+	auth.ClearSessionCookie(w, secure)
+	render.JSON(w, http.StatusOK, resp)
+}
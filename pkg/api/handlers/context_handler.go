@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+const (
+	defaultContextLines  = 20
+	maxContextLines      = 500
+	defaultContextWindow = time.Hour
+	maxContextWindow     = 24 * time.Hour
+)
+
+// defaultContextGroupBy groups records by the resource that emitted them.
+var defaultContextGroupBy = []string{"resource.service.name", "resource.host.name", "resource.k8s.pod.name"}
+
+// GetLogContext returns the records logged before and after a single
+// record by the same service, host and pod, or by the keys in group_by.
+// The record is named by id or, for records stored without one, by its
+// timestamp and a query matching it.
+func (s *Server) GetLogContext(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var id clickhousestore.LogIdentity
+	id.ID = q.Get("id")
+	if ts := q.Get("timestamp"); ts != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'timestamp' parameter", errors.SeverityInfo))
+			return
+		}
+		id.Timestamp = parsed
+	}
+	if id.ID == "" && id.Timestamp.IsZero() {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "'id' or 'timestamp' is required", errors.SeverityInfo))
+		return
+	}
+	match, err := filter.Parse(q.Get("query"))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'query' parameter: "+err.Error(), errors.SeverityInfo))
+		return
+	}
+	id.Match = match
+
+	before, ok := contextLines(w, r, "before")
+	if !ok {
+		return
+	}
+	after, ok := contextLines(w, r, "after")
+	if !ok {
+		return
+	}
+
+	window := defaultContextWindow
+	if v := q.Get("window"); v != "" {
+		window, err = time.ParseDuration(v)
+		if err != nil || window <= 0 || window > maxContextWindow {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'window' parameter, expected a duration up to 24h", errors.SeverityInfo))
+			return
+		}
+	}
+
+	keys := defaultContextGroupBy
+	if v := q.Get("group_by"); v != "" {
+		keys = strings.Split(v, ",")
+	}
+	groupBy := make([]filter.Field, 0, len(keys))
+	for _, key := range keys {
+		field, err := filter.ParseField(strings.TrimSpace(key))
+		if err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'group_by' parameter: "+err.Error(), errors.SeverityInfo))
+			return
+		}
+		groupBy = append(groupBy, field)
+	}
+
+	tenant := tenantOf(r)
+	anchor, err := s.provider.FindLog(r.Context(), tenant, id)
+	if stderrors.Is(err, clickhousestore.ErrLogNotFound) {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "log not found", errors.SeverityInfo))
+		return
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch log", errors.SeverityError, err))
+		return
+	}
+
+	preceding, following, err := s.provider.GetLogContext(r.Context(), tenant, anchor, groupBy, before, after, window)
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch log context", errors.SeverityError, err))
+		return
+	}
+	if preceding == nil {
+		preceding = []telemetrytypes.LogRecord{}
+	}
+	if following == nil {
+		following = []telemetrytypes.LogRecord{}
+	}
+	render.JSON(w, http.StatusOK, telemetrytypes.LogContext{Log: anchor, Before: preceding, After: following})
+}
+
+// contextLines reads a line count parameter, writing the error response if
+// it is invalid.
+func contextLines(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return defaultContextLines, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > maxContextLines {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid '"+name+"' parameter, expected 0 to "+strconv.Itoa(maxContextLines), errors.SeverityInfo))
+		return 0, false
+	}
+	return n, true
+}
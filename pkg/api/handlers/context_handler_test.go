@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/handlers"
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getLogContext(t *testing.T, conn *clickhousestoretest.Conn, query string) (*httptest.ResponseRecorder, render.Envelope) {
+	t.Helper()
+	s := handlers.NewTestServer(clickhousestore.NewProviderFromConn(conn), nil)
+	rec := httptest.NewRecorder()
+	render.RequestID(http.HandlerFunc(s.GetLogContext)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/logs/context?"+query, nil))
+
+	var env render.Envelope
+	if rec.Code != http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+	}
+	return rec, env
+}
+
+func TestGetLogContextInvalidRequests(t *testing.T) {
+	tests := []struct {
+		query string
+		msg   string
+	}{
+		{"", "'id' or 'timestamp' is required"},
+		{"timestamp=yesterday", "invalid 'timestamp' parameter"},
+		{"id=log-1&query=body%20%3D", "invalid 'query' parameter"},
+		{"id=log-1&before=-1", "invalid 'before' parameter, expected 0 to 500"},
+		{"id=log-1&after=501", "invalid 'after' parameter, expected 0 to 500"},
+		{"id=log-1&after=ten", "invalid 'after' parameter, expected 0 to 500"},
+		{"id=log-1&window=0s", "invalid 'window' parameter, expected a duration up to 24h"},
+		{"id=log-1&window=25h", "invalid 'window' parameter, expected a duration up to 24h"},
+		{"id=log-1&group_by=resource.,body", "invalid 'group_by' parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			conn := &clickhousestoretest.Conn{}
+			rec, env := getLogContext(t, conn, tt.query)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, errors.CodeInvalidRequest, env.Error.Code)
+			assert.Contains(t, env.Error.Message, tt.msg)
+			assert.Empty(t, conn.Queries(), "invalid requests are not queried")
+		})
+	}
+}
+
+func TestGetLogContextNotFound(t *testing.T) {
+	conn := &clickhousestoretest.Conn{}
+	rec, env := getLogContext(t, conn, "id=log-9")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, errors.CodeNotFound, env.Error.Code)
+	assert.Equal(t, "log not found", env.Error.Message)
+	assert.Len(t, conn.Queries(), 1)
+}
+
+func TestGetLogContextFound(t *testing.T) {
+	anchor := telemetrytypes.LogRecord{TenantID: "default", ID: "log-1", Timestamp: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), Body: "payment failed"}
+	conn := &clickhousestoretest.Conn{Rows: func(q clickhousestoretest.Query) ([][]any, error) {
+		return [][]any{clickhousestoretest.LogRow(anchor)}, nil
+	}}
+	rec, _ := getLogContext(t, conn, "id=log-1&before=1&after=0")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got telemetrytypes.LogContext
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "log-1", got.Log.ID)
+	assert.Len(t, got.Before, 1)
+	assert.Empty(t, got.After)
+	assert.Len(t, conn.Queries(), 2, "the anchor and the preceding lines")
+}
@@ -1,9 +0,0 @@
-package handlers
-
-import "net/http"
-
-func EnableCORS(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*") // or restrict to your frontend origin
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-}
\ No newline at end of file
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/logexport"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// ExportLogs streams every record matching the query in [start, end) as
+// NDJSON, CSV or Parquet. The response is written as the result is read,
+// so errors after the first record abort the response rather than
+// reporting an error envelope.
+func (s *Server) ExportLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	format, err := logexport.ParseFormat(q.Get("format"))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'format' parameter: "+err.Error(), errors.SeverityInfo))
+		return
+	}
+
+	var columns []logexport.Column
+	if c := q.Get("columns"); c != "" {
+		columns, err = logexport.ParseColumns(strings.Split(c, ","))
+		if err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'columns' parameter: "+err.Error(), errors.SeverityInfo))
+			return
+		}
+	}
+
+	expr, err := filter.Parse(q.Get("query"))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'query' parameter: "+err.Error(), errors.SeverityInfo))
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339Nano, q.Get("start"))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'start' parameter", errors.SeverityInfo))
+		return
+	}
+	end := time.Now()
+	if e := q.Get("end"); e != "" {
+		if end, err = time.Parse(time.RFC3339Nano, e); err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'end' parameter", errors.SeverityInfo))
+			return
+		}
+	}
+	if !start.Before(end) {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "'start' must be before 'end'", errors.SeverityInfo))
+		return
+	}
+
+	// The response starts with the first record, so a query that fails
+	// up front still gets an error envelope.
+	var enc logexport.Writer
+	open := func() error {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="logs-%s.%s"`, start.UTC().Format("20060102T150405Z"), format.Extension()))
+		ew, err := logexport.NewWriter(format, w, columns)
+		if err != nil {
+			w.Header().Del("Content-Disposition")
+			return err
+		}
+		enc = ew
+		return nil
+	}
+
+	var records int
+	err = s.provider.StreamLogs(r.Context(), tenantOf(r), expr, start, end, func(rec telemetrytypes.LogRecord) error {
+		if enc == nil {
+			if err := open(); err != nil {
+				return err
+			}
+		}
+		records++
+		return enc.Write(rec)
+	})
+	if err == nil && enc == nil {
+		err = open()
+	}
+	if err == nil {
+		err = enc.Close()
+	}
+
+	if err != nil {
+		if enc == nil {
+			render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to export logs", errors.SeverityError, err))
+			return
+		}
+		// Abort so the client sees a broken download instead of a
+		// truncated file.
+		slog.ErrorContext(r.Context(), "log export aborted",
+			"request_id", render.RequestIDFromContext(r.Context()), "format", format, "records", records, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	slog.InfoContext(r.Context(), "exported logs", "format", format, "records", records)
+}
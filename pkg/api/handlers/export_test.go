@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/patterns"
+	"github.com/Ricky004/watchdata/pkg/processing"
+	"github.com/Ricky004/watchdata/pkg/redaction"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/gorilla/websocket"
+)
+
+// NewTestServer returns a server on provider that neither processes,
+// redacts nor mines patterns, with a pipeline deployer, which may be nil.
+func NewTestServer(provider *clickhousestore.ClickHouseProvider, pipelines *otelpipeline.Deployer) *Server {
+	p, err := processing.NewProcessor(processing.Config{})
+	if err != nil {
+		panic(err)
+	}
+	r, err := redaction.NewRedactor(redaction.Config{})
+	if err != nil {
+		panic(err)
+	}
+	return &Server{
+		provider:    provider,
+		pipelines:   pipelines,
+		clients:     make(map[*websocket.Conn]string),
+		broadcast:   make(chan telemetrytypes.LogRecord, 16),
+		broadcasted: make(map[string]time.Time),
+		processing:  p,
+		redactor:    r,
+		patterns:    patterns.NewMiner(patterns.Config{}, provider),
+	}
+}
+
+func (s *Server) Poll(ctx context.Context, since time.Time) (time.Time, error) {
+	return s.poll(ctx, since)
+}
+
+func (s *Server) RunBroadcaster(ctx context.Context) error {
+	return s.runBroadcaster(ctx)
+}
+
+// Enqueue queues n records for broadcast.
+func (s *Server) Enqueue(n int) {
+	for range n {
+		s.broadcast <- telemetrytypes.LogRecord{}
+	}
+}
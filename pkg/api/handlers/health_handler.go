@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+)
+
+// readyTimeout bounds the ClickHouse checks of a readiness probe.
+const readyTimeout = 2 * time.Second
+
+// queueFullRatio is the share of the broadcast queue's capacity above
+// which its check reports a backlog.
+const queueFullRatio = 0.9
+
+// check is the result of a readiness check. Why a check failed is logged
+// rather than returned, since probes are not authenticated.
+type check struct {
+	OK bool `json:"ok"`
+}
+
+// queueCheck reports the depth of the broadcast queue, which is not
+// OK above queueFullRatio of its capacity.
+type queueCheck struct {
+	check
+	Depth    int `json:"depth"`
+	Capacity int `json:"capacity"`
+}
+
+type readiness struct {
+	Ready       bool       `json:"ready"`
+	ClickHouse  check      `json:"clickhouse"`
+	Migrations  check      `json:"migrations"`
+	IngestQueue queueCheck `json:"ingest_queue"`
+}
+
+// Healthz is the liveness probe. It only reports that the process serves
+// requests, so a ClickHouse outage does not get the server restarted.
+func (s *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz is the readiness probe. The server is ready when ClickHouse
+// answers and the schema is fully migrated. The broadcast queue's depth is
+// reported too, but a backlog does not make the server unready: it only
+// delays live tail, and taking the server out of rotation would not drain
+// it.
+func (s *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	var res readiness
+	if err := s.provider.Ping(ctx); err != nil {
+		slog.WarnContext(ctx, "readiness check failed: clickhouse is unreachable", "error", err)
+	} else {
+		res.ClickHouse.OK = true
+	}
+
+	current, latest, err := s.provider.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "readiness check failed: cannot read the schema version", "error", err)
+	case current < latest:
+		slog.WarnContext(ctx, "readiness check failed: schema is not fully migrated", "current", current, "latest", latest)
+	default:
+		res.Migrations.OK = true
+	}
+
+	res.IngestQueue.Depth, res.IngestQueue.Capacity = len(s.broadcast), cap(s.broadcast)
+	res.IngestQueue.OK = float64(res.IngestQueue.Depth) < queueFullRatio*float64(res.IngestQueue.Capacity)
+	if !res.IngestQueue.OK {
+		slog.WarnContext(ctx, "broadcast queue is backlogged", "depth", res.IngestQueue.Depth, "capacity", res.IngestQueue.Capacity)
+	}
+
+	res.Ready = res.ClickHouse.OK && res.Migrations.OK
+	status := http.StatusOK
+	if !res.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	render.JSON(w, status, res)
+}
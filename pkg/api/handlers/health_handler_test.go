@@ -0,0 +1,51 @@
+package handlers_test
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/api/handlers"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReadyz checks that the probe only tells unauthenticated callers
+// whether each check passed, not why it failed.
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name    string
+		pingErr error
+		version uint32
+		rowsErr error
+		queued  int
+		status  int
+		want    string
+	}{
+		{"ready", nil, 1 << 30, nil, 0, http.StatusOK, `{"ready":true,"clickhouse":{"ok":true},"migrations":{"ok":true},"ingest_queue":{"ok":true,"depth":0,"capacity":16}}`},
+		{"unreachable", stderrors.New("dial tcp 10.0.0.5:9000: connection refused"), 1 << 30, nil, 0, http.StatusServiceUnavailable, `{"ready":false,"clickhouse":{"ok":false},"migrations":{"ok":true},"ingest_queue":{"ok":true,"depth":0,"capacity":16}}`},
+		{"not migrated", nil, 1, nil, 0, http.StatusServiceUnavailable, `{"ready":false,"clickhouse":{"ok":true},"migrations":{"ok":false},"ingest_queue":{"ok":true,"depth":0,"capacity":16}}`},
+		{"no schema", nil, 0, stderrors.New("code: 60, message: Table watchdata.schema_migrations does not exist"), 0, http.StatusServiceUnavailable, `{"ready":false,"clickhouse":{"ok":true},"migrations":{"ok":false},"ingest_queue":{"ok":true,"depth":0,"capacity":16}}`},
+		// A backlog is reported without taking the server out of rotation.
+		{"backlogged", nil, 1 << 30, nil, 15, http.StatusOK, `{"ready":true,"clickhouse":{"ok":true},"migrations":{"ok":true},"ingest_queue":{"ok":false,"depth":15,"capacity":16}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &clickhousestoretest.Conn{
+				PingErr: tt.pingErr,
+				Rows: func(clickhousestoretest.Query) ([][]any, error) {
+					return [][]any{{tt.version}}, tt.rowsErr
+				},
+			}
+			s := handlers.NewTestServer(clickhousestore.NewProviderFromConn(conn), nil)
+			s.Enqueue(tt.queued)
+			rec := httptest.NewRecorder()
+			s.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.JSONEq(t, tt.want, rec.Body.String())
+		})
+	}
+}
@@ -4,26 +4,63 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/Ricky004/watchdata/pkg/alerting"
+	"github.com/Ricky004/watchdata/pkg/anomaly"
+	"github.com/Ricky004/watchdata/pkg/api"
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/auth"
 	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/opamp"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/patterns"
+	"github.com/Ricky004/watchdata/pkg/processing"
+	"github.com/Ricky004/watchdata/pkg/ratelimit"
+	"github.com/Ricky004/watchdata/pkg/redaction"
+	"github.com/Ricky004/watchdata/pkg/telemetry"
 	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Server struct {
-	provider  *clickhousestore.ClickHouseProvider
-	clients   map[*websocket.Conn]bool
-	clientsMu sync.Mutex
-	broadcast chan telemetrytypes.LogRecord
-	upgrader  websocket.Upgrader
+	provider   *clickhousestore.ClickHouseProvider
+	clients    map[*websocket.Conn]string // tenant of each client
+	clientsMu  sync.Mutex
+	broadcast  chan telemetrytypes.LogRecord
+	upgrader   websocket.Upgrader
+	alerts     *alerting.Engine
+	dispatcher *alerting.Dispatcher
+	auth       *auth.Authenticator
+	oidc       *auth.OIDC
+	limiter    *ratelimit.Limiter
+	patterns   *patterns.Miner
+	anomalies  *anomaly.Detector // nil when detection is disabled
+	pipelines  *otelpipeline.Deployer
+	opamp      *opamp.Server
+	processing *processing.Processor
+	redactor   *redaction.Redactor
+
+	// broadcasted holds when IngestLogs broadcast each of its records, by
+	// ID, so that the poller does not send them a second time.
+	broadcastedMu sync.Mutex
+	broadcasted   map[string]time.Time
+
+	// pipelinesMu serializes pipeline writes, so that a name found free is
+	// not taken before the pipeline is stored. Like the config files
+	// pipelines deploy to, it is local to this server.
+	pipelinesMu sync.Mutex
 }
 
-func NewServer(cfg clickhousestore.Config) (*Server, error) {
+func NewServer(cfg clickhousestore.Config, apiCfg api.Config) (*Server, error) {
 	provider, err := clickhousestore.NewClickHouseProvider(context.Background(), cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider: %w", err)
@@ -31,107 +68,225 @@ func NewServer(cfg clickhousestore.Config) (*Server, error) {
 
 	server := &Server{
 		provider:  provider,
-		clients:   make(map[*websocket.Conn]bool),
+		clients:   make(map[*websocket.Conn]string),
 		broadcast: make(chan telemetrytypes.LogRecord, 1000), // Buffer for high throughput
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || apiCfg.CORS.OriginAllowed(origin)
+			},
 		},
+		broadcasted: make(map[string]time.Time),
 	}
-	// Start the broadcaster goroutine
-	server.startBroadcaster()
-	server.startDatabasePoller()
+
+	err = metrics.Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "watchdata_broadcast_queue_depth",
+		Help: "Log records waiting to be sent to WebSocket clients.",
+	}, func() float64 { return float64(len(server.broadcast)) }))
+	if err == nil {
+		err = metrics.Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "watchdata_broadcast_queue_capacity",
+			Help: "Log records the WebSocket broadcast queue holds before records are dropped.",
+		}, func() float64 { return float64(cap(server.broadcast)) }))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to register metrics: %w", err)
+	}
+
+	authCfg, err := auth.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auth config: %w", err)
+	}
+	if !authCfg.Enabled {
+		slog.Warn("authentication is disabled, do not expose this server beyond localhost")
+	}
+	server.auth = auth.NewAuthenticator(authCfg, provider)
+	if authCfg.Enabled && authCfg.OIDC.Enabled() {
+		server.oidc, err = auth.NewOIDC(context.Background(), authCfg.OIDC)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limitCfg, err := ratelimit.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate limit config: %w", err)
+	}
+	server.limiter = ratelimit.NewLimiter(limitCfg, provider)
+
+	patternsCfg, err := patterns.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load patterns config: %w", err)
+	}
+	server.patterns = patterns.NewMiner(patternsCfg, provider)
+
+	anomalyCfg, err := anomaly.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load anomaly config: %w", err)
+	}
+	if anomalyCfg.Enabled {
+		server.anomalies = anomaly.NewDetector(anomalyCfg, provider)
+	}
+
+	pipelinesCfg, err := otelpipeline.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pipelines config: %w", err)
+	}
+	server.pipelines = otelpipeline.NewDeployer(pipelinesCfg)
+
+	opampCfg, err := opamp.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load opamp config: %w", err)
+	}
+	server.opamp = opamp.NewServer(opampCfg)
+	// Collectors are offered the deployed config until the next deploy.
+	deployed, err := server.pipelines.DeployedCollectorConfig()
+	if err != nil {
+		return nil, err
+	}
+	if deployed != nil {
+		server.opamp.SetConfig(deployed)
+	}
+
+	processingCfg, err := processing.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	server.processing, err = processing.NewProcessor(processingCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	redactionCfg, err := redaction.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	server.redactor, err = redaction.NewRedactor(redactionCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	alertingCfg, err := alerting.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	dispatcher, err := alerting.NewDispatcher(alertingCfg, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert dispatcher: %w", err)
+	}
+	server.dispatcher = dispatcher
+	server.alerts = alerting.NewEngine(provider, dispatcher)
 
 	return server, nil
 }
 
-func (s *Server) startDatabasePoller() {
-	go func() {
-		log.Println("Starting database poller...")
+// broadcastedTTL is how long the poller remembers records IngestLogs
+// broadcast. Those it does not read back by then, because their timestamps
+// are older than what it polls, are forgotten.
+const broadcastedTTL = time.Minute
 
-		// Use current time minus a bit for initial buffer
-		lastTimestamp := time.Now().Add(-5 * time.Second)
+// pollDatabase broadcasts logs written by other writers, such as the
+// collector's exporter, until ctx is cancelled. Its queries run every few
+// seconds, so they are not traced.
+func (s *Server) pollDatabase(ctx context.Context) error {
+	slog.InfoContext(ctx, "starting database poller")
+	ctx = telemetry.Suppress(ctx)
 
-		ticker := time.NewTicker(2 * time.Second)
-		defer ticker.Stop()
+	// Use current time minus a bit for initial buffer
+	lastTimestamp := time.Now().Add(-5 * time.Second)
 
-		for range ticker.C {
-			newLogs, err := s.provider.GetLogsSince(context.Background(), lastTimestamp)
-			if err != nil {
-				log.Printf("Error polling for new logs: %v", err)
-				continue
-			}
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
 
-			if len(newLogs) == 0 {
-				continue
-			}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
 
-			filteredLogs := []telemetrytypes.LogRecord{}
+		var err error
+		lastTimestamp, err = s.poll(ctx, lastTimestamp)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to poll for new logs", "error", err)
+		}
+	}
+}
 
-			for _, logRecord := range newLogs {
-				// skip logs that have the same timestamp as last seen
-				if logRecord.Timestamp.After(lastTimestamp) {
-					filteredLogs = append(filteredLogs, logRecord)
-				}
-			}
+// poll broadcasts the logs stored after since that IngestLogs has not
+// broadcast already and returns the timestamp to poll from next.
+func (s *Server) poll(ctx context.Context, since time.Time) (time.Time, error) {
+	newLogs, err := s.provider.GetLogsSince(ctx, tenanttypes.AllTenants, since)
+	if err != nil {
+		return since, err
+	}
 
-			if len(filteredLogs) > 0 {
-				log.Printf("Found %d new logs to broadcast.", len(filteredLogs))
-				for _, logRecord := range filteredLogs {
-					s.broadcast <- logRecord
-				}
+	filteredLogs := []telemetrytypes.LogRecord{}
 
-				// update to the highest timestamp seen
-				lastTimestamp = filteredLogs[len(filteredLogs)-1].Timestamp
-			}
+	s.broadcastedMu.Lock()
+	for id, at := range s.broadcasted {
+		if time.Since(at) > broadcastedTTL {
+			delete(s.broadcasted, id)
 		}
-	}()
-}
+	}
+	for _, logRecord := range newLogs {
+		// skip logs that have the same timestamp as last seen
+		if !logRecord.Timestamp.After(since) {
+			continue
+		}
+		since = logRecord.Timestamp
+		if _, ok := s.broadcasted[logRecord.ID]; ok {
+			delete(s.broadcasted, logRecord.ID)
+			continue
+		}
+		filteredLogs = append(filteredLogs, logRecord)
+	}
+	s.broadcastedMu.Unlock()
 
-func (s *Server) GetLogs(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
+	if len(filteredLogs) > 0 {
+		slog.DebugContext(ctx, "found new logs to broadcast", "records", len(filteredLogs))
+	}
+	for _, logRecord := range filteredLogs {
+		select {
+		case s.broadcast <- logRecord:
+		case <-ctx.Done():
+			return since, nil
+		}
 	}
+	return since, nil
+}
 
+func (s *Server) GetLogs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	logs, err := s.provider.GetLogs(ctx)
+	logs, err := s.provider.GetLogs(ctx, tenantOf(r))
 	if err != nil {
-		log.Printf("GetLogs error: %v\n", err)
-		http.Error(w, "Failed to fetch logs", http.StatusInternalServerError)
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch logs", errors.SeverityError, err))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(logs); err != nil {
-		http.Error(w, "Failed to encode logs", http.StatusInternalServerError)
+		render.Error(w, r, errors.New(errors.CodeEncodingFailed, "failed to encode logs", errors.SeverityError))
 	}
 }
 
 func (s *Server) GetLogsSince(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
 	ts := r.URL.Query().Get("timestamp")
 	if ts == "" {
-		http.Error(w, "Missing timestamp parameter", http.StatusBadRequest)
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "missing timestamp parameter", errors.SeverityInfo))
 		return
 	}
 
 	parsedTime, err := time.Parse(time.RFC3339Nano, ts)
 	if err != nil {
-		http.Error(w, "Invalid timestamp format", http.StatusBadRequest)
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid timestamp format", errors.SeverityInfo))
 		return
 	}
 
-	logs, err := s.provider.GetLogsSince(r.Context(), parsedTime)
+	logs, err := s.provider.GetLogsSince(r.Context(), tenantOf(r), parsedTime)
 	if err != nil {
-		log.Printf("GetLogsSince error: %v\n", err)
-		http.Error(w, "Failed to fetch logs", http.StatusInternalServerError)
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch logs", errors.SeverityError, err))
 		return
 	}
 
@@ -140,35 +295,28 @@ func (s *Server) GetLogsSince(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) GetLogsInTimeRanges(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
 	start := r.URL.Query().Get("start")
 	end := r.URL.Query().Get("end")
 
 	startTs, err := strconv.ParseInt(start, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid 'start' parameter", http.StatusBadRequest)
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'start' parameter", errors.SeverityInfo))
 		return
 	}
 	endTs, err := strconv.ParseInt(end, 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid 'end' parameter", http.StatusBadRequest)
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'end' parameter", errors.SeverityInfo))
 		return
 	}
 
 	if startTs > endTs {
-		http.Error(w, "'start' must be less than 'end'", http.StatusBadRequest)
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "'start' must be less than 'end'", errors.SeverityInfo))
 		return
 	}
 
-	logs, err := s.provider.GetLogsInTimeRanges(r.Context(), startTs, endTs)
+	logs, err := s.provider.GetLogsInTimeRanges(r.Context(), tenantOf(r), startTs, endTs)
 	if err != nil {
-		log.Printf("GetLogsInTimeRanges error: %v\n", err)
-		http.Error(w, "Failed to fetch logs", http.StatusInternalServerError)
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch logs", errors.SeverityError, err))
 		return
 	}
 
@@ -178,18 +326,19 @@ func (s *Server) GetLogsInTimeRanges(w http.ResponseWriter, r *http.Request) {
 
 // WebSocket handler
 func (s *Server) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w)
 
+	tenant := tenantOf(r)
 	ws, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("WebSocket upgrade error:", err)
+		slog.WarnContext(r.Context(), "failed to upgrade WebSocket connection", "error", err)
 		return
 	}
 
 	// Add client to the map
 	s.clientsMu.Lock()
-	s.clients[ws] = true
-	log.Printf("WebSocket client connected. Total clients: %d", len(s.clients))
+	s.clients[ws] = tenant
+	metrics.WebSocketClients.Set(float64(len(s.clients)))
+	slog.InfoContext(r.Context(), "WebSocket client connected", "tenant", tenant, "clients", len(s.clients))
 	s.clientsMu.Unlock()
 
 	// Handle client disconnection
@@ -197,7 +346,8 @@ func (s *Server) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			s.clientsMu.Lock()
 			delete(s.clients, ws)
-			log.Printf("WebSocket client disconnected. Total clients: %d", len(s.clients))
+			metrics.WebSocketClients.Set(float64(len(s.clients)))
+			slog.Info("WebSocket client disconnected", "tenant", tenant, "clients", len(s.clients))
 			s.clientsMu.Unlock()
 			ws.Close()
 		}()
@@ -206,59 +356,164 @@ func (s *Server) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 		for {
 			// Read message from client (ping/pong or other messages)
 			if _, _, err := ws.ReadMessage(); err != nil {
-				log.Printf("WebSocket read error: %v", err)
+				slog.Debug("WebSocket read failed", "error", err)
 				break
 			}
 		}
 	}()
 }
 
-// Start the broadcaster goroutine
-func (s *Server) startBroadcaster() {
-	go func() {
-		log.Println("WebSocket broadcaster started")
-		for logRecord := range s.broadcast {
-			// FIX: Lock the mutex to safely read the clients map
-			s.clientsMu.Lock()
-
-			if len(s.clients) == 0 {
-				s.clientsMu.Unlock()
-				continue // No clients connected, skip broadcasting
+// runBroadcaster sends queued logs to WebSocket clients until ctx is
+// cancelled. It then sends what is still queued and closes every client
+// with a close frame.
+func (s *Server) runBroadcaster(ctx context.Context) error {
+	slog.InfoContext(ctx, "WebSocket broadcaster started")
+	for {
+		select {
+		case logRecord := <-s.broadcast:
+			s.send(logRecord)
+		case <-ctx.Done():
+			for {
+				select {
+				case logRecord := <-s.broadcast:
+					s.send(logRecord)
+				default:
+					s.closeClients()
+					return nil
+				}
 			}
+		}
+	}
+}
 
-			log.Printf("Broadcasting log to %d clients", len(s.clients))
+// send writes a record to the WebSocket clients of its tenant.
+func (s *Server) send(logRecord telemetrytypes.LogRecord) {
+	// FIX: Lock the mutex to safely read the clients map
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
 
-			// FIX: To prevent issues with modifying the map while iterating,
-			// we collect clients that have disconnected and remove them after the loop.
-			var badClients []*websocket.Conn
+	if len(s.clients) == 0 {
+		return // No clients connected, skip broadcasting
+	}
 
-			// Send to all connected clients
-			for client := range s.clients {
-				err := client.WriteJSON(logRecord)
-				if err != nil {
-					log.Printf("Error sending to WebSocket client: %v", err)
-					badClients = append(badClients, client)
-				}
-			}
+	slog.Debug("broadcasting log", "clients", len(s.clients))
 
-			// Remove any clients that failed to receive the message
-			for _, client := range badClients {
-				client.Close()
-				delete(s.clients, client)
-			}
+	// FIX: To prevent issues with modifying the map while iterating,
+	// we collect clients that have disconnected and remove them after the loop.
+	var badClients []*websocket.Conn
 
-			s.clientsMu.Unlock()
+	// Send to the clients of the record's tenant
+	tenant := logRecord.TenantID
+	if tenant == "" {
+		tenant = tenanttypes.DefaultTenant
+	}
+	for client, clientTenant := range s.clients {
+		if clientTenant != tenant {
+			continue
 		}
-	}()
+		err := client.WriteJSON(logRecord)
+		if err != nil {
+			slog.Warn("failed to send to WebSocket client", "error", err)
+			badClients = append(badClients, client)
+		}
+	}
+
+	// Remove any clients that failed to receive the message
+	for _, client := range badClients {
+		client.Close()
+		delete(s.clients, client)
+	}
+	metrics.WebSocketClients.Set(float64(len(s.clients)))
+}
+
+// closeClients tells every WebSocket client that the server is going away,
+// so browsers reconnect to another instance.
+func (s *Server) closeClients() {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	deadline := time.Now().Add(time.Second)
+	for client := range s.clients {
+		if err := client.WriteControl(websocket.CloseMessage, msg, deadline); err != nil {
+			slog.Warn("failed to close WebSocket client", "error", err)
+		}
+		client.Close()
+		delete(s.clients, client)
+	}
+	metrics.WebSocketClients.Set(float64(len(s.clients)))
+	slog.Info("WebSocket clients closed")
+}
+
+// Authenticator returns the authenticator guarding the server's routes.
+func (s *Server) Authenticator() *auth.Authenticator {
+	return s.auth
+}
+
+// Limiter returns the ingest rate limiter for the native receivers.
+func (s *Server) Limiter() *ratelimit.Limiter {
+	return s.limiter
 }
 
-// IngestLog - called by your collector/exporter (send logs directly to the server's API)
-func (s *Server) IngestLog(logs []telemetrytypes.LogRecord) {
+// Store returns the server's ClickHouse store.
+func (s *Server) Store() *clickhousestore.ClickHouseProvider {
+	return s.provider
+}
+
+// TelemetrySink returns the sink of the server's own telemetry, which
+// stores it redacted like ingested records.
+func (s *Server) TelemetrySink() telemetry.Sink {
+	return selfSink{s}
+}
+
+type selfSink struct {
+	s *Server
+}
+
+func (k selfSink) InsertLogs(ctx context.Context, logs []telemetrytypes.LogRecord) error {
+	logs = k.s.redactor.Redact(logs)
+	if len(logs) == 0 {
+		return nil
+	}
+	return k.s.provider.InsertLogs(ctx, logs)
+}
+
+// IngestLogs runs records received by the native receivers through the
+// processing chain and the redaction rules, assigns them to patterns,
+// stores them and broadcasts them to WebSocket clients. Redaction comes
+// before pattern mining, whose samples are stored too.
+func (s *Server) IngestLogs(ctx context.Context, logs []telemetrytypes.LogRecord) error {
+	logs = s.redactor.Redact(s.processing.Process(logs))
+	if len(logs) == 0 {
+		return nil
+	}
+	s.patterns.Assign(ctx, logs)
+
+	// The poller reads these records back once they are stored, so they
+	// are marked as broadcast before the insert, for it to skip them.
+	now := time.Now()
+	s.broadcastedMu.Lock()
+	for i := range logs {
+		if logs[i].ID == "" {
+			logs[i].ID = telemetrytypes.NewLogID()
+		}
+		s.broadcasted[logs[i].ID] = now
+	}
+	s.broadcastedMu.Unlock()
+
 	// Store to ClickHouse first
-	err := s.provider.InsertLogs(context.Background(), logs)
+	err := s.provider.InsertLogs(ctx, logs)
 	if err != nil {
-		log.Printf("Error storing logs to ClickHouse: %v", err)
-		return // Don't broadcast if storage failed
+		slog.ErrorContext(ctx, "failed to store logs", "records", len(logs), "error", err)
+		s.broadcastedMu.Lock()
+		for _, logRecord := range logs {
+			delete(s.broadcasted, logRecord.ID)
+		}
+		s.broadcastedMu.Unlock()
+		return err // Don't broadcast if storage failed
+	}
+	if err := s.patterns.Flush(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to store log patterns", "error", err)
 	}
 
 	// Broadcast to WebSocket clients
@@ -268,7 +523,9 @@ func (s *Server) IngestLog(logs []telemetrytypes.LogRecord) {
 			// Successfully queued for broadcast
 		default:
 			// This might happen if the channel is full.
-			log.Println("Warning: Broadcast channel full, dropping log")
+			slog.WarnContext(ctx, "broadcast queue full, dropping log")
+			metrics.BroadcastDropped.Inc()
 		}
 	}
-}
\ No newline at end of file
+	return nil
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/handlers"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedLogs answers queries for logs with the records inserted into conn.
+func storedLogs(conn *clickhousestoretest.Conn) func(clickhousestoretest.Query) ([][]any, error) {
+	return func(q clickhousestoretest.Query) ([][]any, error) {
+		if !strings.Contains(q.SQL, "FROM logs") {
+			return nil, nil
+		}
+		var rows [][]any
+		for _, b := range conn.Batches() {
+			if !strings.HasPrefix(b.SQL, "INSERT INTO logs ") {
+				continue
+			}
+			for _, r := range b.Rows {
+				rows = append(rows, clickhousestoretest.LogRow(telemetrytypes.LogRecord{
+					TenantID:  r[0].(string),
+					ID:        r[1].(string),
+					Timestamp: r[3].(time.Time),
+					Body:      r[7].(string),
+				}))
+			}
+		}
+		return rows, nil
+	}
+}
+
+func TestIngestedLogsAreBroadcastOnce(t *testing.T) {
+	conn := &clickhousestoretest.Conn{}
+	conn.Rows = storedLogs(conn)
+	s := handlers.NewTestServer(clickhousestore.NewProviderFromConn(conn), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.RunBroadcaster(ctx)
+
+	srv := httptest.NewServer(http.HandlerFunc(s.WebSocketHandler))
+	defer srv.Close()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.Eventually(t, func() bool { return testutil.ToFloat64(metrics.WebSocketClients) == 1 }, time.Second, 10*time.Millisecond)
+
+	since := time.Now().Add(-time.Minute)
+	require.NoError(t, s.IngestLogs(ctx, []telemetrytypes.LogRecord{{Body: "checkout done", Timestamp: time.Now()}}))
+	// The poller reads the stored record back, but does not send it again.
+	next, err := s.Poll(ctx, since)
+	require.NoError(t, err)
+	assert.True(t, next.After(since))
+
+	var got telemetrytypes.LogRecord
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(time.Second)))
+	require.NoError(t, ws.ReadJSON(&got))
+	assert.Equal(t, "checkout done", got.Body)
+	assert.NotEmpty(t, got.ID)
+
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(300*time.Millisecond)))
+	_, _, err = ws.ReadMessage()
+	assert.Error(t, err, "the record is sent once")
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+const (
+	defaultPatternLimit  = 100
+	maxPatternLimit      = 1000
+	defaultPatternWindow = time.Hour
+	patternSamples       = 3
+)
+
+// GetLogPatterns returns the patterns of the records matching the query
+// in [start, end), most frequent first, with their counts, first and last
+// occurrence and a few sample bodies. By default it reads the patterns
+// assigned at ingest; source=mine mines the range on demand instead,
+// which also covers records stored without a pattern.
+func (s *Server) GetLogPatterns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	expr, err := filter.Parse(q.Get("query"))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'query' parameter: "+err.Error(), errors.SeverityInfo))
+		return
+	}
+
+	end := time.Now()
+	if e := q.Get("end"); e != "" {
+		if end, err = time.Parse(time.RFC3339Nano, e); err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'end' parameter", errors.SeverityInfo))
+			return
+		}
+	}
+	start := end.Add(-defaultPatternWindow)
+	if v := q.Get("start"); v != "" {
+		if start, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'start' parameter", errors.SeverityInfo))
+			return
+		}
+	}
+	if !start.Before(end) {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "'start' must be before 'end'", errors.SeverityInfo))
+		return
+	}
+
+	limit := defaultPatternLimit
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPatternLimit {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'limit' parameter, expected 1 to "+strconv.Itoa(maxPatternLimit), errors.SeverityInfo))
+			return
+		}
+	}
+
+	patternID := q.Get("pattern_id")
+	var summaries []patterntypes.Summary
+	switch q.Get("source") {
+	case "", "stored":
+		if patternID != "" {
+			cond := &filter.Condition{Field: filter.Field{Kind: filter.FieldColumn, Name: "pattern_id"}, Op: filter.OpEq, Value: patternID}
+			if expr == nil {
+				expr = cond
+			} else {
+				expr = &filter.And{Left: expr, Right: cond}
+			}
+		}
+		summaries, err = s.provider.PatternSummaries(r.Context(), tenantOf(r), expr, start, end, limit)
+	case "mine":
+		summaries, err = s.minePatterns(r, expr, start, end, patternID, limit)
+	default:
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid 'source' parameter, expected stored or mine", errors.SeverityInfo))
+		return
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch log patterns", errors.SeverityError, err))
+		return
+	}
+	if summaries == nil {
+		summaries = []patterntypes.Summary{}
+	}
+	render.JSON(w, http.StatusOK, summaries)
+}
+
+// minePatterns mines the records in range, starting from the tenant's
+// known patterns so that shapes seen at ingest keep their IDs. Nothing it
+// finds is stored.
+func (s *Server) minePatterns(r *http.Request, expr filter.Expr, start, end time.Time, patternID string, limit int) ([]patterntypes.Summary, error) {
+	tenant := tenantOf(r)
+	session, err := s.patterns.Session(r.Context(), tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*patterntypes.Summary)
+	err = s.provider.StreamLogs(r.Context(), tenant, expr, start, end, func(rec telemetrytypes.LogRecord) error {
+		id := session.Add(rec.Body)
+		if id == "" || (patternID != "" && id != patternID) {
+			return nil
+		}
+		sum, ok := byID[id]
+		if !ok {
+			sum = &patterntypes.Summary{ID: id, FirstSeen: rec.Timestamp}
+			byID[id] = sum
+		}
+		sum.Count++
+		sum.LastSeen = rec.Timestamp
+		if len(sum.Samples) < patternSamples && !slices.Contains(sum.Samples, rec.Body) {
+			sum.Samples = append(sum.Samples, rec.Body)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]patterntypes.Summary, 0, len(byID))
+	for id, sum := range byID {
+		sum.Template = session.Template(id)
+		summaries = append(summaries, *sum)
+	}
+	slices.SortFunc(summaries, func(a, b patterntypes.Summary) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	return summaries, nil
+}
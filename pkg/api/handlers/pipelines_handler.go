@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/exporter"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/processors"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/receviers"
+	"github.com/Ricky004/watchdata/pkg/processing"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+	"github.com/Ricky004/watchdata/pkg/types/processingtypes"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ListPipelines returns the pipeline definitions.
+func (s *Server) ListPipelines(w http.ResponseWriter, r *http.Request) {
+	defs, ok := s.pipelineDefinitions(w, r)
+	if !ok {
+		return
+	}
+	if defs == nil {
+		defs = []otelpipelinetypes.Definition{}
+	}
+	render.JSON(w, http.StatusOK, defs)
+}
+
+// CreatePipeline stores a new pipeline definition. It is rejected if the
+// definitions including it cannot be rendered into a valid collector
+// config.
+func (s *Server) CreatePipeline(w http.ResponseWriter, r *http.Request) {
+	def, ok := decodePipeline(w, r)
+	if !ok {
+		return
+	}
+
+	s.pipelinesMu.Lock()
+	defer s.pipelinesMu.Unlock()
+	defs, ok := s.pipelineDefinitions(w, r)
+	if !ok {
+		return
+	}
+	if slices.ContainsFunc(defs, func(d otelpipelinetypes.Definition) bool { return d.Name == def.Name }) {
+		render.Error(w, r, errors.NewMeta(errors.CodeAlreadyExists, "pipeline already exists", errors.SeverityInfo,
+			map[string]any{"name": def.Name}))
+		return
+	}
+
+	now := time.Now()
+	def.ID = uuid.NewString()
+	def.CreatedAt = now
+	def.UpdatedAt = now
+	if !s.checkPipelines(w, r, append(defs, def)) {
+		return
+	}
+
+	if err := s.provider.UpsertPipeline(r.Context(), def); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store pipeline", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusCreated, def)
+}
+
+// GetPipeline returns a single pipeline definition.
+func (s *Server) GetPipeline(w http.ResponseWriter, r *http.Request) {
+	if def, ok := s.pipeline(w, r); ok {
+		render.JSON(w, http.StatusOK, def)
+	}
+}
+
+// UpdatePipeline replaces a pipeline definition.
+func (s *Server) UpdatePipeline(w http.ResponseWriter, r *http.Request) {
+	s.pipelinesMu.Lock()
+	defer s.pipelinesMu.Unlock()
+	existing, ok := s.pipeline(w, r)
+	if !ok {
+		return
+	}
+	def, ok := decodePipeline(w, r)
+	if !ok {
+		return
+	}
+	defs, ok := s.pipelineDefinitions(w, r)
+	if !ok {
+		return
+	}
+
+	def.ID = existing.ID
+	def.CreatedAt = existing.CreatedAt
+	def.UpdatedAt = nextPipelineVersion(existing)
+	for i, d := range defs {
+		if d.ID == def.ID {
+			defs[i] = def
+		} else if d.Name == def.Name {
+			render.Error(w, r, errors.NewMeta(errors.CodeAlreadyExists, "pipeline already exists", errors.SeverityInfo,
+				map[string]any{"name": def.Name}))
+			return
+		}
+	}
+	if !s.checkPipelines(w, r, defs) {
+		return
+	}
+
+	if err := s.provider.UpsertPipeline(r.Context(), def); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store pipeline", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusOK, def)
+}
+
+// DeletePipeline removes a pipeline definition. The deployed files are
+// unchanged until the next deploy.
+func (s *Server) DeletePipeline(w http.ResponseWriter, r *http.Request) {
+	s.pipelinesMu.Lock()
+	defer s.pipelinesMu.Unlock()
+	existing, ok := s.pipeline(w, r)
+	if !ok {
+		return
+	}
+	if err := s.provider.DeletePipeline(r.Context(), existing.ID, nextPipelineVersion(existing)); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to delete pipeline", errors.SeverityError, err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// nextPipelineVersion returns the updated_at for a new version of the
+// stored pipeline. Versions decide which row the ReplacingMergeTree keeps,
+// so the new one must be later than the stored one even if this host's
+// clock is behind the host that wrote it. updated_at keeps milliseconds.
+func nextPipelineVersion(existing otelpipelinetypes.Definition) time.Time {
+	now := time.Now()
+	if next := existing.UpdatedAt.Add(time.Millisecond); next.After(now) {
+		return next
+	}
+	return now
+}
+
+// GetPipelinePlan renders the definitions into the collector and builder
+// configs and diffs them against the deployed files.
+func (s *Server) GetPipelinePlan(w http.ResponseWriter, r *http.Request) {
+	defs, ok := s.pipelineDefinitions(w, r)
+	if !ok {
+		return
+	}
+	plan, err := s.pipelines.Plan(defs)
+	if err != nil {
+		renderPipelineError(w, r, "failed to render pipelines", err)
+		return
+	}
+	render.JSON(w, http.StatusOK, plan)
+}
+
+// DeployPipelines writes the rendered configs over the deployed files,
+// keeping backups of the previous versions, and sends the collector config
+// to the collectors managed over OpAMP.
+func (s *Server) DeployPipelines(w http.ResponseWriter, r *http.Request) {
+	defs, ok := s.pipelineDefinitions(w, r)
+	if !ok {
+		return
+	}
+	plan, err := s.pipelines.Deploy(defs)
+	if err != nil {
+		renderPipelineError(w, r, "failed to deploy pipelines", err)
+		return
+	}
+	s.opamp.SetConfig([]byte(plan.CollectorConfig))
+	render.JSON(w, http.StatusOK, plan)
+}
+
+// GetPipelineCatalog returns the component templates pipelines can use,
+// with their params and the signals they carry.
+func (s *Server) GetPipelineCatalog(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, http.StatusOK, map[string]map[string]otelpipelinetypes.Template{
+		"receivers":  receviers.ReceiverTemplates,
+		"processors": processors.ProcessorTemplates,
+		"exporters":  exporter.ExporterTemplates,
+	})
+}
+
+// TestProcessing runs sample records through the ingest processing chain,
+// or through the steps given with them, and returns what it made of each
+// without storing anything.
+func (s *Server) TestProcessing(w http.ResponseWriter, r *http.Request) {
+	var req processingtypes.TestRequest
+	if !decodeBody(w, r, &req, "processing test") {
+		return
+	}
+
+	p := s.processing
+	if len(req.Steps) > 0 {
+		var err error
+		p, err = processing.NewProcessor(processing.Config{Steps: req.Steps})
+		if err != nil {
+			render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid processing steps: "+err.Error(), errors.SeverityInfo))
+			return
+		}
+	}
+	render.JSON(w, http.StatusOK, p.Test(req.Records))
+}
+
+// pipeline loads the definition named in the path, writing the error
+// response if it cannot.
+func (s *Server) pipeline(w http.ResponseWriter, r *http.Request) (otelpipelinetypes.Definition, bool) {
+	def, err := s.provider.GetPipeline(r.Context(), chi.URLParam(r, "id"))
+	if stderrors.Is(err, clickhousestore.ErrPipelineNotFound) {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "pipeline not found", errors.SeverityInfo))
+		return otelpipelinetypes.Definition{}, false
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch pipeline", errors.SeverityError, err))
+		return otelpipelinetypes.Definition{}, false
+	}
+	return def, true
+}
+
+func (s *Server) pipelineDefinitions(w http.ResponseWriter, r *http.Request) ([]otelpipelinetypes.Definition, bool) {
+	defs, err := s.provider.ListPipelines(r.Context())
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch pipelines", errors.SeverityError, err))
+		return nil, false
+	}
+	return defs, true
+}
+
+// checkPipelines renders defs, writing the error response if they are
+// invalid.
+func (s *Server) checkPipelines(w http.ResponseWriter, r *http.Request, defs []otelpipelinetypes.Definition) bool {
+	if _, err := s.pipelines.Render(defs); err != nil {
+		renderPipelineError(w, r, "failed to render pipelines", err)
+		return false
+	}
+	return true
+}
+
+// renderPipelineError reports definitions that do not render as invalid
+// requests, with the config paths of schema violations, and anything else
+// as msg.
+func renderPipelineError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	if !stderrors.Is(err, otelpipeline.ErrInvalid) {
+		render.Error(w, r, errors.New(errors.CodeInternalError, msg, errors.SeverityError, err))
+		return
+	}
+	var verr *otelpipelinetypes.ValidationError
+	if stderrors.As(err, &verr) {
+		render.Error(w, r, errors.NewMeta(errors.CodeInvalidRequest, "invalid pipeline: collector config does not validate", errors.SeverityInfo,
+			map[string]any{"issues": verr.Issues}))
+		return
+	}
+	render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid pipeline: "+err.Error(), errors.SeverityInfo))
+}
+
+func decodePipeline(w http.ResponseWriter, r *http.Request) (otelpipelinetypes.Definition, bool) {
+	var def otelpipelinetypes.Definition
+	if !decodeBody(w, r, &def, "pipeline") {
+		return def, false
+	}
+	if err := def.Validate(); err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid pipeline: "+err.Error(), errors.SeverityInfo))
+		return def, false
+	}
+	return def, true
+}
@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/handlers"
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePipelineKeepsNamesUnique(t *testing.T) {
+	// The fake store lists the pipelines inserted so far, slowly enough
+	// for concurrent requests to all find the name free if they can.
+	conn := &clickhousestoretest.Conn{}
+	conn.Rows = func(q clickhousestoretest.Query) ([][]any, error) {
+		var rows [][]any
+		for _, exec := range conn.Execs() {
+			if strings.HasPrefix(exec.SQL, "INSERT INTO pipelines") {
+				rows = append(rows, []any{exec.Args[1]})
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return rows, nil
+	}
+	dir := t.TempDir()
+	deployer := otelpipeline.NewDeployer(otelpipeline.Config{
+		CollectorConfig: filepath.Join(dir, "otel-collector-config.yaml"),
+		BuilderConfig:   filepath.Join(dir, "builder-config.yaml"),
+	})
+	s := handlers.NewTestServer(clickhousestore.NewProviderFromConn(conn), deployer)
+	handler := render.RequestID(http.HandlerFunc(s.CreatePipeline))
+
+	const requests = 8
+	codes := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := `{"name":"logs","pipeline":{"receivers":["otlp"],"exporters":["watchdataexporter"]}}`
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/pipelines", strings.NewReader(body)))
+			codes[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		} else {
+			assert.Equal(t, http.StatusConflict, code)
+		}
+	}
+	assert.Equal(t, 1, created)
+	require.Len(t, conn.Execs(), 1)
+}
+
+func TestDeletePipelineOutranksStoredVersion(t *testing.T) {
+	// The stored version was written by a host whose clock is ahead.
+	stored := time.Now().UTC().Add(time.Hour).Truncate(time.Millisecond)
+	conn := &clickhousestoretest.Conn{Rows: func(clickhousestoretest.Query) ([][]any, error) {
+		return [][]any{{`{"id":"p1","name":"logs","updated_at":"` + stored.Format(time.RFC3339Nano) + `"}`}}, nil
+	}}
+	s := handlers.NewTestServer(clickhousestore.NewProviderFromConn(conn), nil)
+	router := chi.NewRouter()
+	router.Delete("/v1/pipelines/{id}", s.DeletePipeline)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1/pipelines/p1", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	execs := conn.Execs()
+	require.Len(t, execs, 1)
+	assert.Equal(t, stored.Add(time.Millisecond), execs[0].Args[1])
+}
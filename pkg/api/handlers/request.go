@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+)
+
+// decodeBody decodes a JSON request body into v, writing the error
+// response if it cannot. what names the body in error messages.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any, what string) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if stderrors.As(err, &tooLarge) {
+		render.Error(w, r, errors.NewMeta(errors.CodePayloadTooLarge, what+" body is too large", errors.SeverityInfo,
+			map[string]any{"limit": tooLarge.Limit}))
+		return false
+	}
+	render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid "+what+" body", errors.SeverityInfo))
+	return false
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+)
+
+// Services returns the server's background work as services, in start
+// order. The registry stops them in reverse, so the poller, alerting and
+// the anomaly detector stop before the broadcaster, and the store is
+// closed last. storeWriters are started right after the store, so they
+// are stopped right before it.
+func (s *Server) Services(storeWriters ...factory.IdxService) []factory.IdxService {
+	services := []factory.IdxService{
+		factory.NewRunService(factory.MustNewId("clickhouse"), func(ctx context.Context) error {
+			<-ctx.Done()
+			return s.provider.Close()
+		}),
+	}
+	services = append(services, storeWriters...)
+	services = append(services,
+		factory.NewRunService(factory.MustNewId("broadcaster"), s.runBroadcaster),
+		factory.NewRunService(factory.MustNewId("alert-dispatcher"), func(ctx context.Context) error {
+			s.dispatcher.Run(ctx)
+			return nil
+		}),
+		factory.NewRunService(factory.MustNewId("alert-engine"), func(ctx context.Context) error {
+			s.alerts.Run(ctx)
+			return nil
+		}),
+		factory.NewRunService(factory.MustNewId("poller"), s.pollDatabase),
+		factory.NewRunService(factory.MustNewId("opamp"), s.opamp.Run),
+	)
+	if s.anomalies != nil {
+		services = append(services, factory.NewRunService(factory.MustNewId("anomaly-detector"), s.anomalies.Run))
+	}
+	return services
+}
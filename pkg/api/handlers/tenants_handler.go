@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+	"github.com/go-chi/chi/v5"
+)
+
+// tenantOf returns the tenant the request acts on, as resolved by the
+// auth middleware.
+func tenantOf(r *http.Request) string {
+	p, ok := authtypes.FromContext(r.Context())
+	if !ok || p.TenantID == "" {
+		return tenanttypes.DefaultTenant
+	}
+	return p.TenantID
+}
+
+// RequireGlobal rejects callers that are bound to a tenant. Tenant settings
+// and the shared collector pipelines can only be managed by operators.
+func RequireGlobal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := authtypes.FromContext(r.Context())
+		if !ok || !p.Global {
+			render.Error(w, r, errors.New(errors.CodeForbidden, "this resource can only be managed with a global token", errors.SeverityInfo))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListTenants returns all tenants.
+func (s *Server) ListTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := s.provider.ListTenants(r.Context())
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch tenants", errors.SeverityError, err))
+		return
+	}
+	if tenants == nil {
+		tenants = []tenanttypes.Tenant{}
+	}
+	render.JSON(w, http.StatusOK, tenants)
+}
+
+// CreateTenant stores a new tenant. Existing tenants are not overwritten.
+func (s *Server) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := decodeTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	_, err := s.provider.GetTenant(r.Context(), tenant.ID)
+	if err == nil {
+		render.Error(w, r, errors.New(errors.CodeAlreadyExists, "tenant already exists", errors.SeverityInfo))
+		return
+	}
+	if !stderrors.Is(err, tenanttypes.ErrTenantNotFound) {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store tenant", errors.SeverityError, err))
+		return
+	}
+
+	now := time.Now()
+	tenant.CreatedAt = now
+	tenant.UpdatedAt = now
+	if err := s.provider.UpsertTenant(r.Context(), tenant); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store tenant", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusCreated, tenant)
+}
+
+// GetTenant returns a single tenant.
+func (s *Server) GetTenant(w http.ResponseWriter, r *http.Request) {
+	if tenant, ok := s.tenant(w, r); ok {
+		render.JSON(w, http.StatusOK, tenant)
+	}
+}
+
+// UpdateTenant replaces a tenant's settings.
+func (s *Server) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	existing, ok := s.tenant(w, r)
+	if !ok {
+		return
+	}
+
+	tenant, ok := decodeTenant(w, r, existing.ID)
+	if !ok {
+		return
+	}
+
+	tenant.CreatedAt = existing.CreatedAt
+	tenant.UpdatedAt = time.Now()
+	if err := s.provider.UpsertTenant(r.Context(), tenant); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to store tenant", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusOK, tenant)
+}
+
+// DeleteTenant removes a tenant.
+func (s *Server) DeleteTenant(w http.ResponseWriter, r *http.Request) {
+	existing, ok := s.tenant(w, r)
+	if !ok {
+		return
+	}
+	if err := s.provider.DeleteTenant(r.Context(), existing.ID); err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to delete tenant", errors.SeverityError, err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) tenant(w http.ResponseWriter, r *http.Request) (tenanttypes.Tenant, bool) {
+	tenant, err := s.provider.GetTenant(r.Context(), chi.URLParam(r, "id"))
+	if stderrors.Is(err, tenanttypes.ErrTenantNotFound) {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "tenant not found", errors.SeverityInfo))
+		return tenanttypes.Tenant{}, false
+	}
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch tenant", errors.SeverityError, err))
+		return tenanttypes.Tenant{}, false
+	}
+	return tenant, true
+}
+
+// decodeTenant decodes and validates a tenant body. A non-empty id, taken
+// from the path, replaces the one in the body.
+func decodeTenant(w http.ResponseWriter, r *http.Request, id string) (tenanttypes.Tenant, bool) {
+	var tenant tenanttypes.Tenant
+	if !decodeBody(w, r, &tenant, "tenant") {
+		return tenant, false
+	}
+	if id != "" {
+		tenant.ID = id
+	}
+	if tenant.RetentionDays == 0 {
+		tenant.RetentionDays = tenanttypes.DefaultRetentionDays
+	}
+	if err := tenant.Validate(); err != nil {
+		render.Error(w, r, errors.New(errors.CodeInvalidRequest, "invalid tenant: "+err.Error(), errors.SeverityInfo))
+		return tenant, false
+	}
+	return tenant, true
+}
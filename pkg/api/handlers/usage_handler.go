@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+)
+
+// GetUsage returns the tenant's ingest rates, stored bytes and rejected
+// requests against its limits.
+func (s *Server) GetUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.limiter.Usage(r.Context(), tenantOf(r))
+	if err != nil {
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch usage", errors.SeverityError, err))
+		return
+	}
+	render.JSON(w, http.StatusOK, usage)
+}
@@ -0,0 +1,86 @@
+// Package render writes API responses: JSON bodies, the error envelope
+// and request IDs.
+package render
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID. Clients may set it to correlate
+// their own logs; otherwise the server generates one.
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// RequestID assigns every request an ID, echoes it in the response and
+// stores it in the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the ID assigned by RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ErrorBody is the machine-readable part of an error response.
+type ErrorBody struct {
+	Code      errors.Code    `json:"code"`
+	Message   string         `json:"message"`
+	Meta      map[string]any `json:"meta,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+// Envelope is the body of every error response.
+type Envelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+// JSON writes v with the given status.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+// Error writes err as an error envelope with the status mapped from its
+// code. Only the code, message and meta are sent to the client; server
+// errors are logged with their cause.
+//
+// Server errors of requests whose deadline has passed are reported as
+// timeouts, since the deadline is the likely cause.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	e := errors.From(err)
+	if e.Code.HTTPStatus() >= http.StatusInternalServerError && stderrors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		e = errors.New(errors.CodeDBTimeout, "request timed out", errors.SeverityWarning, e)
+	}
+	status := e.Code.HTTPStatus()
+	id := RequestIDFromContext(r.Context())
+
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed",
+			"request_id", id, "method", r.Method, "path", r.URL.Path, "error", e)
+	}
+
+	JSON(w, status, Envelope{Error: ErrorBody{Code: e.Code, Message: e.Message, Meta: e.Meta, RequestID: id}})
+}
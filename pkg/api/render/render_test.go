@@ -0,0 +1,56 @@
+package render_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, err error, requestID string) (*httptest.ResponseRecorder, render.Envelope) {
+	t.Helper()
+	h := render.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		render.Error(w, r, err)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/logs", nil)
+	if requestID != "" {
+		req.Header.Set(render.RequestIDHeader, requestID)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var env render.Envelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+	return rec, env
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	err := errors.NewMeta(errors.CodeNotFound, "alert rule not found", errors.SeverityInfo, map[string]any{"id": "rule-1"})
+	rec, env := serve(t, fmt.Errorf("lookup: %w", err), "client-req-1")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "client-req-1", rec.Header().Get(render.RequestIDHeader))
+	assert.Equal(t, errors.CodeNotFound, env.Error.Code)
+	assert.Equal(t, "alert rule not found", env.Error.Message)
+	assert.Equal(t, "rule-1", env.Error.Meta["id"])
+	assert.Equal(t, "client-req-1", env.Error.RequestID)
+}
+
+func TestErrorHidesInternalDetails(t *testing.T) {
+	rec, env := serve(t, fmt.Errorf("dial tcp 10.0.0.3:9000: connection refused"), "bad id with spaces")
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, errors.CodeInternalError, env.Error.Code)
+	assert.Equal(t, "internal error", env.Error.Message)
+	assert.NotEqual(t, "bad id with spaces", env.Error.RequestID)
+	assert.Len(t, env.Error.RequestID, 36, "a fresh uuid replaces invalid ids")
+	assert.Equal(t, env.Error.RequestID, rec.Header().Get(render.RequestIDHeader))
+}
@@ -0,0 +1,174 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api"
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/auth"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/telemetry"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CORS allows browsers on the configured origins to call the API and
+// answers preflight requests before they reach authentication. Only
+// listed origins may send credentials; "*" lets any other origin call the
+// API without the session cookie.
+func CORS(cfg api.CORSConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && cfg.OriginAllowed(origin)
+			if allowed && cfg.OriginListed(origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			} else if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			}
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+auth.TenantHeader+", "+render.RequestIDHeader)
+				w.Header().Set("Access-Control-Expose-Headers", render.RequestIDHeader)
+			}
+			w.Header().Add("Vary", "Origin")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Recoverer turns a panicking handler into a 500 response and logs the
+// stack. Aborted handlers are re-panicked so net/http drops the
+// connection.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+
+			slog.ErrorContext(r.Context(), "handler panicked",
+				"request_id", render.RequestIDFromContext(r.Context()), "method", r.Method, "path", r.URL.Path,
+				"panic", fmt.Sprint(rvr), "stack", string(debug.Stack()))
+
+			// A hijacked WebSocket connection can no longer be written to.
+			if r.Header.Get("Connection") == "Upgrade" {
+				return
+			}
+			render.Error(w, r, errors.New(errors.CodePanicRecovered, "internal error", errors.SeverityCritical))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Logger logs every request once it has been served.
+func Logger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			slog.InfoContext(r.Context(), "request",
+				"request_id", render.RequestIDFromContext(r.Context()), "method", r.Method, "path", r.URL.Path,
+				"status", status, "bytes", ww.BytesWritten(), "duration", time.Since(start))
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// Timeout cancels the request context after d. Handlers report the
+// cancelled queries, which render.Error maps to a timeout.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Metrics measures requests by their route pattern.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			metrics.RequestDuration.WithLabelValues(r.Method, routePattern(r), strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// Trace records a server span for every request, continuing the trace of
+// the caller. Spans are named by route pattern once the route is matched.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := telemetry.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r = r.WithContext(ctx)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := routePattern(r)
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+				attribute.Int("http.response.status_code", status),
+				attribute.String("request_id", render.RequestIDFromContext(ctx)),
+			)
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched"
+}
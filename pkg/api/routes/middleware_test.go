@@ -0,0 +1,139 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/api"
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/api/routes"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) render.Envelope {
+	t.Helper()
+	var env render.Envelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &env))
+	return env
+}
+
+func TestRecoverer(t *testing.T) {
+	h := render.RequestID(routes.Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/logs", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	env := decode(t, rec)
+	assert.Equal(t, errors.CodePanicRecovered, env.Error.Code)
+	assert.NotContains(t, rec.Body.String(), "boom")
+	assert.NotEmpty(t, env.Error.RequestID)
+}
+
+func TestRecovererRepanicsOnAbort(t *testing.T) {
+	h := routes.Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestCORS(t *testing.T) {
+	reached := false
+	h := routes.CORS(api.CORSConfig{AllowedOrigins: []string{"http://localhost:3000"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/v1/alerts/rules", nil)
+	preflight.Header.Set("Origin", "http://localhost:3000")
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, preflight)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "http://localhost:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.False(t, reached, "preflight must not reach the handler")
+
+	other := httptest.NewRequest(http.MethodGet, "/v1/logs", nil)
+	other.Header.Set("Origin", "http://evil.example")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, other)
+
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.True(t, reached)
+}
+
+func TestCORSCredentials(t *testing.T) {
+	h := routes.CORS(api.CORSConfig{AllowedOrigins: []string{"http://localhost:3000", "*"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for origin, want := range map[string]struct{ allow, credentials string }{
+		"http://localhost:3000": {allow: "http://localhost:3000", credentials: "true"},
+		"http://evil.example":   {allow: "*"},
+	} {
+		t.Run(origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/logs", nil)
+			req.Header.Set("Origin", origin)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, want.allow, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, want.credentials, rec.Header().Get("Access-Control-Allow-Credentials"))
+		})
+	}
+}
+
+func TestTimeoutIsReportedAsTimeout(t *testing.T) {
+	h := routes.Timeout(time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		render.Error(w, r, errors.New(errors.CodeDBQueryFailed, "failed to fetch logs", errors.SeverityError, context.Cause(r.Context())))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/logs", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, errors.CodeDBTimeout, decode(t, rec).Error.Code)
+}
+
+func TestLoggerKeepsResponse(t *testing.T) {
+	h := routes.Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "short"))
+}
+
+func TestMetricsUsesRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(routes.Metrics)
+	r.Get("/v1/alerts/rules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	before := testutil.CollectAndCount(metrics.RequestDuration)
+	for _, id := range []string{"a", "b", "c"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/alerts/rules/"+id, nil))
+	}
+
+	// Three paths, one series.
+	assert.Equal(t, before+1, testutil.CollectAndCount(metrics.RequestDuration))
+}
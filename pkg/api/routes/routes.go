@@ -0,0 +1,167 @@
+// Package routes declares the HTTP routes of the query API and the OTLP/HTTP
+// receiver.
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Ricky004/watchdata/internals/ingest"
+	"github.com/Ricky004/watchdata/pkg/api"
+	"github.com/Ricky004/watchdata/pkg/api/handlers"
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// compressLevel is the gzip level of compressed responses.
+const compressLevel = 5
+
+// RegisterRoutes returns the query API.
+func RegisterRoutes(cfg api.Config, s *handlers.Server) http.Handler {
+	a := s.Authenticator()
+	viewer := a.Middleware(authtypes.RoleViewer)
+	editor := a.Middleware(authtypes.RoleEditor)
+	admin := a.Middleware(authtypes.RoleAdmin)
+
+	r := newRouter()
+
+	// Probes and scrapes are frequent, so they are neither logged nor
+	// measured.
+	r.Group(func(r chi.Router) {
+		r.Use(Recoverer)
+		r.Get("/healthz", s.Healthz)
+		r.Get("/readyz", s.Readyz)
+		r.Method(http.MethodGet, "/metrics", metrics.Handler())
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(Trace, Logger, Metrics, Recoverer, CORS(cfg.CORS))
+
+		r.Route("/v1", func(r chi.Router) {
+			r.Use(middleware.RequestSize(cfg.MaxRequestBytes))
+			r.Use(middleware.Compress(compressLevel))
+
+			// Exports stream for as long as the result takes.
+			r.With(viewer, Timeout(cfg.ExportTimeout)).Get("/logs/export", s.ExportLogs)
+
+			r.Group(func(r chi.Router) {
+				r.Use(Timeout(cfg.RequestTimeout))
+
+				r.Route("/logs", func(r chi.Router) {
+					r.Use(viewer)
+					r.Get("/", s.GetLogs)
+					r.Get("/since", s.GetLogsSince)
+					r.Get("/timerange", s.GetLogsInTimeRanges)
+					r.Get("/context", s.GetLogContext)
+					r.Get("/patterns", s.GetLogPatterns)
+				})
+
+				r.Route("/alerts", func(r chi.Router) {
+					r.With(viewer).Get("/", s.GetAlerts)
+					r.With(viewer).Get("/history", s.GetAlertHistory)
+					r.With(viewer).Get("/notifications", s.GetNotifications)
+
+					r.Route("/rules", func(r chi.Router) {
+						r.With(viewer).Get("/", s.ListAlertRules)
+						r.With(editor).Post("/", s.CreateAlertRule)
+						r.With(viewer).Get("/{id}", s.GetAlertRule)
+						r.With(editor).Put("/{id}", s.UpdateAlertRule)
+						r.With(editor).Delete("/{id}", s.DeleteAlertRule)
+					})
+
+					r.Route("/silences", func(r chi.Router) {
+						r.With(viewer).Get("/", s.ListSilences)
+						r.With(editor).Post("/", s.CreateSilence)
+						r.With(viewer).Get("/{id}", s.GetSilence)
+						r.With(editor).Delete("/{id}", s.ExpireSilence)
+					})
+				})
+
+				r.Route("/auth", func(r chi.Router) {
+					r.With(viewer).Get("/whoami", s.WhoAmI)
+
+					// Single sign-on runs before the caller has credentials.
+					r.Get("/oidc/login", s.OIDCLogin)
+					r.Get("/oidc/callback", s.OIDCCallback)
+					r.Post("/logout", s.Logout)
+
+					r.Route("/keys", func(r chi.Router) {
+						r.Use(admin)
+						r.Get("/", s.ListAPIKeys)
+						r.Post("/", s.CreateAPIKey)
+						r.Get("/{id}", s.GetAPIKey)
+						r.Delete("/{id}", s.RevokeAPIKey)
+					})
+				})
+
+				r.With(viewer).Get("/usage", s.GetUsage)
+				r.With(viewer).Get("/anomalies", s.GetAnomalies)
+
+				r.Route("/pipelines", func(r chi.Router) {
+					r.Use(admin, handlers.RequireGlobal)
+					r.Get("/", s.ListPipelines)
+					r.Post("/", s.CreatePipeline)
+					r.Get("/catalog", s.GetPipelineCatalog)
+					r.Get("/plan", s.GetPipelinePlan)
+					r.Post("/deploy", s.DeployPipelines)
+					r.Post("/test", s.TestProcessing)
+					r.Get("/{id}", s.GetPipeline)
+					r.Put("/{id}", s.UpdatePipeline)
+					r.Delete("/{id}", s.DeletePipeline)
+				})
+
+				r.Route("/agents", func(r chi.Router) {
+					r.Use(admin, handlers.RequireGlobal)
+					r.Get("/", s.ListAgents)
+					r.Get("/{id}", s.GetAgent)
+				})
+
+				r.Route("/tenants", func(r chi.Router) {
+					r.Use(admin, handlers.RequireGlobal)
+					r.Get("/", s.ListTenants)
+					r.Post("/", s.CreateTenant)
+					r.Get("/{id}", s.GetTenant)
+					r.Put("/{id}", s.UpdateTenant)
+					r.Delete("/{id}", s.DeleteTenant)
+				})
+			})
+		})
+
+		// WebSocket connections are long-lived and hijack the connection, so
+		// they are served outside the timeout and compression middleware.
+		r.With(viewer).Get("/ws", s.WebSocketHandler)
+		// Collectors are sent configs holding credentials, so only global
+		// admins may connect. The OpAMP server limits message sizes.
+		r.With(admin, handlers.RequireGlobal).HandleFunc("/v1/opamp", s.OpAMP)
+	})
+
+	return r
+}
+
+// RegisterIngestRoutes returns the OTLP/HTTP receiver. Its body limit is
+// enforced by the receiver, which accepts compressed bodies.
+func RegisterIngestRoutes(s *handlers.Server) http.Handler {
+	a := s.Authenticator()
+
+	r := newRouter()
+	r.Use(Trace, Logger, Metrics, Recoverer)
+	r.With(a.Middleware(authtypes.RoleEditor)).Post("/v1/logs", ingest.HTTPHandler(s, s.Limiter()))
+	return r
+}
+
+// newRouter returns a router with the request IDs and error responses
+// shared by all servers.
+func newRouter() *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(render.RequestID)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		render.Error(w, r, errors.New(errors.CodeNotFound, "route not found", errors.SeverityInfo))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		render.Error(w, r, errors.New(errors.CodeMethodNotAllowed, "method not allowed", errors.SeverityInfo))
+	})
+	return r
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// tokenPrefix marks watchdata API keys so they are easy to spot in secret
+// scanners. Tokens look like wd_<key id>_<secret>.
+const tokenPrefix = "wd_"
+
+// NewAPIKey generates a key for a tenant and returns it together with the
+// plaintext token. The token is only available at creation time; only its
+// hash is stored.
+func NewAPIKey(tenantID, name string, role authtypes.Role, ttl time.Duration) (string, authtypes.APIKey, error) {
+	if !tenanttypes.ValidID(tenantID) {
+		return "", authtypes.APIKey{}, fmt.Errorf("invalid tenant id %q", tenantID)
+	}
+	if name == "" {
+		return "", authtypes.APIKey{}, fmt.Errorf("name is required")
+	}
+	if !role.Valid() {
+		return "", authtypes.APIKey{}, fmt.Errorf("unknown role %q", role)
+	}
+
+	idBytes := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", authtypes.APIKey{}, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", authtypes.APIKey{}, err
+	}
+
+	id := hex.EncodeToString(idBytes)
+	token := tokenPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	key := authtypes.APIKey{
+		ID:        id,
+		TenantID:  tenantID,
+		Name:      name,
+		Role:      role,
+		Hash:      HashToken(token),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+	return token, key, nil
+}
+
+// HashToken returns the hex SHA-256 of a token. Tokens carry 256 bits of
+// randomness, so a fast hash is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// keyID extracts the key id from a token.
+func keyID(token string) (string, bool) {
+	rest, ok := strings.CutPrefix(token, tokenPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 12 || secret == "" {
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// Store is where API keys and sessions are looked up.
+type Store interface {
+	GetAPIKey(ctx context.Context, id string) (authtypes.APIKey, error)
+	GetSession(ctx context.Context, id string) (authtypes.Session, error)
+}
+
+// cacheTTL bounds how long a revoked key or session may still be accepted
+// by other server instances.
+const cacheTTL = 30 * time.Second
+
+type cachedKey struct {
+	key       authtypes.APIKey
+	fetchedAt time.Time
+}
+
+type cachedSession struct {
+	session   authtypes.Session
+	fetchedAt time.Time
+}
+
+// Authenticator verifies bearer tokens.
+type Authenticator struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+
+	mu       sync.Mutex
+	cache    map[string]cachedKey
+	sessions map[string]cachedSession
+}
+
+func NewAuthenticator(cfg Config, store Store) *Authenticator {
+	return &Authenticator{
+		cfg:      cfg,
+		store:    store,
+		now:      time.Now,
+		cache:    make(map[string]cachedKey),
+		sessions: make(map[string]cachedSession),
+	}
+}
+
+// Enabled reports whether requests must be authenticated.
+func (a *Authenticator) Enabled() bool {
+	return a.cfg.Enabled
+}
+
+var (
+	errMissingToken = errors.New(errors.CodeUnauthorized, "missing bearer token", errors.SeverityInfo)
+	errInvalidToken = errors.New(errors.CodeTokenInvalid, "invalid API key", errors.SeverityInfo)
+	errExpiredToken = errors.New(errors.CodeTokenExpired, "API key expired", errors.SeverityInfo)
+
+	errInvalidSession = errors.New(errors.CodeTokenInvalid, "invalid session", errors.SeverityInfo)
+	errExpiredSession = errors.New(errors.CodeTokenExpired, "session expired", errors.SeverityInfo)
+)
+
+// Authenticate resolves a bearer token to a principal.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (authtypes.Principal, error) {
+	if !a.cfg.Enabled {
+		return authtypes.Principal{Subject: "anonymous", Role: authtypes.RoleAdmin, Method: "disabled", Global: true}, nil
+	}
+	if token == "" {
+		return authtypes.Principal{}, errMissingToken
+	}
+
+	if a.cfg.BootstrapToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.cfg.BootstrapToken)) == 1 {
+		return authtypes.Principal{Subject: "bootstrap", Role: authtypes.RoleAdmin, Method: "bootstrap", Global: true}, nil
+	}
+
+	id, ok := keyID(token)
+	if !ok {
+		return authtypes.Principal{}, errInvalidToken
+	}
+
+	key, err := a.lookup(ctx, id)
+	if err != nil {
+		return authtypes.Principal{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(key.Hash)) != 1 || key.Revoked {
+		return authtypes.Principal{}, errInvalidToken
+	}
+	if key.Expired(a.now()) {
+		return authtypes.Principal{}, errExpiredToken
+	}
+
+	return authtypes.Principal{Subject: key.Name, Role: key.Role, KeyID: key.ID, Method: "api_key", TenantID: tenantOrDefault(key.TenantID)}, nil
+}
+
+func (a *Authenticator) lookup(ctx context.Context, id string) (authtypes.APIKey, error) {
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[id]
+	a.mu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < cacheTTL {
+		return cached.key, nil
+	}
+
+	key, err := a.store.GetAPIKey(ctx, id)
+	if err != nil {
+		// Unknown ids look the same as bad secrets to the caller.
+		if stderrors.Is(err, authtypes.ErrAPIKeyNotFound) {
+			return authtypes.APIKey{}, errInvalidToken
+		}
+		return authtypes.APIKey{}, errors.New(errors.CodeDBQueryFailed, "failed to look up API key", errors.SeverityError, err)
+	}
+
+	a.mu.Lock()
+	a.cache[id] = cachedKey{key: key, fetchedAt: now}
+	a.mu.Unlock()
+	return key, nil
+}
+
+// AuthenticateSession resolves a session cookie value to a principal.
+func (a *Authenticator) AuthenticateSession(ctx context.Context, token string) (authtypes.Principal, error) {
+	if !a.cfg.Enabled {
+		return a.Authenticate(ctx, "")
+	}
+
+	s, err := a.lookupSession(ctx, SessionID(token))
+	if err != nil {
+		return authtypes.Principal{}, err
+	}
+	if s.Revoked {
+		return authtypes.Principal{}, errInvalidSession
+	}
+	if s.Expired(a.now()) {
+		return authtypes.Principal{}, errExpiredSession
+	}
+
+	return authtypes.Principal{
+		Subject:  s.Subject,
+		Role:     s.Role,
+		Method:   "session",
+		TenantID: tenantOrDefault(s.TenantID),
+		Email:    s.Email,
+		Name:     s.Name,
+		Groups:   s.Groups,
+	}, nil
+}
+
+func (a *Authenticator) lookupSession(ctx context.Context, id string) (authtypes.Session, error) {
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.sessions[id]
+	a.mu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < cacheTTL {
+		return cached.session, nil
+	}
+
+	s, err := a.store.GetSession(ctx, id)
+	if err != nil {
+		if stderrors.Is(err, authtypes.ErrSessionNotFound) {
+			return authtypes.Session{}, errInvalidSession
+		}
+		return authtypes.Session{}, errors.New(errors.CodeDBQueryFailed, "failed to look up session", errors.SeverityError, err)
+	}
+
+	a.mu.Lock()
+	a.sessions[id] = cachedSession{session: s, fetchedAt: now}
+	a.mu.Unlock()
+	return s, nil
+}
+
+func tenantOrDefault(id string) string {
+	if id == "" {
+		return tenanttypes.DefaultTenant
+	}
+	return id
+}
+
+// Invalidate drops a key from the cache, e.g. after it was revoked.
+func (a *Authenticator) Invalidate(id string) {
+	a.mu.Lock()
+	delete(a.cache, id)
+	a.mu.Unlock()
+}
+
+// InvalidateSession drops a session from the cache after logout.
+func (a *Authenticator) InvalidateSession(id string) {
+	a.mu.Lock()
+	delete(a.sessions, id)
+	a.mu.Unlock()
+}
@@ -0,0 +1,155 @@
+package auth_test
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/auth"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	keys     map[string]authtypes.APIKey
+	sessions map[string]authtypes.Session
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{keys: map[string]authtypes.APIKey{}, sessions: map[string]authtypes.Session{}}
+}
+
+func (s *fakeStore) GetAPIKey(_ context.Context, id string) (authtypes.APIKey, error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return authtypes.APIKey{}, authtypes.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *fakeStore) GetSession(_ context.Context, id string) (authtypes.Session, error) {
+	session, ok := s.sessions[id]
+	if !ok {
+		return authtypes.Session{}, authtypes.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func assertCode(t *testing.T, err error, code errors.Code) {
+	t.Helper()
+	var e *errors.Error
+	require.True(t, stderrors.As(err, &e), "expected *errors.Error, got %v", err)
+	assert.Equal(t, code, e.Code)
+}
+
+func newKey(t *testing.T, store *fakeStore, role authtypes.Role, ttl time.Duration) string {
+	t.Helper()
+	token, key, err := auth.NewAPIKey("team-a", "test", role, ttl)
+	require.NoError(t, err)
+	store.keys[key.ID] = key
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	store := newFakeStore()
+	a := auth.NewAuthenticator(auth.Config{Enabled: true}, store)
+
+	token := newKey(t, store, authtypes.RoleEditor, 0)
+	p, err := a.Authenticate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, authtypes.RoleEditor, p.Role)
+	assert.Equal(t, "api_key", p.Method)
+	assert.Equal(t, "team-a", p.TenantID)
+	assert.False(t, p.Global)
+
+	_, err = a.Authenticate(context.Background(), "")
+	assertCode(t, err, errors.CodeUnauthorized)
+
+	_, err = a.Authenticate(context.Background(), token+"x")
+	assertCode(t, err, errors.CodeTokenInvalid)
+
+	_, err = a.Authenticate(context.Background(), "wd_000000000000_secret")
+	assertCode(t, err, errors.CodeTokenInvalid)
+
+	expired := newKey(t, store, authtypes.RoleViewer, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	_, err = a.Authenticate(context.Background(), expired)
+	assertCode(t, err, errors.CodeTokenExpired)
+}
+
+func TestRequire(t *testing.T) {
+	store := newFakeStore()
+	a := auth.NewAuthenticator(auth.Config{Enabled: true}, store)
+	viewer := newKey(t, store, authtypes.RoleViewer, 0)
+	admin := newKey(t, store, authtypes.RoleAdmin, 0)
+
+	h := a.Require(authtypes.RoleEditor, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		tenant string
+		want   int
+	}{
+		{"missing", "", "", http.StatusUnauthorized},
+		{"insufficient role", "Bearer " + viewer, "", http.StatusForbidden},
+		{"higher role", "Bearer " + admin, "", http.StatusOK},
+		{"own tenant", "Bearer " + admin, "team-a", http.StatusOK},
+		{"other tenant", "Bearer " + admin, "team-b", http.StatusForbidden},
+		{"editor session", "", "", http.StatusOK},
+		{"revoked session", "", "", http.StatusUnauthorized},
+	}
+
+	editorToken, editor, err := auth.NewSession(auth.Identity{Subject: "u1"}, authtypes.RoleEditor, time.Hour)
+	require.NoError(t, err)
+	store.sessions[editor.ID] = editor
+	revokedToken, revoked, err := auth.NewSession(auth.Identity{Subject: "u2"}, authtypes.RoleAdmin, time.Hour)
+	require.NoError(t, err)
+	revoked.Revoked = true
+	store.sessions[revoked.ID] = revoked
+	cookies := map[string]string{"editor session": editorToken, "revoked session": revokedToken}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.tenant != "" {
+				req.Header.Set(auth.TenantHeader, tt.tenant)
+			}
+			if cookie, ok := cookies[tt.name]; ok {
+				req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: cookie})
+			}
+			rec := httptest.NewRecorder()
+			h(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	global := authtypes.Principal{Subject: "bootstrap", Role: authtypes.RoleAdmin, Global: true}
+
+	p, err := auth.ResolveTenant(global, "")
+	require.NoError(t, err)
+	assert.Equal(t, "default", p.TenantID)
+
+	p, err = auth.ResolveTenant(global, "team-b")
+	require.NoError(t, err)
+	assert.Equal(t, "team-b", p.TenantID)
+
+	_, err = auth.ResolveTenant(global, "*")
+	assert.Error(t, err)
+
+	bound := authtypes.Principal{Subject: "key", Role: authtypes.RoleAdmin, TenantID: "team-a"}
+	_, err = auth.ResolveTenant(bound, "team-b")
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+)
+
+type Config struct {
+	// Enabled turns authentication on. When disabled every request is
+	// treated as coming from an admin, which is only safe on localhost.
+	Enabled bool `mapstructure:"enabled"`
+
+	// BootstrapToken is an admin token that is accepted without being
+	// stored, used to create the first API keys.
+	BootstrapToken string `mapstructure:"bootstrap_token"`
+
+	// OIDC configures browser single sign-on. It is off unless an issuer
+	// is set.
+	OIDC OIDCConfig `mapstructure:"oidc"`
+}
+
+type OIDCConfig struct {
+	// Issuer is the OpenID provider URL used for discovery.
+	Issuer string `mapstructure:"issuer"`
+
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+
+	// RedirectURL is this server's callback, ending in
+	// /v1/auth/oidc/callback.
+	RedirectURL string `mapstructure:"redirect_url"`
+
+	// Scopes requested in addition to "openid".
+	Scopes []string `mapstructure:"scopes"`
+
+	// GroupsClaim is the ID token claim holding the user's groups.
+	GroupsClaim string `mapstructure:"groups_claim"`
+
+	// TenantClaim is the ID token claim holding the user's tenant. When
+	// empty every user belongs to the default tenant.
+	TenantClaim string `mapstructure:"tenant_claim"`
+
+	// RoleMapping maps groups to roles. A user gets the highest role of
+	// all their groups.
+	RoleMapping map[string]authtypes.Role `mapstructure:"role_mapping"`
+
+	// DefaultRole is given to users without a mapped group. Empty denies
+	// them access.
+	DefaultRole authtypes.Role `mapstructure:"default_role"`
+
+	// SessionTTL is how long a login lasts.
+	SessionTTL time.Duration `mapstructure:"session_ttl"`
+
+	// PostLoginURL is where the browser is sent after login, usually the
+	// frontend.
+	PostLoginURL string `mapstructure:"post_login_url"`
+}
+
+// Enabled reports whether single sign-on is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// SecureCookies reports whether session cookies need the Secure flag,
+// which is the case whenever the callback is served over HTTPS.
+func (c OIDCConfig) SecureCookies() bool {
+	return strings.HasPrefix(c.RedirectURL, "https://")
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("auth"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	enabled := true
+	if v := os.Getenv("WATCHDATA_AUTH_ENABLED"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			enabled = parsed
+		}
+	}
+
+	sessionTTL := 12 * time.Hour
+	if v := os.Getenv("WATCHDATA_SESSION_TTL"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			sessionTTL = parsed
+		}
+	}
+
+	scopes := []string{"profile", "email"}
+	if v := os.Getenv("WATCHDATA_OIDC_SCOPES"); v != "" {
+		scopes = splitList(v)
+	}
+
+	// WATCHDATA_OIDC_ROLE_MAPPING looks like "sre=admin,dev=editor".
+	mapping := map[string]authtypes.Role{}
+	for _, pair := range splitList(os.Getenv("WATCHDATA_OIDC_ROLE_MAPPING")) {
+		group, role, _ := strings.Cut(pair, "=")
+		mapping[strings.TrimSpace(group)] = authtypes.Role(strings.TrimSpace(role))
+	}
+
+	return Config{
+		Enabled:        enabled,
+		BootstrapToken: os.Getenv("WATCHDATA_BOOTSTRAP_TOKEN"),
+		OIDC: OIDCConfig{
+			Issuer:       os.Getenv("WATCHDATA_OIDC_ISSUER"),
+			ClientID:     os.Getenv("WATCHDATA_OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("WATCHDATA_OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("WATCHDATA_OIDC_REDIRECT_URL"),
+			Scopes:       scopes,
+			GroupsClaim:  envOr("WATCHDATA_OIDC_GROUPS_CLAIM", "groups"),
+			TenantClaim:  os.Getenv("WATCHDATA_OIDC_TENANT_CLAIM"),
+			RoleMapping:  mapping,
+			DefaultRole:  authtypes.Role(os.Getenv("WATCHDATA_OIDC_DEFAULT_ROLE")),
+			SessionTTL:   sessionTTL,
+			PostLoginURL: envOr("WATCHDATA_OIDC_POST_LOGIN_URL", "http://localhost:3000/"),
+		},
+	}
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func (c Config) Validate() error {
+	if c.BootstrapToken != "" && len(c.BootstrapToken) < 32 {
+		return fmt.Errorf("bootstrap token must be at least 32 characters")
+	}
+	if c.OIDC.Enabled() {
+		if err := c.OIDC.Validate(); err != nil {
+			return fmt.Errorf("oidc: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c OIDCConfig) Validate() error {
+	if c.ClientID == "" {
+		return fmt.Errorf("client id is required")
+	}
+	if _, err := url.ParseRequestURI(c.RedirectURL); err != nil {
+		return fmt.Errorf("invalid redirect url %q", c.RedirectURL)
+	}
+	if _, err := url.ParseRequestURI(c.PostLoginURL); err != nil {
+		return fmt.Errorf("invalid post login url %q", c.PostLoginURL)
+	}
+	if c.GroupsClaim == "" {
+		return fmt.Errorf("groups claim is required")
+	}
+	for group, role := range c.RoleMapping {
+		if group == "" || !role.Valid() {
+			return fmt.Errorf("invalid role mapping %q=%q", group, role)
+		}
+	}
+	if c.DefaultRole != "" && !c.DefaultRole.Valid() {
+		return fmt.Errorf("unknown default role %q", c.DefaultRole)
+	}
+	if c.SessionTTL <= 0 {
+		return fmt.Errorf("session ttl must be positive")
+	}
+	return nil
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
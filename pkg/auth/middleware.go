@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Ricky004/watchdata/pkg/api/render"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TenantHeader selects the tenant for callers that are not bound to one,
+// e.g. an ingest gateway using the bootstrap token. Tenant-bound callers may
+// send it, but only with their own tenant.
+const TenantHeader = "X-Watchdata-Tenant"
+
+// Require wraps a handler so it only runs for callers with at least role.
+// The principal, with its tenant resolved, is stored in the request
+// context. CORS preflight requests must be answered before this runs.
+func (a *Authenticator) Require(role authtypes.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.authenticateRequest(r)
+		if err != nil {
+			writeAuthError(w, r, err)
+			return
+		}
+		if !p.Role.Allows(role) {
+			render.Error(w, r, errors.New(errors.CodeForbidden, "requires role "+string(role), errors.SeverityInfo))
+			return
+		}
+		p, err = ResolveTenant(p, r.Header.Get(TenantHeader))
+		if err != nil {
+			render.Error(w, r, errors.New(errors.CodeForbidden, err.Error(), errors.SeverityInfo))
+			return
+		}
+
+		next(w, r.WithContext(authtypes.NewContext(r.Context(), p)))
+	}
+}
+
+// Middleware is Require for routers that compose handlers, such as
+// chi's With and Group.
+func (a *Authenticator) Middleware(role authtypes.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return a.Require(role, next.ServeHTTP)
+	}
+}
+
+// ResolveTenant sets the tenant a request acts on. Global principals use
+// the requested tenant or the default one; tenant-bound principals may not
+// ask for another tenant.
+func ResolveTenant(p authtypes.Principal, requested string) (authtypes.Principal, error) {
+	if requested != "" && !tenanttypes.ValidID(requested) {
+		return p, fmt.Errorf("invalid tenant %q", requested)
+	}
+
+	if p.Global {
+		p.TenantID = requested
+		if p.TenantID == "" {
+			p.TenantID = tenanttypes.DefaultTenant
+		}
+		return p, nil
+	}
+
+	if requested != "" && requested != p.TenantID {
+		return p, fmt.Errorf("not allowed to access tenant %q", requested)
+	}
+	return p, nil
+}
+
+// authenticateRequest prefers an explicit bearer token and falls back to
+// the single sign-on session cookie.
+func (a *Authenticator) authenticateRequest(r *http.Request) (authtypes.Principal, error) {
+	token := tokenFromRequest(r)
+	if token == "" {
+		if c, err := r.Cookie(SessionCookie); err == nil && c.Value != "" {
+			return a.AuthenticateSession(r.Context(), c.Value)
+		}
+	}
+	return a.Authenticate(r.Context(), token)
+}
+
+// tokenFromRequest reads the bearer token from the Authorization header.
+// Browsers cannot set headers on WebSocket handshakes, so upgrade requests
+// may pass the token as the access_token query parameter instead.
+func tokenFromRequest(r *http.Request) string {
+	if token, ok := bearerToken(r.Header.Get("Authorization")); ok {
+		return token
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func writeAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.From(err).Code.HTTPStatus() == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="watchdata"`)
+	}
+	render.Error(w, r, err)
+}
+
+// UnaryServerInterceptor authenticates gRPC calls using the "authorization"
+// metadata key and requires at least role.
+func (a *Authenticator) UnaryServerInterceptor(role authtypes.Role) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var token, tenant string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				token, _ = bearerToken(values[0])
+			}
+			if values := md.Get(strings.ToLower(TenantHeader)); len(values) > 0 {
+				tenant = values[0]
+			}
+		}
+
+		p, err := a.Authenticate(ctx, token)
+		if err != nil {
+			return nil, errors.GRPCStatus(err).Err()
+		}
+		if !p.Role.Allows(role) {
+			return nil, status.Error(codes.PermissionDenied, "requires role "+string(role))
+		}
+		p, err = ResolveTenant(p, tenant)
+		if err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+
+		return handler(authtypes.NewContext(ctx, p), req)
+	}
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const (
+	flowCookie = "wd_oidc_flow"
+	flowTTL    = 10 * time.Minute
+)
+
+// Flow is the state of a login that is waiting for the provider's callback.
+// It lives in a short-lived cookie so any server instance can finish it.
+type Flow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// ReturnTo is the frontend path to go back to after login.
+	ReturnTo string `json:"return_to"`
+}
+
+// OIDC implements the authorization code flow with PKCE against an OpenID
+// provider.
+type OIDC struct {
+	cfg           OIDCConfig
+	oauth2        oauth2.Config
+	verifier      *oidc.IDTokenVerifier
+	endSessionURL string
+}
+
+// NewOIDC discovers the provider's endpoints and keys.
+func NewOIDC(ctx context.Context, cfg OIDCConfig) (*OIDC, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider %q: %w", cfg.Issuer, err)
+	}
+
+	var discovery struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return nil, fmt.Errorf("failed to read oidc discovery document: %w", err)
+	}
+
+	return &OIDC{
+		cfg: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, cfg.Scopes...),
+		},
+		verifier:      provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		endSessionURL: discovery.EndSessionEndpoint,
+	}, nil
+}
+
+// Config returns the configuration the flow was created with.
+func (o *OIDC) Config() OIDCConfig {
+	return o.cfg
+}
+
+// Begin starts a login and returns the provider URL to redirect the browser
+// to. returnTo is only kept if it is a local path.
+func (o *OIDC) Begin(returnTo string) (string, Flow, error) {
+	state, err := randomString()
+	if err != nil {
+		return "", Flow{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", Flow{}, err
+	}
+	if !isLocalPath(returnTo) {
+		returnTo = "/"
+	}
+
+	flow := Flow{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier(), ReturnTo: returnTo}
+	authURL := o.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(flow.Verifier))
+	return authURL, flow, nil
+}
+
+// Finish exchanges the authorization code and verifies the ID token.
+func (o *OIDC) Finish(ctx context.Context, flow Flow, state, code string) (Identity, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(flow.State)) != 1 {
+		return Identity{}, errors.New(errors.CodeUnauthorized, "login state mismatch", errors.SeverityInfo)
+	}
+	if code == "" {
+		return Identity{}, errors.New(errors.CodeUnauthorized, "missing authorization code", errors.SeverityInfo)
+	}
+
+	token, err := o.oauth2.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return Identity{}, errors.New(errors.CodeUnauthorized, "failed to exchange authorization code", errors.SeverityWarning, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New(errors.CodeTokenInvalid, "token response has no id_token", errors.SeverityWarning)
+	}
+
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, errors.New(errors.CodeTokenInvalid, "invalid id token", errors.SeverityWarning, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(flow.Nonce)) != 1 {
+		return Identity{}, errors.New(errors.CodeTokenInvalid, "id token nonce mismatch", errors.SeverityWarning)
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, errors.New(errors.CodeTokenInvalid, "invalid id token claims", errors.SeverityWarning, err)
+	}
+
+	id := Identity{
+		Tenant:  tenanttypes.DefaultTenant,
+		Subject: idToken.Subject,
+		Groups:  stringList(claims[o.cfg.GroupsClaim]),
+	}
+	if o.cfg.TenantClaim != "" {
+		tenant, _ := claims[o.cfg.TenantClaim].(string)
+		if !tenanttypes.ValidID(tenant) {
+			return Identity{}, errors.New(errors.CodeForbidden, "id token has no valid tenant claim", errors.SeverityInfo)
+		}
+		id.Tenant = tenant
+	}
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	if id.Name == "" {
+		id.Name, _ = claims["preferred_username"].(string)
+	}
+	return id, nil
+}
+
+// RoleFor returns the highest role mapped from the groups, falling back to
+// the default role. ok is false when the user gets no access.
+func (o *OIDC) RoleFor(groups []string) (authtypes.Role, bool) {
+	role := o.cfg.DefaultRole
+	for _, g := range groups {
+		if mapped, ok := o.cfg.RoleMapping[g]; ok && !role.Allows(mapped) {
+			role = mapped
+		}
+	}
+	return role, role.Valid()
+}
+
+// RedirectAfterLogin resolves the flow's return path against the
+// configured frontend URL.
+func (o *OIDC) RedirectAfterLogin(flow Flow) string {
+	base, err := url.Parse(o.cfg.PostLoginURL)
+	if err != nil || !isLocalPath(flow.ReturnTo) {
+		return o.cfg.PostLoginURL
+	}
+	ref, err := url.Parse(flow.ReturnTo)
+	if err != nil {
+		return o.cfg.PostLoginURL
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// LogoutURL is where the browser should go to also end the provider
+// session. It is empty if the provider does not support RP-initiated
+// logout.
+func (o *OIDC) LogoutURL() string {
+	if o.endSessionURL == "" {
+		return ""
+	}
+	u, err := url.Parse(o.endSessionURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("client_id", o.cfg.ClientID)
+	q.Set("post_logout_redirect_uri", o.cfg.PostLoginURL)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// SetFlowCookie stores a pending login in the browser.
+func (o *OIDC) SetFlowCookie(w http.ResponseWriter, flow Flow) error {
+	data, err := json.Marshal(flow)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     flowCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(data),
+		Path:     "/v1/auth/oidc",
+		MaxAge:   int(flowTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   o.cfg.SecureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// FlowFromRequest reads and clears the pending login cookie.
+func (o *OIDC) FlowFromRequest(w http.ResponseWriter, r *http.Request) (Flow, bool) {
+	c, err := r.Cookie(flowCookie)
+	if err != nil {
+		return Flow{}, false
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     flowCookie,
+		Path:     "/v1/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   o.cfg.SecureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	data, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return Flow{}, false
+	}
+	var flow Flow
+	if err := json.Unmarshal(data, &flow); err != nil || flow.State == "" || flow.Verifier == "" {
+		return Flow{}, false
+	}
+	return flow, true
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isLocalPath guards against open redirects by only accepting absolute
+// paths on the same host.
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.Contains(p, `\`)
+}
+
+// stringList accepts a claim that is either a list of strings or a single
+// string, as providers differ.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		slices.Sort(out)
+		return out
+	}
+	return nil
+}
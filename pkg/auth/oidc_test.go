@@ -0,0 +1,200 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/auth"
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/coreos/go-oidc/v3/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testClientID = "watchdata"
+
+// testIssuer is a stand-in OpenID provider. It approves every
+// authorization request immediately and enforces PKCE at the token
+// endpoint.
+type testIssuer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	groups []string
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+type authRequest struct {
+	challenge string
+	nonce     string
+}
+
+func newTestIssuer(t *testing.T, groups ...string) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	discovery := &oidctest.Server{
+		PublicKeys: []oidctest.PublicKey{{PublicKey: key.Public(), KeyID: "test", Algorithm: oidc.RS256}},
+	}
+	issuer := &testIssuer{key: key, groups: groups, codes: map[string]authRequest{}}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", discovery)
+	mux.HandleFunc("/auth", issuer.authorize)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.Server = httptest.NewServer(mux)
+	discovery.SetIssuer(issuer.URL)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func (i *testIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != testClientID {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	code := base64.RawURLEncoding.EncodeToString([]byte(q.Get("state")))
+	i.mu.Lock()
+	i.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	i.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (i *testIssuer) token(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	req, ok := i.codes[r.FormValue("code")]
+	delete(i.codes, r.FormValue("code"))
+	i.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims, _ := json.Marshal(map[string]any{
+		"iss":    i.URL,
+		"aud":    testClientID,
+		"sub":    "user-1",
+		"email":  "jane@example.com",
+		"name":   "Jane Doe",
+		"groups": i.groups,
+		"nonce":  req.nonce,
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     oidctest.SignIDToken(i.key, "test", oidc.RS256, string(claims)),
+	})
+}
+
+func newTestOIDC(t *testing.T, issuer *testIssuer) *auth.OIDC {
+	t.Helper()
+	o, err := auth.NewOIDC(context.Background(), auth.OIDCConfig{
+		Issuer:       issuer.URL,
+		ClientID:     testClientID,
+		RedirectURL:  "http://localhost:8080/v1/auth/oidc/callback",
+		GroupsClaim:  "groups",
+		RoleMapping:  map[string]authtypes.Role{"sre": authtypes.RoleAdmin, "dev": authtypes.RoleEditor},
+		SessionTTL:   time.Hour,
+		PostLoginURL: "http://localhost:3000/",
+	})
+	require.NoError(t, err)
+	return o
+}
+
+// login runs the browser side of the flow and returns the callback query.
+func login(t *testing.T, authURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return callback.Query()
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newTestIssuer(t, "dev", "sre")
+	o := newTestOIDC(t, issuer)
+
+	authURL, flow, err := o.Begin("/logs?live=1")
+	require.NoError(t, err)
+	q := login(t, authURL)
+
+	identity, err := o.Finish(context.Background(), flow, q.Get("state"), q.Get("code"))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", identity.Subject)
+	assert.Equal(t, "jane@example.com", identity.Email)
+	assert.Equal(t, "Jane Doe", identity.Name)
+	assert.Equal(t, []string{"dev", "sre"}, identity.Groups)
+
+	role, ok := o.RoleFor(identity.Groups)
+	assert.True(t, ok)
+	assert.Equal(t, authtypes.RoleAdmin, role)
+	assert.Equal(t, "http://localhost:3000/logs?live=1", o.RedirectAfterLogin(flow))
+}
+
+func TestOIDCRejects(t *testing.T) {
+	issuer := newTestIssuer(t, "marketing")
+	o := newTestOIDC(t, issuer)
+
+	t.Run("state mismatch", func(t *testing.T) {
+		authURL, flow, err := o.Begin("/")
+		require.NoError(t, err)
+		q := login(t, authURL)
+		_, err = o.Finish(context.Background(), flow, "forged", q.Get("code"))
+		assertCode(t, err, errors.CodeUnauthorized)
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		authURL, flow, err := o.Begin("/")
+		require.NoError(t, err)
+		q := login(t, authURL)
+		flow.Verifier = "not-the-verifier-that-was-used-for-the-challenge"
+		_, err = o.Finish(context.Background(), flow, q.Get("state"), q.Get("code"))
+		assertCode(t, err, errors.CodeUnauthorized)
+	})
+
+	t.Run("unmapped group", func(t *testing.T) {
+		_, ok := o.RoleFor([]string{"marketing"})
+		assert.False(t, ok)
+	})
+
+	t.Run("open redirect", func(t *testing.T) {
+		_, flow, err := o.Begin("//evil.example.com/")
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:3000/", o.RedirectAfterLogin(flow))
+	})
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+)
+
+const (
+	// SessionCookie holds the browser session token set after single
+	// sign-on.
+	SessionCookie = "wd_session"
+
+	sessionTokenPrefix = "wds_"
+)
+
+// Identity is a user as asserted by the OpenID provider.
+type Identity struct {
+	// Tenant is the tenant the user belongs to.
+	Tenant  string
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+// NewSession creates a session for an identity and returns it together with
+// the plaintext cookie value. Only the hash of the value is stored.
+func NewSession(id Identity, role authtypes.Role, ttl time.Duration) (string, authtypes.Session, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", authtypes.Session{}, err
+	}
+	token := sessionTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	return token, authtypes.Session{
+		ID:        HashToken(token),
+		TenantID:  id.Tenant,
+		Subject:   id.Subject,
+		Email:     id.Email,
+		Name:      id.Name,
+		Groups:    id.Groups,
+		Role:      role,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		UpdatedAt: now,
+	}, nil
+}
+
+// SessionID returns the stored id of a session cookie value.
+func SessionID(token string) string {
+	return HashToken(token)
+}
+
+// SetSessionCookie stores the session token in the browser. SameSite=Lax
+// keeps the cookie off cross-site POSTs.
+func SetSessionCookie(w http.ResponseWriter, token string, expires time.Time, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie removes the session cookie from the browser.
+func ClearSessionCookie(w http.ResponseWriter, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
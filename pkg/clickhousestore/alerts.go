@@ -0,0 +1,450 @@
+package clickhousestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/alertingtypes"
+)
+
+var (
+	// ErrRuleNotFound is returned when an alert rule does not exist.
+	ErrRuleNotFound = fmt.Errorf("alert rule not found")
+	// ErrSilenceNotFound is returned when a silence does not exist.
+	ErrSilenceNotFound = fmt.Errorf("silence not found")
+)
+
+func (p *ClickHouseProvider) createAlertTables(ctx context.Context) error {
+	statements := []string{
+		`
+		CREATE TABLE IF NOT EXISTS alert_rules (
+			id String,
+			definition String CODEC(ZSTD(1)),
+			deleted UInt8,
+			updated_at DateTime64(3)
+		) ENGINE = ReplacingMergeTree(updated_at)
+		ORDER BY id;
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS alert_history (
+			timestamp DateTime64(3) CODEC(Delta(8), ZSTD(1)),
+			rule_id String,
+			rule_name String,
+			fingerprint String,
+			labels String CODEC(ZSTD(1)),
+			from_state LowCardinality(String),
+			to_state LowCardinality(String),
+			value Float64
+		) ENGINE = MergeTree()
+		PARTITION BY toYYYYMM(timestamp)
+		ORDER BY (rule_id, timestamp)
+		TTL toDateTime(timestamp) + INTERVAL 90 DAY;
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS alert_silences (
+			id String,
+			definition String CODEC(ZSTD(1)),
+			deleted UInt8,
+			updated_at DateTime64(3)
+		) ENGINE = ReplacingMergeTree(updated_at)
+		ORDER BY id;
+		`,
+		`
+		CREATE TABLE IF NOT EXISTS alert_notifications (
+			timestamp DateTime64(3) CODEC(Delta(8), ZSTD(1)),
+			channel LowCardinality(String),
+			group_key String,
+			status LowCardinality(String),
+			alert_count UInt32,
+			success UInt8,
+			error_code LowCardinality(String),
+			error String
+		) ENGINE = MergeTree()
+		PARTITION BY toYYYYMM(timestamp)
+		ORDER BY (channel, timestamp)
+		TTL toDateTime(timestamp) + INTERVAL 30 DAY;
+		`,
+	}
+
+	for _, stmt := range statements {
+		if err := p.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create alert tables: %w", err)
+		}
+	}
+	return nil
+}
+
+// UpsertAlertRule stores a new version of the rule.
+func (p *ClickHouseProvider) UpsertAlertRule(ctx context.Context, rule alertingtypes.Rule) error {
+	definition, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert rule: %w", err)
+	}
+
+	err = p.conn.Exec(ctx,
+		`INSERT INTO alert_rules (id, tenant_id, definition, deleted, updated_at) VALUES (?, ?, ?, 0, ?)`,
+		rule.ID, rule.TenantID, string(definition), rule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert alert rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteAlertRule marks the tenant's rule as deleted.
+func (p *ClickHouseProvider) DeleteAlertRule(ctx context.Context, tenantID, id string) error {
+	rule, err := p.GetAlertRule(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	err = p.conn.Exec(ctx,
+		`INSERT INTO alert_rules (id, tenant_id, definition, deleted, updated_at) VALUES (?, ?, '', 1, ?)`,
+		id, rule.TenantID, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	return nil
+}
+
+// GetAlertRule returns a single rule of the tenant by id.
+func (p *ClickHouseProvider) GetAlertRule(ctx context.Context, tenantID, id string) (alertingtypes.Rule, error) {
+	rules, err := p.queryAlertRules(ctx, tenantID, `AND id = ?`, id)
+	if err != nil {
+		return alertingtypes.Rule{}, err
+	}
+	if len(rules) == 0 {
+		return alertingtypes.Rule{}, ErrRuleNotFound
+	}
+	return rules[0], nil
+}
+
+// ListAlertRules returns the latest version of every rule of the tenant that
+// is not deleted. The alert engine passes tenanttypes.AllTenants.
+func (p *ClickHouseProvider) ListAlertRules(ctx context.Context, tenantID string) ([]alertingtypes.Rule, error) {
+	return p.queryAlertRules(ctx, tenantID, "")
+}
+
+func (p *ClickHouseProvider) queryAlertRules(ctx context.Context, tenantID, where string, args ...any) ([]alertingtypes.Rule, error) {
+	tenant, tenantArgs, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT tenant_id, definition FROM alert_rules FINAL WHERE deleted = 0 AND ` + tenant + ` ` + where + ` ORDER BY id`
+
+	rows, err := p.conn.Query(ctx, query, append(tenantArgs, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []alertingtypes.Rule
+	for rows.Next() {
+		var tenantID, definition string
+		if err := rows.Scan(&tenantID, &definition); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+
+		var rule alertingtypes.Rule
+		if err := json.Unmarshal([]byte(definition), &rule); err != nil {
+			return nil, fmt.Errorf("failed to decode alert rule: %w", err)
+		}
+		// Rules stored before multi-tenancy have no tenant in the definition.
+		rule.TenantID = tenantID
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// InsertAlertTransitions appends state transitions to the alert history.
+func (p *ClickHouseProvider) InsertAlertTransitions(ctx context.Context, transitions []alertingtypes.Transition) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	batch, err := p.conn.PrepareBatch(ctx, "INSERT INTO alert_history (timestamp, tenant_id, rule_id, rule_name, fingerprint, labels, from_state, to_state, value)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+
+	for _, t := range transitions {
+		labels, err := json.Marshal(t.Labels)
+		if err != nil {
+			return fmt.Errorf("failed to encode labels: %w", err)
+		}
+		if err := batch.Append(t.Timestamp, t.TenantID, t.RuleID, t.RuleName, t.Fingerprint, string(labels), string(t.From), string(t.To), t.Value); err != nil {
+			return fmt.Errorf("failed to append to batch: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+	return nil
+}
+
+// GetAlertHistory returns the tenant's most recent transitions, optionally
+// for a single rule.
+func (p *ClickHouseProvider) GetAlertHistory(ctx context.Context, tenantID, ruleID string, limit int) ([]alertingtypes.Transition, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT timestamp, tenant_id, rule_id, rule_name, fingerprint, labels, from_state, to_state, value FROM alert_history WHERE ` + tenant
+	if ruleID != "" {
+		query += ` AND rule_id = ?`
+		args = append(args, ruleID)
+	}
+	query += ` ORDER BY timestamp DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := p.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert history: %w", err)
+	}
+	defer rows.Close()
+
+	var transitions []alertingtypes.Transition
+	for rows.Next() {
+		var t alertingtypes.Transition
+		var labels, from, to string
+		if err := rows.Scan(&t.Timestamp, &t.TenantID, &t.RuleID, &t.RuleName, &t.Fingerprint, &labels, &from, &to, &t.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan alert history row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(labels), &t.Labels); err != nil {
+			return nil, fmt.Errorf("failed to decode alert labels: %w", err)
+		}
+		t.From = alertingtypes.State(from)
+		t.To = alertingtypes.State(to)
+		transitions = append(transitions, t)
+	}
+
+	return transitions, rows.Err()
+}
+
+// UpsertSilence stores a new version of the silence.
+func (p *ClickHouseProvider) UpsertSilence(ctx context.Context, silence alertingtypes.Silence) error {
+	definition, err := json.Marshal(silence)
+	if err != nil {
+		return fmt.Errorf("failed to encode silence: %w", err)
+	}
+
+	err = p.conn.Exec(ctx,
+		`INSERT INTO alert_silences (id, tenant_id, definition, deleted, updated_at) VALUES (?, ?, ?, 0, ?)`,
+		silence.ID, silence.TenantID, string(definition), silence.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert silence: %w", err)
+	}
+	return nil
+}
+
+// GetSilence returns a single silence of the tenant by id.
+func (p *ClickHouseProvider) GetSilence(ctx context.Context, tenantID, id string) (alertingtypes.Silence, error) {
+	silences, err := p.querySilences(ctx, tenantID, `AND id = ?`, id)
+	if err != nil {
+		return alertingtypes.Silence{}, err
+	}
+	if len(silences) == 0 {
+		return alertingtypes.Silence{}, ErrSilenceNotFound
+	}
+	return silences[0], nil
+}
+
+// ListSilences returns all silences of the tenant, or only those that have
+// not yet expired when includeExpired is false. The dispatcher passes
+// tenanttypes.AllTenants.
+func (p *ClickHouseProvider) ListSilences(ctx context.Context, tenantID string, includeExpired bool) ([]alertingtypes.Silence, error) {
+	silences, err := p.querySilences(ctx, tenantID, "")
+	if err != nil || includeExpired {
+		return silences, err
+	}
+
+	now := time.Now()
+	active := silences[:0]
+	for _, s := range silences {
+		if now.Before(s.EndsAt) {
+			active = append(active, s)
+		}
+	}
+	return active, nil
+}
+
+func (p *ClickHouseProvider) querySilences(ctx context.Context, tenantID, where string, args ...any) ([]alertingtypes.Silence, error) {
+	tenant, tenantArgs, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT tenant_id, definition FROM alert_silences FINAL WHERE deleted = 0 AND ` + tenant + ` ` + where + ` ORDER BY id`
+
+	rows, err := p.conn.Query(ctx, query, append(tenantArgs, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query silences: %w", err)
+	}
+	defer rows.Close()
+
+	var silences []alertingtypes.Silence
+	for rows.Next() {
+		var tenantID, definition string
+		if err := rows.Scan(&tenantID, &definition); err != nil {
+			return nil, fmt.Errorf("failed to scan silence: %w", err)
+		}
+
+		var silence alertingtypes.Silence
+		if err := json.Unmarshal([]byte(definition), &silence); err != nil {
+			return nil, fmt.Errorf("failed to decode silence: %w", err)
+		}
+		silence.TenantID = tenantID
+		silences = append(silences, silence)
+	}
+
+	return silences, rows.Err()
+}
+
+// InsertNotificationAttempts records notification deliveries.
+func (p *ClickHouseProvider) InsertNotificationAttempts(ctx context.Context, attempts []alertingtypes.NotificationAttempt) error {
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	batch, err := p.conn.PrepareBatch(ctx, "INSERT INTO alert_notifications (timestamp, tenant_id, channel, group_key, status, alert_count, success, error_code, error)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+
+	for _, a := range attempts {
+		var success uint8
+		if a.Success {
+			success = 1
+		}
+		if err := batch.Append(a.Timestamp, a.TenantID, a.Channel, a.GroupKey, string(a.Status), uint32(a.AlertCount), success, a.ErrorCode, a.Error); err != nil {
+			return fmt.Errorf("failed to append to batch: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+	return nil
+}
+
+// GetNotificationAttempts returns the tenant's most recent notification
+// deliveries.
+func (p *ClickHouseProvider) GetNotificationAttempts(ctx context.Context, tenantID string, limit int) ([]alertingtypes.NotificationAttempt, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.conn.Query(ctx,
+		`SELECT timestamp, tenant_id, channel, group_key, status, alert_count, success, error_code, error
+		 FROM alert_notifications WHERE `+tenant+` ORDER BY timestamp DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []alertingtypes.NotificationAttempt
+	for rows.Next() {
+		var (
+			a          alertingtypes.NotificationAttempt
+			status     string
+			alertCount uint32
+			success    uint8
+		)
+		if err := rows.Scan(&a.Timestamp, &a.TenantID, &a.Channel, &a.GroupKey, &status, &alertCount, &success, &a.ErrorCode, &a.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan notification row: %w", err)
+		}
+		a.Status = alertingtypes.State(status)
+		a.AlertCount = int(alertCount)
+		a.Success = success == 1
+		attempts = append(attempts, a)
+	}
+
+	return attempts, rows.Err()
+}
+
+// AggregateRow is the number of records for one combination of group-by values.
+type AggregateRow struct {
+	Group map[string]string
+	Count uint64
+}
+
+// CountLogs counts the tenant's records matching expr in [start, end), split
+// by the group-by fields.
+func (p *ClickHouseProvider) CountLogs(ctx context.Context, tenantID string, expr filter.Expr, start, end time.Time, groupBy []string) ([]AggregateRow, error) {
+	tenant, tenantArgs, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]filter.Field, 0, len(groupBy))
+	for _, key := range groupBy {
+		field, err := filter.ParseField(key)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+
+	var (
+		selects []string
+		aliases []string
+		args    []any
+	)
+	for i, field := range fields {
+		col, colArgs := fieldSQL(field)
+		alias := fmt.Sprintf("g%d", i)
+		selects = append(selects, fmt.Sprintf("toString(%s) AS %s", col, alias))
+		aliases = append(aliases, alias)
+		args = append(args, colArgs...)
+	}
+
+	where, whereArgs, err := filterSQL(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + strings.Join(append(selects, "count() AS c"), ", ") +
+		" FROM logs WHERE " + tenant + " AND timestamp >= ? AND timestamp < ? AND " + where
+	args = append(args, tenantArgs...)
+	args = append(args, start, end)
+	args = append(args, whereArgs...)
+	if len(aliases) > 0 {
+		query += " GROUP BY " + strings.Join(aliases, ", ")
+	}
+
+	rows, err := p.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count logs: %w", err)
+	}
+	defer rows.Close()
+
+	var result []AggregateRow
+	for rows.Next() {
+		values := make([]string, len(fields))
+		dest := make([]any, 0, len(fields)+1)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		var count uint64
+		dest = append(dest, &count)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan count row: %w", err)
+		}
+
+		group := make(map[string]string, len(groupBy))
+		for i, key := range groupBy {
+			group[key] = values[i]
+		}
+		result = append(result, AggregateRow{Group: group, Count: count})
+	}
+
+	return result, rows.Err()
+}
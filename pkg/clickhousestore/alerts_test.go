@@ -0,0 +1,39 @@
+package clickhousestore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAlertHistoryLabels(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		labels string
+		err    string
+	}{
+		"valid":   {labels: `{"service":"api"}`},
+		"corrupt": {labels: `{"service":`, err: "failed to decode alert labels"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn := &clickhousestoretest.Conn{Rows: func(clickhousestoretest.Query) ([][]any, error) {
+				return [][]any{{at, "default", "r1", "errors", "fp", tc.labels, "ok", "firing", 3.0}}, nil
+			}}
+			p := clickhousestore.NewProviderFromConn(conn)
+
+			history, err := p.GetAlertHistory(context.Background(), "default", "", 10)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, history, 1)
+			assert.Equal(t, map[string]string{"service": "api"}, history[0].Labels)
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package clickhousestore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/anomalytypes"
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+)
+
+// lateIngest is how long before a pattern was first seen its records are
+// looked for, as records may reach the store some time after they were
+// logged.
+const lateIngest = 15 * time.Minute
+
+// VolumeRow is the number of records of one service at one severity in
+// one bucket.
+type VolumeRow struct {
+	TenantID     string
+	Service      string
+	SeverityText string
+	Bucket       time.Time
+	Count        uint64
+}
+
+// CountLogVolume counts the records of every tenant in [start, end) by
+// service, severity text and bucket. Buckets without records are left out.
+func (p *ClickHouseProvider) CountLogVolume(ctx context.Context, start, end time.Time, bucket time.Duration) ([]VolumeRow, error) {
+	rows, err := p.conn.Query(ctx, `
+		SELECT tenant_id, JSONExtractString(resource, 'service.name') AS service, severity_text,
+			toStartOfInterval(timestamp, toIntervalSecond(?)) AS bucket, count()
+		FROM logs
+		WHERE timestamp >= ? AND timestamp < ?
+		GROUP BY tenant_id, service, severity_text, bucket`,
+		int64(bucket.Seconds()), start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count log volume: %w", err)
+	}
+	defer rows.Close()
+
+	var result []VolumeRow
+	for rows.Next() {
+		var r VolumeRow
+		if err := rows.Scan(&r.TenantID, &r.Service, &r.SeverityText, &r.Bucket, &r.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan log volume: %w", err)
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// PatternActivity describes a pattern and its records.
+type PatternActivity struct {
+	Pattern patterntypes.Pattern
+	// Service is the service of one of the records, and SeverityNumber and
+	// SeverityText the highest severity among them.
+	Service        string
+	SeverityNumber int8
+	SeverityText   string
+	Count          uint64
+	Sample         string
+}
+
+// NewPatterns returns the patterns of every tenant first seen in
+// [start, end), with their records up to end.
+func (p *ClickHouseProvider) NewPatterns(ctx context.Context, start, end time.Time) ([]PatternActivity, error) {
+	rows, err := p.conn.Query(ctx, `
+		SELECT tenant_id, id, argMax(template, updated_at), min(created_at) AS first_seen
+		FROM log_patterns
+		GROUP BY tenant_id, id
+		HAVING first_seen >= ? AND first_seen < ?`, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query new patterns: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		activity []PatternActivity
+		ids      []string
+		index    = make(map[string]int)
+	)
+	for rows.Next() {
+		var a PatternActivity
+		if err := rows.Scan(&a.Pattern.TenantID, &a.Pattern.ID, &a.Pattern.Template, &a.Pattern.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan new pattern: %w", err)
+		}
+		index[a.Pattern.TenantID+"\x00"+a.Pattern.ID] = len(activity)
+		activity = append(activity, a)
+		ids = append(ids, a.Pattern.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(activity) == 0 {
+		return nil, nil
+	}
+
+	rows, err = p.conn.Query(ctx, `
+		SELECT tenant_id, pattern_id, count(), max(severity_number), argMax(severity_text, severity_number),
+			any(JSONExtractString(resource, 'service.name')), any(body)
+		FROM logs
+		WHERE timestamp >= ? AND timestamp < ? AND has(?, pattern_id)
+		GROUP BY tenant_id, pattern_id`, start.Add(-lateIngest), end, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query new pattern records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			tenantID, id string
+			a            PatternActivity
+		)
+		if err := rows.Scan(&tenantID, &id, &a.Count, &a.SeverityNumber, &a.SeverityText, &a.Service, &a.Sample); err != nil {
+			return nil, fmt.Errorf("failed to scan new pattern records: %w", err)
+		}
+		i, ok := index[tenantID+"\x00"+id]
+		if !ok {
+			continue
+		}
+		a.Pattern = activity[i].Pattern
+		activity[i] = a
+	}
+	return activity, rows.Err()
+}
+
+// InsertAnomalies stores findings. A finding stored again replaces the
+// earlier one.
+func (p *ClickHouseProvider) InsertAnomalies(ctx context.Context, findings []anomalytypes.Finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+
+	batch, err := p.conn.PrepareBatch(ctx, `INSERT INTO anomalies (tenant_id, id, kind, service, severity_text, pattern_id,
+		template, sample, value, expected, score, window_start, window_end, detected_at)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+	for _, f := range findings {
+		err := batch.Append(f.TenantID, f.ID, string(f.Kind), f.Service, f.SeverityText, f.PatternID,
+			f.Template, f.Sample, f.Value, f.Expected, f.Score, f.WindowStart, f.WindowEnd, f.DetectedAt)
+		if err != nil {
+			return fmt.Errorf("failed to append to batch: %w", err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to store anomalies: %w", err)
+	}
+	return nil
+}
+
+// anomalyCondition returns the WHERE clause for the tenant's findings of
+// the given kinds, all if none, that match expr.
+func anomalyCondition(tenantID string, kinds []anomalytypes.Kind, expr filter.Expr) (string, []any, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return "", nil, err
+	}
+	mapped, err := anomalytypes.MapFilter(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	where, whereArgs, err := filterSQL(mapped)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cond := tenant + " AND " + where
+	args = append(args, whereArgs...)
+	if len(kinds) > 0 {
+		names := make([]string, len(kinds))
+		for i, k := range kinds {
+			names[i] = string(k)
+		}
+		cond += " AND has(?, kind)"
+		args = append(args, names)
+	}
+	return cond, args, nil
+}
+
+// ListAnomalies returns the tenant's most recent findings for windows
+// starting in [start, end) that match kinds and expr. expr may only refer
+// to the fields anomalytypes.Column accepts.
+func (p *ClickHouseProvider) ListAnomalies(ctx context.Context, tenantID string, kinds []anomalytypes.Kind, expr filter.Expr, start, end time.Time, limit int) ([]anomalytypes.Finding, error) {
+	cond, args, err := anomalyCondition(tenantID, kinds, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT tenant_id, id, kind, service, severity_text, pattern_id, template, sample,
+			  value, expected, score, window_start, window_end, detected_at
+			  FROM anomalies FINAL
+			  WHERE ` + cond + ` AND window_start >= ? AND window_start < ?
+			  ORDER BY window_start DESC, abs(score) DESC, id
+			  LIMIT ?`
+	rows, err := p.conn.Query(ctx, query, append(args, start, end, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	var findings []anomalytypes.Finding
+	for rows.Next() {
+		var (
+			f    anomalytypes.Finding
+			kind string
+		)
+		err := rows.Scan(&f.TenantID, &f.ID, &kind, &f.Service, &f.SeverityText, &f.PatternID, &f.Template, &f.Sample,
+			&f.Value, &f.Expected, &f.Score, &f.WindowStart, &f.WindowEnd, &f.DetectedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly: %w", err)
+		}
+		f.Kind = anomalytypes.Kind(kind)
+		findings = append(findings, f)
+	}
+	return findings, rows.Err()
+}
+
+// CountAnomalies counts the tenant's findings detected in [start, end)
+// that match kinds and expr, split by the group-by keys, which are those
+// anomalytypes.GroupColumn accepts.
+func (p *ClickHouseProvider) CountAnomalies(ctx context.Context, tenantID string, kinds []anomalytypes.Kind, expr filter.Expr, start, end time.Time, groupBy []string) ([]AggregateRow, error) {
+	cond, args, err := anomalyCondition(tenantID, kinds, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	selects := make([]string, 0, len(groupBy)+1)
+	aliases := make([]string, 0, len(groupBy))
+	for i, key := range groupBy {
+		col, err := anomalytypes.GroupColumn(key)
+		if err != nil {
+			return nil, err
+		}
+		alias := fmt.Sprintf("g%d", i)
+		selects = append(selects, fmt.Sprintf("toString(%s) AS %s", col, alias))
+		aliases = append(aliases, alias)
+	}
+
+	query := "SELECT " + strings.Join(append(selects, "count() AS c"), ", ") +
+		" FROM anomalies FINAL WHERE " + cond + " AND detected_at >= ? AND detected_at < ?"
+	args = append(args, start, end)
+	if len(aliases) > 0 {
+		query += " GROUP BY " + strings.Join(aliases, ", ")
+	}
+
+	rows, err := p.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	var result []AggregateRow
+	for rows.Next() {
+		values := make([]string, len(groupBy))
+		dest := make([]any, 0, len(groupBy)+1)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		var count uint64
+		dest = append(dest, &count)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly count: %w", err)
+		}
+
+		group := make(map[string]string, len(groupBy))
+		for i, key := range groupBy {
+			group[key] = values[i]
+		}
+		result = append(result, AggregateRow{Group: group, Count: count})
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,193 @@
+// Package clickhousestoretest provides a fake ClickHouse connection for
+// testing code built on clickhousestore without a server.
+package clickhousestoretest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// Query is a query received by Conn.
+type Query struct {
+	SQL  string
+	Args []any
+}
+
+// Conn answers queries with the rows returned by Rows and records them
+// along with statements run with Exec and batches sent. Pings fail with
+// PingErr. Other calls panic.
+type Conn struct {
+	driver.Conn
+
+	// Rows returns the rows of a query, or an error. Without it, every
+	// query returns no rows.
+	Rows func(q Query) ([][]any, error)
+	// PingErr is returned by Ping.
+	PingErr error
+
+	mu      sync.Mutex
+	queries []Query
+	execs   []Query
+	batches []Batch
+}
+
+// Batch is a batch sent to Conn.
+type Batch struct {
+	SQL  string
+	Rows [][]any
+}
+
+// Queries returns the queries received so far.
+func (c *Conn) Queries() []Query {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Query(nil), c.queries...)
+}
+
+// Execs returns the statements run so far.
+func (c *Conn) Execs() []Query {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Query(nil), c.execs...)
+}
+
+func (c *Conn) Exec(_ context.Context, query string, args ...any) error {
+	c.mu.Lock()
+	c.execs = append(c.execs, Query{SQL: query, Args: args})
+	c.mu.Unlock()
+	return nil
+}
+
+// Batches returns the batches sent so far.
+func (c *Conn) Batches() []Batch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Batch(nil), c.batches...)
+}
+
+func (c *Conn) PrepareBatch(_ context.Context, query string, _ ...driver.PrepareBatchOption) (driver.Batch, error) {
+	return &batch{conn: c, sent: Batch{SQL: query}}, nil
+}
+
+func (c *Conn) Ping(context.Context) error { return c.PingErr }
+
+// QueryRow answers with the first row of Query, failing with
+// sql.ErrNoRows if there is none.
+func (c *Conn) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
+	rs, err := c.Query(ctx, query, args...)
+	if err != nil {
+		return &row{err: err}
+	}
+	r := rs.(*rows)
+	if !r.Next() {
+		return &row{err: sql.ErrNoRows}
+	}
+	return &row{rows: r}
+}
+
+func (c *Conn) Query(_ context.Context, query string, args ...any) (driver.Rows, error) {
+	q := Query{SQL: query, Args: args}
+	c.mu.Lock()
+	c.queries = append(c.queries, q)
+	c.mu.Unlock()
+
+	if c.Rows == nil {
+		return &rows{}, nil
+	}
+	data, err := c.Rows(q)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{data: data, next: -1}, nil
+}
+
+// LogRow returns rec as a row of the columns logs are selected with.
+func LogRow(rec telemetrytypes.LogRecord) []any {
+	return []any{
+		rec.TenantID, rec.ID, rec.PatternID,
+		rec.Timestamp, rec.ObservedTime, rec.SeverityNumber, rec.SeverityText, rec.Body,
+		attributesJSON(rec.Attributes), attributesJSON(rec.Resource.Attributes), rec.TraceID, rec.SpanID,
+		rec.TraceFlags, rec.Flags, rec.DroppedAttrCount,
+	}
+}
+
+func attributesJSON(attrs []telemetrytypes.KeyValue) string {
+	m := make(map[string]any, len(attrs))
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+type rows struct {
+	driver.Rows
+	data [][]any
+	next int
+}
+
+func (r *rows) Next() bool {
+	r.next++
+	return r.next < len(r.data)
+}
+
+// Scan copies the current row into dest, converting between types as
+// the driver would.
+func (r *rows) Scan(dest ...any) error {
+	row := r.data[r.next]
+	if len(dest) != len(row) {
+		return fmt.Errorf("scan: %d destinations for %d columns", len(dest), len(row))
+	}
+	for i, v := range row {
+		to := reflect.ValueOf(dest[i]).Elem()
+		from := reflect.ValueOf(v)
+		if !from.CanConvert(to.Type()) {
+			return fmt.Errorf("scan: column %d: cannot convert %T to %s", i, v, to.Type())
+		}
+		to.Set(from.Convert(to.Type()))
+	}
+	return nil
+}
+
+type batch struct {
+	driver.Batch
+	conn *Conn
+	sent Batch
+}
+
+func (b *batch) Append(v ...any) error {
+	b.sent.Rows = append(b.sent.Rows, v)
+	return nil
+}
+
+func (b *batch) Send() error {
+	b.conn.mu.Lock()
+	b.conn.batches = append(b.conn.batches, b.sent)
+	b.conn.mu.Unlock()
+	return nil
+}
+
+type row struct {
+	driver.Row
+	rows *rows
+	err  error
+}
+
+func (r *row) Err() error { return r.err }
+
+func (r *row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Scan(dest...)
+}
+
+func (r *rows) Err() error   { return nil }
+func (r *rows) Close() error { return nil }
@@ -17,6 +17,11 @@ type Config struct {
 
 	// Clickhouse is the clickhouse configuration
 	Clickhouse ClickhouseConfig `mapstructure:"clickhouse"`
+
+	// Migrate applies pending schema migrations on startup. Only the API
+	// server owns the schema; other writers such as the collector exporter
+	// leave it off.
+	Migrate bool `mapstructure:"migrate"`
 }
 
 type ConnectionConfig struct {
@@ -63,6 +68,7 @@ func newConfig() factory.Configurable {
 
 	return Config{
 		Provider: "clickhouse",
+		Migrate:  true,
 		Connection: ConnectionConfig{
 			MaxOpenConns: 100,
 			MaxIdleConns: 50,
@@ -0,0 +1,130 @@
+package clickhousestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// ErrLogNotFound is returned when no record matches a log identity.
+var ErrLogNotFound = errors.New("log not found")
+
+// LogIdentity names a single record, by ID or, for records stored before
+// IDs were assigned, by its exact timestamp and a filter on identifying
+// fields. A timestamp given with an ID narrows the lookup.
+type LogIdentity struct {
+	ID        string
+	Timestamp time.Time
+	Match     filter.Expr
+}
+
+// FindLog returns the tenant's record named by id.
+func (p *ClickHouseProvider) FindLog(ctx context.Context, tenantID string, id LogIdentity) (telemetrytypes.LogRecord, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return telemetrytypes.LogRecord{}, err
+	}
+	where, whereArgs, err := filterSQL(id.Match)
+	if err != nil {
+		return telemetrytypes.LogRecord{}, err
+	}
+
+	query := `SELECT ` + selectLogColumns + ` FROM logs WHERE ` + tenant + ` AND ` + where
+	args = append(args, whereArgs...)
+	if id.ID != "" {
+		query += ` AND id = ?`
+		args = append(args, id.ID)
+	}
+	if !id.Timestamp.IsZero() {
+		// Positional time arguments are bound with second precision.
+		query += ` AND timestamp = fromUnixTimestamp64Nano(?)`
+		args = append(args, id.Timestamp.UnixNano())
+	}
+	query += ` ORDER BY id LIMIT 1`
+
+	rows, err := p.conn.Query(ctx, query, args...)
+	if err != nil {
+		return telemetrytypes.LogRecord{}, fmt.Errorf("failed to find log: %w", err)
+	}
+	defer rows.Close()
+
+	logs, err := scanLogRows(rows)
+	if err != nil {
+		return telemetrytypes.LogRecord{}, err
+	}
+	if len(logs) == 0 {
+		return telemetrytypes.LogRecord{}, ErrLogNotFound
+	}
+	return logs[0], nil
+}
+
+// GetLogContext returns up to before records preceding anchor and up to
+// after records following it, oldest first, within window of its
+// timestamp. Only records that share the anchor's values of groupBy are
+// returned; keys the anchor does not have are ignored.
+func (p *ClickHouseProvider) GetLogContext(ctx context.Context, tenantID string, anchor telemetrytypes.LogRecord, groupBy []filter.Field, before, after int, window time.Duration) ([]telemetrytypes.LogRecord, []telemetrytypes.LogRecord, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	where, whereArgs, err := filterSQL(groupExpr(anchor, groupBy))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts := anchor.Timestamp.UnixNano()
+	base := `SELECT ` + selectLogColumns + ` FROM logs
+			 WHERE ` + tenant + ` AND ` + where + `
+			 AND timestamp >= fromUnixTimestamp64Nano(?) AND timestamp <= fromUnixTimestamp64Nano(?)`
+	args = append(args, whereArgs...)
+	args = append(args, anchor.Timestamp.Add(-window).UnixNano(), anchor.Timestamp.Add(window).UnixNano())
+
+	query := func(cond, order string, limit int) ([]telemetrytypes.LogRecord, error) {
+		if limit == 0 {
+			return nil, nil
+		}
+		q := base + ` AND (timestamp, id) ` + cond + ` (fromUnixTimestamp64Nano(?), ?)
+			 ORDER BY timestamp ` + order + `, id ` + order + ` LIMIT ?`
+		rows, err := p.conn.Query(ctx, q, append(slices.Clone(args), ts, anchor.ID, limit)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query log context: %w", err)
+		}
+		defer rows.Close()
+		return scanLogRows(rows)
+	}
+
+	preceding, err := query("<", "DESC", before)
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Reverse(preceding)
+
+	following, err := query(">", "ASC", after)
+	if err != nil {
+		return nil, nil, err
+	}
+	return preceding, following, nil
+}
+
+// groupExpr matches records that share the anchor's values of fields.
+func groupExpr(anchor telemetrytypes.LogRecord, fields []filter.Field) filter.Expr {
+	var expr filter.Expr
+	for _, f := range fields {
+		v, ok := filter.RecordValue(&anchor, f)
+		if !ok {
+			continue
+		}
+		cond := &filter.Condition{Field: f, Op: filter.OpEq, Value: v}
+		if expr == nil {
+			expr = cond
+		} else {
+			expr = &filter.And{Left: expr, Right: cond}
+		}
+	}
+	return expr
+}
@@ -0,0 +1,170 @@
+package clickhousestore_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var anchor = telemetrytypes.LogRecord{
+	TenantID:       "team-a",
+	ID:             "log-2",
+	Timestamp:      time.Date(2025, 6, 1, 12, 0, 0, 500, time.UTC),
+	SeverityNumber: 17,
+	SeverityText:   "ERROR",
+	Body:           "payment failed",
+	TraceID:        "4bf92f3577b34da6a3ce929d0e0e4736",
+	Attributes:     []telemetrytypes.KeyValue{{Key: "order", Value: "42"}, {Key: "amount", Value: 9.5}, {Key: "retried", Value: true}},
+	Resource:       telemetrytypes.Resource{Attributes: []telemetrytypes.KeyValue{{Key: "service.name", Value: "checkout"}}},
+}
+
+func field(t *testing.T, name string) filter.Field {
+	t.Helper()
+	f, err := filter.ParseField(name)
+	require.NoError(t, err)
+	return f
+}
+
+func TestGroupExpr(t *testing.T) {
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{nil, "<nil>"},
+		{[]string{"resource.host.name"}, "<nil>"},
+		{[]string{"resource.service.name"}, `resource.service.name = "checkout"`},
+		{[]string{"resource.service.name", "resource.host.name", "severity_number"}, `(resource.service.name = "checkout" AND severity_number = 17)`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.fields, ","), func(t *testing.T) {
+			fields := make([]filter.Field, len(tt.fields))
+			for i, name := range tt.fields {
+				fields[i] = field(t, name)
+			}
+			expr := clickhousestore.GroupExpr(anchor, fields)
+			if expr == nil {
+				assert.Equal(t, tt.want, "<nil>")
+				return
+			}
+			assert.Equal(t, tt.want, expr.String())
+		})
+	}
+}
+
+func TestFindLog(t *testing.T) {
+	match, err := filter.Parse(`body = "payment failed"`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		tenant   string
+		id       clickhousestore.LogIdentity
+		rows     [][]any
+		wantSQL  []string
+		wantArgs []any
+		err      error
+	}{
+		{
+			name:     "by id",
+			tenant:   "team-a",
+			id:       clickhousestore.LogIdentity{ID: "log-2"},
+			rows:     [][]any{clickhousestoretest.LogRow(anchor)},
+			wantSQL:  []string{"tenant_id = ?", "AND id = ?", "LIMIT 1"},
+			wantArgs: []any{"team-a", "log-2"},
+		},
+		{
+			name:     "by timestamp and query",
+			tenant:   "team-a",
+			id:       clickhousestore.LogIdentity{Timestamp: anchor.Timestamp, Match: match},
+			rows:     [][]any{clickhousestoretest.LogRow(anchor)},
+			wantSQL:  []string{"body = ?", "timestamp = fromUnixTimestamp64Nano(?)"},
+			wantArgs: []any{"team-a", "payment failed", anchor.Timestamp.UnixNano()},
+		},
+		{
+			name:   "not found",
+			tenant: "team-a",
+			id:     clickhousestore.LogIdentity{ID: "log-9"},
+			err:    clickhousestore.ErrLogNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &clickhousestoretest.Conn{Rows: func(clickhousestoretest.Query) ([][]any, error) { return tt.rows, nil }}
+			p := clickhousestore.NewProviderFromConn(conn)
+
+			got, err := p.FindLog(context.Background(), tt.tenant, tt.id)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, anchor.ID, got.ID)
+			assert.Equal(t, anchor.Body, got.Body)
+
+			queries := conn.Queries()
+			require.Len(t, queries, 1)
+			for _, s := range tt.wantSQL {
+				assert.Contains(t, queries[0].SQL, s)
+			}
+			assert.Equal(t, tt.wantArgs, queries[0].Args)
+		})
+	}
+
+	p := clickhousestore.NewProviderFromConn(&clickhousestoretest.Conn{})
+	_, err = p.FindLog(context.Background(), "", clickhousestore.LogIdentity{ID: "log-2"})
+	assert.ErrorContains(t, err, "invalid tenant id")
+}
+
+func TestGetLogContext(t *testing.T) {
+	at := func(id string, offset time.Duration) telemetrytypes.LogRecord {
+		rec := anchor
+		rec.ID, rec.Timestamp = id, anchor.Timestamp.Add(offset)
+		return rec
+	}
+	conn := &clickhousestoretest.Conn{Rows: func(q clickhousestoretest.Query) ([][]any, error) {
+		// Preceding records come newest first.
+		if strings.Contains(q.SQL, "(timestamp, id) <") {
+			return [][]any{clickhousestoretest.LogRow(at("log-1", -time.Second)), clickhousestoretest.LogRow(at("log-0", -time.Minute))}, nil
+		}
+		return [][]any{clickhousestoretest.LogRow(at("log-3", time.Second))}, nil
+	}}
+	p := clickhousestore.NewProviderFromConn(conn)
+	groupBy := []filter.Field{field(t, "resource.service.name"), field(t, "resource.host.name")}
+
+	before, after, err := p.GetLogContext(context.Background(), "team-a", anchor, groupBy, 2, 5, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, before, 2)
+	assert.Equal(t, "log-0", before[0].ID, "oldest first")
+	assert.Equal(t, "log-1", before[1].ID)
+	require.Len(t, after, 1)
+	assert.Equal(t, "log-3", after[0].ID)
+
+	queries := conn.Queries()
+	require.Len(t, queries, 2)
+	assert.Contains(t, queries[0].SQL, "ORDER BY timestamp DESC, id DESC")
+	assert.Equal(t, []any{
+		"team-a", "service.name", "checkout",
+		anchor.Timestamp.Add(-time.Hour).UnixNano(), anchor.Timestamp.Add(time.Hour).UnixNano(),
+		anchor.Timestamp.UnixNano(), "log-2", 2,
+	}, queries[0].Args)
+	assert.Contains(t, queries[1].SQL, "ORDER BY timestamp ASC, id ASC")
+
+	// Zero lines are not queried.
+	conn = &clickhousestoretest.Conn{}
+	before, after, err = clickhousestore.NewProviderFromConn(conn).GetLogContext(context.Background(), "team-a", anchor, nil, 0, 0, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, before)
+	assert.Empty(t, after)
+	assert.Empty(t, conn.Queries())
+
+	_, _, err = p.GetLogContext(context.Background(), "", anchor, nil, 1, 1, time.Hour)
+	assert.ErrorContains(t, err, "invalid tenant id")
+}
@@ -0,0 +1,9 @@
+package clickhousestore
+
+import "context"
+
+var GroupExpr = groupExpr
+
+func (p *ClickHouseProvider) DrainLegacyLogs(ctx context.Context) error {
+	return p.drainLegacyLogs(ctx)
+}
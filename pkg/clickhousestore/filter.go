@@ -0,0 +1,79 @@
+package clickhousestore
+
+import (
+	"fmt"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+)
+
+// fieldSQL returns the column expression for a field.
+func fieldSQL(f filter.Field) (string, []any) {
+	switch f.Kind {
+	case filter.FieldAttribute:
+		return "JSONExtractString(attributes, ?)", []any{f.Name}
+	case filter.FieldResource:
+		return "JSONExtractString(resource, ?)", []any{f.Name}
+	default:
+		return f.Name, nil
+	}
+}
+
+// filterSQL translates a parsed filter expression into a WHERE clause
+// fragment and its positional arguments. A nil expression yields "1".
+func filterSQL(expr filter.Expr) (string, []any, error) {
+	switch e := expr.(type) {
+	case nil:
+		return "1", nil, nil
+	case *filter.And:
+		return binarySQL("AND", e.Left, e.Right)
+	case *filter.Or:
+		return binarySQL("OR", e.Left, e.Right)
+	case *filter.Not:
+		inner, args, err := filterSQL(e.Expr)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + inner + ")", args, nil
+	case *filter.Condition:
+		return conditionSQL(e)
+	default:
+		return "", nil, fmt.Errorf("unsupported filter expression %T", expr)
+	}
+}
+
+func binarySQL(op string, left, right filter.Expr) (string, []any, error) {
+	l, largs, err := filterSQL(left)
+	if err != nil {
+		return "", nil, err
+	}
+	r, rargs, err := filterSQL(right)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("(%s %s %s)", l, op, r), append(largs, rargs...), nil
+}
+
+func conditionSQL(c *filter.Condition) (string, []any, error) {
+	col, args := fieldSQL(c.Field)
+
+	switch c.Op {
+	case filter.OpContains:
+		return fmt.Sprintf("positionCaseInsensitive(%s, ?) > 0", col), append(args, c.Value), nil
+	case filter.OpMatch:
+		return fmt.Sprintf("match(%s, ?)", col), append(args, c.Value), nil
+	case filter.OpEq, filter.OpNeq, filter.OpGt, filter.OpGte, filter.OpLt, filter.OpLte:
+	default:
+		return "", nil, fmt.Errorf("unsupported operator %q", c.Op)
+	}
+
+	if num, ok := c.Value.(float64); ok {
+		// Attributes are stored as JSON strings, so numeric comparisons
+		// need an explicit conversion.
+		if c.Field.Kind != filter.FieldColumn {
+			col = fmt.Sprintf("toFloat64OrZero(%s)", col)
+		}
+		return fmt.Sprintf("%s %s ?", col, c.Op), append(args, num), nil
+	}
+
+	return fmt.Sprintf("%s %s ?", col, c.Op), append(args, c.Value), nil
+}
@@ -0,0 +1,105 @@
+package clickhousestore
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedConn records a client span for every call and counts failed
+// calls in metrics.ClickHouseErrors. Cancelled requests are not counted;
+// they are not ClickHouse's fault.
+type instrumentedConn struct {
+	driver.Conn
+}
+
+func countError(ctx context.Context, operation string, err error) error {
+	if err != nil && ctx.Err() == nil && !stderrors.Is(err, sql.ErrNoRows) {
+		metrics.ClickHouseErrors.WithLabelValues(operation).Inc()
+	}
+	return err
+}
+
+func startSpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return telemetry.Start(ctx, "clickhouse "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "clickhouse"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", query),
+		))
+}
+
+// endSpan ends span and passes err through, counting it.
+func endSpan(ctx context.Context, span trace.Span, operation string, err error) error {
+	if err != nil && !stderrors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return countError(ctx, operation, err)
+}
+
+func (c instrumentedConn) Exec(ctx context.Context, query string, args ...any) error {
+	ctx, span := startSpan(ctx, "exec", query)
+	return endSpan(ctx, span, "exec", c.Conn.Exec(ctx, query, args...))
+}
+
+func (c instrumentedConn) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
+	ctx, span := startSpan(ctx, "query", query)
+	rows, err := c.Conn.Query(ctx, query, args...)
+	return rows, endSpan(ctx, span, "query", err)
+}
+
+func (c instrumentedConn) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
+	ctx, span := startSpan(ctx, "query", query)
+	return instrumentedRow{Row: c.Conn.QueryRow(ctx, query, args...), ctx: ctx, span: span}
+}
+
+func (c instrumentedConn) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	ctx, span := startSpan(ctx, "insert", query)
+	batch, err := c.Conn.PrepareBatch(ctx, query, opts...)
+	if err != nil {
+		return nil, endSpan(ctx, span, "prepare_batch", err)
+	}
+	return instrumentedBatch{Batch: batch, ctx: ctx, span: span}, nil
+}
+
+func (c instrumentedConn) Ping(ctx context.Context) error {
+	ctx, span := startSpan(ctx, "ping", "")
+	return endSpan(ctx, span, "ping", c.Conn.Ping(ctx))
+}
+
+type instrumentedRow struct {
+	driver.Row
+	ctx  context.Context
+	span trace.Span
+}
+
+func (r instrumentedRow) Scan(dest ...any) error {
+	return endSpan(r.ctx, r.span, "query", r.Row.Scan(dest...))
+}
+
+// instrumentedBatch ends its span once the batch is sent.
+type instrumentedBatch struct {
+	driver.Batch
+	ctx  context.Context
+	span trace.Span
+}
+
+func (b instrumentedBatch) Send() error {
+	b.span.SetAttributes(attribute.Int("db.operation.batch.size", b.Batch.Rows()))
+	return endSpan(b.ctx, b.span, "insert", b.Batch.Send())
+}
+
+func (b instrumentedBatch) Abort() error {
+	b.span.End()
+	return b.Batch.Abort()
+}
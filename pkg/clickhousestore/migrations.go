@@ -0,0 +1,332 @@
+package clickhousestore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migration is one schema change. Migrations run in order, once, and are
+// recorded in schema_migrations. They must be safe to re-run if a previous
+// attempt failed half way.
+type migration struct {
+	version uint32
+	name    string
+	up      func(ctx context.Context, p *ClickHouseProvider) error
+}
+
+var migrations = []migration{
+	{version: 1, name: "initial schema", up: func(ctx context.Context, p *ClickHouseProvider) error {
+		if err := p.createLogsTable(ctx); err != nil {
+			return err
+		}
+		if err := p.createAlertTables(ctx); err != nil {
+			return err
+		}
+		return p.createAuthTables(ctx)
+	}},
+	{version: 2, name: "tenants", up: migrateTenants},
+	{version: 3, name: "tenant-partitioned logs", up: migrateTenantLogs},
+	{version: 4, name: "log ids", up: migrateLogIDs},
+	{version: 5, name: "log patterns", up: migrateLogPatterns},
+	{version: 6, name: "anomalies", up: migrateAnomalies},
+	{version: 7, name: "pipelines", up: migratePipelines},
+	{version: 8, name: "storage snapshots", up: migrateStorageSnapshots},
+}
+
+// migrate applies pending migrations, then finishes moving legacy logs.
+func (p *ClickHouseProvider) migrate(ctx context.Context) error {
+	err := p.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version UInt32,
+			name String,
+			applied_at DateTime64(3)
+		) ENGINE = MergeTree()
+		ORDER BY version;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	current, err := p.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		slog.InfoContext(ctx, "applying ClickHouse migration", "version", m.version, "name", m.name)
+		if err := m.up(ctx, p); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		err := p.conn.Exec(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.version, m.name, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+	}
+	return p.drainLegacyLogs(ctx)
+}
+
+func (p *ClickHouseProvider) schemaVersion(ctx context.Context) (uint32, error) {
+	var current uint32
+	if err := p.conn.QueryRow(ctx, `SELECT max(version) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return current, nil
+}
+
+// SchemaVersion returns the applied and the latest known schema version.
+// They differ until the API server has migrated the database.
+func (p *ClickHouseProvider) SchemaVersion(ctx context.Context) (current, latest uint32, err error) {
+	current, err = p.schemaVersion(ctx)
+	return current, migrations[len(migrations)-1].version, err
+}
+
+// migrateTenants adds the tenants table and a tenant_id column to every
+// tenant-owned table. Existing rows belong to the default tenant.
+func migrateTenants(ctx context.Context, p *ClickHouseProvider) error {
+	err := p.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS tenants (
+			id String,
+			name String,
+			retention_days UInt16,
+			daily_quota_bytes UInt64,
+			created_at DateTime64(3),
+			deleted UInt8,
+			updated_at DateTime64(3)
+		) ENGINE = ReplacingMergeTree(updated_at)
+		ORDER BY id;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create tenants table: %w", err)
+	}
+
+	tables := []string{"alert_rules", "alert_history", "alert_silences", "alert_notifications", "api_keys", "sessions"}
+	for _, table := range tables {
+		stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS tenant_id LowCardinality(String) DEFAULT 'default'`, table)
+		if err := p.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add tenant_id to %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// migrateTenantLogs rebuilds the logs table so that it is ordered and
+// partitioned by tenant, with a per-row retention taken from the tenant's
+// settings at ingest time. The sorting key of a MergeTree table cannot be
+// changed in place, so the data is copied into a new table which is then
+// swapped in, keeping the old one as logs_legacy. Ingest keeps running
+// meanwhile, so logs can reach the old table between the copy and the
+// swap; drainLegacyLogs copies those over and drops it.
+func migrateTenantLogs(ctx context.Context, p *ClickHouseProvider) error {
+	migrated, err := p.hasColumn(ctx, "logs", "tenant_id")
+	if err != nil || migrated {
+		return err
+	}
+
+	statements := []string{
+		`
+		CREATE TABLE IF NOT EXISTS logs_tenant (
+			tenant_id LowCardinality(String) CODEC(ZSTD(1)),
+			timestamp DateTime64(9) CODEC(Delta(8), ZSTD(1)),
+			observed_time DateTime64(9) CODEC(Delta(8), ZSTD(1)),
+			severity_number Int8 CODEC(ZSTD(1)),
+			severity_text LowCardinality(String) CODEC(ZSTD(1)),
+			body String CODEC(ZSTD(1)),
+			attributes String CODEC(ZSTD(1)),
+			resource String CODEC(ZSTD(1)),
+			trace_id FixedString(32) CODEC(ZSTD(1)),
+			span_id FixedString(16) CODEC(ZSTD(1)),
+			trace_flags UInt8 CODEC(ZSTD(1)),
+			flags UInt32 CODEC(ZSTD(1)),
+			dropped_attributes_count UInt32 CODEC(ZSTD(1)),
+			retention_days UInt16 DEFAULT 30 CODEC(ZSTD(1))
+		) ENGINE = MergeTree()
+		PARTITION BY (tenant_id, toDate(timestamp))
+		ORDER BY (tenant_id, timestamp, severity_number)
+		TTL toDateTime(timestamp) + toIntervalDay(retention_days)
+		SETTINGS index_granularity = 8192, compress_marks = false, compress_primary_key = false;
+		`,
+		// A previous attempt may have failed half way through the copy.
+		`TRUNCATE TABLE logs_tenant`,
+		`INSERT INTO logs_tenant (tenant_id, ` + logColumns + `, retention_days)
+		 SELECT 'default', ` + logColumns + `, 30 FROM logs`,
+		`RENAME TABLE logs TO logs_legacy, logs_tenant TO logs`,
+	}
+	for _, stmt := range statements {
+		if err := p.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to rebuild logs table: %w", err)
+		}
+	}
+	return nil
+}
+
+// legacyLogKey identifies a record of logs_legacy in the default tenant
+// of logs. Legacy records have no id, so their contents are compared.
+const legacyLogKey = `timestamp, cityHash64(observed_time, severity_number, severity_text, body,
+	attributes, resource, trace_id, span_id, trace_flags, flags, dropped_attributes_count)`
+
+// drainLegacyLogs copies the records of logs_legacy that are missing from
+// logs, whatever their timestamps, and drops it once none are. It runs on
+// every start while logs_legacy exists, so a swap that was not followed by
+// a complete copy, because the copy failed or the process died, is
+// finished later; records already copied are skipped, so running it again
+// is harmless.
+func (p *ClickHouseProvider) drainLegacyLogs(ctx context.Context) error {
+	var exists uint64
+	err := p.conn.QueryRow(ctx,
+		`SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = 'logs_legacy'`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up legacy logs table: %w", err)
+	}
+	if exists == 0 {
+		return nil
+	}
+
+	missing := `FROM logs_legacy WHERE (` + legacyLogKey + `) NOT IN (SELECT ` + legacyLogKey + ` FROM logs WHERE tenant_id = 'default')`
+	err = p.conn.Exec(ctx, `INSERT INTO logs (tenant_id, `+logColumns+`, retention_days)
+		SELECT 'default', `+logColumns+`, 30 `+missing)
+	if err != nil {
+		return fmt.Errorf("failed to copy legacy logs: %w", err)
+	}
+
+	var left uint64
+	if err := p.conn.QueryRow(ctx, `SELECT count() `+missing).Scan(&left); err != nil {
+		return fmt.Errorf("failed to verify legacy logs copy: %w", err)
+	}
+	if left > 0 {
+		return fmt.Errorf("%d legacy logs were not copied", left)
+	}
+	if err := p.conn.Exec(ctx, `DROP TABLE logs_legacy`); err != nil {
+		return fmt.Errorf("failed to drop legacy logs table: %w", err)
+	}
+	slog.InfoContext(ctx, "moved legacy logs to the default tenant")
+	return nil
+}
+
+// migrateLogIDs adds the id column, with a bloom filter index for single
+// record lookups. Records stored before it have an empty id.
+func migrateLogIDs(ctx context.Context, p *ClickHouseProvider) error {
+	statements := []string{
+		`ALTER TABLE logs ADD COLUMN IF NOT EXISTS id String DEFAULT '' CODEC(ZSTD(1)) AFTER tenant_id`,
+		`ALTER TABLE logs ADD INDEX IF NOT EXISTS idx_id id TYPE bloom_filter(0.01) GRANULARITY 4`,
+	}
+	for _, stmt := range statements {
+		if err := p.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add log ids: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateLogPatterns adds the pattern_id column and the log_patterns
+// table holding the template of every pattern. Records stored before it
+// have an empty pattern_id.
+func migrateLogPatterns(ctx context.Context, p *ClickHouseProvider) error {
+	statements := []string{
+		`ALTER TABLE logs ADD COLUMN IF NOT EXISTS pattern_id LowCardinality(String) DEFAULT '' CODEC(ZSTD(1)) AFTER id`,
+		`
+		CREATE TABLE IF NOT EXISTS log_patterns (
+			tenant_id LowCardinality(String),
+			id String,
+			template String,
+			updated_at DateTime64(3)
+		) ENGINE = ReplacingMergeTree(updated_at)
+		ORDER BY (tenant_id, id);
+		`,
+	}
+	for _, stmt := range statements {
+		if err := p.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add log patterns: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateAnomalies records when patterns were first seen, for new pattern
+// detection, and adds the anomalies table. Patterns stored before it count
+// as first seen when they were last updated.
+func migrateAnomalies(ctx context.Context, p *ClickHouseProvider) error {
+	statements := []string{
+		`ALTER TABLE log_patterns ADD COLUMN IF NOT EXISTS created_at DateTime64(3) DEFAULT updated_at AFTER template`,
+		`
+		CREATE TABLE IF NOT EXISTS anomalies (
+			tenant_id LowCardinality(String),
+			id String,
+			kind LowCardinality(String),
+			service LowCardinality(String),
+			severity_text LowCardinality(String),
+			pattern_id String,
+			template String CODEC(ZSTD(1)),
+			sample String CODEC(ZSTD(1)),
+			value Float64,
+			expected Float64,
+			score Float64,
+			window_start DateTime64(3),
+			window_end DateTime64(3),
+			detected_at DateTime64(3)
+		) ENGINE = ReplacingMergeTree(detected_at)
+		PARTITION BY toYYYYMM(window_start)
+		ORDER BY (tenant_id, window_start, id)
+		TTL toDateTime(window_start) + INTERVAL 90 DAY;
+		`,
+	}
+	for _, stmt := range statements {
+		if err := p.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add anomalies: %w", err)
+		}
+	}
+	return nil
+}
+
+// migratePipelines adds the table of collector pipeline definitions.
+func migratePipelines(ctx context.Context, p *ClickHouseProvider) error {
+	err := p.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS pipelines (
+			id String,
+			definition String CODEC(ZSTD(1)),
+			deleted UInt8,
+			updated_at DateTime64(3)
+		) ENGINE = ReplacingMergeTree(updated_at)
+		ORDER BY id;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add pipelines: %w", err)
+	}
+	return nil
+}
+
+// migrateStorageSnapshots adds the sizes of each tenant's log partitions
+// at the start of a day, which daily quotas measure growth against. A row
+// with an empty partition marks a tenant's snapshot as taken, even if it
+// had no partitions.
+func migrateStorageSnapshots(ctx context.Context, p *ClickHouseProvider) error {
+	err := p.conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS storage_snapshots (
+			tenant_id LowCardinality(String),
+			day Date,
+			partition String,
+			bytes UInt64
+		) ENGINE = ReplacingMergeTree
+		ORDER BY (tenant_id, day, partition)
+		TTL day + INTERVAL 7 DAY;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add storage snapshots: %w", err)
+	}
+	return nil
+}
+
+func (p *ClickHouseProvider) hasColumn(ctx context.Context, table, column string) (bool, error) {
+	var n uint64
+	err := p.conn.QueryRow(ctx,
+		`SELECT count() FROM system.columns WHERE database = currentDatabase() AND table = ? AND name = ?`, table, column).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up column %s.%s: %w", table, column, err)
+	}
+	return n > 0, nil
+}
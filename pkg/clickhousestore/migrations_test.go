@@ -0,0 +1,53 @@
+package clickhousestore_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainLegacyLogs(t *testing.T) {
+	tests := []struct {
+		name    string
+		exists  uint64
+		missing uint64 // legacy records still missing after the copy
+		execs   []string
+		err     string
+	}{
+		{name: "no legacy table"},
+		{name: "copied", exists: 1, execs: []string{"INSERT INTO logs", "DROP TABLE logs_legacy"}},
+		{name: "incomplete", exists: 1, missing: 2, execs: []string{"INSERT INTO logs"}, err: "2 legacy logs were not copied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &clickhousestoretest.Conn{Rows: func(q clickhousestoretest.Query) ([][]any, error) {
+				if strings.Contains(q.SQL, "system.tables") {
+					return [][]any{{tt.exists}}, nil
+				}
+				return [][]any{{tt.missing}}, nil
+			}}
+			err := clickhousestore.NewProviderFromConn(conn).DrainLegacyLogs(context.Background())
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			execs := conn.Execs()
+			require.Len(t, execs, len(tt.execs))
+			for i, prefix := range tt.execs {
+				assert.True(t, strings.HasPrefix(strings.TrimSpace(execs[i].SQL), prefix), execs[i].SQL)
+			}
+			if len(execs) > 0 {
+				// Records already copied are skipped, whatever their
+				// timestamps, so the copy can run again.
+				assert.Contains(t, execs[0].SQL, "NOT IN (SELECT timestamp, cityHash64(")
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package clickhousestore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// LoadPatterns returns the latest template of every pattern of a tenant.
+func (p *ClickHouseProvider) LoadPatterns(ctx context.Context, tenantID string) ([]patterntypes.Pattern, error) {
+	if !tenanttypes.ValidID(tenantID) {
+		return nil, fmt.Errorf("invalid tenant id %q", tenantID)
+	}
+
+	rows, err := p.conn.Query(ctx, `
+		SELECT id, argMax(template, updated_at), min(created_at), max(updated_at)
+		FROM log_patterns WHERE tenant_id = ?
+		GROUP BY id`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load log patterns: %w", err)
+	}
+	defer rows.Close()
+
+	var patterns []patterntypes.Pattern
+	for rows.Next() {
+		pattern := patterntypes.Pattern{TenantID: tenantID}
+		if err := rows.Scan(&pattern.ID, &pattern.Template, &pattern.CreatedAt, &pattern.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan log pattern: %w", err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, rows.Err()
+}
+
+// UpsertPatterns stores patterns, replacing older templates of the same
+// patterns.
+func (p *ClickHouseProvider) UpsertPatterns(ctx context.Context, patterns []patterntypes.Pattern) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	batch, err := p.conn.PrepareBatch(ctx, "INSERT INTO log_patterns (tenant_id, id, template, created_at, updated_at)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+	for _, pattern := range patterns {
+		if err := batch.Append(pattern.TenantID, pattern.ID, pattern.Template, pattern.CreatedAt, pattern.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to append to batch: %w", err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to store log patterns: %w", err)
+	}
+	return nil
+}
+
+// PatternSummaries returns the limit most frequent patterns of the
+// tenant's records matching expr in [start, end), most frequent first.
+// Records stored without a pattern are left out.
+func (p *ClickHouseProvider) PatternSummaries(ctx context.Context, tenantID string, expr filter.Expr, start, end time.Time, limit int) ([]patterntypes.Summary, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	where, whereArgs, err := filterSQL(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT pattern_id, count(), min(timestamp), max(timestamp), groupUniqArray(3)(body)
+			  FROM logs
+			  WHERE ` + tenant + ` AND timestamp >= ? AND timestamp < ? AND pattern_id != '' AND ` + where + `
+			  GROUP BY pattern_id
+			  ORDER BY count() DESC, pattern_id
+			  LIMIT ?`
+	args = append(args, start, end)
+	args = append(args, whereArgs...)
+	args = append(args, limit)
+
+	rows, err := p.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log patterns: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []patterntypes.Summary
+	for rows.Next() {
+		var s patterntypes.Summary
+		if err := rows.Scan(&s.ID, &s.Count, &s.FirstSeen, &s.LastSeen, &s.Samples); err != nil {
+			return nil, fmt.Errorf("failed to scan log pattern: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return summaries, nil
+	}
+
+	ids := make([]string, len(summaries))
+	for i, s := range summaries {
+		ids[i] = s.ID
+	}
+	templates, err := p.patternTemplates(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		summaries[i].Template = templates[summaries[i].ID]
+	}
+	return summaries, nil
+}
+
+// patternTemplates returns the latest templates of the given patterns.
+func (p *ClickHouseProvider) patternTemplates(ctx context.Context, tenantID string, ids []string) (map[string]string, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.conn.Query(ctx, `
+		SELECT id, argMax(template, updated_at)
+		FROM log_patterns WHERE `+tenant+` AND has(?, id)
+		GROUP BY id`, append(args, ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pattern templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make(map[string]string, len(ids))
+	for rows.Next() {
+		var id, template string
+		if err := rows.Scan(&id, &template); err != nil {
+			return nil, fmt.Errorf("failed to scan pattern template: %w", err)
+		}
+		templates[id] = template
+	}
+	return templates, rows.Err()
+}
@@ -0,0 +1,86 @@
+package clickhousestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// ErrPipelineNotFound is returned when a pipeline definition does not exist.
+var ErrPipelineNotFound = fmt.Errorf("pipeline not found")
+
+// UpsertPipeline stores a new version of the pipeline definition.
+// Pipelines configure the shared collector, so they belong to no tenant.
+func (p *ClickHouseProvider) UpsertPipeline(ctx context.Context, def otelpipelinetypes.Definition) error {
+	definition, err := json.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("failed to encode pipeline: %w", err)
+	}
+
+	err = p.conn.Exec(ctx,
+		`INSERT INTO pipelines (id, definition, deleted, updated_at) VALUES (?, ?, 0, ?)`,
+		def.ID, string(definition), def.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert pipeline: %w", err)
+	}
+	return nil
+}
+
+// DeletePipeline marks the pipeline definition as deleted. The tombstone
+// only wins over the stored version if at is later than its updated_at.
+func (p *ClickHouseProvider) DeletePipeline(ctx context.Context, id string, at time.Time) error {
+	err := p.conn.Exec(ctx,
+		`INSERT INTO pipelines (id, definition, deleted, updated_at) VALUES (?, '', 1, ?)`,
+		id, at,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete pipeline: %w", err)
+	}
+	return nil
+}
+
+// GetPipeline returns a single pipeline definition by id.
+func (p *ClickHouseProvider) GetPipeline(ctx context.Context, id string) (otelpipelinetypes.Definition, error) {
+	defs, err := p.queryPipelines(ctx, `AND id = ?`, id)
+	if err != nil {
+		return otelpipelinetypes.Definition{}, err
+	}
+	if len(defs) == 0 {
+		return otelpipelinetypes.Definition{}, ErrPipelineNotFound
+	}
+	return defs[0], nil
+}
+
+// ListPipelines returns the latest version of every pipeline definition
+// that is not deleted.
+func (p *ClickHouseProvider) ListPipelines(ctx context.Context) ([]otelpipelinetypes.Definition, error) {
+	return p.queryPipelines(ctx, "")
+}
+
+func (p *ClickHouseProvider) queryPipelines(ctx context.Context, where string, args ...any) ([]otelpipelinetypes.Definition, error) {
+	rows, err := p.conn.Query(ctx, `SELECT definition FROM pipelines FINAL WHERE deleted = 0 `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pipelines: %w", err)
+	}
+	defer rows.Close()
+
+	var defs []otelpipelinetypes.Definition
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to scan pipeline: %w", err)
+		}
+
+		var def otelpipelinetypes.Definition
+		if err := json.Unmarshal([]byte(definition), &def); err != nil {
+			return nil, fmt.Errorf("failed to decode pipeline: %w", err)
+		}
+		defs = append(defs, def)
+	}
+
+	return defs, rows.Err()
+}
@@ -8,12 +8,17 @@ import (
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/Ricky004/watchdata/pkg/factory"
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/metrics"
 	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
 )
 
 type ClickHouseProvider struct {
-	conn clickhouse.Conn
+	conn    clickhouse.Conn
+	tenants *tenantCache
 }
 
 func NewClickHouseProvider(ctx context.Context, cfg Config) (*ClickHouseProvider, error) {
@@ -47,16 +52,30 @@ func NewClickHouseProvider(ctx context.Context, cfg Config) (*ClickHouseProvider
 		return nil, fmt.Errorf("clickhouse ping failed: %w", err)
 	}
 
-	provider := &ClickHouseProvider{conn: conn}
+	provider := &ClickHouseProvider{conn: instrumentedConn{conn}, tenants: newTenantCache()}
 
-	// Create the logs table if it doesn't exist
-	if err := provider.createLogsTable(ctx); err != nil {
-		return nil, fmt.Errorf("failed to create logs table: %w", err)
+	if cfg.Migrate {
+		if err := provider.migrate(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	return provider, nil
 }
 
+// Ping checks that ClickHouse is reachable.
+func (p *ClickHouseProvider) Ping(ctx context.Context) error {
+	return p.conn.Ping(ctx)
+}
+
+// Close closes the connection pool. Inserts in progress should have
+// finished before it is called.
+func (p *ClickHouseProvider) Close() error {
+	return p.conn.Close()
+}
+
+// createLogsTable creates the original, single-tenant logs table. It is
+// only used by the initial migration; migrateTenantLogs replaces it.
 func (p *ClickHouseProvider) createLogsTable(ctx context.Context) error {
 	createTableSQL := `
 	CREATE TABLE IF NOT EXISTS logs (
@@ -86,6 +105,12 @@ func (p *ClickHouseProvider) createLogsTable(ctx context.Context) error {
 	return nil
 }
 
+// NewProviderFromConn returns a provider using an open connection, without
+// pinging or migrating it. It is meant for tests.
+func NewProviderFromConn(conn driver.Conn) *ClickHouseProvider {
+	return &ClickHouseProvider{conn: conn, tenants: newTenantCache()}
+}
+
 func NewProviderFactory() factory.ProviderFactory[*ClickHouseProvider, Config] {
 	return factory.NewProviderFactory(
 		factory.MustNewId("clickhouse"),
@@ -95,22 +120,50 @@ func NewProviderFactory() factory.ProviderFactory[*ClickHouseProvider, Config] {
 	)
 }
 
+// InsertLogs stores records under their TenantID, or the default tenant
+// when it is empty. Records without an ID are assigned one.
 func (p *ClickHouseProvider) InsertLogs(ctx context.Context, logs []telemetrytypes.LogRecord) error {
 	if len(logs) == 0 {
 		return nil // Nothing to insert
 	}
 
-	batch, err := p.conn.PrepareBatch(ctx, "INSERT INTO logs (timestamp, observed_time, severity_number, severity_text, body, attributes, resource, trace_id, span_id, trace_flags, flags, dropped_attributes_count)")
+	batch, err := p.conn.PrepareBatch(ctx, "INSERT INTO logs (tenant_id, id, pattern_id, "+logColumns+", retention_days)")
 	if err != nil {
 		return fmt.Errorf("failed to prepare batch: %w", err)
 	}
 
+	retention := make(map[string]uint16)
 	for _, log := range logs {
+		tenantID := log.TenantID
+		if tenantID == "" {
+			tenantID = tenanttypes.DefaultTenant
+		}
+		if !tenanttypes.ValidID(tenantID) {
+			return fmt.Errorf("invalid tenant id %q", tenantID)
+		}
+		days, ok := retention[tenantID]
+		if !ok {
+			settings, err := p.TenantSettings(ctx, tenantID)
+			if err != nil {
+				return err
+			}
+			days = settings.RetentionDays
+			retention[tenantID] = days
+		}
+
 		// Convert attributes and resource to JSON strings
 		attributesStr := convertAttributesToString(log.Attributes)
 		resourceStr := convertResourceToString(log.Resource)
 
+		id := log.ID
+		if id == "" {
+			id = telemetrytypes.NewLogID()
+		}
+
 		err := batch.Append(
+			tenantID,
+			id,
+			log.PatternID,
 			log.Timestamp,
 			log.ObservedTime,
 			int8(log.SeverityNumber),
@@ -123,121 +176,189 @@ func (p *ClickHouseProvider) InsertLogs(ctx context.Context, logs []telemetrytyp
 			uint8(log.TraceFlags),
 			log.Flags,
 			uint32(log.DroppedAttrCount),
+			days,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to append to batch: %w", err)
 		}
 	}
 
+	start := time.Now()
 	if err := batch.Send(); err != nil {
 		return fmt.Errorf("failed to send batch: %w", err)
 	}
+	metrics.InsertDuration.Observe(time.Since(start).Seconds())
+	metrics.InsertBatchSize.Observe(float64(len(logs)))
 
 	return nil
 }
 
-func (p *ClickHouseProvider) GetLogs(ctx context.Context) ([]telemetrytypes.LogRecord, error) {
-	var logs []telemetrytypes.LogRecord
+// GetLogs returns the tenant's 1000 most recent records.
+func (p *ClickHouseProvider) GetLogs(ctx context.Context, tenantID string) ([]telemetrytypes.LogRecord, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := p.conn.Query(ctx, `SELECT * FROM logs ORDER BY timestamp DESC LIMIT 1000`)
+	rows, err := p.conn.Query(ctx, `SELECT `+selectLogColumns+` FROM logs WHERE `+tenant+` ORDER BY timestamp DESC LIMIT 1000`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query: %w", err)
 	}
-
 	defer rows.Close()
-	for rows.Next() {
-		var log telemetrytypes.LogRecord
-		var attributesStr, resourceStr string
-
-		// First scan the raw data from database
-		if err := rows.Scan(
-			&log.Timestamp,
-			&log.ObservedTime,
-			&log.SeverityNumber,
-			&log.SeverityText,
-			&log.Body,
-			&attributesStr, // Scan into string variable
-			&resourceStr,   // Scan into string variable
-			&log.TraceID,
-			&log.SpanID,
-			&log.TraceFlags,
-			&log.Flags,
-			&log.DroppedAttrCount,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
 
-		log.Attributes = parseAttributes(attributesStr)
-		log.Resource.Attributes = parseResource(resourceStr)
+	return scanLogRows(rows)
+}
 
-		logs = append(logs, log)
+// GetLogsSince returns the tenant's records newer than since, oldest first.
+// The live tail poller passes tenanttypes.AllTenants.
+func (p *ClickHouseProvider) GetLogsSince(ctx context.Context, tenantID string, since time.Time) ([]telemetrytypes.LogRecord, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
 	}
 
-	return logs, nil
+	query := `SELECT ` + selectLogColumns + ` FROM logs WHERE ` + tenant + ` AND timestamp > ? ORDER BY timestamp ASC`
+	rows, err := p.conn.Query(ctx, query, append(args, since)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query for new logs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanLogRows(rows)
 }
 
-func (p *ClickHouseProvider) GetLogsSince(ctx context.Context, since time.Time) ([]telemetrytypes.LogRecord, error) {
-	query := `SELECT * FROM logs WHERE timestamp > ? ORDER BY timestamp ASC`
+func (p *ClickHouseProvider) GetLogsInTimeRanges(ctx context.Context, tenantID string, startTs, endTs int64) ([]telemetrytypes.LogRecord, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + selectLogColumns + ` FROM logs
+			  WHERE ` + tenant + ` AND timestamp >= toDateTime(?) AND timestamp <= toDateTime(?)
+			  ORDER BY timestamp DESC
+			  LIMIT 1000
+			  `
 
-	rows, err := p.conn.Query(ctx, query, since)
+	rows, err := p.conn.Query(ctx, query, append(args, startTs, endTs)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query for new logs: %w", err)
 	}
 	defer rows.Close()
 
-	var logs []telemetrytypes.LogRecord
-	for rows.Next() {
-		var log telemetrytypes.LogRecord
-		var attributesStr, resourceStr string
-
-		if err := rows.Scan(
-			&log.Timestamp, &log.ObservedTime, &log.SeverityNumber, &log.SeverityText, &log.Body,
-			&attributesStr, &resourceStr, &log.TraceID, &log.SpanID,
-			&log.TraceFlags, &log.Flags, &log.DroppedAttrCount,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan new log row: %w", err)
-		}
+	return scanLogRows(rows)
+}
 
-		log.Attributes = parseAttributes(attributesStr)
-		log.Resource.Attributes = parseResource(resourceStr)
-		logs = append(logs, log)
+// SearchLogs returns the tenant's most recent records matching expr in
+// [start, end).
+func (p *ClickHouseProvider) SearchLogs(ctx context.Context, tenantID string, expr filter.Expr, start, end time.Time, limit int) ([]telemetrytypes.LogRecord, error) {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	where, whereArgs, err := filterSQL(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + selectLogColumns + ` FROM logs
+			  WHERE ` + tenant + ` AND timestamp >= ? AND timestamp < ? AND ` + where + `
+			  ORDER BY timestamp DESC
+			  LIMIT ?`
+	args = append(args, start, end)
+	args = append(args, whereArgs...)
+	args = append(args, limit)
+
+	rows, err := p.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search logs: %w", err)
 	}
+	defer rows.Close()
 
-	return logs, nil
+	return scanLogRows(rows)
 }
 
-func (p *ClickHouseProvider) GetLogsInTimeRanges(ctx context.Context, startTs, endTs int64) ([]telemetrytypes.LogRecord, error) {
-	query := `SELECT * FROM logs
-			  WHERE timestamp >= toDateTime(?) AND timestamp <= toDateTime(?)
-			  ORDER BY timestamp DESC
-			  LIMIT 1000
-			  `
+// StreamLogs calls fn for every record of the tenant matching expr in
+// [start, end), oldest first, as ClickHouse streams the result, so memory
+// stays bounded however large it is. The configured execution time and
+// read limits do not apply; ctx bounds the query instead. An error from fn
+// stops the query and is returned.
+func (p *ClickHouseProvider) StreamLogs(ctx context.Context, tenantID string, expr filter.Expr, start, end time.Time, fn func(telemetrytypes.LogRecord) error) error {
+	tenant, args, err := tenantCondition(tenantID)
+	if err != nil {
+		return err
+	}
+	where, whereArgs, err := filterSQL(expr)
+	if err != nil {
+		return err
+	}
 
-	rows, err := p.conn.Query(ctx, query, startTs, endTs)
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"max_execution_time":      0,
+		"max_execution_time_leaf": 0,
+		"max_bytes_to_read":       0,
+	}))
+	query := `SELECT ` + selectLogColumns + ` FROM logs
+			  WHERE ` + tenant + ` AND timestamp >= ? AND timestamp < ? AND ` + where + `
+			  ORDER BY timestamp ASC`
+	args = append(args, start, end)
+	args = append(args, whereArgs...)
+
+	rows, err := p.conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query for new logs: %w", err)
+		return fmt.Errorf("failed to stream logs: %w", err)
 	}
 	defer rows.Close()
 
-	var logs []telemetrytypes.LogRecord
 	for rows.Next() {
-		var log telemetrytypes.LogRecord
-		var attributesStr, resourceStr string
-
-		if err := rows.Scan(
-			&log.Timestamp, &log.ObservedTime, &log.SeverityNumber, &log.SeverityText, &log.Body,
-			&attributesStr, &resourceStr, &log.TraceID, &log.SpanID,
-			&log.TraceFlags, &log.Flags, &log.DroppedAttrCount,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan new log row: %w", err)
+		log, err := scanLogRow(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(log); err != nil {
+			return err
 		}
+	}
+	return rows.Err()
+}
+
+// logColumns lists the record columns shared by every version of the logs
+// table, in the order scanLogRows expects after tenant_id, id and
+// pattern_id.
+const logColumns = `timestamp, observed_time, severity_number, severity_text, body,
+	attributes, resource, trace_id, span_id, trace_flags, flags, dropped_attributes_count`
+
+// selectLogColumns is what scanLogRows reads.
+const selectLogColumns = `tenant_id, id, pattern_id, ` + logColumns
 
-		log.Attributes = parseAttributes(attributesStr)
-		log.Resource.Attributes = parseResource(resourceStr)
+func scanLogRows(rows driver.Rows) ([]telemetrytypes.LogRecord, error) {
+	var logs []telemetrytypes.LogRecord
+	for rows.Next() {
+		log, err := scanLogRow(rows)
+		if err != nil {
+			return nil, err
+		}
 		logs = append(logs, log)
 	}
 
-	return logs, nil
+	return logs, rows.Err()
+}
+
+func scanLogRow(rows driver.Rows) (telemetrytypes.LogRecord, error) {
+	var log telemetrytypes.LogRecord
+	var attributesStr, resourceStr string
+
+	if err := rows.Scan(
+		&log.TenantID, &log.ID, &log.PatternID,
+		&log.Timestamp, &log.ObservedTime, &log.SeverityNumber, &log.SeverityText, &log.Body,
+		&attributesStr, &resourceStr, &log.TraceID, &log.SpanID,
+		&log.TraceFlags, &log.Flags, &log.DroppedAttrCount,
+	); err != nil {
+		return log, fmt.Errorf("failed to scan log row: %w", err)
+	}
+
+	log.Attributes = parseAttributes(attributesStr)
+	log.Resource.Attributes = parseResource(resourceStr)
+	return log, nil
 }
 
 // helper functions
@@ -0,0 +1,206 @@
+package clickhousestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// tenantCacheTTL bounds how long a settings change takes to reach ingest.
+const tenantCacheTTL = time.Minute
+
+type tenantCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedTenant
+}
+
+type cachedTenant struct {
+	tenant    tenanttypes.Tenant
+	fetchedAt time.Time
+}
+
+func newTenantCache() *tenantCache {
+	return &tenantCache{entries: make(map[string]cachedTenant)}
+}
+
+// UpsertTenant stores a new version of the tenant's settings.
+func (p *ClickHouseProvider) UpsertTenant(ctx context.Context, t tenanttypes.Tenant) error {
+	err := p.conn.Exec(ctx,
+		`INSERT INTO tenants (id, name, retention_days, daily_quota_bytes, created_at, deleted, updated_at) VALUES (?, ?, ?, ?, ?, 0, ?)`,
+		t.ID, t.Name, t.RetentionDays, t.DailyQuotaBytes, t.CreatedAt, t.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert tenant: %w", err)
+	}
+	p.tenants.invalidate(t.ID)
+	return nil
+}
+
+// DeleteTenant removes the tenant's settings. Its logs are kept until
+// they expire.
+func (p *ClickHouseProvider) DeleteTenant(ctx context.Context, id string) error {
+	if _, err := p.GetTenant(ctx, id); err != nil {
+		return err
+	}
+
+	err := p.conn.Exec(ctx,
+		`INSERT INTO tenants (id, name, retention_days, daily_quota_bytes, created_at, deleted, updated_at) VALUES (?, '', 0, 0, ?, 1, ?)`,
+		id, time.Now(), time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	p.tenants.invalidate(id)
+	return nil
+}
+
+// GetTenant returns a single tenant by id.
+func (p *ClickHouseProvider) GetTenant(ctx context.Context, id string) (tenanttypes.Tenant, error) {
+	tenants, err := p.queryTenants(ctx, `AND id = ?`, id)
+	if err != nil {
+		return tenanttypes.Tenant{}, err
+	}
+	if len(tenants) == 0 {
+		return tenanttypes.Tenant{}, tenanttypes.ErrTenantNotFound
+	}
+	return tenants[0], nil
+}
+
+// ListTenants returns every configured tenant.
+func (p *ClickHouseProvider) ListTenants(ctx context.Context) ([]tenanttypes.Tenant, error) {
+	return p.queryTenants(ctx, "")
+}
+
+func (p *ClickHouseProvider) queryTenants(ctx context.Context, where string, args ...any) ([]tenanttypes.Tenant, error) {
+	query := `SELECT id, name, retention_days, daily_quota_bytes, created_at, updated_at FROM tenants FINAL WHERE deleted = 0 ` + where + ` ORDER BY id`
+
+	rows, err := p.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []tenanttypes.Tenant
+	for rows.Next() {
+		var t tenanttypes.Tenant
+		if err := rows.Scan(&t.ID, &t.Name, &t.RetentionDays, &t.DailyQuotaBytes, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+
+	return tenants, rows.Err()
+}
+
+// TenantSettings returns the settings for a tenant, falling back to the
+// defaults for tenants that were never configured. Results are cached
+// because every insert needs them.
+func (p *ClickHouseProvider) TenantSettings(ctx context.Context, id string) (tenanttypes.Tenant, error) {
+	if t, ok := p.tenants.get(id); ok {
+		return t, nil
+	}
+
+	t, err := p.GetTenant(ctx, id)
+	if errors.Is(err, tenanttypes.ErrTenantNotFound) {
+		t, err = tenanttypes.Default(id), nil
+	}
+	if err != nil {
+		return tenanttypes.Tenant{}, err
+	}
+
+	p.tenants.put(t)
+	return t, nil
+}
+
+func (c *tenantCache) get(id string) (tenanttypes.Tenant, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[id]
+	if !ok || time.Since(e.fetchedAt) > tenantCacheTTL {
+		return tenanttypes.Tenant{}, false
+	}
+	return e.tenant, true
+}
+
+func (c *tenantCache) put(t tenanttypes.Tenant) {
+	c.mu.Lock()
+	c.entries[t.ID] = cachedTenant{tenant: t, fetchedAt: time.Now()}
+	c.mu.Unlock()
+}
+
+func (c *tenantCache) invalidate(id string) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+}
+
+// tenantCondition restricts a query to one tenant, or to none for
+// tenanttypes.AllTenants. An empty id is rejected so that a missing tenant
+// can never widen a query.
+func tenantCondition(tenantID string) (string, []any, error) {
+	switch {
+	case tenantID == tenanttypes.AllTenants:
+		return "1", nil, nil
+	case tenanttypes.ValidID(tenantID):
+		return "tenant_id = ?", []any{tenantID}, nil
+	default:
+		return "", nil, fmt.Errorf("invalid tenant id %q", tenantID)
+	}
+}
+
+// tenantParts selects the compressed size of each active partition of a
+// tenant's logs. Tenant ids cannot contain quotes, so the prefix of the
+// partition tuple can be spelled out.
+const tenantParts = `SELECT partition, sum(data_compressed_bytes) AS bytes FROM system.parts
+	WHERE database = currentDatabase() AND table = 'logs' AND active AND startsWith(partition, ?)
+	GROUP BY partition`
+
+// StoredBytes returns how much the compressed size of a tenant's logs grew
+// on one UTC day, read from the active parts of its partitions against a
+// snapshot of them taken the first time the day is asked for. Every
+// partition counts, not only the day's, so records with old timestamps
+// count against the day they are stored on. Partitions that shrank, by
+// merges or retention, count as zero.
+func (p *ClickHouseProvider) StoredBytes(ctx context.Context, tenantID string, day time.Time) (uint64, error) {
+	if !tenanttypes.ValidID(tenantID) {
+		return 0, fmt.Errorf("invalid tenant id %q", tenantID)
+	}
+	prefix := fmt.Sprintf("('%s',", tenantID)
+	date := day.Format(time.DateOnly)
+
+	var snapshots uint64
+	err := p.conn.QueryRow(ctx,
+		`SELECT count() FROM storage_snapshots WHERE tenant_id = ? AND day = ?`,
+		tenantID, date).Scan(&snapshots)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read storage snapshot: %w", err)
+	}
+	if snapshots == 0 {
+		err := p.conn.Exec(ctx, `
+			INSERT INTO storage_snapshots (tenant_id, day, partition, bytes)
+			SELECT ?, ?, '', 0
+			UNION ALL
+			SELECT ?, ?, partition, bytes FROM (`+tenantParts+`)`,
+			tenantID, date, tenantID, date, prefix)
+		if err != nil {
+			return 0, fmt.Errorf("failed to take storage snapshot: %w", err)
+		}
+	}
+
+	var n uint64
+	err = p.conn.QueryRow(ctx, `
+		SELECT toUInt64(sum(greatest(toInt64(current.bytes) - toInt64(snapshot.bytes), 0)))
+		FROM (`+tenantParts+`) AS current
+		LEFT JOIN (
+			SELECT partition, bytes FROM storage_snapshots FINAL WHERE tenant_id = ? AND day = ?
+		) AS snapshot USING partition`,
+		prefix, tenantID, date).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stored bytes: %w", err)
+	}
+	return n, nil
+}
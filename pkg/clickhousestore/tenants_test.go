@@ -0,0 +1,43 @@
+package clickhousestore_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/clickhousestore/clickhousestoretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoredBytes(t *testing.T) {
+	var snapshots uint64
+	conn := &clickhousestoretest.Conn{Rows: func(q clickhousestoretest.Query) ([][]any, error) {
+		if strings.Contains(q.SQL, "count() FROM storage_snapshots") {
+			return [][]any{{snapshots}}, nil
+		}
+		return [][]any{{uint64(4096)}}, nil
+	}}
+	p := clickhousestore.NewProviderFromConn(conn)
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	n, err := p.StoredBytes(context.Background(), "team-a", day)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4096), n)
+
+	// The first read of the day snapshots the tenant's partitions.
+	execs := conn.Execs()
+	require.Len(t, execs, 1)
+	assert.Contains(t, execs[0].SQL, "INSERT INTO storage_snapshots")
+	assert.Equal(t, []any{"team-a", "2025-06-01", "team-a", "2025-06-01", "('team-a',"}, execs[0].Args)
+
+	snapshots = 3
+	_, err = p.StoredBytes(context.Background(), "team-a", day)
+	require.NoError(t, err)
+	assert.Len(t, conn.Execs(), 1, "the snapshot is taken once")
+
+	_, err = p.StoredBytes(context.Background(), "team'a", day)
+	assert.ErrorContains(t, err, "invalid tenant id")
+}
@@ -22,12 +22,16 @@ func (e *Error) Unwrap() error {
 
 
 func (e *Error) LogValue() slog.Value {
-	return slog.GroupValue(
+	attrs := []slog.Attr{
 		slog.String("code", string(e.Code)),
 		slog.String("severity", string(e.Severity)),
 		slog.String("message", e.Message),
 		slog.Any("meta", e.Meta),
-	)
+	}
+	if e.Cause != nil {
+		attrs = append(attrs, slog.Any("cause", e.Cause))
+	}
+	return slog.GroupValue(attrs...)
 }
 
 
@@ -21,6 +21,7 @@ const (
 	CodeInvalidField     Code = "request.invalid_field"
 	CodePayloadTooLarge  Code = "request.payload_too_large"
 	CodeUnsupportedMedia Code = "request.unsupported_media"
+	CodeMethodNotAllowed Code = "request.method_not_allowed"
 
 	// --- Authentication / Authorization ---
 	CodeUnauthorized Code = "auth.unauthorized"
@@ -39,12 +40,14 @@ const (
 	// --- Rate Limiting / Throttling ---
 	CodeRateLimit       Code = "rate_limited"
 	CodeTooManyRequests Code = "rate.too_many_requests"
+	CodeQuotaExceeded   Code = "rate.quota_exceeded"
 
 	// --- Internal / Unexpected Errors ---
 	CodeInternalError  Code = "internal.error"
 	CodePanicRecovered Code = "internal.panic_recovered"
 	CodeEncodingFailed Code = "internal.encoding_failed"
 	CodeUnknown        Code = "internal.unknown"
+	CodeUnavailable    Code = "internal.unavailable"
 
 	// --- External / 3rd Party Services ---
 	CodeExternalAPIError    Code = "external.api_error"
@@ -77,6 +80,7 @@ func validateCodes() error {
 		CodeInvalidField,
 		CodePayloadTooLarge,
 		CodeUnsupportedMedia,
+		CodeMethodNotAllowed,
 		CodeUnauthorized,
 		CodeForbidden,
 		CodeTokenExpired,
@@ -88,10 +92,12 @@ func validateCodes() error {
 		CodeResourceLocked,
 		CodeDependencyMissing,
 		CodeTooManyRequests,
+		CodeQuotaExceeded,
 		CodeInternalError,
 		CodePanicRecovered,
 		CodeEncodingFailed,
 		CodeUnknown,
+		CodeUnavailable,
 		CodeExternalAPIError,
 		CodeExternalTimeout,
 		CodeExternalUnreachable,
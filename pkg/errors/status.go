@@ -0,0 +1,134 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var httpStatuses = map[Code]int{
+	CodeDBConnection:      http.StatusServiceUnavailable,
+	CodeDBQueryFailed:     http.StatusInternalServerError,
+	CodeDBMigrationFailed: http.StatusInternalServerError,
+	CodeCacheUnavailable:  http.StatusServiceUnavailable,
+	CodeDBTimeout:         http.StatusGatewayTimeout,
+
+	CodeInvalidRequest:   http.StatusBadRequest,
+	CodeMissingField:     http.StatusBadRequest,
+	CodeInvalidField:     http.StatusBadRequest,
+	CodePayloadTooLarge:  http.StatusRequestEntityTooLarge,
+	CodeUnsupportedMedia: http.StatusUnsupportedMediaType,
+	CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+
+	CodeUnauthorized: http.StatusUnauthorized,
+	CodeForbidden:    http.StatusForbidden,
+	CodeTokenExpired: http.StatusUnauthorized,
+	CodeTokenInvalid: http.StatusUnauthorized,
+	CodeUserDisabled: http.StatusForbidden,
+
+	CodeNotFound:          http.StatusNotFound,
+	CodeAlreadyExists:     http.StatusConflict,
+	CodeConflict:          http.StatusConflict,
+	CodeResourceLocked:    http.StatusLocked,
+	CodeDependencyMissing: http.StatusFailedDependency,
+
+	CodeRateLimit:       http.StatusTooManyRequests,
+	CodeTooManyRequests: http.StatusTooManyRequests,
+	CodeQuotaExceeded:   http.StatusForbidden,
+
+	CodeInternalError:  http.StatusInternalServerError,
+	CodePanicRecovered: http.StatusInternalServerError,
+	CodeEncodingFailed: http.StatusInternalServerError,
+	CodeUnknown:        http.StatusInternalServerError,
+	CodeUnavailable:    http.StatusServiceUnavailable,
+
+	CodeExternalAPIError:    http.StatusBadGateway,
+	CodeExternalTimeout:     http.StatusGatewayTimeout,
+	CodeExternalUnreachable: http.StatusBadGateway,
+	CodeExternalBadResponse: http.StatusBadGateway,
+
+	CodeTraceMissing:     http.StatusBadRequest,
+	CodeMetricsCorrupted: http.StatusInternalServerError,
+	CodeLogFormatInvalid: http.StatusBadRequest,
+
+	CodeFeatureDisabled: http.StatusNotImplemented,
+	CodeInvalidConfig:   http.StatusInternalServerError,
+	CodeConfigMissing:   http.StatusInternalServerError,
+}
+
+var grpcCodes = map[Code]codes.Code{
+	CodeDBConnection:      codes.Unavailable,
+	CodeDBQueryFailed:     codes.Internal,
+	CodeDBMigrationFailed: codes.Internal,
+	CodeCacheUnavailable:  codes.Unavailable,
+	CodeDBTimeout:         codes.DeadlineExceeded,
+
+	CodeInvalidRequest:   codes.InvalidArgument,
+	CodeMissingField:     codes.InvalidArgument,
+	CodeInvalidField:     codes.InvalidArgument,
+	CodePayloadTooLarge:  codes.InvalidArgument,
+	CodeUnsupportedMedia: codes.InvalidArgument,
+	CodeMethodNotAllowed: codes.Unimplemented,
+
+	CodeUnauthorized: codes.Unauthenticated,
+	CodeForbidden:    codes.PermissionDenied,
+	CodeTokenExpired: codes.Unauthenticated,
+	CodeTokenInvalid: codes.Unauthenticated,
+	CodeUserDisabled: codes.PermissionDenied,
+
+	CodeNotFound:          codes.NotFound,
+	CodeAlreadyExists:     codes.AlreadyExists,
+	CodeConflict:          codes.Aborted,
+	CodeResourceLocked:    codes.FailedPrecondition,
+	CodeDependencyMissing: codes.FailedPrecondition,
+
+	CodeRateLimit:       codes.ResourceExhausted,
+	CodeTooManyRequests: codes.ResourceExhausted,
+	CodeQuotaExceeded:   codes.ResourceExhausted,
+
+	CodeUnavailable: codes.Unavailable,
+
+	CodeExternalTimeout:     codes.DeadlineExceeded,
+	CodeExternalUnreachable: codes.Unavailable,
+
+	CodeTraceMissing:     codes.InvalidArgument,
+	CodeLogFormatInvalid: codes.InvalidArgument,
+
+	CodeFeatureDisabled: codes.Unimplemented,
+}
+
+// HTTPStatus is the response status for errors with this code. Unknown
+// codes are internal errors.
+func (c Code) HTTPStatus() int {
+	if s, ok := httpStatuses[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode is the gRPC status code for errors with this code. Unknown
+// codes are internal errors.
+func (c Code) GRPCCode() codes.Code {
+	if s, ok := grpcCodes[c]; ok {
+		return s
+	}
+	return codes.Internal
+}
+
+// From returns err as an *Error. Errors that are not structured become
+// internal errors so their details are never shown to clients.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return New(CodeInternalError, "internal error", SeverityError, err)
+}
+
+// GRPCStatus converts err to a gRPC status carrying its message.
+func GRPCStatus(err error) *status.Status {
+	e := From(err)
+	return status.New(e.Code.GRPCCode(), e.Message)
+}
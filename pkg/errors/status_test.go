@@ -0,0 +1,40 @@
+package errors_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCodesAreValid(t *testing.T) {
+	assert.NotPanics(t, errors.MustvalidateCodes)
+}
+
+func TestStatusMapping(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, errors.CodeNotFound.HTTPStatus())
+	assert.Equal(t, http.StatusTooManyRequests, errors.CodeRateLimit.HTTPStatus())
+	assert.Equal(t, http.StatusForbidden, errors.CodeQuotaExceeded.HTTPStatus())
+	assert.Equal(t, http.StatusInternalServerError, errors.Code("made.up").HTTPStatus())
+	assert.Equal(t, codes.ResourceExhausted, errors.CodeQuotaExceeded.GRPCCode())
+	assert.Equal(t, codes.Unavailable, errors.CodeUnavailable.GRPCCode())
+	assert.Equal(t, codes.Internal, errors.CodeEncodingFailed.GRPCCode())
+}
+
+func TestFrom(t *testing.T) {
+	cause := fmt.Errorf("connection refused")
+	wrapped := fmt.Errorf("query: %w", errors.New(errors.CodeNotFound, "rule not found", errors.SeverityInfo, cause))
+	assert.Equal(t, errors.CodeNotFound, errors.From(wrapped).Code)
+
+	e := errors.From(cause)
+	assert.Equal(t, errors.CodeInternalError, e.Code)
+	assert.Equal(t, "internal error", e.Message)
+	assert.Equal(t, cause, e.Cause)
+
+	st := errors.GRPCStatus(wrapped)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "rule not found", st.Message())
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,7 +13,6 @@ import (
 type Registry struct {
 	services IdxMap[IdxService]
 	startch  chan error
-	stopch   chan error
 }
 
 func NewRegistry(services ...IdxService) (*Registry, error) {
@@ -28,11 +27,12 @@ func NewRegistry(services ...IdxService) (*Registry, error) {
 
 	return &Registry{
 		services: m,
-		startch: make(chan error, 1),
-		stopch: make(chan error, len(services)),
+		startch:  make(chan error, len(services)),
 	}, nil
 }
 
+// Start starts every service in its own goroutine. Services block in Start
+// until they are stopped; one returning early ends Wait.
 func (r *Registry) Start(ctx context.Context) {
 	for _, s := range r.services.GetInOrder() {
 		go func(s IdxService) {
@@ -42,39 +42,39 @@ func (r *Registry) Start(ctx context.Context) {
 	}
 }
 
+// Wait blocks until ctx is done, SIGINT or SIGTERM is received, or a
+// service exits, whichever comes first.
 func (r *Registry) Wait(ctx context.Context) error {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	select {
 	case <-ctx.Done():
-		log.Printf("caught context error, exiting : %v", ctx.Err())
+		slog.Info("caught context error, exiting", "error", ctx.Err())
 	case s := <-interrupt:
-		log.Printf("caught interrupt signal, exiting : %v", s)
+		slog.Info("caught interrupt signal, exiting", "signal", s.String())
 	case err := <-r.startch:
-		log.Printf("caught service error, exiting : %v", err)
+		slog.Error("caught service error, exiting", "error", err)
 		return err
 	}
 
 	return nil
 }
 
+// Stop stops the services in the reverse of their registration order, so
+// services are stopped before the services they depend on. ctx bounds the
+// whole shutdown.
 func (r *Registry) Stop(ctx context.Context) error {
-	for _, s := range r.services.GetInOrder() {
-		go func(s IdxService) {
-			log.Printf("stopping service : %v", s.Id())
-			err := s.Stop(ctx)
-			r.stopch <- err
-		}(s)
-	}
+	services := r.services.GetInOrder()
 
-	errs := make([]error, len(r.services.GetInOrder()))
-	for range r.services.GetInOrder() {
-		err := <-r.stopch
-		if err != nil {
-			errs = append(errs, err)
+	var errs []error
+	for i := len(services) - 1; i >= 0; i-- {
+		s := services[i]
+		slog.Info("stopping service", "service", s.Id().String())
+		if err := s.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %v: %w", s.Id(), err))
 		}
 	}
 
 	return errors.Join(errs...)
-}
\ No newline at end of file
+}
@@ -0,0 +1,89 @@
+package factory_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) service(id string, stopErr error) factory.IdxService {
+	return factory.NewRunService(factory.MustNewId(id), func(ctx context.Context) error {
+		<-ctx.Done()
+		r.mu.Lock()
+		r.stopped = append(r.stopped, id)
+		r.mu.Unlock()
+		return stopErr
+	})
+}
+
+func TestRegistryStopsInReverseOrder(t *testing.T) {
+	rec := &recorder{}
+	registry, err := factory.NewRegistry(rec.service("store", nil), rec.service("worker", nil), rec.service("api", nil))
+	require.NoError(t, err)
+
+	registry.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, registry.Stop(ctx))
+	assert.Equal(t, []string{"api", "worker", "store"}, rec.stopped)
+}
+
+func TestRegistryWaitReturnsServiceError(t *testing.T) {
+	boom := errors.New("listen failed")
+	failing := factory.NewRunService(factory.MustNewId("api"), func(ctx context.Context) error {
+		return boom
+	})
+	registry, err := factory.NewRegistry(failing)
+	require.NoError(t, err)
+
+	registry.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.ErrorIs(t, registry.Wait(ctx), boom)
+}
+
+func TestRunServiceIgnoresStartContext(t *testing.T) {
+	rec := &recorder{}
+	svc := rec.service("worker", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- svc.Start(ctx) }()
+	cancel()
+
+	select {
+	case <-started:
+		t.Fatal("service stopped with its start context")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, svc.Stop(context.Background()))
+	assert.NoError(t, <-started)
+}
+
+func TestRegistryStopTimesOut(t *testing.T) {
+	stuck := factory.NewRunService(factory.MustNewId("stuck"), func(ctx context.Context) error {
+		select {}
+	})
+	registry, err := factory.NewRegistry(stuck)
+	require.NoError(t, err)
+
+	registry.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, registry.Stop(ctx), context.DeadlineExceeded)
+}
@@ -1,6 +1,9 @@
 package factory
 
-import "context"
+import (
+	"context"
+	"sync"
+)
 
 type Service interface {
 	Start(context.Context) error
@@ -27,3 +30,58 @@ func NewIdxService(id Id, service Service) IdxService {
 		Service: service,
 	}
 }
+
+// RunFunc is the body of a service. It must return once ctx is cancelled.
+type RunFunc func(ctx context.Context) error
+
+type runService struct {
+	id       Id
+	run      RunFunc
+	stopC    chan struct{}
+	doneC    chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRunService returns a service that runs fn from Start until Stop
+// cancels its context. Cancelling the context passed to Start does not stop
+// it, so the registry controls the shutdown order.
+func NewRunService(id Id, fn RunFunc) IdxService {
+	return &runService{
+		id:    id,
+		run:   fn,
+		stopC: make(chan struct{}),
+		doneC: make(chan struct{}),
+	}
+}
+
+func (s *runService) Id() Id {
+	return s.id
+}
+
+func (s *runService) Start(ctx context.Context) error {
+	defer close(s.doneC)
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return s.run(ctx)
+}
+
+// Stop cancels the service and waits for it to return.
+func (s *runService) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopC) })
+
+	select {
+	case <-s.doneC:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,170 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is a comparison operator used in a condition.
+type Op string
+
+const (
+	OpEq       Op = "="
+	OpNeq      Op = "!="
+	OpGt       Op = ">"
+	OpGte      Op = ">="
+	OpLt       Op = "<"
+	OpLte      Op = "<="
+	OpContains Op = "contains"
+	OpMatch    Op = "~"
+)
+
+// FieldKind tells where a field lives in a log record.
+type FieldKind int
+
+const (
+	// FieldColumn is a top level column such as body or severity_number.
+	FieldColumn FieldKind = iota
+	// FieldAttribute is a key inside the log attributes.
+	FieldAttribute
+	// FieldResource is a key inside the resource attributes.
+	FieldResource
+)
+
+// columns are the top level fields that can be used in expressions.
+var columns = map[string]bool{
+	"body":            true,
+	"severity_number": true,
+	"severity_text":   true,
+	"trace_id":        true,
+	"span_id":         true,
+	"pattern_id":      true,
+}
+
+// Field is a reference to a column or an attribute key.
+type Field struct {
+	Kind FieldKind
+	Name string
+}
+
+func (f Field) String() string {
+	switch f.Kind {
+	case FieldAttribute:
+		return "attributes." + f.Name
+	case FieldResource:
+		return "resource." + f.Name
+	default:
+		return f.Name
+	}
+}
+
+// ParseField resolves a field name as written in an expression or a group-by
+// list. "service" is shorthand for "resource.service.name".
+func ParseField(name string) (Field, error) {
+	switch {
+	case name == "service":
+		return Field{Kind: FieldResource, Name: "service.name"}, nil
+	case strings.HasPrefix(name, "attributes.") && len(name) > len("attributes."):
+		return Field{Kind: FieldAttribute, Name: strings.TrimPrefix(name, "attributes.")}, nil
+	case strings.HasPrefix(name, "resource.") && len(name) > len("resource."):
+		return Field{Kind: FieldResource, Name: strings.TrimPrefix(name, "resource.")}, nil
+	case columns[name]:
+		return Field{Kind: FieldColumn, Name: name}, nil
+	}
+	return Field{}, fmt.Errorf("unknown field %q", name)
+}
+
+// Expr is a node of a parsed filter expression.
+type Expr interface {
+	String() string
+}
+
+// And matches when both sides match.
+type And struct {
+	Left, Right Expr
+}
+
+func (e *And) String() string {
+	return fmt.Sprintf("(%s AND %s)", e.Left, e.Right)
+}
+
+// Or matches when either side matches.
+type Or struct {
+	Left, Right Expr
+}
+
+func (e *Or) String() string {
+	return fmt.Sprintf("(%s OR %s)", e.Left, e.Right)
+}
+
+// Not inverts the inner expression.
+type Not struct {
+	Expr Expr
+}
+
+func (e *Not) String() string {
+	return fmt.Sprintf("NOT %s", e.Expr)
+}
+
+// Condition compares a field against a literal value.
+type Condition struct {
+	Field Field
+	Op    Op
+	// Value is either a string or a float64.
+	Value any
+}
+
+func (c *Condition) String() string {
+	if s, ok := c.Value.(string); ok {
+		return fmt.Sprintf("%s %s %q", c.Field, c.Op, s)
+	}
+	return fmt.Sprintf("%s %s %v", c.Field, c.Op, c.Value)
+}
+
+// MapFields returns a copy of expr with every field replaced by fn(field),
+// for stores whose columns differ from the log fields. It stops at the
+// first error of fn.
+func MapFields(expr Expr, fn func(Field) (Field, error)) (Expr, error) {
+	switch e := expr.(type) {
+	case nil:
+		return nil, nil
+	case *And:
+		l, r, err := mapBinary(e.Left, e.Right, fn)
+		if err != nil {
+			return nil, err
+		}
+		return &And{Left: l, Right: r}, nil
+	case *Or:
+		l, r, err := mapBinary(e.Left, e.Right, fn)
+		if err != nil {
+			return nil, err
+		}
+		return &Or{Left: l, Right: r}, nil
+	case *Not:
+		inner, err := MapFields(e.Expr, fn)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: inner}, nil
+	case *Condition:
+		f, err := fn(e.Field)
+		if err != nil {
+			return nil, err
+		}
+		return &Condition{Field: f, Op: e.Op, Value: e.Value}, nil
+	default:
+		return nil, fmt.Errorf("unsupported filter expression %T", expr)
+	}
+}
+
+func mapBinary(left, right Expr, fn func(Field) (Field, error)) (Expr, Expr, error) {
+	l, err := MapFields(left, fn)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := MapFields(right, fn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matcher evaluates an expression in memory with the semantics of the SQL
+// the stores build from it: a missing attribute is the empty string,
+// numeric literals compare numerically with values that are not numbers
+// reading as zero, and contains ignores case.
+type Matcher struct {
+	expr     Expr
+	patterns map[string]*regexp.Regexp
+}
+
+// NewMatcher returns a matcher for expr. A nil expression matches
+// everything.
+func NewMatcher(expr Expr) *Matcher {
+	m := &Matcher{expr: expr, patterns: make(map[string]*regexp.Regexp)}
+	m.compile(expr)
+	return m
+}
+
+func (m *Matcher) compile(expr Expr) {
+	switch e := expr.(type) {
+	case *And:
+		m.compile(e.Left)
+		m.compile(e.Right)
+	case *Or:
+		m.compile(e.Left)
+		m.compile(e.Right)
+	case *Not:
+		m.compile(e.Expr)
+	case *Condition:
+		if s, ok := e.Value.(string); ok && e.Op == OpMatch {
+			// Parse rejects invalid patterns, so this only fails for
+			// hand-built conditions, which then never match.
+			if re, err := regexp.Compile(s); err == nil {
+				m.patterns[s] = re
+			}
+		}
+	}
+}
+
+// Match reports whether the expression matches the values returned by
+// value, which returns the empty string for missing fields.
+func (m *Matcher) Match(value func(Field) string) bool {
+	return m.match(m.expr, value)
+}
+
+func (m *Matcher) match(expr Expr, value func(Field) string) bool {
+	switch e := expr.(type) {
+	case nil:
+		return true
+	case *And:
+		return m.match(e.Left, value) && m.match(e.Right, value)
+	case *Or:
+		return m.match(e.Left, value) || m.match(e.Right, value)
+	case *Not:
+		return !m.match(e.Expr, value)
+	case *Condition:
+		return m.condition(e, value(e.Field))
+	}
+	return false
+}
+
+func (m *Matcher) condition(c *Condition, v string) bool {
+	switch c.Op {
+	case OpContains:
+		s, _ := c.Value.(string)
+		return strings.Contains(strings.ToLower(v), strings.ToLower(s))
+	case OpMatch:
+		s, _ := c.Value.(string)
+		re, ok := m.patterns[s]
+		return ok && re.MatchString(v)
+	}
+
+	var cmp int
+	switch want := c.Value.(type) {
+	case float64:
+		got, _ := strconv.ParseFloat(v, 64)
+		switch {
+		case got < want:
+			cmp = -1
+		case got > want:
+			cmp = 1
+		}
+	case string:
+		cmp = strings.Compare(v, want)
+	default:
+		return false
+	}
+
+	switch c.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNeq:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	values := map[string]string{
+		"body":                  "Payment Timeout after 30s",
+		"severity_number":       "17",
+		"attributes.status":     "503",
+		"resource.service.name": "checkout",
+	}
+	value := func(f filter.Field) string { return values[f.String()] }
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{``, true},
+		{`body contains "timeout"`, true},
+		{`body ~ "^Payment \\w+"`, true},
+		{`body ~ "^timeout"`, false},
+		{`severity_number >= 17`, true},
+		{`severity_number > 17`, false},
+		{`attributes.status >= 500 AND service = "checkout"`, true},
+		{`attributes.status < 500 OR service != "checkout"`, false},
+		{`NOT attributes.missing = "x"`, true},
+		{`attributes.missing = ""`, true},
+		{`attributes.missing < 1`, true},
+		{`severity_text = ERROR`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := filter.Parse(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, filter.NewMatcher(expr).Match(value))
+		})
+	}
+}
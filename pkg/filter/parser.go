@@ -0,0 +1,267 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '\'' || c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(input) && input[i] != c {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				sb.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		case c == '=' || c == '~':
+			tokens = append(tokens, token{kind: tokOp, text: string(c), pos: i})
+			i++
+		case c == '!' || c == '>' || c == '<':
+			if i+1 < len(input) && input[i+1] == '=' {
+				tokens = append(tokens, token{kind: tokOp, text: input[i : i+2], pos: i})
+				i += 2
+				continue
+			}
+			if c == '!' {
+				return nil, fmt.Errorf("unexpected '!' at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: string(c), pos: i})
+			i++
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(input) && (input[i] == '.' || (input[i] >= '0' && input[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: input[start:i], pos: start})
+		case isIdentRune(rune(c)):
+			start := i
+			for i < len(input) && isIdentRune(rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: input[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == '/'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a filter expression such as
+//
+//	service = "checkout" AND severity_number >= 17 AND body contains "timeout"
+//
+// An empty expression returns a nil Expr, which matches every record.
+func Parse(input string) (Expr, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return expr, nil
+}
+
+// MustParse is like Parse but panics on error.
+func MustParse(input string) Expr {
+	expr, err := Parse(input)
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokIdent && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: inner}, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", tok.pos)
+		}
+		return inner, nil
+	}
+
+	return p.parseCondition()
+}
+
+func (p *parser) parseCondition() (Expr, error) {
+	tok := p.next()
+	if tok.kind != tokIdent {
+		return nil, fmt.Errorf("expected field name at position %d", tok.pos)
+	}
+	field, err := ParseField(tok.text)
+	if err != nil {
+		return nil, err
+	}
+
+	var op Op
+	opTok := p.next()
+	switch {
+	case opTok.kind == tokOp:
+		op = Op(opTok.text)
+	case opTok.kind == tokIdent && strings.EqualFold(opTok.text, "contains"):
+		op = OpContains
+	default:
+		return nil, fmt.Errorf("expected operator after %q at position %d", tok.text, opTok.pos)
+	}
+
+	valTok := p.next()
+	var value any
+	switch valTok.kind {
+	case tokString:
+		value = valTok.text
+	case tokNumber:
+		f, err := strconv.ParseFloat(valTok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", valTok.text, valTok.pos)
+		}
+		value = f
+	case tokIdent:
+		// Allow bare words such as severity_text = ERROR.
+		value = valTok.text
+	default:
+		return nil, fmt.Errorf("expected value after %q at position %d", opTok.text, valTok.pos)
+	}
+
+	switch op {
+	case OpContains:
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("contains requires a string value at position %d", valTok.pos)
+		}
+	case OpMatch:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("~ requires a string pattern at position %d", valTok.pos)
+		}
+		if _, err := regexp.Compile(s); err != nil {
+			return nil, fmt.Errorf("invalid pattern at position %d: %w", valTok.pos, err)
+		}
+	}
+
+	return &Condition{Field: field, Op: op, Value: value}, nil
+}
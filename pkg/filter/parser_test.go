@@ -0,0 +1,98 @@
+package filter_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "empty expression",
+			input: "   ",
+			want:  "<nil>",
+		},
+		{
+			name:  "single condition",
+			input: `severity_number >= 17`,
+			want:  `severity_number >= 17`,
+		},
+		{
+			name:  "service shorthand",
+			input: `service = "checkout"`,
+			want:  `resource.service.name = "checkout"`,
+		},
+		{
+			name:  "and binds tighter than or",
+			input: `body contains 'timeout' OR severity_text = ERROR and attributes.env = "prod"`,
+			want:  `(body contains "timeout" OR (severity_text = "ERROR" AND attributes.env = "prod"))`,
+		},
+		{
+			name:  "parentheses and not",
+			input: `NOT (service = "a" or service = "b")`,
+			want:  `NOT (resource.service.name = "a" OR resource.service.name = "b")`,
+		},
+		{
+			name:  "regex match",
+			input: `body ~ "^5\\d\\d"`,
+			want:  `body ~ "^5\\d\\d"`,
+		},
+		{name: "unknown field", input: `foo = 1`, wantErr: true},
+		{name: "missing value", input: `body =`, wantErr: true},
+		{name: "unterminated string", input: `body = "abc`, wantErr: true},
+		{name: "invalid regex", input: `body ~ "("`, wantErr: true},
+		{name: "trailing tokens", input: `body = "a" "b"`, wantErr: true},
+		{name: "unbalanced parentheses", input: `(body = "a"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := filter.Parse(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if expr == nil {
+				assert.Equal(t, tt.want, "<nil>")
+				return
+			}
+			assert.Equal(t, tt.want, expr.String())
+		})
+	}
+}
+
+func TestParseField(t *testing.T) {
+	f, err := filter.ParseField("resource.k8s.pod.name")
+	require.NoError(t, err)
+	assert.Equal(t, filter.FieldResource, f.Kind)
+	assert.Equal(t, "k8s.pod.name", f.Name)
+
+	_, err = filter.ParseField("attributes.")
+	assert.Error(t, err)
+}
+
+func TestMapFields(t *testing.T) {
+	expr := filter.MustParse(`service = "checkout" AND NOT (severity_text = "INFO" OR body contains "x")`)
+	mapped, err := filter.MapFields(expr, func(f filter.Field) (filter.Field, error) {
+		return filter.Field{Kind: filter.FieldColumn, Name: strings.ReplaceAll(f.String(), ".", "_")}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `(resource_service_name = "checkout" AND NOT (severity_text = "INFO" OR body contains "x"))`, mapped.String())
+
+	_, err = filter.MapFields(expr, func(f filter.Field) (filter.Field, error) {
+		return filter.Field{}, fmt.Errorf("no")
+	})
+	assert.Error(t, err)
+}
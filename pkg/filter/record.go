@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// RecordValue returns the value of f in rec as a literal conditions can
+// compare with: numbers are float64 and other values text. It reports
+// false if rec does not have the field, including empty IDs.
+func RecordValue(rec *telemetrytypes.LogRecord, f Field) (any, bool) {
+	switch f.Kind {
+	case FieldAttribute, FieldResource:
+		attrs := rec.Attributes
+		if f.Kind == FieldResource {
+			attrs = rec.Resource.Attributes
+		}
+		for _, kv := range attrs {
+			if kv.Key != f.Name {
+				continue
+			}
+			switch v := kv.Value.(type) {
+			case string, float64:
+				return v, true
+			default:
+				return fmt.Sprint(v), true
+			}
+		}
+		return nil, false
+	}
+
+	switch f.Name {
+	case "body":
+		return rec.Body, true
+	case "severity_number":
+		return float64(rec.SeverityNumber), true
+	case "severity_text":
+		return rec.SeverityText, true
+	case "trace_id":
+		return rec.TraceID, rec.TraceID != ""
+	case "span_id":
+		return rec.SpanID, rec.SpanID != ""
+	case "pattern_id":
+		return rec.PatternID, rec.PatternID != ""
+	}
+	return nil, false
+}
@@ -0,0 +1,49 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordValue(t *testing.T) {
+	rec := &telemetrytypes.LogRecord{
+		SeverityNumber: 17,
+		SeverityText:   "ERROR",
+		Body:           "payment failed",
+		TraceID:        "4bf92f3577b34da6a3ce929d0e0e4736",
+		Attributes:     []telemetrytypes.KeyValue{{Key: "order", Value: "42"}, {Key: "amount", Value: 9.5}, {Key: "retried", Value: true}},
+		Resource:       telemetrytypes.Resource{Attributes: []telemetrytypes.KeyValue{{Key: "service.name", Value: "checkout"}}},
+	}
+
+	tests := []struct {
+		field string
+		want  any
+		ok    bool
+	}{
+		{"body", "payment failed", true},
+		{"severity_number", 17.0, true},
+		{"severity_text", "ERROR", true},
+		{"trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", true},
+		{"span_id", "", false},
+		{"pattern_id", "", false},
+		{"attributes.order", "42", true},
+		{"attributes.amount", 9.5, true},
+		{"attributes.retried", "true", true},
+		{"attributes.missing", nil, false},
+		{"resource.service.name", "checkout", true},
+		{"resource.host.name", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			f, err := filter.ParseField(tt.field)
+			require.NoError(t, err)
+			got, ok := filter.RecordValue(rec, f)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
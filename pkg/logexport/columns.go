@@ -0,0 +1,139 @@
+package logexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// kind is the type of a column's values.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindTime
+)
+
+// Column is a field of the exported records. Attribute and resource keys
+// are flattened into columns of their own.
+type Column struct {
+	name  string
+	kind  kind
+	value func(rec telemetrytypes.LogRecord) any
+}
+
+// DefaultColumns are exported when no columns are chosen.
+var DefaultColumns = []string{
+	"id", "timestamp", "observed_time", "severity_number", "severity_text", "body",
+	"trace_id", "span_id", "attributes", "resource",
+}
+
+var recordColumns = map[string]Column{
+	"id":              {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return rec.ID }},
+	"pattern_id":      {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return rec.PatternID }},
+	"tenant_id":       {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return rec.TenantID }},
+	"timestamp":       {kind: kindTime, value: func(rec telemetrytypes.LogRecord) any { return rec.Timestamp }},
+	"observed_time":   {kind: kindTime, value: func(rec telemetrytypes.LogRecord) any { return rec.ObservedTime }},
+	"severity_number": {kind: kindInt, value: func(rec telemetrytypes.LogRecord) any { return int64(rec.SeverityNumber) }},
+	"severity_text":   {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return rec.SeverityText }},
+	"body":            {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return rec.Body }},
+	"trace_id":        {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return rec.TraceID }},
+	"span_id":         {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return rec.SpanID }},
+	"trace_flags":     {kind: kindInt, value: func(rec telemetrytypes.LogRecord) any { return int64(rec.TraceFlags) }},
+	"flags":           {kind: kindInt, value: func(rec telemetrytypes.LogRecord) any { return int64(rec.Flags) }},
+	"attributes":      {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return encodeAttributes(rec.Attributes) }},
+	"resource":        {kind: kindString, value: func(rec telemetrytypes.LogRecord) any { return encodeAttributes(rec.Resource.Attributes) }},
+}
+
+// ParseColumns resolves column names. Besides the record fields, names
+// such as "attributes.http.method", "resource.host.name" or "service" select
+// a single key. An empty list selects DefaultColumns.
+func ParseColumns(names []string) ([]Column, error) {
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+
+	columns := make([]Column, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+
+		if c, ok := recordColumns[name]; ok {
+			c.name = name
+			columns = append(columns, c)
+			continue
+		}
+
+		field, err := filter.ParseField(name)
+		if err != nil || field.Kind == filter.FieldColumn {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns = append(columns, Column{name: name, kind: kindString, value: attributeValue(field)})
+	}
+	return columns, nil
+}
+
+func attributeValue(field filter.Field) func(rec telemetrytypes.LogRecord) any {
+	return func(rec telemetrytypes.LogRecord) any {
+		attrs := rec.Attributes
+		if field.Kind == filter.FieldResource {
+			attrs = rec.Resource.Attributes
+		}
+		for _, kv := range attrs {
+			if kv.Key == field.Name {
+				return formatValue(kv.Value)
+			}
+		}
+		return nil
+	}
+}
+
+// formatValue renders an attribute value as text: strings as they are,
+// anything else as JSON.
+func formatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// encodeAttributes renders attributes as a JSON object, as they are stored.
+func encodeAttributes(attrs []telemetrytypes.KeyValue) string {
+	m := make(map[string]any, len(attrs))
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// formatText renders a column value for text formats. Missing attributes
+// are empty.
+func formatText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,185 @@
+// Package logexport encodes streams of log records as NDJSON, CSV or
+// Parquet for download.
+package logexport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type Format string
+
+const (
+	FormatNDJSON  Format = "ndjson"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat resolves a format name. Empty selects NDJSON.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case "":
+		return FormatNDJSON, nil
+	case FormatNDJSON, FormatCSV, FormatParquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q", name)
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// Extension returns the file name extension of the format.
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// parquetRowGroupBytes bounds how much of a Parquet file is buffered before
+// it is written out.
+const parquetRowGroupBytes = 8 << 20
+
+// Writer encodes records one at a time. Close writes what is buffered and
+// any trailer; it does not close the underlying writer.
+type Writer interface {
+	Write(rec telemetrytypes.LogRecord) error
+	Close() error
+}
+
+// NewWriter returns a writer of format to w. NDJSON writes whole records
+// unless columns are given; CSV and Parquet write columns, or
+// DefaultColumns when it is empty.
+func NewWriter(format Format, w io.Writer, columns []Column) (Writer, error) {
+	if format == FormatNDJSON && len(columns) == 0 {
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: bw, enc: json.NewEncoder(bw)}, nil
+	}
+
+	if len(columns) == 0 {
+		var err error
+		if columns, err = ParseColumns(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: bw, enc: json.NewEncoder(bw), columns: columns}, nil
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatParquet:
+		return newParquetWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+type ndjsonWriter struct {
+	buf     *bufio.Writer
+	enc     *json.Encoder
+	columns []Column
+}
+
+func (w *ndjsonWriter) Write(rec telemetrytypes.LogRecord) error {
+	if w.columns == nil {
+		return w.enc.Encode(rec)
+	}
+	row := make(map[string]any, len(w.columns))
+	for _, c := range w.columns {
+		row[c.name] = c.value(rec)
+	}
+	return w.enc.Encode(row)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buf.Flush()
+}
+
+type csvWriter struct {
+	csv     *csv.Writer
+	columns []Column
+	row     []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{csv: csv.NewWriter(w), columns: columns, row: make([]string, len(columns))}
+	for i, c := range columns {
+		cw.row[i] = c.name
+	}
+	if err := cw.csv.Write(cw.row); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (w *csvWriter) Write(rec telemetrytypes.LogRecord) error {
+	for i, c := range w.columns {
+		w.row[i] = formatText(c.value(rec))
+	}
+	return w.csv.Write(w.row)
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+type parquetWriter struct {
+	pw      *writer.CSVWriter
+	columns []Column
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	schema := make([]string, len(columns))
+	for i, c := range columns {
+		switch c.kind {
+		case kindTime:
+			schema[i] = "name=" + c.name + ", type=INT64, convertedtype=TIMESTAMP_MICROS, repetitiontype=OPTIONAL"
+		case kindInt:
+			schema[i] = "name=" + c.name + ", type=INT64, repetitiontype=OPTIONAL"
+		default:
+			schema[i] = "name=" + c.name + ", type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"
+		}
+	}
+
+	pw, err := writer.NewCSVWriterFromWriter(schema, w, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.RowGroupSize = parquetRowGroupBytes
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	return &parquetWriter{pw: pw, columns: columns}, nil
+}
+
+func (w *parquetWriter) Write(rec telemetrytypes.LogRecord) error {
+	row := make([]any, len(w.columns))
+	for i, c := range w.columns {
+		switch v := c.value(rec).(type) {
+		case time.Time:
+			row[i] = v.UnixMicro()
+		default:
+			row[i] = v
+		}
+	}
+	return w.pw.Write(row)
+}
+
+func (w *parquetWriter) Close() error {
+	return w.pw.WriteStop()
+}
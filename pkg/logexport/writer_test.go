@@ -0,0 +1,103 @@
+package logexport_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/logexport"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+var ts = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func records() []telemetrytypes.LogRecord {
+	return []telemetrytypes.LogRecord{
+		{
+			Timestamp: ts, SeverityNumber: 17, SeverityText: "ERROR", Body: "payment failed, retrying",
+			Attributes: []telemetrytypes.KeyValue{{Key: "http.status", Value: float64(502)}, {Key: "user", Value: "ann"}},
+			Resource:   telemetrytypes.Resource{Attributes: []telemetrytypes.KeyValue{{Key: "service.name", Value: "checkout"}}},
+		},
+		{Timestamp: ts.Add(time.Second), SeverityNumber: 9, SeverityText: "INFO", Body: "ok"},
+	}
+}
+
+func export(t *testing.T, format logexport.Format, columns []string) []byte {
+	t.Helper()
+	var cols []logexport.Column
+	if columns != nil {
+		var err error
+		cols, err = logexport.ParseColumns(columns)
+		require.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	w, err := logexport.NewWriter(format, &buf, cols)
+	require.NoError(t, err)
+	for _, rec := range records() {
+		require.NoError(t, w.Write(rec))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(export(t, logexport.FormatNDJSON, nil))), "\n")
+	require.Len(t, lines, 2)
+
+	var rec telemetrytypes.LogRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "payment failed, retrying", rec.Body)
+	assert.True(t, ts.Equal(rec.Timestamp))
+}
+
+func TestCSVFlattensAttributes(t *testing.T) {
+	out := export(t, logexport.FormatCSV, []string{"timestamp", "body", "attributes.http.status", "attributes.user", "service"})
+
+	rows, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"timestamp", "body", "attributes.http.status", "attributes.user", "service"},
+		{"2025-03-01T12:00:00Z", "payment failed, retrying", "502", "ann", "checkout"},
+		{"2025-03-01T12:00:01Z", "ok", "", "", ""},
+	}, rows)
+}
+
+func TestParquet(t *testing.T) {
+	out := export(t, logexport.FormatParquet, append(append([]string(nil), logexport.DefaultColumns...), "attributes.http.status"))
+
+	f, err := buffer.NewBufferFile(out)
+	require.NoError(t, err)
+	pr, err := reader.NewParquetColumnReader(f, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	assert.EqualValues(t, 2, pr.GetNumRows())
+	bodies, _, _, err := pr.ReadColumnByIndex(5, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"payment failed, retrying", "ok"}, bodies)
+	times, _, _, err := pr.ReadColumnByIndex(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, ts.UnixMicro(), times[0])
+	statuses, _, _, err := pr.ReadColumnByIndex(int64(len(logexport.DefaultColumns)), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"502", nil}, statuses)
+}
+
+func TestParseColumns(t *testing.T) {
+	_, err := logexport.ParseColumns([]string{"body", "nope"})
+	assert.Error(t, err)
+	_, err = logexport.ParseColumns([]string{"body", "body"})
+	assert.Error(t, err)
+
+	cols, err := logexport.ParseColumns(nil)
+	require.NoError(t, err)
+	assert.Len(t, cols, len(logexport.DefaultColumns))
+}
@@ -0,0 +1,113 @@
+// Package metrics holds watchdata's own Prometheus metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every watchdata metric plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// IngestRecords counts records accepted by the native receivers.
+	IngestRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdata_ingest_records_total",
+		Help: "Log records accepted by the native OTLP receivers.",
+	}, []string{"transport", "tenant"})
+
+	// IngestBytes counts the encoded size of accepted export requests.
+	IngestBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdata_ingest_bytes_total",
+		Help: "Encoded bytes of export requests accepted by the native OTLP receivers.",
+	}, []string{"transport"})
+
+	// InsertDuration measures ClickHouse log inserts.
+	InsertDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "watchdata_insert_duration_seconds",
+		Help:    "Duration of log inserts into ClickHouse.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// InsertBatchSize measures the records per ClickHouse log insert.
+	InsertBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "watchdata_insert_batch_size",
+		Help:    "Log records per ClickHouse insert.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 9),
+	})
+
+	// BroadcastDropped counts records not sent to WebSocket clients
+	// because the broadcast queue was full.
+	BroadcastDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "watchdata_broadcast_dropped_total",
+		Help: "Log records dropped because the WebSocket broadcast queue was full.",
+	})
+
+	// WebSocketClients is the number of connected WebSocket clients.
+	WebSocketClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "watchdata_websocket_clients",
+		Help: "Connected WebSocket clients.",
+	})
+
+	// RequestDuration measures HTTP requests by route pattern, so path
+	// parameters do not create new series.
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "watchdata_http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// ClickHouseErrors counts failed ClickHouse calls by operation.
+	ClickHouseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdata_clickhouse_errors_total",
+		Help: "Failed ClickHouse calls by operation.",
+	}, []string{"operation"})
+
+	// ProcessingDropped counts records dropped by the routes of the ingest
+	// processing chain.
+	ProcessingDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "watchdata_processing_dropped_total",
+		Help: "Log records dropped by the ingest processing chain.",
+	})
+
+	// QuotaUnchecked counts ingest requests admitted without a quota check
+	// because the tenant's settings or storage usage could not be read.
+	QuotaUnchecked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdata_quota_unchecked_total",
+		Help: "Ingest requests admitted without a daily quota check because usage could not be read.",
+	}, []string{"tenant"})
+
+	// Redactions counts the personal data redacted before storage by rule.
+	// Records dropped by a rule count once per match.
+	Redactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "watchdata_redactions_total",
+		Help: "Values redacted from log records before storage by rule.",
+	}, []string{"rule"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		IngestRecords,
+		IngestBytes,
+		InsertDuration,
+		InsertBatchSize,
+		BroadcastDropped,
+		WebSocketClients,
+		RequestDuration,
+		ClickHouseErrors,
+		ProcessingDropped,
+		QuotaUnchecked,
+		Redactions,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
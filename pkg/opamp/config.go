@@ -0,0 +1,83 @@
+package opamp
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+)
+
+type Config struct {
+	// PingInterval is how often agents connected over WebSocket are
+	// pinged. Agents that answer neither a ping nor send anything for two
+	// intervals are disconnected.
+	PingInterval time.Duration `mapstructure:"ping_interval"`
+
+	// PollTimeout is how long after its last poll an agent using the plain
+	// HTTP transport is considered disconnected.
+	PollTimeout time.Duration `mapstructure:"poll_timeout"`
+
+	// Retention is how long disconnected agents stay listed.
+	Retention time.Duration `mapstructure:"retention"`
+
+	// MaxMessageBytes limits the size of agent messages, which carry the
+	// agent's effective config.
+	MaxMessageBytes int64 `mapstructure:"max_message_bytes"`
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("opamp"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	return Config{
+		PingInterval:    envDuration("WATCHDATA_OPAMP_PING_INTERVAL", 30*time.Second),
+		PollTimeout:     envDuration("WATCHDATA_OPAMP_POLL_TIMEOUT", 2*time.Minute),
+		Retention:       envDuration("WATCHDATA_OPAMP_RETENTION", 24*time.Hour),
+		MaxMessageBytes: envInt("WATCHDATA_OPAMP_MAX_MESSAGE_BYTES", 4<<20),
+	}
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func envInt(key string, def int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func (c Config) Validate() error {
+	if c.PingInterval <= 0 {
+		return fmt.Errorf("opamp ping interval must be positive")
+	}
+	if c.PollTimeout <= 0 {
+		return fmt.Errorf("opamp poll timeout must be positive")
+	}
+	if c.Retention < c.PollTimeout {
+		return fmt.Errorf("opamp retention must be at least the poll timeout")
+	}
+	if c.MaxMessageBytes <= 0 {
+		return fmt.Errorf("opamp max message bytes must be positive")
+	}
+	return nil
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,89 @@
+package opamp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// capabilityNames names the agent capabilities in status reports.
+var capabilityNames = []struct {
+	bit  protobufs.AgentCapabilities
+	name string
+}{
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsStatus, "reports_status"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsRemoteConfig, "accepts_remote_config"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsEffectiveConfig, "reports_effective_config"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsPackages, "accepts_packages"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsPackageStatuses, "reports_package_statuses"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnTraces, "reports_own_traces"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnMetrics, "reports_own_metrics"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnLogs, "reports_own_logs"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsOpAMPConnectionSettings, "accepts_opamp_connection_settings"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsOtherConnectionSettings, "accepts_other_connection_settings"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsRestartCommand, "accepts_restart_command"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsHealth, "reports_health"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsRemoteConfig, "reports_remote_config"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsHeartbeat, "reports_heartbeat"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsAvailableComponents, "reports_available_components"},
+}
+
+const serverCapabilities = uint64(protobufs.ServerCapabilities_ServerCapabilities_AcceptsStatus |
+	protobufs.ServerCapabilities_ServerCapabilities_OffersRemoteConfig |
+	protobufs.ServerCapabilities_ServerCapabilities_AcceptsEffectiveConfig)
+
+// EncodeFrame encodes msg for the WebSocket transport, prefixed with its
+// header, a varint that is always 0.
+func EncodeFrame(msg proto.Message) ([]byte, error) {
+	return proto.MarshalOptions{}.MarshalAppend(protowire.AppendVarint(nil, 0), msg)
+}
+
+// decodeFrame decodes an agent message sent over WebSocket.
+func decodeFrame(data []byte) (*protobufs.AgentToServer, error) {
+	msg := &protobufs.AgentToServer{}
+	if err := unmarshalFrame(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// DecodeFrame decodes a server message sent over WebSocket.
+func DecodeFrame(data []byte) (*protobufs.ServerToAgent, error) {
+	msg := &protobufs.ServerToAgent{}
+	if err := unmarshalFrame(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func unmarshalFrame(data []byte, msg proto.Message) error {
+	header, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return protowire.ParseError(n)
+	}
+	if header != 0 {
+		return fmt.Errorf("unsupported message header %d", header)
+	}
+	return proto.Unmarshal(data[n:], msg)
+}
+
+// anyValueText returns a scalar attribute value as text. Lists and maps
+// are left empty.
+func anyValueText(v *protobufs.AnyValue) string {
+	switch v := v.GetValue().(type) {
+	case *protobufs.AnyValue_StringValue:
+		return v.StringValue
+	case *protobufs.AnyValue_BytesValue:
+		return string(v.BytesValue)
+	case *protobufs.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *protobufs.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *protobufs.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,511 @@
+// Package opamp is an OpAMP server. Collectors connect to it over
+// WebSocket, or poll it over plain HTTP, to report their description,
+// health and effective config, and are sent the collector config deployed
+// from pipeline definitions as their remote config.
+package opamp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/open-telemetry/opamp-go/protobufs"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Ricky004/watchdata/pkg/types/opamptypes"
+)
+
+// ContentType is the media type of messages over the HTTP transport.
+const ContentType = "application/x-protobuf"
+
+// writeTimeout bounds writes to a WebSocket agent.
+const writeTimeout = 10 * time.Second
+
+// Server tracks the agents connected to it and offers them the collector
+// config set with SetConfig.
+type Server struct {
+	cfg Config
+	now func() time.Time
+
+	mu     sync.Mutex
+	agents map[string]*agent
+	// config is the remote config offered to agents, nil until SetConfig.
+	config *protobufs.AgentRemoteConfig
+}
+
+type agent struct {
+	uid    []byte
+	status opamptypes.Agent
+	seq    uint64
+	// connected is false once the agent disconnected. Agents polling over
+	// HTTP are also disconnected once their poll timeout passes.
+	connected bool
+	// conn is the agent's WebSocket connection, nil for HTTP agents.
+	conn *conn
+	// hash is the hash of the last remote config the agent reported and
+	// sent that of the last one it was sent.
+	hash, sent []byte
+	// capabilities is the agent's capabilities bitmask.
+	capabilities uint64
+}
+
+// conn is a WebSocket connection to an agent. Writes are serialized, as
+// config pushes and responses are written from different goroutines.
+type conn struct {
+	ws *websocket.Conn
+	mu sync.Mutex
+}
+
+func NewServer(cfg Config) *Server {
+	return &Server{cfg: cfg, now: time.Now, agents: make(map[string]*agent)}
+}
+
+// SetConfig makes body the collector config offered to agents that accept
+// remote config. Agents connected over WebSocket are sent it right away,
+// those polling over HTTP with their next poll.
+func (s *Server) SetConfig(body []byte) {
+	config := &protobufs.AgentConfigMap{ConfigMap: map[string]*protobufs.AgentConfigFile{
+		"": {Body: body, ContentType: "text/yaml"},
+	}}
+	encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(config)
+	if err != nil {
+		slog.Error("failed to encode remote config", "error", err)
+		return
+	}
+	hash := sha256.Sum256(encoded)
+
+	type push struct {
+		conn *conn
+		msg  *protobufs.ServerToAgent
+	}
+	var pushes []push
+
+	s.mu.Lock()
+	if s.config != nil && bytes.Equal(s.config.ConfigHash, hash[:]) {
+		s.mu.Unlock()
+		return
+	}
+	s.config = &protobufs.AgentRemoteConfig{Config: config, ConfigHash: hash[:]}
+	for _, a := range s.agents {
+		if a.conn == nil {
+			continue
+		}
+		msg := &protobufs.ServerToAgent{InstanceUid: a.uid, Capabilities: serverCapabilities}
+		if s.offer(a, msg) {
+			pushes = append(pushes, push{a.conn, msg})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, p := range pushes {
+		if err := p.conn.send(p.msg); err != nil {
+			slog.Warn("failed to send remote config to agent", "error", err)
+		}
+	}
+}
+
+// Serve reads the messages of an agent connected over WebSocket and
+// answers them until the connection closes or the agent disconnects.
+func (s *Server) Serve(ws *websocket.Conn) {
+	c := &conn{ws: ws}
+	defer ws.Close()
+
+	// Each message or pong extends the deadline by two ping intervals.
+	extend := func() {
+		ws.SetReadDeadline(time.Now().Add(2 * s.cfg.PingInterval))
+	}
+	extend()
+	ws.SetReadLimit(s.cfg.MaxMessageBytes)
+	ws.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.ping(s.cfg.PingInterval, done)
+
+	var id string
+	defer func() {
+		if id != "" {
+			s.disconnect(id, c)
+		}
+	}()
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			slog.Debug("OpAMP connection closed", "agent", id, "error", err)
+			return
+		}
+		extend()
+
+		msg, err := decodeFrame(data)
+		if err != nil {
+			c.send(badRequest(err))
+			continue
+		}
+		resp, agentID := s.handle(msg, c)
+		if agentID != "" {
+			id = agentID
+		}
+		if err := c.send(resp); err != nil {
+			slog.Debug("failed to answer agent", "agent", id, "error", err)
+			return
+		}
+		if msg.AgentDisconnect != nil {
+			return
+		}
+	}
+}
+
+// ReadHTTP decodes the message of an agent polling over HTTP from the body
+// of r, which may be gzipped.
+func (s *Server) ReadHTTP(r *http.Request) (*protobufs.AgentToServer, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+	data, err := io.ReadAll(io.LimitReader(body, s.cfg.MaxMessageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if int64(len(data)) > s.cfg.MaxMessageBytes {
+		return nil, fmt.Errorf("message larger than %d bytes", s.cfg.MaxMessageBytes)
+	}
+	msg := &protobufs.AgentToServer{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	return msg, nil
+}
+
+// HandleHTTP answers the message of an agent polling over HTTP.
+func (s *Server) HandleHTTP(msg *protobufs.AgentToServer) *protobufs.ServerToAgent {
+	resp, _ := s.handle(msg, nil)
+	return resp
+}
+
+// Run forgets agents that have been disconnected for longer than the
+// retention until ctx is cancelled, and then closes every WebSocket
+// connection.
+func (s *Server) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.prune()
+		case <-ctx.Done():
+			s.mu.Lock()
+			var conns []*conn
+			for _, a := range s.agents {
+				if a.conn != nil {
+					conns = append(conns, a.conn)
+				}
+			}
+			s.mu.Unlock()
+			for _, c := range conns {
+				c.close()
+			}
+			return nil
+		}
+	}
+}
+
+// Agents returns the state of every known agent, ordered by ID.
+func (s *Server) Agents() []opamptypes.Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	agents := make([]opamptypes.Agent, 0, len(s.agents))
+	for _, a := range s.agents {
+		agents = append(agents, s.statusOf(a, now))
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents
+}
+
+// Agent returns the state of the agent with the given instance UID.
+func (s *Server) Agent(id string) (opamptypes.Agent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.agents[id]
+	if !ok {
+		return opamptypes.Agent{}, false
+	}
+	return s.statusOf(a, s.now()), true
+}
+
+// handle records the state an agent reports and returns the answer to it,
+// and the agent's ID. c is nil for agents polling over HTTP.
+func (s *Server) handle(msg *protobufs.AgentToServer, c *conn) (*protobufs.ServerToAgent, string) {
+	uid, err := uuid.FromBytes(msg.InstanceUid)
+	if err != nil {
+		return badRequest(fmt.Errorf("instance_uid must be 16 bytes")), ""
+	}
+	id := uid.String()
+	resp := &protobufs.ServerToAgent{InstanceUid: msg.InstanceUid, Capabilities: serverCapabilities}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	a, known := s.agents[id]
+	if !known {
+		a = &agent{uid: msg.InstanceUid, status: opamptypes.Agent{ID: id}}
+		s.agents[id] = a
+	}
+
+	// An agent the server does not know, such as after a restart of the
+	// server, or whose messages were lost, must report everything again.
+	// It may also have lost the config it was sent.
+	if (!known && msg.AgentDescription == nil) || (known && msg.SequenceNum != a.seq+1) {
+		resp.Flags |= uint64(protobufs.ServerToAgentFlags_ServerToAgentFlags_ReportFullState)
+		a.sent = nil
+	}
+	a.seq = msg.SequenceNum
+
+	if !s.connected(a, now) || (c != nil && a.conn != c) {
+		a.status.ConnectedAt = now
+		a.sent = nil
+		slog.Info("OpAMP agent connected", "agent", id, "transport", transport(c))
+	}
+	a.connected = true
+	a.conn = c
+	a.status.Transport = transport(c)
+	a.status.LastSeen = now
+
+	if msg.AgentDescription != nil {
+		a.status.IdentifyingAttributes = attributes(msg.AgentDescription.IdentifyingAttributes)
+		a.status.NonIdentifyingAttributes = attributes(msg.AgentDescription.NonIdentifyingAttributes)
+	}
+	if msg.Capabilities != 0 {
+		a.capabilities = msg.Capabilities
+		a.status.Capabilities = capabilities(msg.Capabilities)
+	}
+	if msg.Health != nil {
+		health := componentHealth(msg.Health)
+		a.status.Health = &health
+	}
+	if msg.EffectiveConfig != nil {
+		configMap := msg.EffectiveConfig.GetConfigMap().GetConfigMap()
+		files := make(map[string]string, len(configMap))
+		for name, f := range configMap {
+			files[name] = string(f.GetBody())
+		}
+		a.status.EffectiveConfig = files
+	}
+	if st := msg.RemoteConfigStatus; st != nil {
+		a.hash = st.LastRemoteConfigHash
+		a.status.RemoteConfig = opamptypes.RemoteConfigStatus{
+			Status: remoteConfigStatus(st.Status),
+			Hash:   hex.EncodeToString(st.LastRemoteConfigHash),
+			Error:  st.ErrorMessage,
+		}
+	}
+
+	if msg.Flags&uint64(protobufs.AgentToServerFlags_AgentToServerFlags_RequestInstanceUid) != 0 {
+		next := uuid.New()
+		delete(s.agents, id)
+		id = next.String()
+		a.uid = next[:]
+		a.status.ID = id
+		s.agents[id] = a
+		resp.AgentIdentification = &protobufs.AgentIdentification{NewInstanceUid: next[:]}
+	}
+
+	if msg.AgentDisconnect != nil {
+		s.disconnectLocked(a, c)
+		return resp, id
+	}
+	s.offer(a, resp)
+	return resp, id
+}
+
+// offer adds the remote config to msg if a accepts remote config and was
+// neither sent it nor reported running it. It reports whether it did.
+func (s *Server) offer(a *agent, msg *protobufs.ServerToAgent) bool {
+	if s.config == nil || a.capabilities&uint64(protobufs.AgentCapabilities_AgentCapabilities_AcceptsRemoteConfig) == 0 {
+		return false
+	}
+	if bytes.Equal(a.hash, s.config.ConfigHash) || bytes.Equal(a.sent, s.config.ConfigHash) {
+		return false
+	}
+	msg.RemoteConfig = s.config
+	a.sent = s.config.ConfigHash
+	return true
+}
+
+// disconnect marks the agent with the given ID disconnected if c is still
+// its connection.
+func (s *Server) disconnect(id string, c *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.agents[id]; ok {
+		s.disconnectLocked(a, c)
+	}
+}
+
+func (s *Server) disconnectLocked(a *agent, c *conn) {
+	if a.conn != c || !a.connected {
+		return
+	}
+	a.connected = false
+	a.conn = nil
+	slog.Info("OpAMP agent disconnected", "agent", a.status.ID, "transport", a.status.Transport)
+}
+
+// connected reports whether a is connected: over WebSocket until the
+// connection closes, and over HTTP until its poll timeout passes.
+func (s *Server) connected(a *agent, now time.Time) bool {
+	if !a.connected {
+		return false
+	}
+	return a.conn != nil || now.Sub(a.status.LastSeen) < s.cfg.PollTimeout
+}
+
+func (s *Server) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, a := range s.agents {
+		if !s.connected(a, now) && now.Sub(a.status.LastSeen) > s.cfg.Retention {
+			delete(s.agents, id)
+		}
+	}
+}
+
+func (s *Server) statusOf(a *agent, now time.Time) opamptypes.Agent {
+	status := a.status
+	status.Connected = s.connected(a, now)
+	if status.RemoteConfig.Status == "" {
+		status.RemoteConfig.Status = opamptypes.RemoteConfigUnset
+	}
+	status.RemoteConfig.Current = s.config != nil && bytes.Equal(a.hash, s.config.ConfigHash)
+	return status
+}
+
+// send writes msg to the agent.
+func (c *conn) send(msg *protobufs.ServerToAgent) error {
+	data, err := EncodeFrame(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.ws.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// ping pings the agent every interval until done is closed.
+func (c *conn) ping(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+			c.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close closes the connection with a close frame, ending Serve.
+func (c *conn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeTimeout))
+	c.ws.Close()
+}
+
+func badRequest(err error) *protobufs.ServerToAgent {
+	return &protobufs.ServerToAgent{ErrorResponse: &protobufs.ServerErrorResponse{
+		Type:         protobufs.ServerErrorResponseType_ServerErrorResponseType_BadRequest,
+		ErrorMessage: err.Error(),
+	}}
+}
+
+func transport(c *conn) string {
+	if c == nil {
+		return opamptypes.TransportHTTP
+	}
+	return opamptypes.TransportWebSocket
+}
+
+func attributes(kvs []*protobufs.KeyValue) map[string]string {
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.GetKey()] = anyValueText(kv.GetValue())
+	}
+	return attrs
+}
+
+func capabilities(bits uint64) []string {
+	names := []string{}
+	for _, c := range capabilityNames {
+		if bits&uint64(c.bit) != 0 {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+func componentHealth(h *protobufs.ComponentHealth) opamptypes.ComponentHealth {
+	health := opamptypes.ComponentHealth{
+		Healthy:    h.Healthy,
+		Status:     h.Status,
+		LastError:  h.LastError,
+		StartTime:  unixNano(h.StartTimeUnixNano),
+		StatusTime: unixNano(h.StatusTimeUnixNano),
+	}
+	if len(h.ComponentHealthMap) > 0 {
+		health.Components = make(map[string]opamptypes.ComponentHealth, len(h.ComponentHealthMap))
+		for name, c := range h.ComponentHealthMap {
+			health.Components[name] = componentHealth(c)
+		}
+	}
+	return health
+}
+
+func unixNano(ns uint64) *time.Time {
+	if ns == 0 {
+		return nil
+	}
+	t := time.Unix(0, int64(ns)).UTC()
+	return &t
+}
+
+func remoteConfigStatus(status protobufs.RemoteConfigStatuses) string {
+	switch status {
+	case protobufs.RemoteConfigStatuses_RemoteConfigStatuses_APPLIED:
+		return opamptypes.RemoteConfigApplied
+	case protobufs.RemoteConfigStatuses_RemoteConfigStatuses_APPLYING:
+		return opamptypes.RemoteConfigApplying
+	case protobufs.RemoteConfigStatuses_RemoteConfigStatuses_FAILED:
+		return opamptypes.RemoteConfigFailed
+	}
+	return opamptypes.RemoteConfigUnset
+}
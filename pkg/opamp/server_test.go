@@ -0,0 +1,403 @@
+package opamp_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/opamp"
+	"github.com/Ricky004/watchdata/pkg/types/opamptypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/open-telemetry/opamp-go/client"
+	"github.com/open-telemetry/opamp-go/client/types"
+	"github.com/open-telemetry/opamp-go/protobufs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const collectorCapabilities = protobufs.AgentCapabilities_AgentCapabilities_ReportsStatus |
+	protobufs.AgentCapabilities_AgentCapabilities_AcceptsRemoteConfig |
+	protobufs.AgentCapabilities_AgentCapabilities_ReportsEffectiveConfig |
+	protobufs.AgentCapabilities_AgentCapabilities_ReportsHealth |
+	protobufs.AgentCapabilities_AgentCapabilities_ReportsRemoteConfig
+
+const reportFullState = uint64(protobufs.ServerToAgentFlags_ServerToAgentFlags_ReportFullState)
+
+func testConfig() opamp.Config {
+	return opamp.Config{
+		PingInterval:    time.Minute,
+		PollTimeout:     time.Minute,
+		Retention:       time.Hour,
+		MaxMessageBytes: 1 << 20,
+	}
+}
+
+// agent is an in-process OpAMP agent connected over WebSocket.
+type agent struct {
+	t   *testing.T
+	ws  *websocket.Conn
+	uid uuid.UUID
+	seq uint64
+}
+
+// serve serves srv over both transports, as the API server does.
+func serve(t *testing.T, srv *opamp.Server) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			ws, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			srv.Serve(ws)
+			return
+		}
+		msg, err := srv.ReadHTTP(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		out, err := proto.Marshal(srv.HandleHTTP(msg))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", opamp.ContentType)
+		w.Write(out)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func connect(t *testing.T, srv *opamp.Server) *agent {
+	t.Helper()
+	ts := serve(t, srv)
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/v1/opamp", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+	return &agent{t: t, ws: ws, uid: uuid.New()}
+}
+
+// send sends msg with the client's instance UID and next sequence number.
+func (c *agent) send(msg *protobufs.AgentToServer) {
+	c.t.Helper()
+	msg.InstanceUid = c.uid[:]
+	msg.SequenceNum = c.seq
+	c.seq++
+	data, err := opamp.EncodeFrame(msg)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.ws.WriteMessage(websocket.BinaryMessage, data))
+}
+
+func (c *agent) receive() *protobufs.ServerToAgent {
+	c.t.Helper()
+	c.ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := c.ws.ReadMessage()
+	require.NoError(c.t, err)
+	msg, err := opamp.DecodeFrame(data)
+	require.NoError(c.t, err)
+	return msg
+}
+
+func attr(key, value string) *protobufs.KeyValue {
+	return &protobufs.KeyValue{Key: key, Value: &protobufs.AnyValue{Value: &protobufs.AnyValue_StringValue{StringValue: value}}}
+}
+
+func description() *protobufs.AgentDescription {
+	return &protobufs.AgentDescription{
+		IdentifyingAttributes:    []*protobufs.KeyValue{attr("service.name", "otelcol-contrib"), attr("host.name", "node-1")},
+		NonIdentifyingAttributes: []*protobufs.KeyValue{attr("os.type", "linux")},
+	}
+}
+
+func effectiveConfig(body string) *protobufs.EffectiveConfig {
+	return &protobufs.EffectiveConfig{ConfigMap: &protobufs.AgentConfigMap{ConfigMap: map[string]*protobufs.AgentConfigFile{
+		"": {Body: []byte(body), ContentType: "text/yaml"},
+	}}}
+}
+
+func fullState() *protobufs.AgentToServer {
+	return &protobufs.AgentToServer{
+		AgentDescription: description(),
+		Capabilities:     uint64(collectorCapabilities),
+		Health: &protobufs.ComponentHealth{
+			Healthy:           true,
+			StartTimeUnixNano: uint64(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano()),
+			Status:            "StatusOK",
+			ComponentHealthMap: map[string]*protobufs.ComponentHealth{
+				"pipeline:logs": {Healthy: false, Status: "StatusRecoverableError", LastError: "connection refused"},
+			},
+		},
+		EffectiveConfig: effectiveConfig("receivers: {}\n"),
+	}
+}
+
+func TestServerReportsAgents(t *testing.T) {
+	srv := opamp.NewServer(testConfig())
+	c := connect(t, srv)
+
+	c.send(fullState())
+	resp := c.receive()
+	assert.Equal(t, c.uid[:], resp.InstanceUid)
+	assert.Nil(t, resp.ErrorResponse)
+	assert.Zero(t, resp.Flags)
+	assert.NotZero(t, resp.Capabilities&uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersRemoteConfig))
+	assert.Nil(t, resp.RemoteConfig, "no config set yet")
+
+	agents := srv.Agents()
+	require.Len(t, agents, 1)
+	a := agents[0]
+	assert.Equal(t, c.uid.String(), a.ID)
+	assert.True(t, a.Connected)
+	assert.Equal(t, opamptypes.TransportWebSocket, a.Transport)
+	assert.Equal(t, map[string]string{"service.name": "otelcol-contrib", "host.name": "node-1"}, a.IdentifyingAttributes)
+	assert.Equal(t, map[string]string{"os.type": "linux"}, a.NonIdentifyingAttributes)
+	assert.Contains(t, a.Capabilities, "accepts_remote_config")
+	assert.Contains(t, a.Capabilities, "reports_health")
+	require.NotNil(t, a.Health)
+	assert.True(t, a.Health.Healthy)
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), *a.Health.StartTime)
+	assert.Equal(t, "connection refused", a.Health.Components["pipeline:logs"].LastError)
+	assert.Equal(t, map[string]string{"": "receivers: {}\n"}, a.EffectiveConfig)
+	assert.Equal(t, opamptypes.RemoteConfigUnset, a.RemoteConfig.Status)
+
+	// Later messages only carry what changed.
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities), Health: &protobufs.ComponentHealth{Healthy: false, LastError: "exporter failing"}})
+	c.receive()
+	a, ok := srv.Agent(c.uid.String())
+	require.True(t, ok)
+	assert.Equal(t, "exporter failing", a.Health.LastError)
+	assert.Equal(t, "node-1", a.IdentifyingAttributes["host.name"])
+	assert.Equal(t, map[string]string{"": "receivers: {}\n"}, a.EffectiveConfig)
+
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities), AgentDisconnect: &protobufs.AgentDisconnect{}})
+	c.receive()
+	a, _ = srv.Agent(c.uid.String())
+	assert.False(t, a.Connected)
+}
+
+func TestServerSendsRemoteConfig(t *testing.T) {
+	srv := opamp.NewServer(testConfig())
+	srv.SetConfig([]byte("exporters: {}\n"))
+	c := connect(t, srv)
+
+	c.send(fullState())
+	resp := c.receive()
+	require.NotNil(t, resp.RemoteConfig, "config is offered on connect")
+	assert.Equal(t, []byte("exporters: {}\n"), resp.RemoteConfig.Config.ConfigMap[""].Body)
+	hash := resp.RemoteConfig.ConfigHash
+
+	// It is not offered again while the agent applies it.
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities), RemoteConfigStatus: &protobufs.RemoteConfigStatus{
+		LastRemoteConfigHash: hash,
+		Status:               protobufs.RemoteConfigStatuses_RemoteConfigStatuses_APPLYING,
+	}})
+	assert.Nil(t, c.receive().RemoteConfig)
+
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities), RemoteConfigStatus: &protobufs.RemoteConfigStatus{
+		LastRemoteConfigHash: hash,
+		Status:               protobufs.RemoteConfigStatuses_RemoteConfigStatuses_APPLIED,
+	}})
+	assert.Nil(t, c.receive().RemoteConfig)
+	a, _ := srv.Agent(c.uid.String())
+	assert.Equal(t, opamptypes.RemoteConfigApplied, a.RemoteConfig.Status)
+	assert.True(t, a.RemoteConfig.Current)
+
+	// Setting the same config again sends nothing; a new one is pushed.
+	srv.SetConfig([]byte("exporters: {}\n"))
+	srv.SetConfig([]byte("exporters: {debug: {}}\n"))
+	push := c.receive()
+	require.NotNil(t, push.RemoteConfig)
+	assert.Equal(t, c.uid[:], push.InstanceUid)
+	assert.Equal(t, []byte("exporters: {debug: {}}\n"), push.RemoteConfig.Config.ConfigMap[""].Body)
+	assert.NotEqual(t, hash, push.RemoteConfig.ConfigHash)
+
+	a, _ = srv.Agent(c.uid.String())
+	assert.False(t, a.RemoteConfig.Current)
+
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities), RemoteConfigStatus: &protobufs.RemoteConfigStatus{
+		LastRemoteConfigHash: push.RemoteConfig.ConfigHash,
+		Status:               protobufs.RemoteConfigStatuses_RemoteConfigStatuses_FAILED,
+		ErrorMessage:         "unknown exporter debug",
+	}})
+	assert.Nil(t, c.receive().RemoteConfig, "a failed config is not resent")
+	a, _ = srv.Agent(c.uid.String())
+	assert.Equal(t, opamptypes.RemoteConfigFailed, a.RemoteConfig.Status)
+	assert.Equal(t, "unknown exporter debug", a.RemoteConfig.Error)
+}
+
+func TestServerOnlyOffersConfigToAcceptingAgents(t *testing.T) {
+	srv := opamp.NewServer(testConfig())
+	srv.SetConfig([]byte("exporters: {}\n"))
+	c := connect(t, srv)
+
+	msg := fullState()
+	msg.Capabilities = uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsStatus | protobufs.AgentCapabilities_AgentCapabilities_ReportsEffectiveConfig)
+	c.send(msg)
+	assert.Nil(t, c.receive().RemoteConfig)
+}
+
+func TestServerRequestsFullState(t *testing.T) {
+	srv := opamp.NewServer(testConfig())
+	c := connect(t, srv)
+
+	// An agent the server does not know must describe itself.
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities)})
+	assert.Equal(t, reportFullState, c.receive().Flags&reportFullState)
+
+	c.send(fullState())
+	assert.Zero(t, c.receive().Flags)
+
+	// So must one whose messages were lost.
+	c.seq += 2
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities)})
+	assert.Equal(t, reportFullState, c.receive().Flags&reportFullState)
+}
+
+func TestServerAssignsInstanceUID(t *testing.T) {
+	srv := opamp.NewServer(testConfig())
+	c := connect(t, srv)
+
+	msg := fullState()
+	msg.Flags = uint64(protobufs.AgentToServerFlags_AgentToServerFlags_RequestInstanceUid)
+	c.send(msg)
+	resp := c.receive()
+	require.Len(t, resp.GetAgentIdentification().GetNewInstanceUid(), 16)
+
+	uid, err := uuid.FromBytes(resp.AgentIdentification.NewInstanceUid)
+	require.NoError(t, err)
+	_, ok := srv.Agent(c.uid.String())
+	assert.False(t, ok)
+	a, ok := srv.Agent(uid.String())
+	require.True(t, ok)
+	assert.Equal(t, "node-1", a.IdentifyingAttributes["host.name"])
+
+	c.uid = uid
+	c.send(&protobufs.AgentToServer{Capabilities: uint64(collectorCapabilities)})
+	assert.Zero(t, c.receive().Flags)
+}
+
+func TestServerRejectsBadMessages(t *testing.T) {
+	srv := opamp.NewServer(testConfig())
+	c := connect(t, srv)
+
+	data, err := opamp.EncodeFrame(&protobufs.AgentToServer{InstanceUid: []byte("short")})
+	require.NoError(t, err)
+	require.NoError(t, c.ws.WriteMessage(websocket.BinaryMessage, data))
+	resp := c.receive()
+	require.NotNil(t, resp.ErrorResponse)
+	assert.Equal(t, protobufs.ServerErrorResponseType_ServerErrorResponseType_BadRequest, resp.ErrorResponse.Type)
+
+	require.NoError(t, c.ws.WriteMessage(websocket.BinaryMessage, []byte{1, 2, 3}))
+	resp = c.receive()
+	require.NotNil(t, resp.ErrorResponse)
+	assert.Contains(t, resp.ErrorResponse.ErrorMessage, "unsupported message header 1")
+	assert.Empty(t, srv.Agents())
+}
+
+func TestServerHTTPTransport(t *testing.T) {
+	srv := opamp.NewServer(testConfig())
+	srv.SetConfig([]byte("exporters: {}\n"))
+
+	uid := uuid.New()
+	msg := fullState()
+	msg.InstanceUid = uid[:]
+	body, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/v1/opamp", bytes.NewReader(body))
+	req.Header.Set("Content-Type", opamp.ContentType)
+
+	decoded, err := srv.ReadHTTP(req)
+	require.NoError(t, err)
+	resp := srv.HandleHTTP(decoded)
+	require.NotNil(t, resp.RemoteConfig)
+
+	a, ok := srv.Agent(uid.String())
+	require.True(t, ok)
+	assert.True(t, a.Connected)
+	assert.Equal(t, opamptypes.TransportHTTP, a.Transport)
+
+	// The config is not repeated with the next poll.
+	resp = srv.HandleHTTP(&protobufs.AgentToServer{InstanceUid: uid[:], SequenceNum: 1, Capabilities: uint64(collectorCapabilities)})
+	assert.Nil(t, resp.RemoteConfig)
+	assert.Zero(t, resp.Flags)
+}
+
+// TestOpAMPGoClient runs the reference client against the server over
+// both transports.
+func TestOpAMPGoClient(t *testing.T) {
+	for _, transport := range []string{opamptypes.TransportWebSocket, opamptypes.TransportHTTP} {
+		t.Run(transport, func(t *testing.T) {
+			srv := opamp.NewServer(testConfig())
+			srv.SetConfig([]byte("exporters: {}\n"))
+			url := serve(t, srv).URL + "/v1/opamp"
+
+			var c client.OpAMPClient
+			if transport == opamptypes.TransportWebSocket {
+				c = client.NewWebSocket(nil)
+				url = "ws" + strings.TrimPrefix(url, "http")
+			} else {
+				c = client.NewHTTP(nil)
+			}
+
+			configs := make(chan *protobufs.AgentRemoteConfig, 1)
+			uid := types.InstanceUid(uuid.New())
+			require.NoError(t, c.SetAgentDescription(description()))
+			require.NoError(t, c.SetHealth(&protobufs.ComponentHealth{Healthy: true, Status: "StatusOK"}))
+			require.NoError(t, c.Start(context.Background(), types.StartSettings{
+				OpAMPServerURL: url,
+				InstanceUid:    uid,
+				Capabilities:   collectorCapabilities,
+				Callbacks: types.Callbacks{
+					OnMessage: func(_ context.Context, msg *types.MessageData) {
+						if msg.RemoteConfig != nil {
+							select {
+							case configs <- msg.RemoteConfig:
+							default:
+							}
+						}
+					},
+					GetEffectiveConfig: func(context.Context) (*protobufs.EffectiveConfig, error) {
+						return effectiveConfig("receivers: {}\n"), nil
+					},
+				},
+			}))
+			t.Cleanup(func() { c.Stop(context.Background()) })
+
+			var remote *protobufs.AgentRemoteConfig
+			select {
+			case remote = <-configs:
+			case <-time.After(5 * time.Second):
+				t.Fatal("no remote config received")
+			}
+			assert.Equal(t, []byte("exporters: {}\n"), remote.GetConfig().GetConfigMap()[""].GetBody())
+
+			require.NoError(t, c.SetRemoteConfigStatus(&protobufs.RemoteConfigStatus{
+				LastRemoteConfigHash: remote.ConfigHash,
+				Status:               protobufs.RemoteConfigStatuses_RemoteConfigStatuses_APPLIED,
+			}))
+			id := uuid.UUID(uid).String()
+			require.Eventually(t, func() bool {
+				a, ok := srv.Agent(id)
+				return ok && a.RemoteConfig.Current
+			}, 5*time.Second, 10*time.Millisecond)
+
+			a, _ := srv.Agent(id)
+			assert.True(t, a.Connected)
+			assert.Equal(t, transport, a.Transport)
+			assert.Equal(t, "node-1", a.IdentifyingAttributes["host.name"])
+			assert.Contains(t, a.Capabilities, "accepts_remote_config")
+			require.NotNil(t, a.Health)
+			assert.True(t, a.Health.Healthy)
+			assert.Equal(t, map[string]string{"": "receivers: {}\n"}, a.EffectiveConfig)
+			assert.Equal(t, opamptypes.RemoteConfigApplied, a.RemoteConfig.Status)
+		})
+	}
+}
@@ -1,54 +1,117 @@
 package builderconfig
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
 )
 
+// ReceiverModules, ProcessorModules and ExporterModules are the Go
+// modules of the components, keyed by type and without a version: AddModules
+// pins them to a collector release.
 var ReceiverModules = map[string]otelpipelinetypes.ModuleEntry{
 	"otlp": {
-		Gomod: "go.opentelemetry.io/collector/receiver/otlpreceiver v0.128.0",
+		Gomod: "go.opentelemetry.io/collector/receiver/otlpreceiver",
 	},
 	"filelog": {
-		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/filelogreceiver v0.127.0",
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/filelogreceiver",
+	},
+	"syslog": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/syslogreceiver",
+	},
+	"journald": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/journaldreceiver",
+	},
+	"fluentforward": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/fluentforwardreceiver",
+	},
+	"hostmetrics": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/hostmetricsreceiver",
+	},
+	"kubeletstats": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/kubeletstatsreceiver",
+	},
+	"prometheus": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver",
 	},
 }
 
 var ProcessorModules = map[string]otelpipelinetypes.ModuleEntry{
 	"batch": {
-		Gomod: "go.opentelemetry.io/collector/processor/batchprocessor v0.127.0",
+		Gomod: "go.opentelemetry.io/collector/processor/batchprocessor",
+	},
+	"memory_limiter": {
+		Gomod: "go.opentelemetry.io/collector/processor/memorylimiterprocessor",
+	},
+	"attributes": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor",
+	},
+	"resource": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourceprocessor",
+	},
+	"resourcedetection": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor",
+	},
+	"filter": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/filterprocessor",
+	},
+	"transform": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/transformprocessor",
+	},
+	"redaction": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/redactionprocessor",
+	},
+	"k8sattributes": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/k8sattributesprocessor",
+	},
+	"tail_sampling": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor",
+	},
+	"groupbyattrs": {
+		Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/processor/groupbyattrsprocessor",
 	},
 }
 
 var ExporterModules = map[string]otelpipelinetypes.ModuleEntry{
 	"watchdataexporter": {
-		Gomod:  "github.com/Ricky004/watchdata v0.0.7-0.20250610145142-6cee7eaf3324",
+		Gomod:  "github.com/Ricky004/watchdata",
 		Import: "github.com/Ricky004/watchdata/pkg/watchdataexporter",
 	},
 }
 
-func SyncBuilderConfig(path string, receivers, processors, exporters []string) error {
+// SyncBuilderConfig adds the modules of the given components to the
+// builder config at path and pins all of its modules to release. Unknown
+// components are an error and leave the file unchanged.
+func SyncBuilderConfig(path string, release Release, receivers, processors, exporters []string) error {
 	cfg, err := LoadBuilderConfig(path)
 	if err != nil {
 		return err
 	}
-
-	for _, name := range receivers {
-		if mod, ok := ReceiverModules[name]; ok {
-			AddModule(&cfg.Receivers, mod.Gomod, mod.Import)
-		}
-	}
-
-	for _, name := range processors {
-		if mod, ok := ProcessorModules[name]; ok {
-			AddModule(&cfg.Processors, mod.Gomod, mod.Import)
-		}
+	Pin(cfg, release)
+	if err := AddModules(cfg, release, receivers, processors, exporters); err != nil {
+		return err
 	}
+	return SaveBuilderConfig(path, cfg)
+}
 
-	for _, name := range exporters {
-		if mod, ok := ExporterModules[name]; ok {
-			AddModule(&cfg.Exporters, mod.Gomod, mod.Import)
+// AddModules adds the modules of the given components to cfg at their
+// version in release, once per module. Named components such as "otlp/2"
+// use the module of their type.
+func AddModules(cfg *otelpipelinetypes.BuilderConfigs, release Release, receivers, processors, exporters []string) error {
+	var errs []error
+	add := func(entries *[]otelpipelinetypes.ModuleEntry, modules map[string]otelpipelinetypes.ModuleEntry, kind string, names []string) {
+		for _, name := range names {
+			mod, ok := modules[otelpipelinetypes.ComponentType(name)]
+			if !ok {
+				errs = append(errs, fmt.Errorf("no module for %s %q", kind, name))
+				continue
+			}
+			AddModule(entries, mod.Gomod+" "+release.version(mod.Gomod), mod.Import)
 		}
 	}
-
-	return SaveBuilderConfig(path, cfg)
+	add(&cfg.Receivers, ReceiverModules, "receiver", receivers)
+	add(&cfg.Processors, ProcessorModules, "processor", processors)
+	add(&cfg.Exporters, ExporterModules, "exporter", exporters)
+	return errors.Join(errs...)
 }
@@ -0,0 +1,198 @@
+package builderconfig
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// Module paths of the collector repositories and of watchdata.
+const (
+	coreModules     = "go.opentelemetry.io/collector/"
+	contribModules  = "github.com/open-telemetry/opentelemetry-collector-contrib/"
+	watchdataModule = "github.com/Ricky004/watchdata"
+)
+
+// watchdataFallback is the watchdata version used when the running binary
+// was not built from a fetchable one.
+const watchdataFallback = "v0.0.7-0.20250610145142-6cee7eaf3324"
+
+// Release is the module versions of a collector release. The builder
+// fails when components of different releases are mixed.
+type Release struct {
+	// Core is the version of the go.opentelemetry.io/collector modules.
+	Core string `json:"core"`
+	// Contrib is the version of the opentelemetry-collector-contrib
+	// modules.
+	Contrib string `json:"contrib"`
+}
+
+// DefaultRelease is the collector release generated builder configs pin
+// when neither the selection nor an existing builder config names one.
+const DefaultRelease = "0.127.0"
+
+// Releases are the collector releases builder configs can be pinned to,
+// keyed by release.
+var Releases = map[string]Release{
+	"0.125.0": {Core: "v0.125.0", Contrib: "v0.125.0"},
+	"0.126.0": {Core: "v0.126.0", Contrib: "v0.126.0"},
+	"0.127.0": {Core: "v0.127.0", Contrib: "v0.127.0"},
+	"0.128.0": {Core: "v0.128.0", Contrib: "v0.128.0"},
+	"0.129.0": {Core: "v0.129.0", Contrib: "v0.129.0"},
+}
+
+// LookupRelease returns the versions of a collector release.
+func LookupRelease(name string) (Release, error) {
+	r, ok := Releases[name]
+	if !ok {
+		return Release{}, fmt.Errorf("unknown collector release %q, known releases are %s", name, strings.Join(releaseNames(), ", "))
+	}
+	return r, nil
+}
+
+// version returns the version path is pinned to in r, or "" for modules
+// outside the collector repositories and watchdata.
+func (r Release) version(path string) string {
+	switch {
+	case strings.HasPrefix(path, coreModules):
+		return r.Core
+	case strings.HasPrefix(path, contribModules):
+		return r.Contrib
+	case path == watchdataModule:
+		return WatchdataVersion()
+	}
+	return ""
+}
+
+// WatchdataVersion returns the version of the watchdata module the builder
+// fetches the exporter from: the one this binary was built from, as
+// recorded in its build info. Development builds, whose version cannot be
+// fetched, fall back to a known good one.
+func WatchdataVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return watchdataFallback
+	}
+	if info.Main.Path == watchdataModule && fetchable(info.Main.Version) {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == watchdataModule && fetchable(dep.Version) {
+			return dep.Version
+		}
+	}
+	return watchdataFallback
+}
+
+// fetchable reports whether version can be fetched by the builder, unlike
+// "(devel)" and versions of modified trees such as "v1.2.3+dirty".
+func fetchable(version string) bool {
+	return strings.HasPrefix(version, "v") && !strings.Contains(version, "+")
+}
+
+// Pin rewrites the version of every collector and watchdata module in cfg
+// to those of release. Other modules are left as they are.
+func Pin(cfg *otelpipelinetypes.BuilderConfigs, release Release) {
+	for _, entries := range []*[]otelpipelinetypes.ModuleEntry{&cfg.Receivers, &cfg.Processors, &cfg.Exporters} {
+		for i, e := range *entries {
+			path, _ := splitGomod(e.Gomod)
+			if v := release.version(path); v != "" {
+				(*entries)[i].Gomod = path + " " + v
+			}
+		}
+	}
+}
+
+// DetectRelease returns the collector release the modules of cfg are
+// pinned to. Modules at a version of another release are reported in a
+// *otelpipelinetypes.ValidationError, each with its path in the builder
+// config.
+func DetectRelease(cfg *otelpipelinetypes.BuilderConfigs) (string, error) {
+	type module struct {
+		path, modPath, version string
+	}
+	var modules []module
+	sections := []struct {
+		name    string
+		entries []otelpipelinetypes.ModuleEntry
+	}{
+		{"receivers", cfg.Receivers},
+		{"processors", cfg.Processors},
+		{"exporters", cfg.Exporters},
+	}
+	for _, s := range sections {
+		for i, e := range s.entries {
+			modPath, version := splitGomod(e.Gomod)
+			if strings.HasPrefix(modPath, coreModules) || strings.HasPrefix(modPath, contribModules) {
+				modules = append(modules, module{fmt.Sprintf("%s[%d].gomod", s.name, i), modPath, version})
+			}
+		}
+	}
+	if len(modules) == 0 {
+		return "", fmt.Errorf("no collector modules to detect the release from")
+	}
+
+	// The release is the one most modules are pinned to.
+	best, bestCount := "", 0
+	for _, name := range releaseNames() {
+		count := 0
+		for _, m := range modules {
+			if Releases[name].version(m.modPath) == m.version {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = name, count
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("modules are pinned to %s, which is not a known collector release", modules[0].version)
+	}
+
+	var issues []otelpipelinetypes.Issue
+	for _, m := range modules {
+		if want := Releases[best].version(m.modPath); m.version != want {
+			issues = append(issues, otelpipelinetypes.Issue{
+				Path:    m.path,
+				Message: fmt.Sprintf("%s %s does not match release %s of the other modules (%s)", m.modPath, versionOrNone(m.version), best, want),
+			})
+		}
+	}
+	if len(issues) > 0 {
+		return best, &otelpipelinetypes.ValidationError{Issues: issues}
+	}
+	return best, nil
+}
+
+// splitGomod splits a gomod line such as
+// "go.opentelemetry.io/collector/receiver/otlpreceiver v0.127.0" into its
+// module path and version.
+func splitGomod(gomod string) (path, version string) {
+	fields := strings.Fields(gomod)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	}
+	return fields[0], fields[1]
+}
+
+func versionOrNone(v string) string {
+	if v == "" {
+		return "no version"
+	}
+	return v
+}
+
+func releaseNames() []string {
+	names := make([]string, 0, len(Releases))
+	for name := range Releases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,79 @@
+package builderconfig_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/builderconfig"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mixedConfig() *otelpipelinetypes.BuilderConfigs {
+	return &otelpipelinetypes.BuilderConfigs{
+		Receivers: []otelpipelinetypes.ModuleEntry{
+			{Gomod: "go.opentelemetry.io/collector/receiver/otlpreceiver v0.128.0"},
+			{Gomod: "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/filelogreceiver v0.127.0"},
+			{Gomod: "example.com/acme/customreceiver v1.2.0"},
+		},
+		Processors: []otelpipelinetypes.ModuleEntry{
+			{Gomod: "go.opentelemetry.io/collector/processor/batchprocessor v0.127.0"},
+		},
+		Exporters: []otelpipelinetypes.ModuleEntry{
+			{Gomod: "github.com/Ricky004/watchdata v0.0.1", Import: "github.com/Ricky004/watchdata/pkg/watchdataexporter"},
+		},
+	}
+}
+
+func TestDetectRelease(t *testing.T) {
+	release, err := builderconfig.DetectRelease(mixedConfig())
+	assert.Equal(t, "0.127.0", release)
+	var verr *otelpipelinetypes.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []otelpipelinetypes.Issue{{
+		Path:    "receivers[0].gomod",
+		Message: "go.opentelemetry.io/collector/receiver/otlpreceiver v0.128.0 does not match release 0.127.0 of the other modules (v0.127.0)",
+	}}, verr.Issues)
+
+	cfg := mixedConfig()
+	cfg.Receivers[0].Gomod = "go.opentelemetry.io/collector/receiver/otlpreceiver v0.127.0"
+	release, err = builderconfig.DetectRelease(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "0.127.0", release)
+
+	_, err = builderconfig.DetectRelease(&otelpipelinetypes.BuilderConfigs{
+		Receivers: []otelpipelinetypes.ModuleEntry{{Gomod: "go.opentelemetry.io/collector/receiver/otlpreceiver v0.90.0"}},
+	})
+	assert.ErrorContains(t, err, "v0.90.0, which is not a known collector release")
+}
+
+func TestPin(t *testing.T) {
+	release, err := builderconfig.LookupRelease("0.128.0")
+	require.NoError(t, err)
+	cfg := mixedConfig()
+	builderconfig.Pin(cfg, release)
+
+	assert.Equal(t, "go.opentelemetry.io/collector/receiver/otlpreceiver v0.128.0", cfg.Receivers[0].Gomod)
+	assert.Equal(t, "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/filelogreceiver v0.128.0", cfg.Receivers[1].Gomod)
+	assert.Equal(t, "go.opentelemetry.io/collector/processor/batchprocessor v0.128.0", cfg.Processors[0].Gomod)
+	// Modules of other repositories are left alone.
+	assert.Equal(t, "example.com/acme/customreceiver v1.2.0", cfg.Receivers[2].Gomod)
+	assert.Equal(t, "github.com/Ricky004/watchdata "+builderconfig.WatchdataVersion(), cfg.Exporters[0].Gomod)
+	assert.Equal(t, "github.com/Ricky004/watchdata/pkg/watchdataexporter", cfg.Exporters[0].Import)
+
+	detected, err := builderconfig.DetectRelease(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "0.128.0", detected)
+}
+
+func TestWatchdataVersion(t *testing.T) {
+	v := builderconfig.WatchdataVersion()
+	assert.True(t, strings.HasPrefix(v, "v"), v)
+	assert.NotContains(t, v, "+")
+}
+
+func TestLookupRelease(t *testing.T) {
+	_, err := builderconfig.LookupRelease("1.0.0")
+	assert.ErrorContains(t, err, `unknown collector release "1.0.0", known releases are 0.125.0,`)
+}
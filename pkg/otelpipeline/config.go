@@ -0,0 +1,78 @@
+package otelpipeline
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/builderconfig"
+)
+
+type Config struct {
+	// CollectorConfig and BuilderConfig are the files pipeline definitions
+	// are deployed to.
+	CollectorConfig string `mapstructure:"collector_config"`
+	BuilderConfig   string `mapstructure:"builder_config"`
+
+	// Backups is how many earlier versions of each file are kept next to
+	// it when deploying.
+	Backups int `mapstructure:"backups"`
+
+	// Release is the collector release deployed builder configs are pinned
+	// to. If empty, the release of the deployed builder config is kept.
+	Release string `mapstructure:"release"`
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("pipelines"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	cfg := Config{
+		CollectorConfig: "configs/otel-collector-config.yaml",
+		BuilderConfig:   "configs/builder-config.yaml",
+		Backups:         10,
+	}
+	if v := os.Getenv("WATCHDATA_PIPELINES_COLLECTOR_CONFIG"); v != "" {
+		cfg.CollectorConfig = v
+	}
+	if v := os.Getenv("WATCHDATA_PIPELINES_BUILDER_CONFIG"); v != "" {
+		cfg.BuilderConfig = v
+	}
+	if v := os.Getenv("WATCHDATA_PIPELINES_BACKUPS"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			cfg.Backups = parsed
+		}
+	}
+	if v := os.Getenv("WATCHDATA_PIPELINES_RELEASE"); v != "" {
+		cfg.Release = v
+	}
+	return cfg
+}
+
+func (c Config) Validate() error {
+	if c.CollectorConfig == "" || c.BuilderConfig == "" {
+		return fmt.Errorf("pipeline collector and builder config paths are required")
+	}
+	if c.CollectorConfig == c.BuilderConfig {
+		return fmt.Errorf("pipeline collector and builder configs must be different files")
+	}
+	if c.Backups < 0 {
+		return fmt.Errorf("pipeline backups must not be negative")
+	}
+	if c.Release != "" {
+		if _, err := builderconfig.LookupRelease(c.Release); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
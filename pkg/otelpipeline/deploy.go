@@ -0,0 +1,323 @@
+package otelpipeline
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// ErrInvalid is wrapped by errors of definitions that cannot be rendered
+// into a collector config.
+var ErrInvalid = errors.New("invalid pipeline definitions")
+
+// SelectionOf combines definitions into one selection. Two definitions
+// with the same name, or setting the params or overrides of a shared
+// component differently, are an error.
+func SelectionOf(defs []otelpipelinetypes.Definition) (otelpipelinetypes.Selection, error) {
+	sel := otelpipelinetypes.Selection{
+		Pipelines: make(map[string]otelpipelinetypes.Pipeline, len(defs)),
+		Params: otelpipelinetypes.ComponentValues{
+			Receivers:  make(map[string]map[string]interface{}),
+			Processors: make(map[string]map[string]interface{}),
+			Exporters:  make(map[string]map[string]interface{}),
+		},
+		Receivers:  make(map[string]map[string]interface{}),
+		Processors: make(map[string]map[string]interface{}),
+		Exporters:  make(map[string]map[string]interface{}),
+	}
+
+	var errs []error
+	owners := make(map[string]string)
+	add := func(def otelpipelinetypes.Definition, what, kind string, dst, src map[string]map[string]interface{}) {
+		for name, values := range src {
+			key := what + "/" + kind + "/" + name
+			if existing, ok := dst[name]; ok && !reflect.DeepEqual(existing, values) {
+				errs = append(errs, fmt.Errorf("pipelines %q and %q set the %s of %s %q differently", owners[key], def.Name, what, kind, name))
+				continue
+			}
+			dst[name] = values
+			owners[key] = def.Name
+		}
+	}
+	for _, def := range defs {
+		if _, ok := sel.Pipelines[def.Name]; ok {
+			errs = append(errs, fmt.Errorf("pipeline %q is defined more than once", def.Name))
+			continue
+		}
+		sel.Pipelines[def.Name] = def.Pipeline
+		add(def, "params", "receiver", sel.Params.Receivers, def.Params.Receivers)
+		add(def, "params", "processor", sel.Params.Processors, def.Params.Processors)
+		add(def, "params", "exporter", sel.Params.Exporters, def.Params.Exporters)
+		add(def, "overrides", "receiver", sel.Receivers, def.Receivers)
+		add(def, "overrides", "processor", sel.Processors, def.Processors)
+		add(def, "overrides", "exporter", sel.Exporters, def.Exporters)
+	}
+	return sel, errors.Join(errs...)
+}
+
+// Plan is the collector and builder config rendered from the definitions
+// and their unified diffs against the deployed files. The collector config
+// is the deployed one edited with Result.Apply. Missing files diff as
+// empty.
+type Plan struct {
+	CollectorConfig string `json:"collector_config"`
+	BuilderConfig   string `json:"builder_config"`
+	CollectorDiff   string `json:"collector_diff"`
+	BuilderDiff     string `json:"builder_diff"`
+	Changed         bool   `json:"changed"`
+
+	deployedCollector, deployedBuilder []byte
+}
+
+// Deployer renders pipeline definitions and deploys them to the
+// collector and builder config files.
+type Deployer struct {
+	cfg Config
+	now func() time.Time
+
+	// mu serializes deploys, so backups and writes do not interleave.
+	mu sync.Mutex
+}
+
+func NewDeployer(cfg Config) *Deployer {
+	return &Deployer{cfg: cfg, now: time.Now}
+}
+
+// Render generates the configs of defs. The builder config keeps the
+// distribution of the deployed one and its collector release, unless the
+// config names a release. Errors caused by the definitions wrap
+// ErrInvalid.
+func (d *Deployer) Render(defs []otelpipelinetypes.Definition) (*Result, error) {
+	sel, err := SelectionOf(defs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	sel.Release = d.cfg.Release
+	if err := KeepBuild(&sel, d.cfg.BuilderConfig); err != nil {
+		return nil, err
+	}
+
+	res, err := Generate(sel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	return res, nil
+}
+
+// Plan renders defs and diffs the result against the deployed files.
+func (d *Deployer) Plan(defs []otelpipelinetypes.Definition) (*Plan, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.plan(defs)
+}
+
+// Deploy writes the configs rendered from defs over the deployed files,
+// keeping the previous versions as backups; unchanged files are not
+// touched. Both files are written to temporary files first and only then
+// renamed over the deployed ones, so a failed write leaves the deployed
+// pair as it was. If the second rename fails, the first file is restored.
+func (d *Deployer) Deploy(defs []otelpipelinetypes.Definition) (*Plan, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	plan, err := d.plan(defs)
+	if err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		path, tmp      string
+		deployed, next []byte
+	}
+	var files []file
+	for _, f := range []file{
+		{path: d.cfg.CollectorConfig, deployed: plan.deployedCollector, next: []byte(plan.CollectorConfig)},
+		{path: d.cfg.BuilderConfig, deployed: plan.deployedBuilder, next: []byte(plan.BuilderConfig)},
+	} {
+		if f.deployed == nil || string(f.deployed) != string(f.next) {
+			files = append(files, f)
+		}
+	}
+	defer func() {
+		for _, f := range files {
+			if f.tmp != "" {
+				os.Remove(f.tmp)
+			}
+		}
+	}()
+	for i := range files {
+		f := &files[i]
+		if f.tmp, err = writeTemp(f.path, f.next); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+	}
+
+	stamp := d.now().UTC().Format("20060102T150405.000Z")
+	for _, f := range files {
+		if f.deployed == nil || d.cfg.Backups == 0 {
+			continue
+		}
+		if err := writeAtomic(f.path+"."+stamp+".bak", f.deployed); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", f.path, err)
+		}
+		if err := pruneBackups(f.path, d.cfg.Backups); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, f := range files {
+		if err := os.Rename(f.tmp, f.path); err != nil {
+			for _, done := range files[:i] {
+				restore(done.path, done.deployed)
+			}
+			return nil, fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+	}
+	return plan, nil
+}
+
+// DeployedCollectorConfig returns the deployed collector config, or nil if
+// there is none.
+func (d *Deployer) DeployedCollectorConfig() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return readDeployed(d.cfg.CollectorConfig)
+}
+
+func (d *Deployer) plan(defs []otelpipelinetypes.Definition) (*Plan, error) {
+	res, err := d.Render(defs)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := res.BuilderYAML()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{BuilderConfig: string(builder)}
+	if plan.deployedCollector, err = readDeployed(d.cfg.CollectorConfig); err != nil {
+		return nil, err
+	}
+	if plan.deployedBuilder, err = readDeployed(d.cfg.BuilderConfig); err != nil {
+		return nil, err
+	}
+	// The deployed collector config is edited rather than replaced, so
+	// that what was written by hand is kept.
+	collector, err := res.EditCollectorYAML(plan.deployedCollector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalid, d.cfg.CollectorConfig, err)
+	}
+	plan.CollectorConfig = string(collector)
+	if plan.CollectorDiff, err = diff(d.cfg.CollectorConfig, plan.deployedCollector, collector); err != nil {
+		return nil, err
+	}
+	if plan.BuilderDiff, err = diff(d.cfg.BuilderConfig, plan.deployedBuilder, builder); err != nil {
+		return nil, err
+	}
+	plan.Changed = plan.CollectorDiff != "" || plan.BuilderDiff != ""
+	return plan, nil
+}
+
+// readDeployed returns the contents of a deployed file, or nil if there is
+// none.
+func readDeployed(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+func diff(path string, deployed, rendered []byte) (string, error) {
+	out, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(deployed)),
+		B:        difflib.SplitLines(string(rendered)),
+		FromFile: "a/" + path,
+		ToFile:   "b/" + path,
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to diff %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// writeAtomic replaces path with data through a temporary file in the same
+// directory, so readers see either the old or the new contents.
+func writeAtomic(path string, data []byte) error {
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Rename(tmp, path)
+}
+
+// writeTemp writes data to a temporary file next to path, ready to be
+// renamed over it, and returns its name.
+func writeTemp(path string, data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+// restore puts back the deployed contents of path after a failed deploy,
+// removing it if there were none.
+func restore(path string, deployed []byte) {
+	if deployed == nil {
+		os.Remove(path)
+		return
+	}
+	writeAtomic(path, deployed)
+}
+
+// pruneBackups removes all but the keep most recent backups of path.
+func pruneBackups(path string, keep int) error {
+	backups, err := filepath.Glob(path + ".*.bak")
+	if err != nil {
+		return err
+	}
+	// Backup names sort by the time they were taken.
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("failed to remove backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
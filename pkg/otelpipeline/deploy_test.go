@@ -0,0 +1,178 @@
+package otelpipeline_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func definition(name string, receivers ...string) otelpipelinetypes.Definition {
+	return otelpipelinetypes.Definition{
+		Name: name,
+		Pipeline: otelpipelinetypes.Pipeline{
+			Receivers: receivers,
+			Exporters: []string{"watchdataexporter"},
+		},
+	}
+}
+
+func TestSelectionOf(t *testing.T) {
+	a := definition("logs", "otlp")
+	a.Exporters = map[string]map[string]interface{}{"watchdataexporter": {"tenant_id": "acme"}}
+	b := definition("logs/app", "filelog/app")
+	b.Exporters = map[string]map[string]interface{}{"watchdataexporter": {"tenant_id": "acme"}}
+
+	sel, err := otelpipeline.SelectionOf([]otelpipelinetypes.Definition{a, b})
+	require.NoError(t, err)
+	assert.Len(t, sel.Pipelines, 2)
+	assert.Equal(t, "acme", sel.Exporters["watchdataexporter"]["tenant_id"])
+
+	b.Exporters["watchdataexporter"] = map[string]interface{}{"tenant_id": "other"}
+	_, err = otelpipeline.SelectionOf([]otelpipelinetypes.Definition{a, b, definition("logs", "otlp")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `pipelines "logs" and "logs/app" set the overrides of exporter "watchdataexporter" differently`)
+	assert.Contains(t, err.Error(), `pipeline "logs" is defined more than once`)
+}
+
+func deployer(t *testing.T, backups int) (*otelpipeline.Deployer, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := otelpipeline.Config{
+		CollectorConfig: filepath.Join(dir, "otel-collector-config.yaml"),
+		BuilderConfig:   filepath.Join(dir, "builder-config.yaml"),
+		Backups:         backups,
+	}
+	require.NoError(t, cfg.Validate())
+	return otelpipeline.NewDeployer(cfg), cfg.CollectorConfig, cfg.BuilderConfig
+}
+
+func TestDeployerPlanAndDeploy(t *testing.T) {
+	d, collectorPath, builderPath := deployer(t, 1)
+	v1 := []otelpipelinetypes.Definition{definition("logs", "otlp")}
+
+	plan, err := d.Plan(v1)
+	require.NoError(t, err)
+	assert.True(t, plan.Changed)
+	assert.Contains(t, plan.CollectorDiff, "+receivers:")
+	_, err = os.Stat(collectorPath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "planning does not write")
+	deployed, err := d.DeployedCollectorConfig()
+	require.NoError(t, err)
+	assert.Nil(t, deployed)
+
+	_, err = d.Deploy(v1)
+	require.NoError(t, err)
+	deployed, err = os.ReadFile(collectorPath)
+	require.NoError(t, err)
+	assert.Equal(t, plan.CollectorConfig, string(deployed))
+	deployed, err = d.DeployedCollectorConfig()
+	require.NoError(t, err)
+	assert.Equal(t, plan.CollectorConfig, string(deployed))
+	_, err = os.Stat(builderPath)
+	require.NoError(t, err)
+
+	plan, err = d.Plan(v1)
+	require.NoError(t, err)
+	assert.False(t, plan.Changed)
+	assert.Empty(t, plan.CollectorDiff)
+
+	// Adding a filelog source changes both files and backs up the old ones.
+	v2 := append(v1, definition("logs/app", "filelog/nginx"))
+	plan, err = d.Deploy(v2)
+	require.NoError(t, err)
+	assert.Contains(t, plan.CollectorDiff, "+  filelog/nginx:")
+	assert.Contains(t, plan.BuilderDiff, "filelogreceiver")
+
+	v3 := append(v2, definition("logs/2", "otlp/2"))
+	_, err = d.Deploy(v3)
+	require.NoError(t, err)
+
+	backups, err := filepath.Glob(collectorPath + ".*.bak")
+	require.NoError(t, err)
+	require.Len(t, backups, 1, "older backups are pruned")
+	backup, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(backup), "filelog/nginx:")
+	assert.NotContains(t, string(backup), "otlp/2:")
+
+	leftovers, err := filepath.Glob(filepath.Join(filepath.Dir(collectorPath), ".*tmp-*"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestDeployerKeepsBuild(t *testing.T) {
+	d, _, builderPath := deployer(t, 0)
+	require.NoError(t, os.WriteFile(builderPath, []byte(`
+dist:
+  name: custom
+  output_path: ./out
+receivers:
+  - gomod: go.opentelemetry.io/collector/receiver/otlpreceiver v0.126.0
+`), 0644))
+
+	res, err := d.Render([]otelpipelinetypes.Definition{definition("logs", "otlp")})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", res.Builder.Dist.Name)
+	assert.Equal(t, "0.126.0", res.Release)
+
+	// A configured release replaces the deployed one.
+	cfg := otelpipeline.Config{CollectorConfig: "collector.yaml", BuilderConfig: builderPath, Release: "0.128.0"}
+	require.NoError(t, cfg.Validate())
+	res, err = otelpipeline.NewDeployer(cfg).Render([]otelpipelinetypes.Definition{definition("logs", "otlp")})
+	require.NoError(t, err)
+	assert.Equal(t, "go.opentelemetry.io/collector/receiver/otlpreceiver v0.128.0", res.Builder.Receivers[0].Gomod)
+
+	cfg.Release = "0.1.0"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestDeployerInvalid(t *testing.T) {
+	d, collectorPath, _ := deployer(t, 0)
+
+	_, err := d.Deploy([]otelpipelinetypes.Definition{definition("logs", "kafka")})
+	assert.ErrorIs(t, err, otelpipeline.ErrInvalid)
+
+	_, err = d.Plan(nil)
+	assert.ErrorIs(t, err, otelpipeline.ErrInvalid)
+
+	_, err = os.Stat(collectorPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDeployerWritesBothOrNeither(t *testing.T) {
+	d, collectorPath, builderPath := deployer(t, 1)
+	v1 := []otelpipelinetypes.Definition{definition("logs", "otlp")}
+	_, err := d.Deploy(v1)
+	require.NoError(t, err)
+	collector, err := os.ReadFile(collectorPath)
+	require.NoError(t, err)
+
+	// The builder config cannot be written, so the collector config that
+	// would need it is not either.
+	dir := filepath.Dir(collectorPath)
+	cfg := otelpipeline.Config{
+		CollectorConfig: collectorPath,
+		BuilderConfig:   filepath.Join(dir, "missing", "builder-config.yaml"),
+		Backups:         1,
+	}
+	require.NoError(t, cfg.Validate())
+	_, err = otelpipeline.NewDeployer(cfg).Deploy(append(v1, definition("logs/app", "filelog/nginx")))
+	require.Error(t, err)
+
+	deployed, err := os.ReadFile(collectorPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(collector), string(deployed))
+	_, err = os.Stat(builderPath)
+	require.NoError(t, err)
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".*tmp-*"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+	backups, err := filepath.Glob(collectorPath + ".*.bak")
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
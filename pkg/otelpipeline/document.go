@@ -0,0 +1,257 @@
+package otelpipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// managedComment marks the pipelines of a collector config rendered from
+// pipeline definitions, so that the next edit can remove those whose
+// definition is gone.
+const managedComment = "# managed by watchdata"
+
+// modeled lists the keys of the root and service mappings that
+// OTelConfig models. Other keys are kept as they are.
+var modeled = map[string][]string{
+	"":        {"receivers", "processors", "exporters", "extensions", "connectors", "service"},
+	"service": {"extensions", "telemetry", "pipelines"},
+}
+
+// Document is a collector config file loaded for editing. Config is its
+// typed model; Bytes re-encodes the file with the edits made to Config,
+// keeping comments, key order and the formatting of unchanged values, as
+// well as the sections Config does not model.
+type Document struct {
+	Config otelpipelinetypes.OTelConfig
+
+	root *yaml.Node
+	// managed are the pipelines marked with managedComment.
+	managed []string
+}
+
+// ParseDocument parses a collector config. Empty data is an empty config.
+func ParseDocument(data []byte) (*Document, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if len(root.Content) != 1 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("collector config must be a mapping")
+	}
+
+	d := &Document{root: &root}
+	if err := root.Content[0].Decode(&d.Config); err != nil {
+		return nil, err
+	}
+	if pipelines := lookup(root.Content[0], "service", "pipelines"); pipelines != nil && pipelines.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(pipelines.Content); i += 2 {
+			if pipelines.Content[i].LineComment == managedComment {
+				d.managed = append(d.managed, pipelines.Content[i].Value)
+			}
+		}
+	}
+	return d, nil
+}
+
+// LoadDocument reads the collector config at path.
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Bytes encodes the document with the edits made to Config.
+func (d *Document) Bytes() ([]byte, error) {
+	var model yaml.Node
+	if err := model.Encode(d.Config); err != nil {
+		return nil, err
+	}
+	root := d.root.Content[0]
+	update("", root, &model)
+
+	if pipelines := lookup(root, "service", "pipelines"); pipelines != nil && pipelines.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(pipelines.Content); i += 2 {
+			key := pipelines.Content[i]
+			switch {
+			case slices.Contains(d.managed, key.Value):
+				key.LineComment = managedComment
+			case key.LineComment == managedComment:
+				key.LineComment = ""
+			}
+		}
+	}
+	return marshal(d.root)
+}
+
+// Apply edits doc to run the pipelines of r. The pipelines and components
+// r renders replace those of the same name; the rest of the config, such
+// as hand-written pipelines and their components, extensions, connectors
+// and telemetry settings, is kept. Pipelines rendered by an earlier Apply
+// that r no longer renders are removed, as are the components they used
+// that no pipeline uses anymore. A config whose pipelines do not validate
+// afterwards is an error.
+func (r *Result) Apply(doc *Document) error {
+	cfg := &doc.Config
+	if cfg.Service.Pipelines == nil {
+		cfg.Service.Pipelines = make(map[string]otelpipelinetypes.Pipeline)
+	}
+
+	// The components of the pipelines rendered last time are removed once
+	// nothing uses them.
+	var previous otelpipelinetypes.Pipeline
+	for _, name := range doc.managed {
+		p := cfg.Service.Pipelines[name]
+		previous.Receivers = appendUnique(previous.Receivers, p.Receivers...)
+		previous.Processors = appendUnique(previous.Processors, p.Processors...)
+		previous.Exporters = appendUnique(previous.Exporters, p.Exporters...)
+		delete(cfg.Service.Pipelines, name)
+	}
+
+	for name, p := range r.Collector.Service.Pipelines {
+		cfg.Service.Pipelines[name] = p
+	}
+	cfg.Receivers = setComponents(cfg.Receivers, r.Collector.Receivers)
+	cfg.Processors = setComponents(cfg.Processors, r.Collector.Processors)
+	cfg.Exporters = setComponents(cfg.Exporters, r.Collector.Exporters)
+
+	prune(cfg.Receivers, previous.Receivers, cfg.Service.Pipelines, func(p otelpipelinetypes.Pipeline) []string { return p.Receivers })
+	prune(cfg.Processors, previous.Processors, cfg.Service.Pipelines, func(p otelpipelinetypes.Pipeline) []string { return p.Processors })
+	prune(cfg.Exporters, previous.Exporters, cfg.Service.Pipelines, func(p otelpipelinetypes.Pipeline) []string { return p.Exporters })
+
+	doc.managed = sortedKeys(r.Collector.Service.Pipelines)
+	if issues := validateService(*cfg); len(issues) > 0 {
+		return &otelpipelinetypes.ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// EditCollectorYAML returns the collector config existing edited to run
+// the pipelines of r, as Apply does. A nil existing config is empty.
+func (r *Result) EditCollectorYAML(existing []byte) ([]byte, error) {
+	doc, err := ParseDocument(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse collector config: %w", err)
+	}
+	if err := r.Apply(doc); err != nil {
+		return nil, err
+	}
+	return doc.Bytes()
+}
+
+func setComponents(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for name, block := range src {
+		dst[name] = block
+	}
+	return dst
+}
+
+// prune removes the components of names that no pipeline lists.
+func prune(blocks map[string]interface{}, names []string, pipelines map[string]otelpipelinetypes.Pipeline, list func(otelpipelinetypes.Pipeline) []string) {
+	for _, name := range names {
+		used := false
+		for _, p := range pipelines {
+			used = used || slices.Contains(list(p), name)
+		}
+		if !used {
+			delete(blocks, name)
+		}
+	}
+}
+
+// update makes dst encode the same value as src, leaving the nodes whose
+// value is unchanged as they are. Keys of dst missing from src are
+// removed, unless path is the root or service mapping and they are not
+// modeled.
+func update(path string, dst, src *yaml.Node) {
+	if sameValue(dst, src) {
+		return
+	}
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			if j := find(dst, key.Value); j >= 0 {
+				update(otelpipelinetypes.JoinPath(path, key.Value), dst.Content[j+1], value)
+			} else {
+				dst.Content = append(dst.Content, key, value)
+			}
+		}
+		known, partial := modeled[path]
+		var content []*yaml.Node
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			key := dst.Content[i].Value
+			if find(src, key) >= 0 || (partial && !slices.Contains(known, key)) {
+				content = append(content, dst.Content[i], dst.Content[i+1])
+			}
+		}
+		dst.Content = content
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		for i, item := range src.Content {
+			if i < len(dst.Content) {
+				update(path, dst.Content[i], item)
+			} else {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		dst.Content = dst.Content[:len(src.Content)]
+	default:
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+	}
+}
+
+// sameValue reports whether two nodes decode to the same value, so that
+// "batch:" and "batch: null", or an alias and what it refers to, are the
+// same.
+func sameValue(a, b *yaml.Node) bool {
+	var av, bv interface{}
+	if a.Decode(&av) != nil || b.Decode(&bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// find returns the index of key in the mapping m, or -1.
+func find(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// lookup returns the node at the keys below the mapping m, or nil.
+func lookup(m *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if m.Kind != yaml.MappingNode {
+			return nil
+		}
+		i := find(m, key)
+		if i < 0 {
+			return nil
+		}
+		m = m.Content[i+1]
+	}
+	return m
+}
@@ -0,0 +1,229 @@
+package otelpipeline_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handTuned is a collector config written by hand, with sections and
+// settings OTelConfig does not model.
+const handTuned = `# Collector of the edge nodes.
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317 # behind the load balancer
+  otlp/2:
+    protocols:
+      http:
+        endpoint: 0.0.0.0:4319
+  hostmetrics:
+    collection_interval: 30s
+    scrapers:
+      cpu:
+processors:
+  # Keep under the pod limit.
+  memory_limiter:
+    check_interval: 1s
+    limit_mib: 400
+  batch:
+exporters:
+  debug:
+    verbosity: detailed
+  watchdataexporter:
+    dsn: "tcp://clickhouse:9000/default"
+    tenant_id: default
+extensions:
+  health_check:
+    endpoint: 0.0.0.0:13133
+connectors:
+  count:
+    logs:
+      log.count:
+        description: Log records
+service:
+  extensions: [health_check]
+  telemetry:
+    logs:
+      level: info
+  pipelines:
+    logs:
+      receivers: [otlp, otlp/2]
+      processors: [memory_limiter, batch]
+      exporters: [watchdataexporter, count]
+    metrics:
+      receivers: [hostmetrics, count]
+      exporters: [debug]
+  unmodeled: kept
+x-notes: also kept
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, err := otelpipeline.ParseDocument([]byte(handTuned))
+	require.NoError(t, err)
+
+	cfg := doc.Config
+	assert.Contains(t, cfg.Receivers, "otlp/2")
+	assert.Nil(t, cfg.Processors["batch"])
+	assert.Equal(t, map[string]interface{}{"endpoint": "0.0.0.0:13133"}, cfg.Extensions["health_check"])
+	assert.Contains(t, cfg.Connectors, "count")
+	assert.Equal(t, []string{"health_check"}, cfg.Service.Extensions)
+	assert.Equal(t, map[string]interface{}{"logs": map[string]interface{}{"level": "info"}}, cfg.Service.Telemetry)
+	assert.Equal(t, []string{"hostmetrics", "count"}, cfg.Service.Pipelines["metrics"].Receivers)
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, handTuned, string(out))
+}
+
+func TestDocumentEdit(t *testing.T) {
+	doc, err := otelpipeline.ParseDocument([]byte(handTuned))
+	require.NoError(t, err)
+
+	doc.Config.Processors["memory_limiter"].(map[string]interface{})["limit_mib"] = 800
+	delete(doc.Config.Receivers, "otlp/2")
+	doc.Config.Receivers["filelog"] = map[string]interface{}{"include": []string{"/var/log/app/*.log"}}
+	p := doc.Config.Service.Pipelines["logs"]
+	p.Receivers = []string{"otlp", "filelog"}
+	doc.Config.Service.Pipelines["logs"] = p
+	doc.Config.Service.Telemetry = nil
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, `# Collector of the edge nodes.
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317 # behind the load balancer
+  hostmetrics:
+    collection_interval: 30s
+    scrapers:
+      cpu:
+  filelog:
+    include:
+      - /var/log/app/*.log
+processors:
+  # Keep under the pod limit.
+  memory_limiter:
+    check_interval: 1s
+    limit_mib: 800
+  batch:
+exporters:
+  debug:
+    verbosity: detailed
+  watchdataexporter:
+    dsn: "tcp://clickhouse:9000/default"
+    tenant_id: default
+extensions:
+  health_check:
+    endpoint: 0.0.0.0:13133
+connectors:
+  count:
+    logs:
+      log.count:
+        description: Log records
+service:
+  extensions: [health_check]
+  pipelines:
+    logs:
+      receivers: [otlp, filelog]
+      processors: [memory_limiter, batch]
+      exporters: [watchdataexporter, count]
+    metrics:
+      receivers: [hostmetrics, count]
+      exporters: [debug]
+  unmodeled: kept
+x-notes: also kept
+`, string(out))
+
+	_, err = otelpipeline.ParseDocument([]byte("- receivers\n"))
+	assert.ErrorContains(t, err, "collector config must be a mapping")
+}
+
+func TestApply(t *testing.T) {
+	doc, err := otelpipeline.ParseDocument([]byte(handTuned))
+	require.NoError(t, err)
+
+	// Definitions take over the logs pipeline and add one of their own.
+	sel := otelpipelinetypes.Selection{
+		Pipelines: map[string]otelpipelinetypes.Pipeline{
+			"logs":     {Receivers: []string{"otlp"}, Processors: []string{"batch"}, Exporters: []string{"watchdataexporter"}},
+			"logs/app": {Receivers: []string{"filelog/nginx"}, Exporters: []string{"watchdataexporter"}},
+		},
+	}
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	require.NoError(t, res.Apply(doc))
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+
+	doc, err = otelpipeline.ParseDocument(out)
+	require.NoError(t, err)
+	cfg := doc.Config
+	assert.Contains(t, string(out), "    logs: # managed by watchdata\n")
+	assert.Contains(t, string(out), "    logs/app: # managed by watchdata\n")
+	assert.Contains(t, string(out), "# Keep under the pod limit.\n  memory_limiter:")
+	assert.Contains(t, string(out), "endpoint: 0.0.0.0:4317 # behind the load balancer")
+	assert.Contains(t, string(out), "x-notes: also kept")
+	assert.Equal(t, []string{"hostmetrics", "count"}, cfg.Service.Pipelines["metrics"].Receivers, "hand-written pipelines are kept")
+	assert.Contains(t, cfg.Receivers, "otlp/2", "components the pipeline used before are kept")
+	assert.Contains(t, cfg.Receivers, "filelog/nginx")
+	assert.Equal(t, res.Collector.Processors["batch"], cfg.Processors["batch"])
+	assert.Contains(t, cfg.Extensions, "health_check")
+
+	// Dropping a definition removes its pipeline and the components only
+	// it used.
+	delete(sel.Pipelines, "logs/app")
+	res, err = otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	require.NoError(t, res.Apply(doc))
+	out, err = doc.Bytes()
+	require.NoError(t, err)
+	doc, err = otelpipeline.ParseDocument(out)
+	require.NoError(t, err)
+	assert.NotContains(t, doc.Config.Service.Pipelines, "logs/app")
+	assert.NotContains(t, doc.Config.Receivers, "filelog/nginx")
+	assert.Contains(t, doc.Config.Receivers, "otlp")
+	assert.Contains(t, doc.Config.Exporters, "watchdataexporter")
+	assert.NotContains(t, string(out), "logs/app")
+
+	// Hand-written pipelines must still validate.
+	doc.Config.Service.Extensions = append(doc.Config.Service.Extensions, "pprof")
+	var verr *otelpipelinetypes.ValidationError
+	require.ErrorAs(t, res.Apply(doc), &verr)
+	assert.Equal(t, []otelpipelinetypes.Issue{{Path: "service.extensions[1]", Message: `extension "pprof" is not defined`}}, verr.Issues)
+}
+
+func TestDeployerEditsCollectorConfig(t *testing.T) {
+	d, collectorPath, _ := deployer(t, 0)
+	require.NoError(t, os.WriteFile(collectorPath, []byte(handTuned), 0644))
+
+	_, err := d.Deploy([]otelpipelinetypes.Definition{definition("logs/app", "otlp")})
+	require.NoError(t, err)
+	data, err := os.ReadFile(collectorPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Collector of the edge nodes.\n")
+	assert.Contains(t, string(data), "    logs/app: # managed by watchdata\n")
+
+	doc, err := otelpipeline.LoadDocument(collectorPath)
+	require.NoError(t, err)
+	assert.Len(t, doc.Config.Service.Pipelines, 3)
+
+	// Deploying the same definitions again changes nothing.
+	plan, err := d.Plan([]otelpipelinetypes.Definition{definition("logs/app", "otlp")})
+	require.NoError(t, err)
+	assert.False(t, plan.Changed)
+
+	// A broken hand edit is reported against the file.
+	require.NoError(t, os.WriteFile(collectorPath, []byte("receivers: [\n"), 0644))
+	_, err = d.Plan([]otelpipelinetypes.Definition{definition("logs/app", "otlp")})
+	assert.ErrorIs(t, err, otelpipeline.ErrInvalid)
+	assert.ErrorContains(t, err, filepath.Base(collectorPath))
+}
@@ -0,0 +1,16 @@
+package exporter
+
+import "github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+
+// ExporterSchemas describe the config of every exporter in
+// ExporterTemplates.
+var ExporterSchemas = map[string]*otelpipelinetypes.Schema{
+	"watchdataexporter": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"dsn":       {Type: otelpipelinetypes.TypeString, Required: true, Description: "ClickHouse DSN, e.g. tcp://clickhouse:9000/default"},
+			"insecure":  {Type: otelpipelinetypes.TypeBool, Default: false},
+			"tenant_id": {Type: otelpipelinetypes.TypeString, Default: "default", Description: "Tenant the exported logs are stored under"},
+		},
+	},
+}
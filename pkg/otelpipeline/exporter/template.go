@@ -1,8 +1,21 @@
 package exporter
 
-var ExporterTemplates = map[string]interface{}{
-	"watchdataexporter": map[string]interface{}{
-		"dsn": "tcp://clickhouse:9000/default?username=default&password=pass",
-		"insecure": true,
+import "github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+
+// ExporterTemplates are the exporters a pipeline can use, keyed by type.
+var ExporterTemplates = map[string]otelpipelinetypes.Template{
+	"watchdataexporter": {
+		Description: "Stores logs in watchdata's ClickHouse",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "dsn", Description: "ClickHouse DSN", Default: "tcp://clickhouse:9000/default?username=default&password=pass"},
+			{Name: "insecure", Description: "Skip TLS certificate verification", Default: true},
+			{Name: "tenant_id", Description: "Tenant the logs are stored under", Default: "default"},
+		},
+		Config: map[string]interface{}{
+			"dsn":       "{{dsn}}",
+			"insecure":  "{{insecure}}",
+			"tenant_id": "{{tenant_id}}",
+		},
 	},
-}
\ No newline at end of file
+}
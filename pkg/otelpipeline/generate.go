@@ -0,0 +1,266 @@
+package otelpipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/builderconfig"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/exporter"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/processors"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/receviers"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// DefaultDist is the collector distribution built when a selection does
+// not name one.
+var DefaultDist = otelpipelinetypes.Dist{
+	Name:        "watchdataexporter",
+	Description: "Custom Collector with watchdata exporter",
+	OutputPath:  "./dist",
+}
+
+// pipelineTypes are the signals a collector pipeline can carry.
+var pipelineTypes = []string{"logs", "metrics", "traces"}
+
+// Result is a generated collector config and the builder config of a
+// collector that can run it, pinned to collector release Release.
+type Result struct {
+	Collector otelpipelinetypes.OTelConfig
+	Builder   otelpipelinetypes.BuilderConfigs
+	Release   string
+}
+
+// Generate builds the collector config of sel from the component
+// templates and the builder config with exactly the modules it needs.
+// Unknown components, invalid params, params and overrides of components
+// no pipeline uses are errors, as is a config that fails Validate. The
+// modules are pinned to sel.Release, or builderconfig.DefaultRelease.
+func Generate(sel otelpipelinetypes.Selection) (*Result, error) {
+	releaseName := sel.Release
+	if releaseName == "" {
+		releaseName = builderconfig.DefaultRelease
+	}
+	release, err := builderconfig.LookupRelease(releaseName)
+	errs := []error{err}
+
+	var receiverNames, processorNames, exporterNames []string
+	for _, name := range sortedKeys(sel.Pipelines) {
+		p := sel.Pipelines[name]
+		receiverNames = appendUnique(receiverNames, p.Receivers...)
+		processorNames = appendUnique(processorNames, p.Processors...)
+		exporterNames = appendUnique(exporterNames, p.Exporters...)
+	}
+
+	receivers, err := receviers.BuildReceivers(receiverNames, sel.Params.Receivers)
+	errs = append(errs, err)
+	procs, err := processors.BuildProcessors(processorNames, sel.Params.Processors)
+	errs = append(errs, err)
+	exporters, err := exporter.BuildExporter(exporterNames, sel.Params.Exporters)
+	errs = append(errs, err)
+
+	errs = append(errs,
+		unused(sel.Params.Receivers, receiverNames, "params of receiver"),
+		unused(sel.Params.Processors, processorNames, "params of processor"),
+		unused(sel.Params.Exporters, exporterNames, "params of exporter"),
+		override(receivers, sel.Receivers, "receiver"),
+		override(procs, sel.Processors, "processor"),
+		override(exporters, sel.Exporters, "exporter"),
+	)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	res := &Result{
+		Collector: otelpipelinetypes.OTelConfig{
+			Receivers:  receivers,
+			Processors: procs,
+			Exporters:  exporters,
+			Service:    otelpipelinetypes.ServiceConfig{Pipelines: sel.Pipelines},
+		},
+		Builder: otelpipelinetypes.BuilderConfigs{Dist: DefaultDist},
+		Release: releaseName,
+	}
+	if sel.Dist != nil {
+		res.Builder.Dist = *sel.Dist
+	}
+	if err := Validate(res.Collector); err != nil {
+		return nil, err
+	}
+	if err := builderconfig.AddModules(&res.Builder, release, receiverNames, processorNames, exporterNames); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// KeepBuild sets the dist and collector release sel does not name to
+// those of the builder config at path, so that regenerating it only
+// changes its modules. Modules at mixed versions are repinned to the
+// release most of them are at. A missing file is not an error.
+func KeepBuild(sel *otelpipelinetypes.Selection, path string) error {
+	existing, err := builderconfig.LoadBuilderConfig(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if sel.Dist == nil && existing.Dist.Name != "" {
+		sel.Dist = &existing.Dist
+	}
+	if sel.Release == "" {
+		sel.Release, _ = builderconfig.DetectRelease(existing)
+	}
+	return nil
+}
+
+// CollectorYAML returns the collector config as YAML.
+func (r *Result) CollectorYAML() ([]byte, error) {
+	return marshal(r.Collector)
+}
+
+// BuilderYAML returns the builder config as YAML.
+func (r *Result) BuilderYAML() ([]byte, error) {
+	return marshal(r.Builder)
+}
+
+// WriteFiles edits the collector config at collectorPath to run the
+// pipelines of r, as Apply does, creating it if it does not exist, and
+// writes the builder config to builderPath.
+func (r *Result) WriteFiles(collectorPath, builderPath string) error {
+	existing, err := readDeployed(collectorPath)
+	if err != nil {
+		return err
+	}
+	collector, err := r.EditCollectorYAML(existing)
+	if err != nil {
+		return fmt.Errorf("%s: %w", collectorPath, err)
+	}
+	builder, err := r.BuilderYAML()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(collectorPath, collector, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(builderPath, builder, 0644)
+}
+
+// LoadSelection reads a selection from a YAML or JSON file. Unknown
+// fields are an error so that typos do not go unnoticed.
+func LoadSelection(path string) (otelpipelinetypes.Selection, error) {
+	var sel otelpipelinetypes.Selection
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sel, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&sel); err != nil {
+		return sel, fmt.Errorf("failed to parse selection %s: %w", path, err)
+	}
+	return sel, nil
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// override merges the overrides into the rendered component blocks.
+func override(blocks map[string]interface{}, overrides map[string]map[string]interface{}, kind string) error {
+	var errs []error
+	for _, name := range sortedKeys(overrides) {
+		values := overrides[name]
+		block, ok := blocks[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("override of %s %q, which no pipeline uses", kind, name))
+			continue
+		}
+		m, ok := block.(map[string]interface{})
+		if !ok || m == nil {
+			m = make(map[string]interface{})
+		}
+		merge(m, values)
+		blocks[name] = m
+	}
+	return errors.Join(errs...)
+}
+
+// merge merges src into dst. Nested maps are merged, other values
+// replaced and null values removed.
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+		sv, ok := v.(map[string]interface{})
+		dv, dok := dst[k].(map[string]interface{})
+		if ok && dok {
+			merge(dv, sv)
+			continue
+		}
+		dst[k] = clone(v)
+	}
+}
+
+// clone deep-copies the maps and slices of an override value.
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = clone(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = clone(e)
+		}
+		return s
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = clone(e)
+		}
+		return s
+	case []string:
+		return slices.Clone(v)
+	}
+	return v
+}
+
+// unused reports values given for components no pipeline uses.
+func unused(values map[string]map[string]interface{}, used []string, what string) error {
+	var errs []error
+	for _, name := range sortedKeys(values) {
+		if !slices.Contains(used, name) {
+			errs = append(errs, fmt.Errorf("%s %q, which no pipeline uses", what, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func appendUnique(s []string, names ...string) []string {
+	for _, name := range names {
+		if !slices.Contains(s, name) {
+			s = append(s, name)
+		}
+	}
+	return s
+}
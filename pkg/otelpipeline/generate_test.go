@@ -0,0 +1,302 @@
+package otelpipeline_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/receviers"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logsSelection() otelpipelinetypes.Selection {
+	return otelpipelinetypes.Selection{
+		Pipelines: map[string]otelpipelinetypes.Pipeline{
+			"logs": {
+				Receivers:  []string{"otlp", "filelog"},
+				Processors: []string{"batch"},
+				Exporters:  []string{"watchdataexporter"},
+			},
+		},
+		Params: otelpipelinetypes.ComponentValues{
+			Receivers: map[string]map[string]interface{}{
+				"filelog": {"include": []string{"/var/log/app/*.log"}},
+			},
+		},
+	}
+}
+
+func TestGenerateUnknownComponents(t *testing.T) {
+	sel := logsSelection()
+	sel.Pipelines["logs"] = otelpipelinetypes.Pipeline{
+		Receivers: []string{"otlp", "kafka"},
+		Exporters: []string{"nope"},
+	}
+
+	_, err := otelpipeline.Generate(sel)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown receiver "kafka"`)
+	assert.Contains(t, err.Error(), `unknown exporter "nope"`)
+}
+
+func TestGenerateInvalidPipelines(t *testing.T) {
+	_, err := otelpipeline.Generate(otelpipelinetypes.Selection{})
+	assert.Error(t, err)
+
+	sel := logsSelection()
+	sel.Pipelines["profiles"] = otelpipelinetypes.Pipeline{Receivers: []string{"otlp"}}
+	_, err = otelpipeline.Generate(sel)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `service.pipelines.profiles: pipeline type must be`)
+	assert.Contains(t, err.Error(), `service.pipelines.profiles.exporters: at least one exporter is required`)
+}
+
+func TestGenerateNamedComponents(t *testing.T) {
+	sel := logsSelection()
+	sel.Pipelines["logs/2"] = otelpipelinetypes.Pipeline{
+		Receivers: []string{"otlp/2"},
+		Exporters: []string{"watchdataexporter"},
+	}
+	sel.Receivers = map[string]map[string]interface{}{
+		"otlp/2": {"protocols": map[string]interface{}{"grpc": map[string]interface{}{"endpoint": "0.0.0.0:14317"}}},
+	}
+
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	assert.Contains(t, res.Collector.Receivers, "otlp/2")
+	assert.Equal(t, "0.0.0.0:4317", endpoint(res.Collector.Receivers["otlp"]))
+	assert.Equal(t, "0.0.0.0:14317", endpoint(res.Collector.Receivers["otlp/2"]))
+
+	// Both otlp receivers are built from the same module.
+	assert.Len(t, res.Builder.Receivers, 2)
+}
+
+func TestGenerateOverrides(t *testing.T) {
+	sel := logsSelection()
+	sel.Receivers = map[string]map[string]interface{}{
+		"filelog": {"include": []interface{}{"/var/log/app.json"}, "start_at": nil},
+	}
+	sel.Exporters = map[string]map[string]interface{}{
+		"watchdataexporter": {"tenant_id": "acme"},
+	}
+
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	filelog := res.Collector.Receivers["filelog"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"/var/log/app.json"}, filelog["include"])
+	assert.NotContains(t, filelog, "start_at")
+	assert.Equal(t, true, filelog["include_file_path"])
+	assert.Equal(t, "acme", res.Collector.Exporters["watchdataexporter"].(map[string]interface{})["tenant_id"])
+
+	// Templates are left untouched.
+	assert.Contains(t, receviers.ReceiverTemplates["filelog"].Config, "start_at")
+
+	sel.Processors = map[string]map[string]interface{}{"memory_limiter": {"limit_mib": 512}}
+	_, err = otelpipeline.Generate(sel)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `processor "memory_limiter"`)
+
+	// Overrides are checked against the schemas.
+	sel.Processors = map[string]map[string]interface{}{"batch": {"timout": "5s"}}
+	_, err = otelpipeline.Generate(sel)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `processors.batch.timout: unknown field (did you mean "timeout"?)`)
+}
+
+func TestGenerateParams(t *testing.T) {
+	sel := logsSelection()
+	sel.Params.Receivers["otlp"] = map[string]interface{}{"grpc_endpoint": "127.0.0.1:4317"}
+	sel.Params.Processors = map[string]map[string]interface{}{"batch": {"timeout": "5s"}}
+
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:4317", endpoint(res.Collector.Receivers["otlp"]))
+	filelog := res.Collector.Receivers["filelog"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"/var/log/app/*.log"}, filelog["include"])
+	assert.Equal(t, "end", filelog["start_at"])
+	// Fields of unset params without a default are left out.
+	assert.NotContains(t, filelog, "exclude")
+	batch := res.Collector.Processors["batch"].(map[string]interface{})
+	assert.Equal(t, "5s", batch["timeout"])
+	assert.Equal(t, 10000, batch["send_batch_size"])
+
+	sel.Params.Receivers["filelog"] = map[string]interface{}{"includ": "/var/log/*.log"}
+	sel.Params.Exporters = map[string]map[string]interface{}{"clickhouse": {"dsn": "tcp://localhost:9000"}}
+	_, err = otelpipeline.Generate(sel)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `receiver "filelog": param "include" is required, unknown param "includ"`)
+	assert.Contains(t, err.Error(), `params of exporter "clickhouse", which no pipeline uses`)
+}
+
+func TestGeneratePresets(t *testing.T) {
+	sel := logsSelection()
+	sel.Pipelines["logs"] = otelpipelinetypes.Pipeline{
+		Receivers: []string{"filelog/nginx", "filelog/app"},
+		Exporters: []string{"watchdataexporter"},
+	}
+	sel.Params.Receivers = map[string]map[string]interface{}{
+		"filelog/app": {"include": []string{"/srv/app/*.log"}},
+	}
+
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	nginx := res.Collector.Receivers["filelog/nginx"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"/var/log/nginx/access.log"}, nginx["include"])
+	assert.Contains(t, nginx, "operators")
+	// Names without a preset of their own use the template of their type.
+	app := res.Collector.Receivers["filelog/app"].(map[string]interface{})
+	assert.NotContains(t, app, "operators")
+
+	// Presets are built from the module of their type.
+	require.Len(t, res.Builder.Receivers, 1)
+	assert.Contains(t, res.Builder.Receivers[0].Gomod, "filelogreceiver")
+}
+
+func TestGenerateProcessors(t *testing.T) {
+	sel := logsSelection()
+	sel.Pipelines["logs"] = otelpipelinetypes.Pipeline{
+		Receivers:  []string{"otlp"},
+		Processors: []string{"memory_limiter", "k8sattributes", "filter", "redaction", "batch"},
+		Exporters:  []string{"watchdataexporter"},
+	}
+	sel.Params = otelpipelinetypes.ComponentValues{
+		Processors: map[string]map[string]interface{}{
+			"filter": {"log_conditions": []string{`severity_number < SEVERITY_NUMBER_INFO`}},
+		},
+	}
+
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	filter := res.Collector.Processors["filter"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"log_record": []interface{}{"severity_number < SEVERITY_NUMBER_INFO"}}, filter["logs"])
+	// Sections whose params are all unset are left out.
+	assert.NotContains(t, filter, "metrics")
+	assert.NotContains(t, filter, "traces")
+	assert.NotContains(t, res.Collector.Processors["k8sattributes"], "filter")
+	assert.Len(t, res.Builder.Processors, 5)
+
+	sel.Pipelines["logs"] = otelpipelinetypes.Pipeline{
+		Receivers:  []string{"otlp"},
+		Processors: []string{"tail_sampling"},
+		Exporters:  []string{"watchdataexporter"},
+	}
+	sel.Params = otelpipelinetypes.ComponentValues{}
+	_, err = otelpipeline.Generate(sel)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `processor "tail_sampling" does not support logs`)
+}
+
+func TestGenerateSignals(t *testing.T) {
+	sel := logsSelection()
+	sel.Pipelines["logs"] = otelpipelinetypes.Pipeline{
+		Receivers: []string{"otlp", "hostmetrics"},
+		Exporters: []string{"watchdataexporter"},
+	}
+	sel.Params = otelpipelinetypes.ComponentValues{}
+
+	_, err := otelpipeline.Generate(sel)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `service.pipelines.logs.receivers[1]: receiver "hostmetrics" does not support logs`)
+}
+
+func TestGenerateBuilderModules(t *testing.T) {
+	sel := logsSelection()
+	sel.Pipelines["logs"] = otelpipelinetypes.Pipeline{
+		Receivers: []string{"otlp"},
+		Exporters: []string{"watchdataexporter"},
+	}
+
+	sel.Params = otelpipelinetypes.ComponentValues{}
+
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	assert.Equal(t, otelpipeline.DefaultDist, res.Builder.Dist)
+	require.Len(t, res.Builder.Receivers, 1)
+	assert.Contains(t, res.Builder.Receivers[0].Gomod, "otlpreceiver")
+	assert.Empty(t, res.Builder.Processors)
+	require.Len(t, res.Builder.Exporters, 1)
+	assert.Equal(t, "github.com/Ricky004/watchdata/pkg/watchdataexporter", res.Builder.Exporters[0].Import)
+}
+
+func TestGenerateRelease(t *testing.T) {
+	sel := logsSelection()
+	sel.Release = "0.128.0"
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+	assert.Equal(t, "0.128.0", res.Release)
+	for _, m := range append(res.Builder.Receivers, res.Builder.Processors...) {
+		assert.True(t, strings.HasSuffix(m.Gomod, " v0.128.0"), m.Gomod)
+	}
+
+	sel.Release = "0.1.0"
+	_, err = otelpipeline.Generate(sel)
+	assert.ErrorContains(t, err, `unknown collector release "0.1.0"`)
+}
+
+func TestKeepBuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builder-config.yaml")
+	var sel otelpipelinetypes.Selection
+	require.NoError(t, otelpipeline.KeepBuild(&sel, path))
+	assert.Nil(t, sel.Dist)
+	assert.Empty(t, sel.Release)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+dist:
+  name: mycollector
+receivers:
+  - gomod: go.opentelemetry.io/collector/receiver/otlpreceiver v0.126.0
+processors:
+  - gomod: go.opentelemetry.io/collector/processor/batchprocessor v0.127.0
+  - gomod: github.com/open-telemetry/opentelemetry-collector-contrib/processor/filterprocessor v0.126.0
+`), 0644))
+	require.NoError(t, otelpipeline.KeepBuild(&sel, path))
+	require.NotNil(t, sel.Dist)
+	assert.Equal(t, "mycollector", sel.Dist.Name)
+	// Mixed versions are repinned to the release most modules are at.
+	assert.Equal(t, "0.126.0", sel.Release)
+
+	sel = otelpipelinetypes.Selection{Release: "0.128.0"}
+	require.NoError(t, otelpipeline.KeepBuild(&sel, path))
+	assert.Equal(t, "0.128.0", sel.Release)
+}
+
+func TestWriteFilesAndLoadSelection(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "selection.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+pipelines:
+  logs:
+    receivers: [otlp]
+    exporters: [watchdataexporter]
+dist:
+  name: mycollector
+  output_path: ./out
+`), 0644))
+
+	sel, err := otelpipeline.LoadSelection(path)
+	require.NoError(t, err)
+	res, err := otelpipeline.Generate(sel)
+	require.NoError(t, err)
+
+	collector, builder := filepath.Join(dir, "collector.yaml"), filepath.Join(dir, "builder.yaml")
+	require.NoError(t, res.WriteFiles(collector, builder))
+	data, err := os.ReadFile(builder)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "name: mycollector")
+	data, err = os.ReadFile(collector)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "endpoint: 0.0.0.0:4317")
+
+	require.NoError(t, os.WriteFile(path, []byte("pipelnes: {}\n"), 0644))
+	_, err = otelpipeline.LoadSelection(path)
+	assert.Error(t, err)
+}
+
+func endpoint(block interface{}) interface{} {
+	protocols := block.(map[string]interface{})["protocols"].(map[string]interface{})
+	return protocols["grpc"].(map[string]interface{})["endpoint"]
+}
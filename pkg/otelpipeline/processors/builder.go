@@ -1,11 +1,31 @@
 package processors
 
-func BuildProcessors(selected []string) map[string]interface{} {
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// BuildProcessors returns the config blocks of the selected processors, keyed by
+// name, rendered with the params given for each name. Named components
+// such as "otlp/2" use the template of their type unless there is a
+// preset of that name. Unknown processors and invalid params are an error.
+func BuildProcessors(selected []string, params map[string]map[string]interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
+	var errs []error
 	for _, name := range selected {
-		if tmpl, ok := ProcessorTemplates[name]; ok {
-			result[name] = tmpl
+		tmpl, ok := otelpipelinetypes.LookupTemplate(ProcessorTemplates, name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown processor %q", name))
+			continue
+		}
+		cfg, err := tmpl.Render(params[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("processor %q: %w", name, err))
+			continue
 		}
+		result[name] = cfg
 	}
-	return result
-}
\ No newline at end of file
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,181 @@
+package processors
+
+import "github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+
+var (
+	stringList = &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}}
+	errorMode  = &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString, Enum: []string{"ignore", "silent", "propagate"}, Default: "propagate"}
+)
+
+// attributeActions describes the actions of the attributes and resource
+// processors.
+var attributeActions = &otelpipelinetypes.Schema{
+	Type: otelpipelinetypes.TypeList,
+	Items: &otelpipelinetypes.Schema{
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"key":            {Type: otelpipelinetypes.TypeString},
+			"pattern":        {Type: otelpipelinetypes.TypeString},
+			"action":         {Type: otelpipelinetypes.TypeString, Required: true, Enum: []string{"insert", "update", "upsert", "delete", "hash", "extract", "convert"}},
+			"value":          {Type: otelpipelinetypes.TypeAny},
+			"from_attribute": {Type: otelpipelinetypes.TypeString},
+			"from_context":   {Type: otelpipelinetypes.TypeString},
+			"converted_type": {Type: otelpipelinetypes.TypeString, Enum: []string{"int", "double", "string"}},
+		},
+	},
+}
+
+// ProcessorSchemas describe the config of every processor in
+// ProcessorTemplates.
+var ProcessorSchemas = map[string]*otelpipelinetypes.Schema{
+	"batch": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"send_batch_size":            {Type: otelpipelinetypes.TypeInt, Default: 8192},
+			"send_batch_max_size":        {Type: otelpipelinetypes.TypeInt, Default: 0},
+			"timeout":                    {Type: otelpipelinetypes.TypeDuration, Default: "200ms"},
+			"metadata_keys":              stringList,
+			"metadata_cardinality_limit": {Type: otelpipelinetypes.TypeInt, Default: 1000},
+		},
+	},
+
+	"memory_limiter": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"check_interval":         {Type: otelpipelinetypes.TypeDuration, Required: true},
+			"limit_mib":              {Type: otelpipelinetypes.TypeInt},
+			"spike_limit_mib":        {Type: otelpipelinetypes.TypeInt},
+			"limit_percentage":       {Type: otelpipelinetypes.TypeInt},
+			"spike_limit_percentage": {Type: otelpipelinetypes.TypeInt},
+		},
+	},
+
+	"attributes": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"actions": attributeActions,
+			"include": {Type: otelpipelinetypes.TypeObject, Open: true},
+			"exclude": {Type: otelpipelinetypes.TypeObject, Open: true},
+		},
+	},
+
+	"resource": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"attributes": attributeActions,
+		},
+	},
+
+	"resourcedetection": {
+		Type: otelpipelinetypes.TypeObject,
+		Open: true,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"detectors": {
+				Type: otelpipelinetypes.TypeList,
+				Items: &otelpipelinetypes.Schema{
+					Type: otelpipelinetypes.TypeString,
+					Enum: []string{"env", "system", "docker", "ec2", "ecs", "eks", "elasticbeanstalk", "lambda", "gcp", "azure", "aks", "consul", "heroku", "k8snode", "openshift", "kubeadm", "dynatrace", "akamai", "scaleway", "upcloud", "vultr", "hetzner", "openstacknova", "oraclecloud"},
+				},
+			},
+			"timeout":  {Type: otelpipelinetypes.TypeDuration, Default: "5s"},
+			"override": {Type: otelpipelinetypes.TypeBool, Default: true},
+		},
+	},
+
+	"filter": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"error_mode": errorMode,
+			"logs": {
+				Type:   otelpipelinetypes.TypeObject,
+				Fields: map[string]*otelpipelinetypes.Schema{"log_record": stringList},
+			},
+			"metrics": {
+				Type:   otelpipelinetypes.TypeObject,
+				Fields: map[string]*otelpipelinetypes.Schema{"metric": stringList, "datapoint": stringList},
+			},
+			"traces": {
+				Type:   otelpipelinetypes.TypeObject,
+				Fields: map[string]*otelpipelinetypes.Schema{"span": stringList, "spanevent": stringList},
+			},
+		},
+	},
+
+	"transform": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"error_mode": errorMode,
+			// Statements are either strings or groups with a context.
+			"log_statements":    {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeAny}},
+			"metric_statements": {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeAny}},
+			"trace_statements":  {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeAny}},
+		},
+	},
+
+	"redaction": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"allow_all_keys":       {Type: otelpipelinetypes.TypeBool, Default: false},
+			"allowed_keys":         stringList,
+			"ignored_keys":         stringList,
+			"blocked_key_patterns": stringList,
+			"blocked_values":       stringList,
+			"allowed_values":       stringList,
+			"hash_function":        {Type: otelpipelinetypes.TypeString, Enum: []string{"md5", "sha1", "sha3"}},
+			"summary":              {Type: otelpipelinetypes.TypeString, Enum: []string{"debug", "info", "silent"}, Default: "info"},
+		},
+	},
+
+	"k8sattributes": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"auth_type":   {Type: otelpipelinetypes.TypeString, Enum: []string{"serviceAccount", "kubeConfig", "none"}, Default: "serviceAccount"},
+			"passthrough": {Type: otelpipelinetypes.TypeBool, Default: false},
+			"filter":      {Type: otelpipelinetypes.TypeObject, Open: true},
+			"extract": {
+				Type: otelpipelinetypes.TypeObject,
+				Open: true,
+				Fields: map[string]*otelpipelinetypes.Schema{
+					"metadata": stringList,
+				},
+			},
+			"pod_association":           {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeObject, Open: true}},
+			"exclude":                   {Type: otelpipelinetypes.TypeObject, Open: true},
+			"wait_for_metadata":         {Type: otelpipelinetypes.TypeBool, Default: false},
+			"wait_for_metadata_timeout": {Type: otelpipelinetypes.TypeDuration, Default: "10s"},
+		},
+	},
+
+	"tail_sampling": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"decision_wait":               {Type: otelpipelinetypes.TypeDuration, Default: "30s"},
+			"num_traces":                  {Type: otelpipelinetypes.TypeInt, Default: 50000},
+			"expected_new_traces_per_sec": {Type: otelpipelinetypes.TypeInt, Default: 0},
+			"decision_cache":              {Type: otelpipelinetypes.TypeObject, Open: true},
+			"policies": {
+				Type:     otelpipelinetypes.TypeList,
+				Required: true,
+				Items: &otelpipelinetypes.Schema{
+					Type: otelpipelinetypes.TypeObject,
+					Open: true,
+					Fields: map[string]*otelpipelinetypes.Schema{
+						"name": {Type: otelpipelinetypes.TypeString, Required: true},
+						"type": {
+							Type:     otelpipelinetypes.TypeString,
+							Required: true,
+							Enum:     []string{"always_sample", "latency", "numeric_attribute", "probabilistic", "status_code", "string_attribute", "rate_limiting", "span_count", "trace_state", "boolean_attribute", "ottl_condition", "and", "composite", "drop"},
+						},
+					},
+				},
+			},
+		},
+	},
+
+	"groupbyattrs": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"keys": stringList,
+		},
+	},
+}
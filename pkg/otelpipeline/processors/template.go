@@ -1,8 +1,230 @@
 package processors
 
-var ProcessorTemplates = map[string]interface{}{
-	"batch": map[string]interface{}{
-		"send_batch_size": 10000,
-		"timeout": "10s",
+import "github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+
+var allSignals = []string{otelpipelinetypes.SignalLogs, otelpipelinetypes.SignalMetrics, otelpipelinetypes.SignalTraces}
+
+// ProcessorTemplates are the processors a pipeline can use, keyed by type.
+// Processors run in the order a pipeline lists them; memory_limiter must
+// come first and batch last.
+var ProcessorTemplates = map[string]otelpipelinetypes.Template{
+	"memory_limiter": {
+		Description: "Refuses data while the collector is short of memory, so it does not run out",
+		Signals:     allSignals,
+		Position:    otelpipelinetypes.PositionFirst,
+		Params: []otelpipelinetypes.Param{
+			{Name: "check_interval", Description: "How often memory usage is measured", Default: "1s"},
+			{Name: "limit_percentage", Description: "Share of the available memory the collector may use", Default: 80},
+			{Name: "spike_limit_percentage", Description: "Headroom kept below the limit for spikes between checks", Default: 25},
+		},
+		Config: map[string]interface{}{
+			"check_interval":         "{{check_interval}}",
+			"limit_percentage":       "{{limit_percentage}}",
+			"spike_limit_percentage": "{{spike_limit_percentage}}",
+		},
+	},
+
+	"batch": {
+		Description: "Batches records before export",
+		Signals:     allSignals,
+		Position:    otelpipelinetypes.PositionLast,
+		Params: []otelpipelinetypes.Param{
+			{Name: "send_batch_size", Description: "Records per batch", Default: 10000},
+			{Name: "timeout", Description: "Longest a record waits for its batch to fill", Default: "10s"},
+		},
+		Config: map[string]interface{}{
+			"send_batch_size": "{{send_batch_size}}",
+			"timeout":         "{{timeout}}",
+		},
+	},
+
+	"attributes": {
+		Description: "Inserts, updates, hashes or deletes record attributes",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{
+				Name:        "actions",
+				Description: "Actions applied in order, each with a key, an action and, depending on it, a value",
+				Required:    true,
+				Example: []map[string]interface{}{
+					{"key": "environment", "value": "production", "action": "upsert"},
+					{"key": "user.password", "action": "delete"},
+				},
+			},
+		},
+		Config: map[string]interface{}{
+			"actions": "{{actions}}",
+		},
+	},
+
+	"resource": {
+		Description: "Inserts, updates or deletes resource attributes",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{
+				Name:        "attributes",
+				Description: "Actions applied in order, as in the attributes processor",
+				Required:    true,
+				Example: []map[string]interface{}{
+					{"key": "deployment.environment", "value": "production", "action": "upsert"},
+				},
+			},
+		},
+		Config: map[string]interface{}{
+			"attributes": "{{attributes}}",
+		},
+	},
+
+	"resourcedetection": {
+		Description: "Adds resource attributes of the host, cloud or container the collector runs on",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{Name: "detectors", Description: "Detectors to run in order, such as env, system, docker, ec2, gcp, azure or eks", Default: []string{"env", "system"}},
+			{Name: "timeout", Description: "Longest detection may take", Default: "5s"},
+			{Name: "override", Description: "Replace attributes the data already has", Default: false},
+		},
+		Config: map[string]interface{}{
+			"detectors": "{{detectors}}",
+			"timeout":   "{{timeout}}",
+			"override":  "{{override}}",
+		},
+	},
+
+	"filter": {
+		Description: "Drops records matching OTTL conditions",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{Name: "error_mode", Description: "What to do when a condition fails: ignore, silent or propagate", Default: "ignore"},
+			{Name: "log_conditions", Description: "Log records matching any of these are dropped", Example: []string{"severity_number < SEVERITY_NUMBER_INFO"}},
+			{Name: "metric_conditions", Description: "Metrics matching any of these are dropped"},
+			{Name: "span_conditions", Description: "Spans matching any of these are dropped"},
+		},
+		Config: map[string]interface{}{
+			"error_mode": "{{error_mode}}",
+			"logs": map[string]interface{}{
+				"log_record": "{{log_conditions}}",
+			},
+			"metrics": map[string]interface{}{
+				"metric": "{{metric_conditions}}",
+			},
+			"traces": map[string]interface{}{
+				"span": "{{span_conditions}}",
+			},
+		},
+	},
+
+	"transform": {
+		Description: "Modifies records with OTTL statements",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{Name: "error_mode", Description: "What to do when a statement fails: ignore, silent or propagate", Default: "ignore"},
+			{Name: "log_statements", Description: "Statements run on logs, their context taken from the paths they use", Example: []string{`set(log.attributes["env"], "production")`}},
+			{Name: "metric_statements", Description: "Statements run on metrics"},
+			{Name: "trace_statements", Description: "Statements run on traces"},
+		},
+		Config: map[string]interface{}{
+			"error_mode":        "{{error_mode}}",
+			"log_statements":    "{{log_statements}}",
+			"metric_statements": "{{metric_statements}}",
+			"trace_statements":  "{{trace_statements}}",
+		},
+	},
+
+	"redaction": {
+		Description: "Masks attribute values that look like secrets or personal data",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{Name: "allow_all_keys", Description: "Keep attributes whatever their key, only masking blocked values", Default: true},
+			{Name: "allowed_keys", Description: "Attributes to keep when allow_all_keys is false"},
+			{
+				Name:        "blocked_values",
+				Description: "Regular expressions of values to mask",
+				Default: []string{
+					`4[0-9]{12}(?:[0-9]{3})?`,
+					`(5[1-5][0-9]{14})`,
+					`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`,
+				},
+			},
+			{Name: "summary", Description: "Detail of the redaction summary attributes: debug, info or silent", Default: "silent"},
+		},
+		Config: map[string]interface{}{
+			"allow_all_keys": "{{allow_all_keys}}",
+			"allowed_keys":   "{{allowed_keys}}",
+			"blocked_values": "{{blocked_values}}",
+			"summary":        "{{summary}}",
+		},
+	},
+
+	"k8sattributes": {
+		Description: "Adds the namespace, pod, deployment and node of the Kubernetes pod that sent the data",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{Name: "auth_type", Description: "How to reach the Kubernetes API: serviceAccount, kubeConfig or none", Default: "serviceAccount"},
+			{Name: "passthrough", Description: "Only add the pod IP, for agents that forward to a gateway", Default: false},
+			{Name: "node_from_env_var", Description: "Environment variable naming the node, to only watch its pods when running as an agent"},
+			{
+				Name:        "metadata",
+				Description: "Resource attributes to add",
+				Default:     []string{"k8s.namespace.name", "k8s.pod.name", "k8s.pod.uid", "k8s.deployment.name", "k8s.node.name"},
+			},
+		},
+		Config: map[string]interface{}{
+			"auth_type":   "{{auth_type}}",
+			"passthrough": "{{passthrough}}",
+			"filter": map[string]interface{}{
+				"node_from_env_var": "{{node_from_env_var}}",
+			},
+			"extract": map[string]interface{}{
+				"metadata": "{{metadata}}",
+			},
+			"pod_association": []map[string]interface{}{
+				{"sources": []map[string]interface{}{{"from": "resource_attribute", "name": "k8s.pod.ip"}}},
+				{"sources": []map[string]interface{}{{"from": "resource_attribute", "name": "k8s.pod.uid"}}},
+				{"sources": []map[string]interface{}{{"from": "connection"}}},
+			},
+		},
+	},
+
+	"tail_sampling": {
+		Description: "Samples whole traces once complete, keeping errors, slow traces and a share of the rest",
+		Signals:     []string{otelpipelinetypes.SignalTraces},
+		Params: []otelpipelinetypes.Param{
+			{Name: "decision_wait", Description: "How long after its first span a trace is decided on", Default: "10s"},
+			{Name: "num_traces", Description: "Traces kept in memory awaiting a decision", Default: 50000},
+			{Name: "latency_threshold_ms", Description: "Traces at least this slow are kept", Default: 1000},
+			{Name: "sampling_percentage", Description: "Share of the other traces kept", Default: 10},
+		},
+		Config: map[string]interface{}{
+			"decision_wait": "{{decision_wait}}",
+			"num_traces":    "{{num_traces}}",
+			"policies": []map[string]interface{}{
+				{
+					"name":        "errors",
+					"type":        "status_code",
+					"status_code": map[string]interface{}{"status_codes": []string{"ERROR"}},
+				},
+				{
+					"name":    "slow",
+					"type":    "latency",
+					"latency": map[string]interface{}{"threshold_ms": "{{latency_threshold_ms}}"},
+				},
+				{
+					"name":          "sample",
+					"type":          "probabilistic",
+					"probabilistic": map[string]interface{}{"sampling_percentage": "{{sampling_percentage}}"},
+				},
+			},
+		},
+	},
+
+	"groupbyattrs": {
+		Description: "Moves the given record attributes to the resource, regrouping records by them",
+		Signals:     allSignals,
+		Params: []otelpipelinetypes.Param{
+			{Name: "keys", Description: "Attributes to group by", Required: true, Example: []string{"host.name"}},
+		},
+		Config: map[string]interface{}{
+			"keys": "{{keys}}",
+		},
 	},
-}
\ No newline at end of file
+}
@@ -1,11 +1,31 @@
 package receviers
 
-func BuildReceivers(selected []string) map[string]interface{} {
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// BuildReceivers returns the config blocks of the selected receivers, keyed by
+// name, rendered with the params given for each name. Named components
+// such as "otlp/2" use the template of their type unless there is a
+// preset of that name. Unknown receivers and invalid params are an error.
+func BuildReceivers(selected []string, params map[string]map[string]interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
+	var errs []error
 	for _, name := range selected {
-		if tmpl, ok := ReceiverTemplates[name]; ok {
-			result[name] = tmpl
+		tmpl, ok := otelpipelinetypes.LookupTemplate(ReceiverTemplates, name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown receiver %q", name))
+			continue
+		}
+		cfg, err := tmpl.Render(params[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("receiver %q: %w", name, err))
+			continue
 		}
+		result[name] = cfg
 	}
-	return result
+	return result, errors.Join(errs...)
 }
@@ -0,0 +1,226 @@
+package receviers
+
+import "github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+
+// ReceiverSchemas describe the config of every receiver in
+// ReceiverTemplates.
+var ReceiverSchemas = map[string]*otelpipelinetypes.Schema{
+	"otlp": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"protocols": {
+				Type:     otelpipelinetypes.TypeObject,
+				Required: true,
+				Fields: map[string]*otelpipelinetypes.Schema{
+					"grpc": {
+						Type: otelpipelinetypes.TypeObject,
+						Fields: map[string]*otelpipelinetypes.Schema{
+							"endpoint":               {Type: otelpipelinetypes.TypeString, Default: "localhost:4317"},
+							"transport":              {Type: otelpipelinetypes.TypeString, Enum: []string{"tcp", "tcp4", "tcp6", "unix"}, Default: "tcp"},
+							"max_recv_msg_size_mib":  {Type: otelpipelinetypes.TypeInt, Default: 4},
+							"max_concurrent_streams": {Type: otelpipelinetypes.TypeInt},
+							"read_buffer_size":       {Type: otelpipelinetypes.TypeInt},
+							"write_buffer_size":      {Type: otelpipelinetypes.TypeInt},
+							"include_metadata":       {Type: otelpipelinetypes.TypeBool, Default: false},
+							"keepalive":              {Type: otelpipelinetypes.TypeObject, Open: true},
+							"tls":                    {Type: otelpipelinetypes.TypeObject, Open: true},
+							"auth":                   {Type: otelpipelinetypes.TypeObject, Open: true},
+						},
+					},
+					"http": {
+						Type: otelpipelinetypes.TypeObject,
+						Fields: map[string]*otelpipelinetypes.Schema{
+							"endpoint":              {Type: otelpipelinetypes.TypeString, Default: "localhost:4318"},
+							"logs_url_path":         {Type: otelpipelinetypes.TypeString, Default: "/v1/logs"},
+							"traces_url_path":       {Type: otelpipelinetypes.TypeString, Default: "/v1/traces"},
+							"metrics_url_path":      {Type: otelpipelinetypes.TypeString, Default: "/v1/metrics"},
+							"max_request_body_size": {Type: otelpipelinetypes.TypeInt},
+							"include_metadata":      {Type: otelpipelinetypes.TypeBool, Default: false},
+							"cors":                  {Type: otelpipelinetypes.TypeObject, Open: true},
+							"response_headers":      {Type: otelpipelinetypes.TypeObject, Open: true},
+							"tls":                   {Type: otelpipelinetypes.TypeObject, Open: true},
+							"auth":                  {Type: otelpipelinetypes.TypeObject, Open: true},
+						},
+					},
+				},
+			},
+		},
+	},
+
+	"filelog": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"include":                       {Type: otelpipelinetypes.TypeList, Required: true, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}},
+			"exclude":                       {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}},
+			"start_at":                      {Type: otelpipelinetypes.TypeString, Enum: []string{"beginning", "end"}, Default: "end"},
+			"poll_interval":                 {Type: otelpipelinetypes.TypeDuration, Default: "200ms"},
+			"encoding":                      {Type: otelpipelinetypes.TypeString, Default: "utf-8"},
+			"include_file_name":             {Type: otelpipelinetypes.TypeBool, Default: true},
+			"include_file_path":             {Type: otelpipelinetypes.TypeBool, Default: false},
+			"include_file_name_resolved":    {Type: otelpipelinetypes.TypeBool, Default: false},
+			"include_file_path_resolved":    {Type: otelpipelinetypes.TypeBool, Default: false},
+			"max_log_size":                  {Type: otelpipelinetypes.TypeString, Default: "1MiB"},
+			"max_concurrent_files":          {Type: otelpipelinetypes.TypeInt, Default: 1024},
+			"max_batches":                   {Type: otelpipelinetypes.TypeInt},
+			"fingerprint_size":              {Type: otelpipelinetypes.TypeString, Default: "1kb"},
+			"storage":                       {Type: otelpipelinetypes.TypeString},
+			"delete_after_read":             {Type: otelpipelinetypes.TypeBool, Default: false},
+			"multiline":                     {Type: otelpipelinetypes.TypeObject, Open: true},
+			"header":                        {Type: otelpipelinetypes.TypeObject, Open: true},
+			"retry_on_failure":              {Type: otelpipelinetypes.TypeObject, Open: true},
+			"attributes":                    {Type: otelpipelinetypes.TypeObject, Open: true},
+			"resource":                      {Type: otelpipelinetypes.TypeObject, Open: true},
+			"preserve_leading_whitespaces":  {Type: otelpipelinetypes.TypeBool, Default: false},
+			"preserve_trailing_whitespaces": {Type: otelpipelinetypes.TypeBool, Default: false},
+			"operators": {
+				Type: otelpipelinetypes.TypeList,
+				Items: &otelpipelinetypes.Schema{
+					Type: otelpipelinetypes.TypeObject,
+					Open: true,
+					Fields: map[string]*otelpipelinetypes.Schema{
+						"type": {Type: otelpipelinetypes.TypeString, Required: true},
+						"id":   {Type: otelpipelinetypes.TypeString},
+					},
+				},
+			},
+		},
+	},
+
+	"syslog": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"protocol":                        {Type: otelpipelinetypes.TypeString, Required: true, Enum: []string{"rfc3164", "rfc5424"}},
+			"location":                        {Type: otelpipelinetypes.TypeString, Default: "UTC"},
+			"enable_octet_counting":           {Type: otelpipelinetypes.TypeBool, Default: false},
+			"allow_skip_pri_header":           {Type: otelpipelinetypes.TypeBool, Default: false},
+			"non_transparent_framing_trailer": {Type: otelpipelinetypes.TypeString, Enum: []string{"LF", "NUL"}},
+			"max_octets":                      {Type: otelpipelinetypes.TypeInt, Default: 8192},
+			"tcp": {
+				Type:   otelpipelinetypes.TypeObject,
+				Open:   true,
+				Fields: map[string]*otelpipelinetypes.Schema{"listen_address": {Type: otelpipelinetypes.TypeString, Required: true}},
+			},
+			"udp": {
+				Type:   otelpipelinetypes.TypeObject,
+				Open:   true,
+				Fields: map[string]*otelpipelinetypes.Schema{"listen_address": {Type: otelpipelinetypes.TypeString, Required: true}},
+			},
+			"operators":        {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeObject, Open: true}},
+			"retry_on_failure": {Type: otelpipelinetypes.TypeObject, Open: true},
+		},
+	},
+
+	"journald": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"directory":        {Type: otelpipelinetypes.TypeString},
+			"files":            {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}},
+			"units":            {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}},
+			"identifiers":      {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}},
+			"matches":          {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeObject, Open: true}},
+			"priority":         {Type: otelpipelinetypes.TypeString, Enum: []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}, Default: "info"},
+			"grep":             {Type: otelpipelinetypes.TypeString},
+			"dmesg":            {Type: otelpipelinetypes.TypeBool, Default: false},
+			"all":              {Type: otelpipelinetypes.TypeBool, Default: false},
+			"start_at":         {Type: otelpipelinetypes.TypeString, Enum: []string{"beginning", "end"}, Default: "end"},
+			"storage":          {Type: otelpipelinetypes.TypeString},
+			"root_path":        {Type: otelpipelinetypes.TypeString},
+			"journalctl_path":  {Type: otelpipelinetypes.TypeString, Default: "journalctl"},
+			"operators":        {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeObject, Open: true}},
+			"retry_on_failure": {Type: otelpipelinetypes.TypeObject, Open: true},
+		},
+	},
+
+	"fluentforward": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"endpoint": {Type: otelpipelinetypes.TypeString, Required: true},
+		},
+	},
+
+	"hostmetrics": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"collection_interval": {Type: otelpipelinetypes.TypeDuration, Default: "1m"},
+			"initial_delay":       {Type: otelpipelinetypes.TypeDuration, Default: "1s"},
+			"root_path":           {Type: otelpipelinetypes.TypeString},
+			"scrapers": {
+				Type:     otelpipelinetypes.TypeObject,
+				Required: true,
+				Fields: map[string]*otelpipelinetypes.Schema{
+					"cpu":        {Type: otelpipelinetypes.TypeObject, Open: true},
+					"disk":       {Type: otelpipelinetypes.TypeObject, Open: true},
+					"filesystem": {Type: otelpipelinetypes.TypeObject, Open: true},
+					"load":       {Type: otelpipelinetypes.TypeObject, Open: true},
+					"memory":     {Type: otelpipelinetypes.TypeObject, Open: true},
+					"network":    {Type: otelpipelinetypes.TypeObject, Open: true},
+					"paging":     {Type: otelpipelinetypes.TypeObject, Open: true},
+					"processes":  {Type: otelpipelinetypes.TypeObject, Open: true},
+					"process":    {Type: otelpipelinetypes.TypeObject, Open: true},
+					"system":     {Type: otelpipelinetypes.TypeObject, Open: true},
+				},
+			},
+		},
+	},
+
+	"kubeletstats": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"endpoint":              {Type: otelpipelinetypes.TypeString},
+			"auth_type":             {Type: otelpipelinetypes.TypeString, Enum: []string{"serviceAccount", "tls", "kubeConfig", "none"}, Default: "tls"},
+			"collection_interval":   {Type: otelpipelinetypes.TypeDuration, Default: "10s"},
+			"initial_delay":         {Type: otelpipelinetypes.TypeDuration, Default: "1s"},
+			"insecure_skip_verify":  {Type: otelpipelinetypes.TypeBool, Default: false},
+			"ca_file":               {Type: otelpipelinetypes.TypeString},
+			"cert_file":             {Type: otelpipelinetypes.TypeString},
+			"key_file":              {Type: otelpipelinetypes.TypeString},
+			"context":               {Type: otelpipelinetypes.TypeString},
+			"node":                  {Type: otelpipelinetypes.TypeString},
+			"metric_groups":         {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString, Enum: []string{"container", "pod", "node", "volume"}}},
+			"extra_metadata_labels": {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}},
+			"k8s_api_config":        {Type: otelpipelinetypes.TypeObject, Open: true},
+			"metrics":               {Type: otelpipelinetypes.TypeObject, Open: true},
+			"resource_attributes":   {Type: otelpipelinetypes.TypeObject, Open: true},
+		},
+	},
+
+	"prometheus": {
+		Type: otelpipelinetypes.TypeObject,
+		Fields: map[string]*otelpipelinetypes.Schema{
+			"config": {
+				Type:     otelpipelinetypes.TypeObject,
+				Required: true,
+				Open:     true,
+				Fields: map[string]*otelpipelinetypes.Schema{
+					"scrape_configs": {
+						Type: otelpipelinetypes.TypeList,
+						Items: &otelpipelinetypes.Schema{
+							Type: otelpipelinetypes.TypeObject,
+							Open: true,
+							Fields: map[string]*otelpipelinetypes.Schema{
+								"job_name":        {Type: otelpipelinetypes.TypeString, Required: true},
+								"scrape_interval": {Type: otelpipelinetypes.TypeDuration},
+								"metrics_path":    {Type: otelpipelinetypes.TypeString},
+								"static_configs": {
+									Type: otelpipelinetypes.TypeList,
+									Items: &otelpipelinetypes.Schema{
+										Type: otelpipelinetypes.TypeObject,
+										Open: true,
+										Fields: map[string]*otelpipelinetypes.Schema{
+											"targets": {Type: otelpipelinetypes.TypeList, Items: &otelpipelinetypes.Schema{Type: otelpipelinetypes.TypeString}},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"target_allocator":        {Type: otelpipelinetypes.TypeObject, Open: true},
+			"api_server":              {Type: otelpipelinetypes.TypeObject, Open: true},
+			"trim_metric_suffixes":    {Type: otelpipelinetypes.TypeBool, Default: false},
+			"use_start_time_metric":   {Type: otelpipelinetypes.TypeBool, Default: false},
+			"start_time_metric_regex": {Type: otelpipelinetypes.TypeString},
+		},
+	},
+}
@@ -1,23 +1,307 @@
 package receviers
 
-var ReceiverTemplates = map[string]interface{}{
-	"otlp": map[string]interface{}{
-		"protocols": map[string]interface{}{
-			"grpc": map[string]interface{}{
-				"endpoint": "0.0.0.0:4317",
-			},
-		},
-	},
-	
-	"filelog": map[string]interface{}{
-		"include":  []string{"../tmp/test.json"},
-		"start_at": "beginning",
-		"operators": []map[string]interface{}{
-			{
-				"type": "json_parser",
-				"timestamp": map[string]interface{}{
-					"parse_from": "attributes.time",
-					"layout":     "%Y-%m-%d %H:%M:%S",
+import "github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+
+// ReceiverTemplates are the receivers a pipeline can use, keyed by type.
+// Entries with a name, such as "filelog/nginx", are presets of their type
+// used by receivers of exactly that name.
+var ReceiverTemplates = map[string]otelpipelinetypes.Template{
+	"otlp": {
+		Description: "OTLP over gRPC and HTTP, from OpenTelemetry SDKs and other collectors",
+		Signals:     []string{otelpipelinetypes.SignalLogs, otelpipelinetypes.SignalMetrics, otelpipelinetypes.SignalTraces},
+		Params: []otelpipelinetypes.Param{
+			{Name: "grpc_endpoint", Description: "Address the gRPC server listens on", Default: "0.0.0.0:4317"},
+			{Name: "http_endpoint", Description: "Address the HTTP server listens on", Default: "0.0.0.0:4318"},
+		},
+		Config: map[string]interface{}{
+			"protocols": map[string]interface{}{
+				"grpc": map[string]interface{}{
+					"endpoint": "{{grpc_endpoint}}",
+				},
+				"http": map[string]interface{}{
+					"endpoint": "{{http_endpoint}}",
+				},
+			},
+		},
+	},
+
+	"filelog": {
+		Description: "Tails log files, one record per line",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "include", Description: "Glob patterns of the files to read", Required: true, Example: []string{"/var/log/app/*.log"}},
+			{Name: "exclude", Description: "Glob patterns of files to skip"},
+			{Name: "start_at", Description: "Read new files from the beginning or the end", Default: "end"},
+		},
+		Config: map[string]interface{}{
+			"include":           "{{include}}",
+			"exclude":           "{{exclude}}",
+			"start_at":          "{{start_at}}",
+			"include_file_path": true,
+		},
+	},
+
+	"filelog/json": {
+		Description: "Tails files of JSON log lines, taking the timestamp and severity from their fields",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "include", Description: "Glob patterns of the files to read", Required: true, Example: []string{"/var/log/app/*.json"}},
+			{Name: "start_at", Description: "Read new files from the beginning or the end", Default: "end"},
+			{Name: "time_key", Description: "Field holding the timestamp", Default: "time"},
+			{Name: "time_layout", Description: "Go layout of the timestamp", Default: "2006-01-02T15:04:05.999999999Z07:00"},
+			{Name: "level_key", Description: "Field holding the severity", Default: "level"},
+		},
+		Config: map[string]interface{}{
+			"include":           "{{include}}",
+			"start_at":          "{{start_at}}",
+			"include_file_path": true,
+			"operators": []map[string]interface{}{
+				{
+					"type": "json_parser",
+					"timestamp": map[string]interface{}{
+						"parse_from":  "attributes.{{time_key}}",
+						"layout_type": "gotime",
+						"layout":      "{{time_layout}}",
+					},
+					"severity": map[string]interface{}{
+						"parse_from": "attributes.{{level_key}}",
+					},
+				},
+			},
+		},
+	},
+
+	"filelog/nginx": {
+		Description: "Tails nginx access logs in the combined format",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "include", Description: "Glob patterns of the access logs", Default: []string{"/var/log/nginx/access.log"}},
+			{Name: "start_at", Description: "Read new files from the beginning or the end", Default: "end"},
+		},
+		Config: map[string]interface{}{
+			"include":           "{{include}}",
+			"start_at":          "{{start_at}}",
+			"include_file_path": true,
+			"operators": []map[string]interface{}{
+				{
+					"type":  "regex_parser",
+					"regex": `^(?P<client_address>\S+) - (?P<user>\S+) \[(?P<time>[^\]]+)\] "(?P<method>\S+) (?P<path>\S+) (?P<protocol>[^"]+)" (?P<status>\d{3}) (?P<body_bytes>\d+) "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)"`,
+					"timestamp": map[string]interface{}{
+						"parse_from":  "attributes.time",
+						"layout_type": "strptime",
+						"layout":      "%d/%b/%Y:%H:%M:%S %z",
+					},
+					"severity": map[string]interface{}{
+						"parse_from": "attributes.status",
+						"mapping": map[string]interface{}{
+							"info":  "2xx",
+							"warn":  "4xx",
+							"error": "5xx",
+						},
+					},
+				},
+			},
+		},
+	},
+
+	"filelog/apache": {
+		Description: "Tails Apache httpd access logs in the common or combined format",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "include", Description: "Glob patterns of the access logs", Default: []string{"/var/log/apache2/access.log"}},
+			{Name: "start_at", Description: "Read new files from the beginning or the end", Default: "end"},
+		},
+		Config: map[string]interface{}{
+			"include":           "{{include}}",
+			"start_at":          "{{start_at}}",
+			"include_file_path": true,
+			"operators": []map[string]interface{}{
+				{
+					"type":  "regex_parser",
+					"regex": `^(?P<client_address>\S+) \S+ (?P<user>\S+) \[(?P<time>[^\]]+)\] "(?P<method>\S+) (?P<path>\S+) (?P<protocol>[^"]+)" (?P<status>\d{3}) (?P<body_bytes>\S+)(?: "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)")?`,
+					"timestamp": map[string]interface{}{
+						"parse_from":  "attributes.time",
+						"layout_type": "strptime",
+						"layout":      "%d/%b/%Y:%H:%M:%S %z",
+					},
+					"severity": map[string]interface{}{
+						"parse_from": "attributes.status",
+						"mapping": map[string]interface{}{
+							"info":  "2xx",
+							"warn":  "4xx",
+							"error": "5xx",
+						},
+					},
+				},
+			},
+		},
+	},
+
+	"filelog/klog": {
+		Description: "Tails logs of Kubernetes components in the klog format",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "include", Description: "Glob patterns of the files to read", Required: true, Example: []string{"/var/log/kube-apiserver.log"}},
+			{Name: "start_at", Description: "Read new files from the beginning or the end", Default: "end"},
+		},
+		Config: map[string]interface{}{
+			"include":           "{{include}}",
+			"start_at":          "{{start_at}}",
+			"include_file_path": true,
+			"operators": []map[string]interface{}{
+				{
+					"type":  "regex_parser",
+					"regex": `^(?P<level>[IWEF])(?P<time>\d{4} \d{2}:\d{2}:\d{2}\.\d{6})\s+(?P<thread_id>\d+)\s+(?P<source>[^:\]]+:\d+)\]\s+(?P<message>.*)$`,
+					"timestamp": map[string]interface{}{
+						"parse_from":  "attributes.time",
+						"layout_type": "strptime",
+						"layout":      "%m%d %H:%M:%S.%f",
+					},
+					"severity": map[string]interface{}{
+						"parse_from": "attributes.level",
+						"mapping": map[string]interface{}{
+							"info":  "I",
+							"warn":  "W",
+							"error": "E",
+							"fatal": "F",
+						},
+					},
+				},
+				{
+					"type": "move",
+					"from": "attributes.message",
+					"to":   "body",
+				},
+			},
+		},
+	},
+
+	"filelog/cri": {
+		Description: "Tails Kubernetes pod logs written by containerd or CRI-O, with pod metadata from the file path",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "include", Description: "Glob patterns of the pod log files", Default: []string{"/var/log/pods/*/*/*.log"}},
+			{Name: "exclude", Description: "Glob patterns of files to skip, such as the collector's own", Default: []string{"/var/log/pods/*/otel-collector*/*.log"}},
+			{Name: "start_at", Description: "Read new files from the beginning or the end", Default: "end"},
+			{Name: "format", Description: "Log format: containerd, crio or docker", Default: "containerd"},
+		},
+		Config: map[string]interface{}{
+			"include":           "{{include}}",
+			"exclude":           "{{exclude}}",
+			"start_at":          "{{start_at}}",
+			"include_file_path": true,
+			"operators": []map[string]interface{}{
+				{
+					"type":                       "container",
+					"format":                     "{{format}}",
+					"add_metadata_from_filepath": true,
+				},
+			},
+		},
+	},
+
+	"syslog": {
+		Description: "Syslog messages over TCP",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "listen_address", Description: "Address to listen on", Default: "0.0.0.0:54526"},
+			{Name: "protocol", Description: "Message format: rfc3164 or rfc5424", Default: "rfc5424"},
+		},
+		Config: map[string]interface{}{
+			"protocol": "{{protocol}}",
+			"tcp": map[string]interface{}{
+				"listen_address": "{{listen_address}}",
+			},
+		},
+	},
+
+	"journald": {
+		Description: "Reads the systemd journal",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "directory", Description: "Directory of the journal files", Default: "/var/log/journal"},
+			{Name: "units", Description: "Units to read, all if unset"},
+			{Name: "priority", Description: "Lowest priority to read", Default: "info"},
+			{Name: "start_at", Description: "Read the journal from the beginning or the end", Default: "end"},
+		},
+		Config: map[string]interface{}{
+			"directory": "{{directory}}",
+			"units":     "{{units}}",
+			"priority":  "{{priority}}",
+			"start_at":  "{{start_at}}",
+		},
+	},
+
+	"fluentforward": {
+		Description: "Fluent Forward protocol, from Fluentd and Fluent Bit",
+		Signals:     []string{otelpipelinetypes.SignalLogs},
+		Params: []otelpipelinetypes.Param{
+			{Name: "endpoint", Description: "Address to listen on", Default: "0.0.0.0:8006"},
+		},
+		Config: map[string]interface{}{
+			"endpoint": "{{endpoint}}",
+		},
+	},
+
+	"hostmetrics": {
+		Description: "CPU, memory, disk, filesystem, load and network metrics of the host",
+		Signals:     []string{otelpipelinetypes.SignalMetrics},
+		Params: []otelpipelinetypes.Param{
+			{Name: "collection_interval", Description: "How often to scrape", Default: "30s"},
+			{Name: "root_path", Description: "Host root filesystem, when running in a container"},
+		},
+		Config: map[string]interface{}{
+			"collection_interval": "{{collection_interval}}",
+			"root_path":           "{{root_path}}",
+			"scrapers": map[string]interface{}{
+				"cpu":        map[string]interface{}{},
+				"memory":     map[string]interface{}{},
+				"disk":       map[string]interface{}{},
+				"filesystem": map[string]interface{}{},
+				"load":       map[string]interface{}{},
+				"network":    map[string]interface{}{},
+			},
+		},
+	},
+
+	"kubeletstats": {
+		Description: "Node, pod and container metrics from the kubelet",
+		Signals:     []string{otelpipelinetypes.SignalMetrics},
+		Params: []otelpipelinetypes.Param{
+			{Name: "endpoint", Description: "Kubelet address", Default: "https://${env:K8S_NODE_NAME}:10250"},
+			{Name: "auth_type", Description: "How to authenticate: serviceAccount, tls, kubeConfig or none", Default: "serviceAccount"},
+			{Name: "collection_interval", Description: "How often to scrape", Default: "20s"},
+			{Name: "insecure_skip_verify", Description: "Skip verifying the kubelet's certificate", Default: false},
+		},
+		Config: map[string]interface{}{
+			"endpoint":             "{{endpoint}}",
+			"auth_type":            "{{auth_type}}",
+			"collection_interval":  "{{collection_interval}}",
+			"insecure_skip_verify": "{{insecure_skip_verify}}",
+			"metric_groups":        []string{"node", "pod", "container"},
+		},
+	},
+
+	"prometheus": {
+		Description: "Scrapes Prometheus endpoints",
+		Signals:     []string{otelpipelinetypes.SignalMetrics},
+		Params: []otelpipelinetypes.Param{
+			{Name: "targets", Description: "host:port of the endpoints to scrape", Required: true, Example: []string{"localhost:9090"}},
+			{Name: "job_name", Description: "Job label of the scraped series", Default: "watchdata"},
+			{Name: "scrape_interval", Description: "How often to scrape", Default: "30s"},
+			{Name: "metrics_path", Description: "Path of the metrics", Default: "/metrics"},
+		},
+		Config: map[string]interface{}{
+			"config": map[string]interface{}{
+				"scrape_configs": []map[string]interface{}{
+					{
+						"job_name":        "{{job_name}}",
+						"scrape_interval": "{{scrape_interval}}",
+						"metrics_path":    "{{metrics_path}}",
+						"static_configs": []map[string]interface{}{
+							{"targets": "{{targets}}"},
+						},
+					},
 				},
 			},
 		},
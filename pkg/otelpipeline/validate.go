@@ -0,0 +1,148 @@
+package otelpipeline
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/exporter"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/processors"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/receviers"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+)
+
+// Validate checks every receiver, processor and exporter of cfg against
+// the schema of its type and that every pipeline has receivers and
+// exporters, all of them defined and able to carry the pipeline's signal,
+// with processors pinned to the start or end of a pipeline in place.
+// Extensions and connectors have no schemas; they only have to be defined
+// where the service uses them. All issues are reported in a
+// *otelpipelinetypes.ValidationError, each with the YAML path it was
+// found at.
+func Validate(cfg otelpipelinetypes.OTelConfig) error {
+	var issues []otelpipelinetypes.Issue
+	issues = append(issues, validateComponents("receivers", "receiver", cfg.Receivers, receviers.ReceiverSchemas)...)
+	issues = append(issues, validateComponents("processors", "processor", cfg.Processors, processors.ProcessorSchemas)...)
+	issues = append(issues, validateComponents("exporters", "exporter", cfg.Exporters, exporter.ExporterSchemas)...)
+	issues = append(issues, validateService(cfg)...)
+	if len(issues) > 0 {
+		return &otelpipelinetypes.ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// ValidateFile validates the collector config at path. Sections
+// OTelConfig does not model are not checked.
+func ValidateFile(path string) error {
+	doc, err := LoadDocument(path)
+	if err != nil {
+		return err
+	}
+	return Validate(doc.Config)
+}
+
+func validateComponents(section, kind string, blocks map[string]interface{}, schemas map[string]*otelpipelinetypes.Schema) []otelpipelinetypes.Issue {
+	var issues []otelpipelinetypes.Issue
+	for _, name := range sortedKeys(blocks) {
+		path := otelpipelinetypes.JoinPath(section, name)
+		schema, ok := schemas[otelpipelinetypes.ComponentType(name)]
+		if !ok {
+			issues = append(issues, otelpipelinetypes.Issue{Path: path, Message: fmt.Sprintf("unknown %s type %q", kind, otelpipelinetypes.ComponentType(name))})
+			continue
+		}
+		issues = append(issues, schema.Validate(path, blocks[name])...)
+	}
+	return issues
+}
+
+// validateService checks the pipelines and extensions of the service.
+func validateService(cfg otelpipelinetypes.OTelConfig) []otelpipelinetypes.Issue {
+	var issues []otelpipelinetypes.Issue
+	for i, name := range cfg.Service.Extensions {
+		if _, ok := cfg.Extensions[name]; !ok {
+			issues = append(issues, otelpipelinetypes.Issue{Path: fmt.Sprintf("service.extensions[%d]", i), Message: fmt.Sprintf("extension %q is not defined", name)})
+		}
+	}
+	if len(cfg.Service.Pipelines) == 0 {
+		return append(issues, otelpipelinetypes.Issue{Path: "service.pipelines", Message: "at least one pipeline is required"})
+	}
+
+	// Connectors are both exporters and receivers.
+	receivers, exporters := withConnectors(cfg.Receivers, cfg.Connectors), withConnectors(cfg.Exporters, cfg.Connectors)
+	for _, name := range sortedKeys(cfg.Service.Pipelines) {
+		p := cfg.Service.Pipelines[name]
+		path := otelpipelinetypes.JoinPath("service.pipelines", name)
+		if !slices.Contains(pipelineTypes, otelpipelinetypes.ComponentType(name)) {
+			issues = append(issues, otelpipelinetypes.Issue{Path: path, Message: "pipeline type must be one of " + strings.Join(pipelineTypes, ", ")})
+		}
+		signal := otelpipelinetypes.ComponentType(name)
+		issues = append(issues, validateRefs(otelpipelinetypes.JoinPath(path, "receivers"), "receiver", signal, p.Receivers, receivers, receviers.ReceiverTemplates, true)...)
+		issues = append(issues, validateRefs(otelpipelinetypes.JoinPath(path, "processors"), "processor", signal, p.Processors, cfg.Processors, processors.ProcessorTemplates, false)...)
+		issues = append(issues, validateOrder(otelpipelinetypes.JoinPath(path, "processors"), p.Processors)...)
+		issues = append(issues, validateRefs(otelpipelinetypes.JoinPath(path, "exporters"), "exporter", signal, p.Exporters, exporters, exporter.ExporterTemplates, true)...)
+	}
+	return issues
+}
+
+// validateRefs checks that the components a pipeline names are defined,
+// once each, and support the signal of the pipeline.
+func validateRefs(path, kind, signal string, names []string, defined map[string]interface{}, templates map[string]otelpipelinetypes.Template, required bool) []otelpipelinetypes.Issue {
+	if required && len(names) == 0 {
+		return []otelpipelinetypes.Issue{{Path: path, Message: "at least one " + kind + " is required"}}
+	}
+
+	var issues []otelpipelinetypes.Issue
+	for i, name := range names {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if _, ok := defined[name]; !ok {
+			issues = append(issues, otelpipelinetypes.Issue{Path: itemPath, Message: fmt.Sprintf("%s %q is not defined", kind, name)})
+		}
+		if slices.Index(names, name) < i {
+			issues = append(issues, otelpipelinetypes.Issue{Path: itemPath, Message: fmt.Sprintf("%s %q is listed more than once", kind, name)})
+		}
+		if t, ok := templates[otelpipelinetypes.ComponentType(name)]; ok && !slices.Contains(t.Signals, signal) && slices.Contains(pipelineTypes, signal) {
+			issues = append(issues, otelpipelinetypes.Issue{Path: itemPath, Message: fmt.Sprintf("%s %q does not support %s", kind, name, signal)})
+		}
+	}
+	return issues
+}
+
+// validateOrder checks that processors with a Position, such as
+// memory_limiter and batch, come first or last.
+func validateOrder(path string, names []string) []otelpipelinetypes.Issue {
+	var issues []otelpipelinetypes.Issue
+	for i, name := range names {
+		t := processors.ProcessorTemplates[otelpipelinetypes.ComponentType(name)]
+		switch {
+		case t.Position == otelpipelinetypes.PositionFirst && i != 0:
+			issues = append(issues, otelpipelinetypes.Issue{Path: fmt.Sprintf("%s[%d]", path, i), Message: fmt.Sprintf("processor %q must come first", name)})
+		case t.Position == otelpipelinetypes.PositionLast && i != len(names)-1:
+			issues = append(issues, otelpipelinetypes.Issue{Path: fmt.Sprintf("%s[%d]", path, i), Message: fmt.Sprintf("processor %q must come last", name)})
+		}
+	}
+	return issues
+}
+
+func withConnectors(components, connectors map[string]interface{}) map[string]interface{} {
+	if len(connectors) == 0 {
+		return components
+	}
+	all := make(map[string]interface{}, len(components)+len(connectors))
+	for name, block := range components {
+		all[name] = block
+	}
+	for name, block := range connectors {
+		all[name] = block
+	}
+	return all
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
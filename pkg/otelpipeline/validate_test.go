@@ -0,0 +1,158 @@
+package otelpipeline_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/otelpipeline"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/builderconfig"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/exporter"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/processors"
+	"github.com/Ricky004/watchdata/pkg/otelpipeline/receviers"
+	"github.com/Ricky004/watchdata/pkg/types/otelpipelinetypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTemplatesMatchSchemas(t *testing.T) {
+	check := func(templates map[string]otelpipelinetypes.Template, schemas map[string]*otelpipelinetypes.Schema) {
+		for name, tmpl := range templates {
+			values := make(map[string]interface{})
+			for _, p := range tmpl.Params {
+				if p.Required {
+					require.NotNil(t, p.Example, "%s: required param %q has no example", name, p.Name)
+					values[p.Name] = p.Example
+				}
+			}
+			cfg, err := tmpl.Render(values)
+			require.NoError(t, err, name)
+			require.Contains(t, schemas, otelpipelinetypes.ComponentType(name))
+			assert.Empty(t, schemas[otelpipelinetypes.ComponentType(name)].Validate(name, cfg), name)
+			assert.NotEmpty(t, tmpl.Signals, name)
+		}
+	}
+	check(receviers.ReceiverTemplates, receviers.ReceiverSchemas)
+	check(processors.ProcessorTemplates, processors.ProcessorSchemas)
+	check(exporter.ExporterTemplates, exporter.ExporterSchemas)
+}
+
+func TestModules(t *testing.T) {
+	check := func(templates map[string]otelpipelinetypes.Template, modules map[string]otelpipelinetypes.ModuleEntry) {
+		for name := range templates {
+			assert.Contains(t, modules, otelpipelinetypes.ComponentType(name), name)
+		}
+	}
+	check(receviers.ReceiverTemplates, builderconfig.ReceiverModules)
+	check(processors.ProcessorTemplates, builderconfig.ProcessorModules)
+	check(exporter.ExporterTemplates, builderconfig.ExporterModules)
+}
+
+func issues(t *testing.T, config string) []otelpipelinetypes.Issue {
+	t.Helper()
+	var cfg otelpipelinetypes.OTelConfig
+	require.NoError(t, yaml.Unmarshal([]byte(config), &cfg))
+	err := otelpipeline.Validate(cfg)
+	if err == nil {
+		return nil
+	}
+	var verr *otelpipelinetypes.ValidationError
+	require.ErrorAs(t, err, &verr)
+	return verr.Issues
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, issues(t, `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+processors:
+  batch:
+exporters:
+  watchdataexporter:
+    dsn: tcp://clickhouse:9000/default
+service:
+  pipelines:
+    logs:
+      receivers: [otlp]
+      processors: [batch]
+      exporters: [watchdataexporter]
+`))
+
+	assert.Equal(t, []otelpipelinetypes.Issue{
+		{Path: "receivers.filelog.include[1]", Message: "expected a string, got a number"},
+		{Path: "receivers.filelog.operators[0].type", Message: "required field is missing"},
+		{Path: "receivers.filelog.start_at", Message: `"start" is not one of beginning, end`},
+		{Path: "receivers.kafka", Message: `unknown receiver type "kafka"`},
+		{Path: "processors.batch.send_batch_size", Message: "expected an integer, got a string"},
+		{Path: "processors.batch.timeout", Message: `invalid duration "10 seconds"`},
+		{Path: "exporters.watchdataexporter.dsn", Message: "required field is missing"},
+		{Path: "exporters.watchdataexporter.insecure", Message: "expected true or false, got a string"},
+		{Path: "service.pipelines.logs.receivers[1]", Message: `receiver "otlp" is not defined`},
+		{Path: "service.pipelines.logs.processors[1]", Message: `processor "batch" is listed more than once`},
+		{Path: "service.pipelines.logs.processors[0]", Message: `processor "batch" must come last`},
+		{Path: "service.pipelines.logs.exporters", Message: "at least one exporter is required"},
+		{Path: "service.pipelines.metrics/2.receivers", Message: "at least one receiver is required"},
+		{Path: "service.pipelines.metrics/2.exporters[0]", Message: `exporter "clickhouse" is not defined`},
+	}, issues(t, `
+receivers:
+  filelog:
+    include: [/var/log/a.log, 3]
+    start_at: start
+    operators:
+      - regex: '^(?P<x>.*)$'
+  kafka: {}
+processors:
+  batch:
+    send_batch_size: "1000"
+    timeout: 10 seconds
+exporters:
+  watchdataexporter:
+    insecure: "yes"
+service:
+  pipelines:
+    logs:
+      receivers: [filelog, otlp]
+      processors: [batch, batch]
+    metrics/2:
+      exporters: [clickhouse]
+`))
+}
+
+func TestValidateNoPipelines(t *testing.T) {
+	assert.Equal(t, []otelpipelinetypes.Issue{
+		{Path: "service.pipelines", Message: "at least one pipeline is required"},
+	}, issues(t, "receivers: {}\n"))
+}
+
+func TestValidateProcessorOrder(t *testing.T) {
+	const config = `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+processors:
+  memory_limiter:
+    check_interval: 1s
+  batch:
+  filter:
+  transform:
+exporters:
+  watchdataexporter:
+    dsn: tcp://clickhouse:9000/default
+service:
+  pipelines:
+    logs:
+      receivers: [otlp]
+      processors: [%s]
+      exporters: [watchdataexporter]
+`
+	assert.Empty(t, issues(t, fmt.Sprintf(config, "memory_limiter, filter, transform, batch")))
+	assert.Empty(t, issues(t, fmt.Sprintf(config, "filter")))
+	assert.Equal(t, []otelpipelinetypes.Issue{
+		{Path: "service.pipelines.logs.processors[0]", Message: `processor "batch" must come last`},
+		{Path: "service.pipelines.logs.processors[2]", Message: `processor "memory_limiter" must come first`},
+	}, issues(t, fmt.Sprintf(config, "batch, filter, memory_limiter")))
+}
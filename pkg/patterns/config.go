@@ -0,0 +1,93 @@
+package patterns
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+)
+
+type Config struct {
+	// Enabled turns on pattern mining at ingest.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Similarity is the share of a body's tokens that must equal a
+	// template's for the body to join its pattern.
+	Similarity float64 `mapstructure:"similarity"`
+
+	// Depth is how many leading tokens route a body to the patterns it is
+	// compared with. Bodies whose leading tokens differ never share a
+	// pattern, so it is best kept low.
+	Depth int `mapstructure:"depth"`
+
+	// MaxChildren bounds the distinct tokens per tree node. Further tokens
+	// share a wildcard branch.
+	MaxChildren int `mapstructure:"max_children"`
+
+	// MaxPatterns bounds the patterns per tenant. Once reached, bodies of a
+	// new shape are stored without a pattern.
+	MaxPatterns int `mapstructure:"max_patterns"`
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("patterns"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	cfg := Config{
+		Enabled:     true,
+		Similarity:  envFloat("WATCHDATA_PATTERNS_SIMILARITY", 0.4),
+		Depth:       envInt("WATCHDATA_PATTERNS_DEPTH", 1),
+		MaxChildren: envInt("WATCHDATA_PATTERNS_MAX_CHILDREN", 100),
+		MaxPatterns: envInt("WATCHDATA_PATTERNS_MAX_PATTERNS", 5000),
+	}
+	if v := os.Getenv("WATCHDATA_PATTERNS_ENABLED"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			cfg.Enabled = parsed
+		}
+	}
+	return cfg
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func envFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func (c Config) Validate() error {
+	if c.Similarity <= 0 || c.Similarity > 1 {
+		return fmt.Errorf("pattern similarity must be in (0, 1]")
+	}
+	if c.Depth < 1 {
+		return fmt.Errorf("pattern depth must be at least 1")
+	}
+	if c.MaxChildren < 2 {
+		return fmt.Errorf("pattern max children must be at least 2")
+	}
+	if c.MaxPatterns < 1 {
+		return fmt.Errorf("pattern max patterns must be at least 1")
+	}
+	return nil
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
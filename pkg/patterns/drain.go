@@ -0,0 +1,214 @@
+package patterns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+)
+
+// cluster is one pattern: a template whose tokens are either literal or
+// patterntypes.Wildcard.
+type cluster struct {
+	id        string
+	template  []string
+	createdAt time.Time
+	// dirty is set when the template has changed since it was stored.
+	dirty bool
+}
+
+func (c *cluster) String() string {
+	return strings.Join(c.template, " ")
+}
+
+// similarity returns the share of tokens that equal the template's.
+// Wildcards do not count, so a template cannot widen until it matches
+// everything of its length.
+func (c *cluster) similarity(tokens []string) float64 {
+	same := 0
+	for i, tok := range c.template {
+		if tok == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+// merge replaces the tokens that differ from tokens with wildcards.
+func (c *cluster) merge(tokens []string) {
+	for i, tok := range c.template {
+		if tok != tokens[i] && tok != patterntypes.Wildcard {
+			c.template[i] = patterntypes.Wildcard
+			c.dirty = true
+		}
+	}
+}
+
+type node struct {
+	children map[string]*node
+	clusters []*cluster
+}
+
+func newNode() *node {
+	return &node{children: make(map[string]*node)}
+}
+
+// tree is the Drain parse tree of one tenant (He et al., "Drain: An Online
+// Log Parsing Approach with Fixed Depth Tree", 2017). Bodies are routed by
+// their token count and first tokens to a leaf, and join the most similar
+// cluster of the leaf or start a new one.
+type tree struct {
+	cfg    Config
+	tenant string
+	byLen  map[int]*node
+	byID   map[string]*cluster
+}
+
+func newTree(cfg Config, tenant string) *tree {
+	return &tree{cfg: cfg, tenant: tenant, byLen: make(map[int]*node), byID: make(map[string]*cluster)}
+}
+
+// add assigns a body to a cluster, creating one if none is similar
+// enough. It returns nil for empty bodies and for new shapes once the
+// tree is full.
+func (t *tree) add(body string) *cluster {
+	tokens := tokenize(body)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	if leaf := t.leaf(tokens, false); leaf != nil {
+		if c := t.match(leaf, tokens); c != nil {
+			c.merge(tokens)
+			return c
+		}
+	}
+	if len(t.byID) >= t.cfg.MaxPatterns {
+		return nil
+	}
+
+	c := &cluster{id: t.newID(tokens), template: tokens, createdAt: time.Now(), dirty: true}
+	t.insert(c)
+	return c
+}
+
+// restore adds a stored pattern.
+func (t *tree) restore(p patterntypes.Pattern) {
+	if _, ok := t.byID[p.ID]; ok {
+		return
+	}
+	template := strings.Fields(p.Template)
+	if len(template) == 0 {
+		return
+	}
+	t.insert(&cluster{id: p.ID, template: template, createdAt: p.CreatedAt})
+}
+
+func (t *tree) insert(c *cluster) {
+	leaf := t.leaf(c.template, true)
+	leaf.clusters = append(leaf.clusters, c)
+	t.byID[c.id] = c
+}
+
+// leaf returns the leaf for tokens. Tokens with digits are routed as
+// wildcards, as are new tokens of a node that has MaxChildren already.
+// Without create, tokens a node lacks follow its wildcard branch, and nil
+// is returned if there is none.
+func (t *tree) leaf(tokens []string, create bool) *node {
+	n, ok := t.byLen[len(tokens)]
+	if !ok {
+		if !create {
+			return nil
+		}
+		n = newNode()
+		t.byLen[len(tokens)] = n
+	}
+
+	for _, tok := range tokens[:min(t.cfg.Depth, len(tokens))] {
+		if hasDigit(tok) {
+			tok = patterntypes.Wildcard
+		}
+		next, ok := n.children[tok]
+		switch {
+		case ok:
+		case !create:
+			if next, ok = n.children[patterntypes.Wildcard]; !ok {
+				return nil
+			}
+		default:
+			// Keep a slot for the wildcard branch.
+			if len(n.children) >= t.cfg.MaxChildren-1 {
+				tok = patterntypes.Wildcard
+			}
+			if next, ok = n.children[tok]; !ok {
+				next = newNode()
+				n.children[tok] = next
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+// match returns the most similar cluster of leaf, if it is similar enough.
+func (t *tree) match(leaf *node, tokens []string) *cluster {
+	var best *cluster
+	bestSim := -1.0
+	for _, c := range leaf.clusters {
+		if sim := c.similarity(tokens); sim > bestSim {
+			best, bestSim = c, sim
+		}
+	}
+	if best == nil || bestSim < t.cfg.Similarity {
+		return nil
+	}
+	return best
+}
+
+// newID derives a cluster ID from the tenant and the first template, so
+// that miners in different processes agree on the IDs of the shapes they
+// both see.
+func (t *tree) newID(tokens []string) string {
+	seed := t.tenant + "\x00" + strings.Join(tokens, " ")
+	for i := 0; ; i++ {
+		s := seed
+		if i > 0 {
+			s += "\x00" + strconv.Itoa(i)
+		}
+		sum := sha256.Sum256([]byte(s))
+		id := hex.EncodeToString(sum[:8])
+		if _, ok := t.byID[id]; !ok {
+			return id
+		}
+	}
+}
+
+// dirty returns the clusters changed since they were stored, and marks
+// them as stored.
+func (t *tree) dirty() []*cluster {
+	var out []*cluster
+	for _, c := range t.byID {
+		if c.dirty {
+			c.dirty = false
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// clone returns a copy of the tree whose changes are not stored.
+func (t *tree) clone() *tree {
+	next := newTree(t.cfg, t.tenant)
+	for _, c := range t.byID {
+		next.insert(&cluster{id: c.id, template: append([]string(nil), c.template...), createdAt: c.createdAt})
+	}
+	return next
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
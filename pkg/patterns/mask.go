@@ -0,0 +1,55 @@
+package patterns
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+)
+
+// variable matches tokens that are almost always variable: numbers with an
+// optional unit, hex ids, UUIDs, IPv4 addresses, dates and times.
+var variable = regexp.MustCompile(`^(?:` +
+	`[-+]?\d+(?:\.\d+)?[a-zA-Zµ%]{0,3}` +
+	`|(?:0x)?[0-9a-fA-F]*\d[0-9a-fA-F]*` +
+	`|[0-9a-fA-F]{8}(?:-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}` +
+	`|\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?` +
+	`|\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[-+]\d{2}:?\d{2})?)?` +
+	`|\d{2}:\d{2}:\d{2}(?:\.\d+)?` +
+	`)$`)
+
+const (
+	openers = `([{<"'`
+	closers = `)]}>"',;:.!?`
+)
+
+// tokenize splits a body on whitespace and masks variable tokens, keeping
+// the punctuation around them, so "(took 12ms)" becomes "(took <*>)".
+// Values of key=value tokens are masked the same way.
+func tokenize(body string) []string {
+	tokens := strings.Fields(body)
+	for i, tok := range tokens {
+		tokens[i] = mask(tok)
+	}
+	return tokens
+}
+
+func mask(tok string) string {
+	if tok == patterntypes.Wildcard {
+		return tok
+	}
+	core := strings.TrimLeft(tok, openers)
+	prefix := tok[:len(tok)-len(core)]
+	core = strings.TrimRight(core, closers)
+	suffix := tok[len(prefix)+len(core):]
+
+	if variable.MatchString(core) {
+		return prefix + patterntypes.Wildcard + suffix
+	}
+	if key, value, ok := strings.Cut(core, "="); ok && key != "" {
+		if v := strings.Trim(value, `"'`); v != "" && variable.MatchString(v) {
+			return prefix + key + "=" + patterntypes.Wildcard + suffix
+		}
+	}
+	return tok
+}
@@ -0,0 +1,177 @@
+// Package patterns groups log bodies that differ only in their variable
+// parts, such as ids, numbers and addresses, into patterns.
+package patterns
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// Store persists patterns.
+type Store interface {
+	LoadPatterns(ctx context.Context, tenantID string) ([]patterntypes.Pattern, error)
+	UpsertPatterns(ctx context.Context, patterns []patterntypes.Pattern) error
+}
+
+// Miner assigns records to patterns as they are ingested. Each tenant's
+// patterns are loaded from the store when its first records arrive, and
+// new or widened patterns are written back by Flush. Miners in different
+// processes derive the same ID for a shape they both see first, but only
+// learn each other's patterns when they restart.
+type Miner struct {
+	cfg   Config
+	store Store
+
+	mu    sync.Mutex
+	trees map[string]*tenantTree
+}
+
+type tenantTree struct {
+	mu     sync.Mutex
+	tree   *tree
+	loaded bool
+}
+
+func NewMiner(cfg Config, store Store) *Miner {
+	return &Miner{cfg: cfg, store: store, trees: make(map[string]*tenantTree)}
+}
+
+// Enabled reports whether records are assigned to patterns at ingest.
+func (m *Miner) Enabled() bool {
+	return m.cfg.Enabled
+}
+
+// lock returns the tenant's tree, loaded and locked.
+func (m *Miner) lock(ctx context.Context, tenantID string) (*tenantTree, error) {
+	m.mu.Lock()
+	t, ok := m.trees[tenantID]
+	if !ok {
+		t = &tenantTree{tree: newTree(m.cfg, tenantID)}
+		m.trees[tenantID] = t
+	}
+	m.mu.Unlock()
+
+	t.mu.Lock()
+	if t.loaded {
+		return t, nil
+	}
+	stored, err := m.store.LoadPatterns(ctx, tenantID)
+	if err != nil {
+		t.mu.Unlock()
+		return nil, err
+	}
+	for _, p := range stored {
+		t.tree.restore(p)
+	}
+	t.loaded = true
+	return t, nil
+}
+
+// Assign sets the PatternID of records. Records of a tenant whose
+// patterns cannot be loaded are left without one, and loading is retried
+// with the next records.
+func (m *Miner) Assign(ctx context.Context, records []telemetrytypes.LogRecord) {
+	if !m.cfg.Enabled {
+		return
+	}
+
+	byTenant := make(map[string][]int)
+	for i, rec := range records {
+		tenant := rec.TenantID
+		if tenant == "" {
+			tenant = tenanttypes.DefaultTenant
+		}
+		byTenant[tenant] = append(byTenant[tenant], i)
+	}
+
+	for tenant, idx := range byTenant {
+		t, err := m.lock(ctx, tenant)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to load log patterns, storing records without patterns", "tenant", tenant, "error", err)
+			continue
+		}
+		for _, i := range idx {
+			if c := t.tree.add(records[i].Body); c != nil {
+				records[i].PatternID = c.id
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// Flush stores the patterns created or widened since the last flush. On
+// error they are kept for the next one.
+func (m *Miner) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	trees := make(map[string]*tenantTree, len(m.trees))
+	for tenant, t := range m.trees {
+		trees[tenant] = t
+	}
+	m.mu.Unlock()
+
+	now := time.Now()
+	var patterns []patterntypes.Pattern
+	changed := make(map[*tenantTree][]*cluster)
+	for tenant, t := range trees {
+		t.mu.Lock()
+		for _, c := range t.tree.dirty() {
+			patterns = append(patterns, patterntypes.Pattern{TenantID: tenant, ID: c.id, Template: c.String(), CreatedAt: c.createdAt, UpdatedAt: now})
+			changed[t] = append(changed[t], c)
+		}
+		t.mu.Unlock()
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	if err := m.store.UpsertPatterns(ctx, patterns); err != nil {
+		for t, clusters := range changed {
+			t.mu.Lock()
+			for _, c := range clusters {
+				c.dirty = true
+			}
+			t.mu.Unlock()
+		}
+		return err
+	}
+	return nil
+}
+
+// Session mines bodies of one tenant on demand, starting from the
+// tenant's known patterns. Its patterns are not stored and it is not safe
+// for concurrent use.
+type Session struct {
+	tree *tree
+}
+
+// Session returns a session for the tenant.
+func (m *Miner) Session(ctx context.Context, tenantID string) (*Session, error) {
+	t, err := m.lock(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer t.mu.Unlock()
+	return &Session{tree: t.tree.clone()}, nil
+}
+
+// Add returns the ID of the body's pattern, or "" if it has none.
+func (s *Session) Add(body string) string {
+	if c := s.tree.add(body); c != nil {
+		return c.id
+	}
+	return ""
+}
+
+// Template returns the current template of a pattern.
+func (s *Session) Template(id string) string {
+	if c, ok := s.tree.byID[id]; ok {
+		return c.String()
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package patterns_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Ricky004/watchdata/pkg/patterns"
+	"github.com/Ricky004/watchdata/pkg/types/patterntypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	patterns map[string]patterntypes.Pattern
+	fail     error
+}
+
+func newMemStore() *memStore {
+	return &memStore{patterns: make(map[string]patterntypes.Pattern)}
+}
+
+func (s *memStore) LoadPatterns(_ context.Context, tenantID string) ([]patterntypes.Pattern, error) {
+	if s.fail != nil {
+		return nil, s.fail
+	}
+	var out []patterntypes.Pattern
+	for _, p := range s.patterns {
+		if p.TenantID == tenantID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) UpsertPatterns(_ context.Context, patterns []patterntypes.Pattern) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	for _, p := range patterns {
+		s.patterns[p.TenantID+"/"+p.ID] = p
+	}
+	return nil
+}
+
+func config() patterns.Config {
+	return patterns.Config{Enabled: true, Similarity: 0.4, Depth: 1, MaxChildren: 100, MaxPatterns: 100}
+}
+
+func logs(bodies ...string) []telemetrytypes.LogRecord {
+	records := make([]telemetrytypes.LogRecord, len(bodies))
+	for i, body := range bodies {
+		records[i] = telemetrytypes.LogRecord{TenantID: "acme", Body: body}
+	}
+	return records
+}
+
+func TestAssignGroupsBodies(t *testing.T) {
+	store := newMemStore()
+	m := patterns.NewMiner(config(), store)
+
+	records := logs(
+		"user alice logged in from 10.0.0.1 in 12ms",
+		"user bob logged in from 10.0.0.7 in 3ms",
+		"payment 4f1c2a9e-0b1d-4c7e-9a55-3e2f1b0c9d8e failed: card declined",
+		"user carol logged in from 192.168.1.20 in 140ms",
+		"",
+	)
+	m.Assign(context.Background(), records)
+
+	assert.NotEmpty(t, records[0].PatternID)
+	assert.Equal(t, records[0].PatternID, records[1].PatternID)
+	assert.Equal(t, records[0].PatternID, records[3].PatternID)
+	assert.NotEqual(t, records[0].PatternID, records[2].PatternID)
+	assert.Empty(t, records[4].PatternID)
+
+	require.NoError(t, m.Flush(context.Background()))
+	require.Len(t, store.patterns, 2)
+	assert.Equal(t, "user <*> logged in from <*> in <*>", store.patterns["acme/"+records[0].PatternID].Template)
+	assert.Equal(t, "payment <*> failed: card declined", store.patterns["acme/"+records[2].PatternID].Template)
+
+	// A restarted miner keeps the IDs.
+	again := logs("user dave logged in from 10.0.0.9 in 1ms")
+	patterns.NewMiner(config(), store).Assign(context.Background(), again)
+	assert.Equal(t, records[0].PatternID, again[0].PatternID)
+}
+
+func TestAssignIsPerTenant(t *testing.T) {
+	m := patterns.NewMiner(config(), newMemStore())
+	records := logs("cache miss for key session", "cache miss for key session")
+	records[1].TenantID = "other"
+	m.Assign(context.Background(), records)
+	assert.NotEqual(t, records[0].PatternID, records[1].PatternID)
+}
+
+func TestMaxPatterns(t *testing.T) {
+	cfg := config()
+	cfg.MaxPatterns = 2
+	m := patterns.NewMiner(cfg, newMemStore())
+	records := logs("disk is full", "queue is empty", "shutting down now, goodbye")
+	m.Assign(context.Background(), records)
+	assert.NotEmpty(t, records[1].PatternID)
+	assert.Empty(t, records[2].PatternID)
+}
+
+func TestFlushRetries(t *testing.T) {
+	store := newMemStore()
+	m := patterns.NewMiner(config(), store)
+	m.Assign(context.Background(), logs("connection reset by peer"))
+
+	store.fail = fmt.Errorf("unavailable")
+	assert.Error(t, m.Flush(context.Background()))
+	store.fail = nil
+	require.NoError(t, m.Flush(context.Background()))
+	assert.Len(t, store.patterns, 1)
+}
+
+func TestLoadFailureLeavesRecordsUnassigned(t *testing.T) {
+	store := newMemStore()
+	store.fail = fmt.Errorf("unavailable")
+	m := patterns.NewMiner(config(), store)
+
+	records := logs("connection reset by peer")
+	m.Assign(context.Background(), records)
+	assert.Empty(t, records[0].PatternID)
+
+	store.fail = nil
+	m.Assign(context.Background(), records)
+	assert.NotEmpty(t, records[0].PatternID)
+}
+
+func TestSessionDoesNotStore(t *testing.T) {
+	store := newMemStore()
+	m := patterns.NewMiner(config(), store)
+	records := logs("retrying request 1 of 5")
+	m.Assign(context.Background(), records)
+	require.NoError(t, m.Flush(context.Background()))
+
+	session, err := m.Session(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, records[0].PatternID, session.Add("retrying request 2 of 5"))
+	id := session.Add("worker pool resized")
+	assert.NotEmpty(t, id)
+	assert.Equal(t, "worker pool resized", session.Template(id))
+
+	require.NoError(t, m.Flush(context.Background()))
+	assert.Len(t, store.patterns, 1)
+}
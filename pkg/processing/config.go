@@ -0,0 +1,48 @@
+package processing
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Ricky004/watchdata/pkg/types/processingtypes"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPathEnv names the environment variable holding the path of the
+// processing config file.
+const ConfigPathEnv = "WATCHDATA_PROCESSING_CONFIG"
+
+// Config is the chain of steps applied to records received by the native
+// receivers before they are stored.
+type Config struct {
+	Steps []processingtypes.Step `yaml:"steps"`
+}
+
+func (c Config) Validate() error {
+	_, err := compile(c.Steps)
+	return err
+}
+
+// LoadConfig reads the processing config from the file named by
+// WATCHDATA_PROCESSING_CONFIG. Without it, records are stored as they are
+// received.
+func LoadConfig() (Config, error) {
+	var cfg Config
+
+	path := os.Getenv(ConfigPathEnv)
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read processing config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse processing config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid processing config: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,112 @@
+package processing
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// grokPatterns are the patterns grok expressions can reference, a subset
+// of the Logstash library. Patterns may reference each other.
+var grokPatterns = map[string]string{
+	"WORD":         `\b\w+\b`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"INT":          `[+-]?\d+`,
+	"POSINT":       `\b[1-9]\d*\b`,
+	"NUMBER":       `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"BASE16NUM":    `(?:0[xX])?[0-9A-Fa-f]+`,
+	"UUID":         `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"QUOTEDSTRING": `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"IPV4":         `(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)`,
+	"IPV6":         `[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}(?:%[0-9A-Za-z]+)?`,
+	"IP":           `%{IPV6}|%{IPV4}`,
+	"HOSTNAME":     `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":     `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT":     `%{IPORHOST}:%{POSINT}`,
+	"EMAILADDRESS": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+`,
+	"PATH":         `(?:/[^\s/]*)+`,
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"LOGLEVEL":     `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert|panic)`,
+	"MONTH":        `\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\b`,
+	"TIME":         `\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?`,
+
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]%{TIME}(?:Z|[+-]\d{2}:?\d{2})?`,
+	"HTTPDATE":          `\d{2}/%{MONTH}/\d{4}:%{TIME} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +\d{1,2} %{TIME}`,
+}
+
+// grokRef matches %{PATTERN} and %{PATTERN:key} references.
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?\}`)
+
+// compileGrok expands the references of a grok expression into a regular
+// expression. It returns the attribute key of each group, empty for groups
+// that are not extracted. Text outside references is a regular expression.
+func compileGrok(expr string) (*regexp.Regexp, []string, error) {
+	var (
+		names []string
+		err   error
+	)
+	pattern := grokRef.ReplaceAllStringFunc(expr, func(ref string) string {
+		m := grokRef.FindStringSubmatch(ref)
+		expanded, e := expandGrok(m[1], 0)
+		if e != nil {
+			err = e
+			return ""
+		}
+		if m[2] == "" {
+			return "(?:" + expanded + ")"
+		}
+		names = append(names, m[2])
+		return "(?P<grok" + strconv.Itoa(len(names)) + ">" + expanded + ")"
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("pattern has no %%{PATTERN:key} references")
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	// Groups of the text between references are not extracted.
+	keys := make([]string, len(re.SubexpNames()))
+	for i, name := range re.SubexpNames() {
+		if n, ok := strings.CutPrefix(name, "grok"); ok {
+			if idx, err := strconv.Atoi(n); err == nil && idx >= 1 && idx <= len(names) {
+				keys[i] = names[idx-1]
+			}
+		}
+	}
+	return re, keys, nil
+}
+
+// expandGrok returns the regular expression of a library pattern, with the
+// patterns it references expanded.
+func expandGrok(name string, depth int) (string, error) {
+	pattern, ok := grokPatterns[name]
+	if !ok {
+		return "", fmt.Errorf("unknown grok pattern %q", name)
+	}
+	if depth > 10 {
+		return "", fmt.Errorf("grok pattern %q nests too deep", name)
+	}
+
+	var err error
+	expanded := grokRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		inner, e := expandGrok(grokRef.FindStringSubmatch(ref)[1], depth+1)
+		if e != nil {
+			err = e
+		}
+		return "(?:" + inner + ")"
+	})
+	return expanded, err
+}
@@ -0,0 +1,155 @@
+package processing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/processingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// unixUnits are the layouts of timestamps given as numbers.
+var unixUnits = map[string]time.Duration{
+	"unix":    time.Second,
+	"unix_ms": time.Millisecond,
+	"unix_us": time.Microsecond,
+	"unix_ns": time.Nanosecond,
+}
+
+func compileTimestamp(s processingtypes.Step) (func(rec *telemetrytypes.LogRecord) bool, error) {
+	if s.Field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+	from, err := source(s.Field, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var parse func(v string, observed time.Time) (time.Time, error)
+	switch layout := s.Layout; {
+	case layout == "" || layout == "rfc3339":
+		parse = func(v string, _ time.Time) (time.Time, error) { return time.Parse(time.RFC3339Nano, v) }
+	case unixUnits[layout] != 0:
+		unit := unixUnits[layout]
+		parse = func(v string, _ time.Time) (time.Time, error) { return parseUnix(v, unit) }
+	default:
+		parse = func(v string, observed time.Time) (time.Time, error) {
+			t, err := time.Parse(layout, v)
+			if err == nil && t.Year() == 0 {
+				t = inYearOf(t, observed)
+			}
+			return t, err
+		}
+	}
+
+	return func(rec *telemetrytypes.LogRecord) bool {
+		if v := fieldValue(rec, from); v != "" {
+			if t, err := parse(v, rec.ObservedTime); err == nil {
+				rec.Timestamp = t.UTC()
+			}
+		}
+		return true
+	}, nil
+}
+
+// inYearOf puts t, parsed from a layout without a year such as syslog's,
+// in the year it was observed, or the current one if that is unknown.
+// Records written just before a new year and observed after it would land
+// almost a year ahead, so those more than a day ahead go in the year
+// before.
+func inYearOf(t, observed time.Time) time.Time {
+	if observed.IsZero() {
+		observed = time.Now()
+	}
+	t = t.AddDate(observed.Year(), 0, 0)
+	if t.Sub(observed) > 24*time.Hour {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+func parseUnix(v string, unit time.Duration) (time.Time, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(0, n*int64(unit)), nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(f*float64(unit))), nil
+}
+
+// severities maps level names to the first severity number of their
+// OpenTelemetry range.
+var severities = map[string]int8{
+	"trace":       1,
+	"debug":       5,
+	"info":        9,
+	"information": 9,
+	"notice":      10,
+	"warn":        13,
+	"warning":     13,
+	"error":       17,
+	"err":         17,
+	"severe":      17,
+	"crit":        21,
+	"critical":    21,
+	"alert":       21,
+	"emerg":       21,
+	"emergency":   21,
+	"fatal":       21,
+	"panic":       21,
+}
+
+// compileSeverity compiles a step setting the severity of records that
+// have none from a level name. Without a field, it reads the severity text
+// and then the first upper case level name in the body, such as the ERROR
+// of "2024-05-01 12:00:00 ERROR payment failed".
+func compileSeverity(s processingtypes.Step) (func(rec *telemetrytypes.LogRecord) bool, error) {
+	var from *filter.Field
+	if s.Field != "" {
+		f, err := source(s.Field, "")
+		if err != nil {
+			return nil, err
+		}
+		from = &f
+	}
+
+	return func(rec *telemetrytypes.LogRecord) bool {
+		if rec.SeverityNumber != 0 {
+			return true
+		}
+
+		var level string
+		switch {
+		case from != nil:
+			level = strings.TrimSpace(fieldValue(rec, *from))
+		case rec.SeverityText != "":
+			level = rec.SeverityText
+		default:
+			level = bodyLevel(rec.Body)
+		}
+		if n, ok := severities[strings.ToLower(level)]; ok {
+			rec.SeverityNumber = n
+			if rec.SeverityText == "" {
+				rec.SeverityText = level
+			}
+		}
+		return true
+	}, nil
+}
+
+// bodyLevel returns the first upper case level name among the words of
+// body. Lower case words are too often part of the message.
+func bodyLevel(body string) string {
+	for _, word := range strings.FieldsFunc(body, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if _, ok := severities[strings.ToLower(word)]; ok && word == strings.ToUpper(word) {
+			return word
+		}
+	}
+	return ""
+}
@@ -0,0 +1,187 @@
+package processing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Ricky004/watchdata/pkg/types/processingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// compileParser compiles the steps that turn a field into attributes.
+func compileParser(s processingtypes.Step) (func(rec *telemetrytypes.LogRecord) bool, error) {
+	from, err := source(s.Field, "body")
+	if err != nil {
+		return nil, err
+	}
+
+	var parse func(string) []telemetrytypes.KeyValue
+	switch s.Type {
+	case processingtypes.StepJSON:
+		parse = parseJSON
+	case processingtypes.StepLogfmt:
+		parse = parseLogfmt
+	case processingtypes.StepRegex:
+		if s.Pattern == "" {
+			return nil, fmt.Errorf("pattern is required")
+		}
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !slices.ContainsFunc(re.SubexpNames(), func(name string) bool { return name != "" }) {
+			return nil, fmt.Errorf("pattern has no named groups")
+		}
+		parse = extractor(re, re.SubexpNames())
+	case processingtypes.StepGrok:
+		if s.Pattern == "" {
+			return nil, fmt.Errorf("pattern is required")
+		}
+		re, names, err := compileGrok(s.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		parse = extractor(re, names)
+	}
+
+	return func(rec *telemetrytypes.LogRecord) bool {
+		for _, kv := range parse(fieldValue(rec, from)) {
+			v := kv.Value.(string)
+			if s.Body != "" && kv.Key == s.Body {
+				rec.Body = v
+				continue
+			}
+			setAttr(rec, s.Prefix+kv.Key, v)
+		}
+		return true
+	}, nil
+}
+
+// extractor returns a parser setting names[i] to the text of group i of
+// re. Groups without a name are skipped.
+func extractor(re *regexp.Regexp, names []string) func(string) []telemetrytypes.KeyValue {
+	return func(text string) []telemetrytypes.KeyValue {
+		m := re.FindStringSubmatchIndex(text)
+		if m == nil {
+			return nil
+		}
+		var kvs []telemetrytypes.KeyValue
+		for i, name := range names {
+			if name == "" || m[2*i] < 0 {
+				continue
+			}
+			kvs = append(kvs, telemetrytypes.KeyValue{Key: name, Value: text[m[2*i]:m[2*i+1]]})
+		}
+		return kvs
+	}
+}
+
+// parseJSON parses a JSON object. Nested objects are flattened into dotted
+// keys, arrays are kept as JSON and scalars become text, the way the
+// native receivers store attribute values.
+func parseJSON(text string) []telemetrytypes.KeyValue {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil
+	}
+	var kvs []telemetrytypes.KeyValue
+	flatten(&kvs, "", obj)
+	return kvs
+}
+
+func flatten(kvs *[]telemetrytypes.KeyValue, prefix string, obj map[string]any) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		key := prefix + k
+		switch v := obj[k].(type) {
+		case map[string]any:
+			flatten(kvs, key+".", v)
+		case string:
+			*kvs = append(*kvs, telemetrytypes.KeyValue{Key: key, Value: v})
+		case nil:
+			*kvs = append(*kvs, telemetrytypes.KeyValue{Key: key, Value: ""})
+		case []any:
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			enc.Encode(v)
+			*kvs = append(*kvs, telemetrytypes.KeyValue{Key: key, Value: strings.TrimSuffix(buf.String(), "\n")})
+		default:
+			*kvs = append(*kvs, telemetrytypes.KeyValue{Key: key, Value: fmt.Sprint(v)})
+		}
+	}
+}
+
+// parseLogfmt parses key=value pairs, with values optionally double
+// quoted. Words that are not pairs are skipped, so that a message followed
+// by pairs parses too.
+func parseLogfmt(text string) []telemetrytypes.KeyValue {
+	var kvs []telemetrytypes.KeyValue
+	for i := 0; i < len(text); {
+		if text[i] == ' ' || text[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(text) && text[i] != ' ' && text[i] != '\t' && text[i] != '=' {
+			i++
+		}
+		key := text[start:i]
+		if i == len(text) || text[i] != '=' || key == "" {
+			for i < len(text) && text[i] != ' ' && text[i] != '\t' {
+				i++
+			}
+			continue
+		}
+		i++
+
+		var value string
+		if i < len(text) && text[i] == '"' {
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				// An unterminated quote runs to the end of the text.
+				value = text[i+1:]
+				i = len(text)
+			} else {
+				quoted := text[i : end+1]
+				if unquoted, err := strconv.Unquote(quoted); err == nil {
+					value = unquoted
+				} else {
+					value = quoted[1 : len(quoted)-1]
+				}
+				i = end + 1
+			}
+		} else {
+			start := i
+			for i < len(text) && text[i] != ' ' && text[i] != '\t' {
+				i++
+			}
+			value = text[start:i]
+		}
+		kvs = append(kvs, telemetrytypes.KeyValue{Key: key, Value: value})
+	}
+	return kvs
+}
@@ -0,0 +1,266 @@
+// Package processing runs records received by the native receivers
+// through a chain of steps before they are stored: parsing structured
+// bodies into attributes, extracting fields, fixing timestamps and
+// severities, and routing.
+package processing
+
+import (
+	"fmt"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/types/processingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+// Processor applies a chain of steps to log records.
+type Processor struct {
+	steps []step
+}
+
+// step is a compiled processingtypes.Step. apply returns false to drop
+// the record.
+type step struct {
+	when  *filter.Matcher // nil applies the step to every record
+	apply func(rec *telemetrytypes.LogRecord) bool
+}
+
+// NewProcessor compiles the steps of cfg.
+func NewProcessor(cfg Config) (*Processor, error) {
+	steps, err := compile(cfg.Steps)
+	if err != nil {
+		return nil, err
+	}
+	return &Processor{steps: steps}, nil
+}
+
+// Process applies the chain to logs in place and returns the records that
+// were not dropped, reusing the array of logs.
+func (p *Processor) Process(logs []telemetrytypes.LogRecord) []telemetrytypes.LogRecord {
+	if len(p.steps) == 0 {
+		return logs
+	}
+	kept := logs[:0]
+	for i := range logs {
+		if run(p.steps, &logs[i]) {
+			kept = append(kept, logs[i])
+		} else {
+			metrics.ProcessingDropped.Inc()
+		}
+	}
+	return kept
+}
+
+// Test applies the chain to sample records without counting what it drops.
+func (p *Processor) Test(logs []telemetrytypes.LogRecord) []processingtypes.TestResult {
+	results := make([]processingtypes.TestResult, len(logs))
+	for i, rec := range logs {
+		dropped := !run(p.steps, &rec)
+		results[i] = processingtypes.TestResult{Record: rec, Dropped: dropped}
+	}
+	return results
+}
+
+// run applies steps to rec and reports whether it is kept.
+func run(steps []step, rec *telemetrytypes.LogRecord) bool {
+	for _, s := range steps {
+		if s.when != nil && !s.when.Match(func(f filter.Field) string { return fieldValue(rec, f) }) {
+			continue
+		}
+		if !s.apply(rec) {
+			return false
+		}
+	}
+	return true
+}
+
+func compile(steps []processingtypes.Step) ([]step, error) {
+	compiled := make([]step, 0, len(steps))
+	for i, s := range steps {
+		c, err := compileStep(s)
+		if err != nil && s.Type == "" {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i+1, s.Type, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compileStep(s processingtypes.Step) (step, error) {
+	when, err := matcher(s.When)
+	if err != nil {
+		return step{}, err
+	}
+
+	var apply func(rec *telemetrytypes.LogRecord) bool
+	switch s.Type {
+	case processingtypes.StepJSON, processingtypes.StepLogfmt, processingtypes.StepRegex, processingtypes.StepGrok:
+		apply, err = compileParser(s)
+	case processingtypes.StepTimestamp:
+		apply, err = compileTimestamp(s)
+	case processingtypes.StepSeverity:
+		apply, err = compileSeverity(s)
+	case processingtypes.StepRename:
+		apply, err = compileRename(s)
+	case processingtypes.StepDrop:
+		apply, err = compileDrop(s)
+	case processingtypes.StepRoute:
+		apply, err = compileRoute(s)
+	case "":
+		err = fmt.Errorf("type is required")
+	default:
+		err = fmt.Errorf("unsupported type")
+	}
+	if err != nil {
+		return step{}, err
+	}
+	return step{when: when, apply: apply}, nil
+}
+
+// matcher parses a step or route condition. An empty condition is nil.
+func matcher(when string) (*filter.Matcher, error) {
+	expr, err := filter.Parse(when)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+	if expr == nil {
+		return nil, nil
+	}
+	return filter.NewMatcher(expr), nil
+}
+
+func compileRoute(s processingtypes.Step) (func(rec *telemetrytypes.LogRecord) bool, error) {
+	if len(s.Routes) == 0 {
+		return nil, fmt.Errorf("routes are required")
+	}
+
+	type route struct {
+		when  *filter.Matcher
+		drop  bool
+		steps []step
+	}
+	routes := make([]route, len(s.Routes))
+	for i, r := range s.Routes {
+		when, err := matcher(r.When)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i+1, err)
+		}
+		if r.Drop && len(r.Steps) > 0 {
+			return nil, fmt.Errorf("route %d: a route that drops records has no steps", i+1)
+		}
+		steps, err := compile(r.Steps)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i+1, err)
+		}
+		routes[i] = route{when: when, drop: r.Drop, steps: steps}
+	}
+
+	return func(rec *telemetrytypes.LogRecord) bool {
+		value := func(f filter.Field) string { return fieldValue(rec, f) }
+		for _, r := range routes {
+			if r.when != nil && !r.when.Match(value) {
+				continue
+			}
+			return !r.drop && run(r.steps, rec)
+		}
+		return true
+	}, nil
+}
+
+func compileRename(s processingtypes.Step) (func(rec *telemetrytypes.LogRecord) bool, error) {
+	if len(s.Rename) == 0 {
+		return nil, fmt.Errorf("rename is required")
+	}
+	for from, to := range s.Rename {
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("attribute keys must not be empty")
+		}
+	}
+	return func(rec *telemetrytypes.LogRecord) bool {
+		var renamed map[string]bool
+		for _, kv := range rec.Attributes {
+			if to, ok := s.Rename[kv.Key]; ok {
+				if renamed == nil {
+					renamed = make(map[string]bool)
+				}
+				renamed[to] = true
+			}
+		}
+		if renamed == nil {
+			return true
+		}
+
+		attrs := make([]telemetrytypes.KeyValue, 0, len(rec.Attributes))
+		for _, kv := range rec.Attributes {
+			if to, ok := s.Rename[kv.Key]; ok {
+				kv.Key = to
+			} else if renamed[kv.Key] {
+				// Replaced by the attribute renamed to its key.
+				continue
+			}
+			attrs = append(attrs, kv)
+		}
+		rec.Attributes = attrs
+		return true
+	}, nil
+}
+
+func compileDrop(s processingtypes.Step) (func(rec *telemetrytypes.LogRecord) bool, error) {
+	if len(s.Keys) == 0 {
+		return nil, fmt.Errorf("keys are required")
+	}
+	return func(rec *telemetrytypes.LogRecord) bool {
+		for _, key := range s.Keys {
+			removeAttr(rec, key)
+		}
+		return true
+	}, nil
+}
+
+// source resolves the field a step reads. Empty is def.
+func source(field, def string) (filter.Field, error) {
+	if field == "" {
+		field = def
+	}
+	f, err := filter.ParseField(field)
+	if err != nil {
+		return filter.Field{}, fmt.Errorf("invalid field: %w", err)
+	}
+	return f, nil
+}
+
+// fieldValue returns the value of f in rec as text, or the empty string if
+// rec does not have it.
+func fieldValue(rec *telemetrytypes.LogRecord, f filter.Field) string {
+	v, ok := filter.RecordValue(rec, f)
+	if !ok {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// setAttr sets the attribute key, replacing an existing value.
+func setAttr(rec *telemetrytypes.LogRecord, key, value string) {
+	for i := range rec.Attributes {
+		if rec.Attributes[i].Key == key {
+			rec.Attributes[i].Value = value
+			return
+		}
+	}
+	rec.Attributes = append(rec.Attributes, telemetrytypes.KeyValue{Key: key, Value: value})
+}
+
+func removeAttr(rec *telemetrytypes.LogRecord, key string) {
+	for i := range rec.Attributes {
+		if rec.Attributes[i].Key == key {
+			rec.Attributes = append(rec.Attributes[:i], rec.Attributes[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package processing_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/processing"
+	"github.com/Ricky004/watchdata/pkg/types/processingtypes"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func process(t *testing.T, steps []processingtypes.Step, body string, attrs ...telemetrytypes.KeyValue) processingtypes.TestResult {
+	t.Helper()
+	p, err := processing.NewProcessor(processing.Config{Steps: steps})
+	require.NoError(t, err)
+	return p.Test([]telemetrytypes.LogRecord{{Body: body, Attributes: attrs}})[0]
+}
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		step  processingtypes.Step
+		body  string
+		want  []telemetrytypes.KeyValue
+		wantB string
+	}{
+		{
+			name:  "json",
+			step:  processingtypes.Step{Type: processingtypes.StepJSON, Body: "msg"},
+			body:  `{"msg":"payment failed","level":"error","http":{"status":502,"ok":false},"tags":["a","b"],"user":null}`,
+			want:  []telemetrytypes.KeyValue{{Key: "http.ok", Value: "false"}, {Key: "http.status", Value: "502"}, {Key: "level", Value: "error"}, {Key: "tags", Value: `["a","b"]`}, {Key: "user", Value: ""}},
+			wantB: "payment failed",
+		},
+		{
+			name:  "json ignores plain strings",
+			step:  processingtypes.Step{Type: processingtypes.StepJSON},
+			body:  "payment failed",
+			wantB: "payment failed",
+		},
+		{
+			name:  "logfmt",
+			step:  processingtypes.Step{Type: processingtypes.StepLogfmt, Prefix: "log."},
+			body:  `payment failed order=42 reason="card \"declined\"" empty=`,
+			want:  []telemetrytypes.KeyValue{{Key: "log.order", Value: "42"}, {Key: "log.reason", Value: `card "declined"`}, {Key: "log.empty", Value: ""}},
+			wantB: `payment failed order=42 reason="card \"declined\"" empty=`,
+		},
+		{
+			name:  "regex",
+			step:  processingtypes.Step{Type: processingtypes.StepRegex, Pattern: `order (?P<order>\d+) took (?P<took>\d+)ms`},
+			body:  "order 42 took 180ms",
+			want:  []telemetrytypes.KeyValue{{Key: "order", Value: "42"}, {Key: "took", Value: "180"}},
+			wantB: "order 42 took 180ms",
+		},
+		{
+			name:  "grok",
+			step:  processingtypes.Step{Type: processingtypes.StepGrok, Pattern: `%{IPORHOST:client.address} - - \[%{HTTPDATE:time}\] "%{WORD:http.method} %{URIPATHPARAM:url.path} [^"]*" %{INT:http.status} (\d+)`},
+			body:  `10.0.0.7 - - [01/May/2024:12:00:00 +0000] "GET /orders?id=42 HTTP/1.1" 404 153`,
+			want:  []telemetrytypes.KeyValue{{Key: "client.address", Value: "10.0.0.7"}, {Key: "time", Value: "01/May/2024:12:00:00 +0000"}, {Key: "http.method", Value: "GET"}, {Key: "url.path", Value: "/orders?id=42"}, {Key: "http.status", Value: "404"}},
+			wantB: `10.0.0.7 - - [01/May/2024:12:00:00 +0000] "GET /orders?id=42 HTTP/1.1" 404 153`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := process(t, []processingtypes.Step{tt.step}, tt.body)
+			assert.Equal(t, tt.want, res.Record.Attributes)
+			assert.Equal(t, tt.wantB, res.Record.Body)
+		})
+	}
+}
+
+func TestTimestampAndSeverity(t *testing.T) {
+	steps := []processingtypes.Step{
+		{Type: processingtypes.StepLogfmt},
+		{Type: processingtypes.StepTimestamp, Field: "attributes.ts", Layout: "unix_ms"},
+		{Type: processingtypes.StepTimestamp, Field: "attributes.time", Layout: "Jan _2 15:04:05"},
+		{Type: processingtypes.StepSeverity, When: `attributes.level != ""`, Field: "attributes.level"},
+		{Type: processingtypes.StepSeverity},
+	}
+
+	res := process(t, steps, "ts=1714564800123 level=warning disk almost full")
+	assert.Equal(t, time.UnixMilli(1714564800123).UTC(), res.Record.Timestamp)
+	assert.Equal(t, int8(13), res.Record.SeverityNumber)
+	assert.Equal(t, "warning", res.Record.SeverityText)
+
+	res = process(t, steps, `time="May  1 12:00:00" 2024-05-01 ERROR: payment failed, retrying at info level`)
+	assert.Equal(t, "May  1 12:00:00", res.Record.Timestamp.Format("Jan _2 15:04:05"))
+	assert.Equal(t, int8(17), res.Record.SeverityNumber)
+	assert.Equal(t, "ERROR", res.Record.SeverityText)
+
+	// Records with a severity and bodies without an upper case level are
+	// left alone.
+	p, err := processing.NewProcessor(processing.Config{Steps: steps})
+	require.NoError(t, err)
+	results := p.Test([]telemetrytypes.LogRecord{
+		{Body: "ERROR ignored", SeverityNumber: 9, SeverityText: "INFO"},
+		{Body: "an error happened"},
+		{Body: "text only", SeverityText: "Fatal"},
+	})
+	assert.Equal(t, int8(9), results[0].Record.SeverityNumber)
+	assert.Equal(t, int8(0), results[1].Record.SeverityNumber)
+	assert.Equal(t, int8(21), results[2].Record.SeverityNumber)
+	assert.Equal(t, "Fatal", results[2].Record.SeverityText)
+}
+
+func TestYearlessTimestamps(t *testing.T) {
+	p, err := processing.NewProcessor(processing.Config{Steps: []processingtypes.Step{
+		{Type: processingtypes.StepTimestamp, Field: "body", Layout: "Jan _2 15:04:05"},
+	}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		body     string
+		observed time.Time
+		want     time.Time
+	}{
+		{"same year", "May  1 12:00:00", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{"new year", "Dec 31 23:59:58", time.Date(2025, 1, 1, 0, 0, 3, 0, time.UTC), time.Date(2024, 12, 31, 23, 59, 58, 0, time.UTC)},
+		{"slightly ahead", "Jun  1 12:00:00", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := p.Test([]telemetrytypes.LogRecord{{Body: tt.body, ObservedTime: tt.observed}})
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.want, results[0].Record.Timestamp)
+		})
+	}
+}
+
+func TestRenameDropAndRoute(t *testing.T) {
+	steps := []processingtypes.Step{
+		{Type: processingtypes.StepJSON},
+		{Type: processingtypes.StepRename, Rename: map[string]string{"lvl": "level", "svc": "service"}},
+		{Type: processingtypes.StepDrop, Keys: []string{"password"}},
+		{Type: processingtypes.StepRoute, Routes: []processingtypes.Route{
+			{When: `attributes.path = "/healthz"`, Drop: true},
+			{When: `body contains "nginx"`, Steps: []processingtypes.Step{
+				{Type: processingtypes.StepGrok, Pattern: `%{INT:http.status}`},
+			}},
+			{Steps: []processingtypes.Step{
+				{Type: processingtypes.StepDrop, Keys: []string{"debug"}},
+			}},
+		}},
+	}
+	p, err := processing.NewProcessor(processing.Config{Steps: steps})
+	require.NoError(t, err)
+
+	results := p.Test([]telemetrytypes.LogRecord{
+		{Body: `{"lvl":"info","level":"old","password":"hunter2","debug":"x"}`},
+		{Body: `{"path":"/healthz"}`},
+		{Body: "nginx 200"},
+	})
+	assert.Equal(t, []telemetrytypes.KeyValue{{Key: "level", Value: "info"}}, results[0].Record.Attributes)
+	assert.True(t, results[1].Dropped)
+	assert.False(t, results[2].Dropped)
+	assert.Equal(t, []telemetrytypes.KeyValue{{Key: "http.status", Value: "200"}}, results[2].Record.Attributes)
+
+	logs := []telemetrytypes.LogRecord{{Body: `{"path":"/healthz"}`}, {Body: "kept"}}
+	kept := p.Process(logs)
+	require.Len(t, kept, 1)
+	assert.Equal(t, "kept", kept[0].Body)
+}
+
+func TestInvalidSteps(t *testing.T) {
+	tests := []struct {
+		step processingtypes.Step
+		err  string
+	}{
+		{processingtypes.Step{}, "step 1: type is required"},
+		{processingtypes.Step{Type: "xml"}, "step 1 (xml): unsupported type"},
+		{processingtypes.Step{Type: processingtypes.StepJSON, When: "foo = 1"}, `invalid condition: unknown field "foo"`},
+		{processingtypes.Step{Type: processingtypes.StepRegex, Pattern: `(\d+)`}, "pattern has no named groups"},
+		{processingtypes.Step{Type: processingtypes.StepGrok, Pattern: `%{NOPE:x}`}, `unknown grok pattern "NOPE"`},
+		{processingtypes.Step{Type: processingtypes.StepTimestamp}, "field is required"},
+		{processingtypes.Step{Type: processingtypes.StepRoute, Routes: []processingtypes.Route{
+			{Drop: true, Steps: []processingtypes.Step{{Type: processingtypes.StepJSON}}},
+		}}, "route 1: a route that drops records has no steps"},
+	}
+	for _, tt := range tests {
+		err := processing.Config{Steps: []processingtypes.Step{tt.step}}.Validate()
+		assert.ErrorContains(t, err, tt.err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "processing.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`steps:
+  - type: json
+    when: body ~ "^\\{"
+    body: message
+  - type: severity
+`), 0644))
+	t.Setenv(processing.ConfigPathEnv, path)
+
+	cfg, err := processing.LoadConfig()
+	require.NoError(t, err)
+	require.Len(t, cfg.Steps, 2)
+	assert.Equal(t, processingtypes.StepJSON, cfg.Steps[0].Type)
+	assert.Equal(t, "message", cfg.Steps[0].Body)
+
+	require.NoError(t, os.WriteFile(path, []byte("steps:\n  - type: drop\n"), 0644))
+	_, err = processing.LoadConfig()
+	assert.ErrorContains(t, err, "invalid processing config: step 1 (drop): keys are required")
+}
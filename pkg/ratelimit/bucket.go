@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// bucket is a token bucket. A nil bucket is unlimited.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64, now time.Time) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b == nil {
+		return
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns how long until n tokens are available. A request larger
+// than the bucket only needs a full bucket; it then leaves the bucket in
+// debt so the average rate still holds.
+func (b *bucket) wait(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	need := min(n, b.burst)
+	if b.tokens >= need {
+		return 0
+	}
+	return time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take(n float64) {
+	if b != nil {
+		b.tokens -= n
+	}
+}
+
+// full reports whether the bucket holds its burst. Unlimited buckets are
+// always full.
+func (b *bucket) full() bool {
+	return b == nil || b.tokens >= b.burst
+}
+
+// available returns the tokens left, or nil for an unlimited bucket.
+func (b *bucket) available() *float64 {
+	if b == nil {
+		return nil
+	}
+	tokens := max(b.tokens, 0)
+	return &tokens
+}
+
+// meterWindow is the resolution of the measured ingest rates.
+const meterWindow = 10 * time.Second
+
+// meter measures accepted traffic over the last complete window.
+type meter struct {
+	start                  time.Time
+	records, bytes         uint64
+	prevRecords, prevBytes uint64
+}
+
+func (m *meter) add(now time.Time, records, bytes int) {
+	m.roll(now)
+	m.records += uint64(records)
+	m.bytes += uint64(bytes)
+}
+
+// rates returns records and bytes per second.
+func (m *meter) rates(now time.Time) (float64, float64) {
+	m.roll(now)
+	return float64(m.prevRecords) / meterWindow.Seconds(), float64(m.prevBytes) / meterWindow.Seconds()
+}
+
+func (m *meter) roll(now time.Time) {
+	switch elapsed := now.Sub(m.start); {
+	case elapsed >= 2*meterWindow:
+		m.prevRecords, m.prevBytes = 0, 0
+		m.records, m.bytes = 0, 0
+		m.start = now.Truncate(meterWindow)
+	case elapsed >= meterWindow:
+		m.prevRecords, m.prevBytes = m.records, m.bytes
+		m.records, m.bytes = 0, 0
+		m.start = m.start.Add(meterWindow)
+	}
+}
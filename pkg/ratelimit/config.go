@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+)
+
+// Scope selects who shares a token bucket.
+type Scope string
+
+const (
+	// ScopeTenant gives every tenant one bucket shared by all its callers.
+	ScopeTenant Scope = "tenant"
+	// ScopeKey gives every API key its own bucket. Callers without a key,
+	// such as browser sessions and the bootstrap token, share their
+	// tenant's bucket.
+	ScopeKey Scope = "key"
+)
+
+type Config struct {
+	// Enabled turns on ingest rate limits and daily quotas.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Scope is who a bucket belongs to: "tenant" or "key".
+	Scope Scope `mapstructure:"scope"`
+
+	// RecordsPerSecond and BytesPerSecond are the sustained ingest rates.
+	// Zero means unlimited.
+	RecordsPerSecond float64 `mapstructure:"records_per_second"`
+	BytesPerSecond   float64 `mapstructure:"bytes_per_second"`
+
+	// RecordsBurst and BytesBurst are the bucket sizes, i.e. how much may
+	// be sent at once after a quiet period. They default to two seconds of
+	// the sustained rate.
+	RecordsBurst float64 `mapstructure:"records_burst"`
+	BytesBurst   float64 `mapstructure:"bytes_burst"`
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("ratelimit"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	cfg := Config{
+		Enabled:          true,
+		Scope:            Scope(envOr("WATCHDATA_RATE_LIMIT_SCOPE", string(ScopeTenant))),
+		RecordsPerSecond: envFloat("WATCHDATA_RATE_LIMIT_RECORDS", 10000),
+		BytesPerSecond:   envFloat("WATCHDATA_RATE_LIMIT_BYTES", 10<<20),
+	}
+	if v := os.Getenv("WATCHDATA_RATE_LIMIT_ENABLED"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			cfg.Enabled = parsed
+		}
+	}
+	cfg.RecordsBurst = envFloat("WATCHDATA_RATE_LIMIT_RECORDS_BURST", 2*cfg.RecordsPerSecond)
+	cfg.BytesBurst = envFloat("WATCHDATA_RATE_LIMIT_BYTES_BURST", 2*cfg.BytesPerSecond)
+	return cfg
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func (c Config) Validate() error {
+	if c.Scope != ScopeTenant && c.Scope != ScopeKey {
+		return fmt.Errorf("unknown rate limit scope %q", c.Scope)
+	}
+	if c.RecordsPerSecond < 0 || c.BytesPerSecond < 0 {
+		return fmt.Errorf("rate limits must not be negative")
+	}
+	if c.RecordsPerSecond > 0 && c.RecordsBurst < 1 {
+		return fmt.Errorf("records burst must be at least 1")
+	}
+	if c.BytesPerSecond > 0 && c.BytesBurst < 1 {
+		return fmt.Errorf("bytes burst must be at least 1")
+	}
+	return nil
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
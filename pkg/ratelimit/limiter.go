@@ -0,0 +1,325 @@
+// Package ratelimit enforces ingest rate limits and daily storage quotas.
+package ratelimit
+
+import (
+	"context"
+	stderrors "errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+)
+
+// storedRefresh bounds how often the stored size of a tenant's logs is
+// read from ClickHouse. A tenant can overshoot its quota by what it sends
+// in this interval.
+const storedRefresh = 30 * time.Second
+
+// sweepInterval is how often buckets that have refilled are evicted. A
+// full bucket is the same as a new one, so eviction loses nothing and
+// keeps buckets of keys that stopped sending from piling up.
+const sweepInterval = time.Minute
+
+// Store provides tenant settings and storage usage.
+type Store interface {
+	TenantSettings(ctx context.Context, id string) (tenanttypes.Tenant, error)
+	StoredBytes(ctx context.Context, tenantID string, day time.Time) (uint64, error)
+}
+
+// Limiter admits or throttles ingest requests.
+type Limiter struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*limits
+	tenants   map[string]*tenantState
+	lastSweep time.Time
+}
+
+type limits struct {
+	tenantID string
+	records  *bucket
+	bytes    *bucket
+}
+
+type tenantState struct {
+	meter         meter
+	rateLimited   uint64
+	quotaExceeded uint64
+
+	stored    uint64
+	storedDay time.Time
+	storedAt  time.Time
+}
+
+func NewLimiter(cfg Config, store Store) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		store:   store,
+		now:     time.Now,
+		buckets: make(map[string]*limits),
+		tenants: make(map[string]*tenantState),
+	}
+}
+
+// SetClock replaces the clock, for tests.
+func (l *Limiter) SetClock(now func() time.Time) {
+	l.now = now
+}
+
+// Admit decides whether the caller in ctx may ingest a request of the
+// given size. Rejections are errors with CodeRateLimit, which may be
+// retried after RetryAfter, or CodeQuotaExceeded, which may not be
+// retried before the quota resets.
+func (l *Limiter) Admit(ctx context.Context, records, bytes int) error {
+	p, _ := authtypes.FromContext(ctx)
+	tenant := p.TenantID
+	if tenant == "" {
+		tenant = tenanttypes.DefaultTenant
+	}
+	now := l.now()
+
+	if l.cfg.Enabled {
+		if err := l.checkQuota(ctx, tenant, now); err != nil {
+			l.mu.Lock()
+			l.tenant(tenant).quotaExceeded++
+			l.mu.Unlock()
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.tenant(tenant)
+
+	if l.cfg.Enabled {
+		l.sweep(now)
+		b := l.bucket(l.key(p, tenant), tenant, now)
+		b.records.refill(now)
+		b.bytes.refill(now)
+		if wait := max(b.records.wait(float64(records)), b.bytes.wait(float64(bytes))); wait > 0 {
+			state.rateLimited++
+			return errors.NewMeta(errors.CodeRateLimit, "ingest rate limit exceeded", errors.SeverityInfo,
+				map[string]any{"tenant_id": tenant, "retry_after": wait})
+		}
+		b.records.take(float64(records))
+		b.bytes.take(float64(bytes))
+	}
+
+	state.meter.add(now, records, bytes)
+	return nil
+}
+
+// checkQuota rejects tenants that have stored more than their daily quota.
+// Quotas fail open: if settings or usage cannot be read the insert itself
+// will report the storage problem, and watchdata_quota_unchecked_total
+// counts the requests admitted unchecked.
+func (l *Limiter) checkQuota(ctx context.Context, tenant string, now time.Time) error {
+	settings, err := l.store.TenantSettings(ctx, tenant)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read tenant settings", "tenant", tenant, "error", err)
+		metrics.QuotaUnchecked.WithLabelValues(tenant).Inc()
+		return nil
+	}
+	if settings.DailyQuotaBytes == 0 {
+		return nil
+	}
+
+	stored, err := l.storedBytes(ctx, tenant, now, false)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read tenant storage usage", "tenant", tenant, "error", err)
+		metrics.QuotaUnchecked.WithLabelValues(tenant).Inc()
+		return nil
+	}
+	if stored < settings.DailyQuotaBytes {
+		return nil
+	}
+
+	// Retrying cannot succeed before midnight, so the error carries when
+	// the quota resets rather than a retry delay.
+	return errors.NewMeta(errors.CodeQuotaExceeded, "daily storage quota exceeded", errors.SeverityWarning,
+		map[string]any{"tenant_id": tenant, "resets_at": nextDay(now)})
+}
+
+// storedBytes returns the tenant's stored bytes for today, cached for
+// storedRefresh unless fresh is set.
+func (l *Limiter) storedBytes(ctx context.Context, tenant string, now time.Time, fresh bool) (uint64, error) {
+	day := now.UTC().Truncate(24 * time.Hour)
+
+	l.mu.Lock()
+	state := l.tenant(tenant)
+	if !fresh && state.storedDay.Equal(day) && now.Sub(state.storedAt) < storedRefresh {
+		stored := state.stored
+		l.mu.Unlock()
+		return stored, nil
+	}
+	l.mu.Unlock()
+
+	stored, err := l.store.StoredBytes(ctx, tenant, day)
+	if err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	state.stored, state.storedDay, state.storedAt = stored, day, now
+	l.mu.Unlock()
+	return stored, nil
+}
+
+func (l *Limiter) key(p authtypes.Principal, tenant string) string {
+	if l.cfg.Scope == ScopeKey && p.KeyID != "" {
+		return "key:" + p.KeyID
+	}
+	return "tenant:" + tenant
+}
+
+// bucket returns the buckets for key. It must be called with l.mu held.
+func (l *Limiter) bucket(key, tenant string, now time.Time) *limits {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &limits{
+			tenantID: tenant,
+			records:  newBucket(l.cfg.RecordsPerSecond, l.cfg.RecordsBurst, now),
+			bytes:    newBucket(l.cfg.BytesPerSecond, l.cfg.BytesBurst, now),
+		}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// sweep evicts full buckets at most once per sweepInterval. It must be
+// called with l.mu held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.records.refill(now)
+		b.bytes.refill(now)
+		if b.records.full() && b.bytes.full() {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// tenant returns the state of a tenant. It must be called with l.mu held.
+func (l *Limiter) tenant(id string) *tenantState {
+	s, ok := l.tenants[id]
+	if !ok {
+		s = &tenantState{}
+		l.tenants[id] = s
+	}
+	return s
+}
+
+// Limits are the limits that apply to a tenant.
+type Limits struct {
+	Enabled          bool    `json:"enabled"`
+	Scope            Scope   `json:"scope"`
+	RecordsPerSecond float64 `json:"records_per_second,omitempty"`
+	RecordsBurst     float64 `json:"records_burst,omitempty"`
+	BytesPerSecond   float64 `json:"bytes_per_second,omitempty"`
+	BytesBurst       float64 `json:"bytes_burst,omitempty"`
+	DailyQuotaBytes  uint64  `json:"daily_quota_bytes,omitempty"`
+}
+
+// BucketUsage is the state of one token bucket. Available tokens are
+// omitted for unlimited dimensions.
+type BucketUsage struct {
+	Key              string   `json:"key"`
+	RecordsAvailable *float64 `json:"records_available,omitempty"`
+	BytesAvailable   *float64 `json:"bytes_available,omitempty"`
+}
+
+// Usage is a tenant's current ingest usage against its limits.
+type Usage struct {
+	TenantID string `json:"tenant_id"`
+	Limits   Limits `json:"limits"`
+
+	// RecordsPerSecond and BytesPerSecond are measured over the last ten
+	// seconds on this server.
+	RecordsPerSecond float64 `json:"records_per_second"`
+	BytesPerSecond   float64 `json:"bytes_per_second"`
+
+	// StoredBytesToday is how much the compressed size of the tenant's
+	// logs grew today (UTC).
+	StoredBytesToday uint64 `json:"stored_bytes_today"`
+
+	// RateLimited and QuotaExceeded count rejected requests since the
+	// server started.
+	RateLimited   uint64 `json:"rate_limited"`
+	QuotaExceeded uint64 `json:"quota_exceeded"`
+
+	Buckets []BucketUsage `json:"buckets"`
+}
+
+// Usage reports a tenant's usage against its limits.
+func (l *Limiter) Usage(ctx context.Context, tenant string) (Usage, error) {
+	settings, err := l.store.TenantSettings(ctx, tenant)
+	if err != nil {
+		return Usage{}, err
+	}
+	now := l.now()
+	stored, err := l.storedBytes(ctx, tenant, now, true)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	u := Usage{
+		TenantID: tenant,
+		Limits: Limits{
+			Enabled:         l.cfg.Enabled,
+			Scope:           l.cfg.Scope,
+			DailyQuotaBytes: settings.DailyQuotaBytes,
+		},
+		StoredBytesToday: stored,
+		Buckets:          []BucketUsage{},
+	}
+	if l.cfg.Enabled {
+		u.Limits.RecordsPerSecond, u.Limits.RecordsBurst = l.cfg.RecordsPerSecond, l.cfg.RecordsBurst
+		u.Limits.BytesPerSecond, u.Limits.BytesBurst = l.cfg.BytesPerSecond, l.cfg.BytesBurst
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.tenant(tenant)
+	u.RecordsPerSecond, u.BytesPerSecond = state.meter.rates(now)
+	u.RateLimited, u.QuotaExceeded = state.rateLimited, state.quotaExceeded
+	for key, b := range l.buckets {
+		if b.tenantID != tenant {
+			continue
+		}
+		b.records.refill(now)
+		b.bytes.refill(now)
+		u.Buckets = append(u.Buckets, BucketUsage{Key: key, RecordsAvailable: b.records.available(), BytesAvailable: b.bytes.available()})
+	}
+	slices.SortFunc(u.Buckets, func(a, b BucketUsage) int { return strings.Compare(a.Key, b.Key) })
+	return u, nil
+}
+
+// RetryAfter returns when a request rejected by Admit may be retried. It
+// reports false for errors that must not be retried, such as exceeded
+// quotas.
+func RetryAfter(err error) (time.Duration, bool) {
+	var e *errors.Error
+	if !stderrors.As(err, &e) || e.Code != errors.CodeRateLimit {
+		return 0, false
+	}
+	wait, ok := e.Meta["retry_after"].(time.Duration)
+	return wait, ok
+}
+
+// nextDay is when daily quotas reset. Storage growth is measured per UTC
+// day.
+func nextDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
@@ -0,0 +1,170 @@
+package ratelimit_test
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/ratelimit"
+	"github.com/Ricky004/watchdata/pkg/types/authtypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	quota  uint64
+	stored uint64
+	err    error
+	reads  int
+}
+
+func (f *fakeStore) TenantSettings(_ context.Context, id string) (tenanttypes.Tenant, error) {
+	t := tenanttypes.Default(id)
+	t.DailyQuotaBytes = f.quota
+	return t, nil
+}
+
+func (f *fakeStore) StoredBytes(context.Context, string, time.Time) (uint64, error) {
+	f.reads++
+	return f.stored, f.err
+}
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newTestLimiter(cfg ratelimit.Config, store ratelimit.Store) (*ratelimit.Limiter, *clock) {
+	c := &clock{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l := ratelimit.NewLimiter(cfg, store)
+	l.SetClock(c.Now)
+	return l, c
+}
+
+func withPrincipal(tenant, keyID string) context.Context {
+	return authtypes.NewContext(context.Background(), authtypes.Principal{TenantID: tenant, KeyID: keyID, Role: authtypes.RoleEditor})
+}
+
+func assertCode(t *testing.T, err error, code errors.Code) {
+	t.Helper()
+	var e *errors.Error
+	require.True(t, stderrors.As(err, &e), "expected *errors.Error, got %v", err)
+	assert.Equal(t, code, e.Code)
+}
+
+func TestLimiterRecords(t *testing.T) {
+	cfg := ratelimit.Config{Enabled: true, Scope: ratelimit.ScopeTenant, RecordsPerSecond: 100, RecordsBurst: 200}
+	l, c := newTestLimiter(cfg, &fakeStore{})
+	ctx := withPrincipal("team-a", "")
+
+	require.NoError(t, l.Admit(ctx, 150, 0))
+	err := l.Admit(ctx, 100, 0)
+	assertCode(t, err, errors.CodeRateLimit)
+	wait, ok := ratelimit.RetryAfter(err)
+	require.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Other tenants have their own bucket.
+	require.NoError(t, l.Admit(withPrincipal("team-b", ""), 200, 0))
+
+	c.now = c.now.Add(wait)
+	require.NoError(t, l.Admit(ctx, 100, 0))
+
+	// A batch larger than the bucket is admitted once the bucket is full.
+	c.now = c.now.Add(10 * time.Second)
+	require.NoError(t, l.Admit(ctx, 1000, 0))
+	assertCode(t, l.Admit(ctx, 1, 0), errors.CodeRateLimit)
+}
+
+func TestLimiterBytesAndKeys(t *testing.T) {
+	cfg := ratelimit.Config{Enabled: true, Scope: ratelimit.ScopeKey, BytesPerSecond: 1000, BytesBurst: 1000}
+	l, _ := newTestLimiter(cfg, &fakeStore{})
+
+	require.NoError(t, l.Admit(withPrincipal("team-a", "key-1"), 1, 1000))
+	assertCode(t, l.Admit(withPrincipal("team-a", "key-1"), 1, 10), errors.CodeRateLimit)
+	require.NoError(t, l.Admit(withPrincipal("team-a", "key-2"), 1, 1000))
+
+	u, err := l.Usage(context.Background(), "team-a")
+	require.NoError(t, err)
+	require.Len(t, u.Buckets, 2)
+	assert.Equal(t, "key:key-1", u.Buckets[0].Key)
+	assert.Nil(t, u.Buckets[0].RecordsAvailable)
+	require.NotNil(t, u.Buckets[0].BytesAvailable)
+	assert.Zero(t, *u.Buckets[0].BytesAvailable)
+	assert.Equal(t, uint64(1), u.RateLimited)
+}
+
+func TestLimiterEvictsIdleBuckets(t *testing.T) {
+	cfg := ratelimit.Config{Enabled: true, Scope: ratelimit.ScopeKey, RecordsPerSecond: 100, RecordsBurst: 100}
+	l, c := newTestLimiter(cfg, &fakeStore{})
+
+	for _, key := range []string{"key-1", "key-2", "key-3"} {
+		require.NoError(t, l.Admit(withPrincipal("team-a", key), 100, 0))
+	}
+	u, err := l.Usage(context.Background(), "team-a")
+	require.NoError(t, err)
+	assert.Len(t, u.Buckets, 3)
+
+	// Buckets that have refilled are evicted by the next sweep.
+	c.now = c.now.Add(time.Minute)
+	require.NoError(t, l.Admit(withPrincipal("team-a", "key-1"), 50, 0))
+	u, err = l.Usage(context.Background(), "team-a")
+	require.NoError(t, err)
+	require.Len(t, u.Buckets, 1)
+	assert.Equal(t, "key:key-1", u.Buckets[0].Key)
+	assert.Equal(t, 50.0, *u.Buckets[0].RecordsAvailable)
+}
+
+func TestLimiterQuota(t *testing.T) {
+	store := &fakeStore{quota: 1 << 20, stored: 1 << 19}
+	cfg := ratelimit.Config{Enabled: true, Scope: ratelimit.ScopeTenant}
+	l, c := newTestLimiter(cfg, store)
+	ctx := withPrincipal("team-a", "")
+
+	require.NoError(t, l.Admit(ctx, 10, 100))
+	require.NoError(t, l.Admit(ctx, 10, 100))
+	assert.Equal(t, 1, store.reads, "stored bytes are cached")
+
+	store.stored = 1 << 20
+	c.now = c.now.Add(time.Minute)
+	err := l.Admit(ctx, 10, 100)
+	assertCode(t, err, errors.CodeQuotaExceeded)
+	_, ok := ratelimit.RetryAfter(err)
+	assert.False(t, ok, "exceeded quotas are not retried")
+	assert.Equal(t, time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), errors.From(err).Meta["resets_at"], "quotas reset at midnight UTC")
+
+	u, err := l.Usage(context.Background(), "team-a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1<<20), u.StoredBytesToday)
+	assert.Equal(t, uint64(1<<20), u.Limits.DailyQuotaBytes)
+	assert.Equal(t, uint64(1), u.QuotaExceeded)
+}
+
+func TestLimiterQuotaFailsOpen(t *testing.T) {
+	store := &fakeStore{quota: 1, err: stderrors.New("connection refused")}
+	l, _ := newTestLimiter(ratelimit.Config{Enabled: true, Scope: ratelimit.ScopeTenant}, store)
+
+	before := testutil.ToFloat64(metrics.QuotaUnchecked.WithLabelValues("team-b"))
+	require.NoError(t, l.Admit(withPrincipal("team-b", ""), 10, 100))
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.QuotaUnchecked.WithLabelValues("team-b")))
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	store := &fakeStore{quota: 1, stored: 10}
+	l, c := newTestLimiter(ratelimit.Config{Scope: ratelimit.ScopeTenant, RecordsPerSecond: 1, RecordsBurst: 1}, store)
+	ctx := withPrincipal("", "")
+
+	for range 5 {
+		require.NoError(t, l.Admit(ctx, 100, 1000))
+	}
+
+	c.now = c.now.Add(10 * time.Second)
+	u, err := l.Usage(context.Background(), tenanttypes.DefaultTenant)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, u.RecordsPerSecond)
+	assert.Equal(t, 500.0, u.BytesPerSecond)
+}
@@ -0,0 +1,146 @@
+package redaction
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigPathEnv names the environment variable holding the path of the
+	// redaction config file.
+	ConfigPathEnv = "WATCHDATA_REDACTION_CONFIG"
+	// HashKeyEnv names the environment variable holding the key hashes
+	// are salted with. It is kept out of the config file.
+	HashKeyEnv = "WATCHDATA_REDACTION_HASH_KEY"
+)
+
+// Config holds the redaction rules, applied in order to every record
+// before it is stored.
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+
+	// HashKey salts the hashes of rules in ModeHash, so that the values
+	// they replace cannot be recovered by hashing guesses.
+	HashKey string `yaml:"-"`
+}
+
+type Mode string
+
+const (
+	// ModeMask replaces what a rule matches with its replacement.
+	ModeMask Mode = "mask"
+	// ModeHash replaces what a rule matches with a keyed hash of it, so
+	// that records about the same value can still be correlated.
+	ModeHash Mode = "hash"
+	// ModeDrop discards the records a rule matches.
+	ModeDrop Mode = "drop"
+)
+
+type Detector string
+
+const (
+	DetectorEmail       Detector = "email"
+	DetectorCreditCard  Detector = "credit_card"
+	DetectorIPv4        Detector = "ipv4"
+	DetectorIPv6        Detector = "ipv6"
+	DetectorIP          Detector = "ip"
+	DetectorBearerToken Detector = "bearer_token"
+	DetectorJWT         Detector = "jwt"
+)
+
+// Rule finds personal data in bodies and attribute values with a built-in
+// detector or a regular expression. If the expression has groups, only
+// the first one is redacted, as in `password=(\S+)`.
+type Rule struct {
+	// Name labels the rule's redaction counter. It defaults to the
+	// detector.
+	Name     string   `yaml:"name"`
+	Detector Detector `yaml:"detector"`
+	Pattern  string   `yaml:"pattern"`
+	// Keys limits the rule to the values of these attribute keys, leaving
+	// the body alone. Without a detector or pattern, the whole value is
+	// redacted.
+	Keys []string `yaml:"keys"`
+
+	Mode Mode `yaml:"mode"`
+	// Replacement is what ModeMask replaces matches with. It defaults to
+	// [REDACTED].
+	Replacement string `yaml:"replacement"`
+}
+
+func (c Config) Validate() error {
+	names := make(map[string]bool, len(c.Rules))
+	for i, r := range c.Rules {
+		name := r.name()
+		if name == "" {
+			return fmt.Errorf("rule %d: name is required", i+1)
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate rule %q", name)
+		}
+		names[name] = true
+
+		if r.Detector != "" && r.Pattern != "" {
+			return fmt.Errorf("rule %q: detector and pattern are exclusive", name)
+		}
+		if r.Detector == "" && r.Pattern == "" && len(r.Keys) == 0 {
+			return fmt.Errorf("rule %q: a detector, pattern or keys are required", name)
+		}
+		if r.Detector != "" {
+			if _, ok := detectors[r.Detector]; !ok {
+				return fmt.Errorf("rule %q: unsupported detector %q", name, r.Detector)
+			}
+		}
+		if r.Pattern != "" {
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return fmt.Errorf("rule %q: invalid pattern: %w", name, err)
+			}
+		}
+
+		switch r.Mode {
+		case ModeMask, ModeDrop:
+		case ModeHash:
+			if c.HashKey == "" {
+				return fmt.Errorf("rule %q: hashing requires %s", name, HashKeyEnv)
+			}
+		case "":
+			return fmt.Errorf("rule %q: mode is required", name)
+		default:
+			return fmt.Errorf("rule %q: unsupported mode %q", name, r.Mode)
+		}
+	}
+	return nil
+}
+
+func (r Rule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return string(r.Detector)
+}
+
+// LoadConfig reads the redaction rules from the file named by
+// WATCHDATA_REDACTION_CONFIG. Without it, nothing is redacted.
+func LoadConfig() (Config, error) {
+	cfg := Config{HashKey: os.Getenv(HashKeyEnv)}
+
+	path := os.Getenv(ConfigPathEnv)
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read redaction config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse redaction config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid redaction config: %w", err)
+	}
+	return cfg, nil
+}
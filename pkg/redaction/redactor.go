@@ -0,0 +1,236 @@
+// Package redaction removes personal data from log records before they
+// are stored, masking or hashing what its rules match or dropping the
+// records.
+package redaction
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Ricky004/watchdata/pkg/metrics"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+)
+
+const defaultReplacement = "[REDACTED]"
+
+// detector is a built-in rule. valid, if set, rejects matches that only
+// look like the data, such as digits failing the Luhn check.
+type detector struct {
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+var (
+	ipv4 = `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`
+	ipv6 = `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}(?:%[0-9A-Za-z]+)?`
+
+	detectors = map[Detector]detector{
+		DetectorEmail: {re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+		// Card numbers start with 2 to 6, which keeps millisecond
+		// timestamps out.
+		DetectorCreditCard: {re: regexp.MustCompile(`\b[2-6](?:[ -]?\d){12,18}\b`), valid: luhn},
+		DetectorIPv4:       {re: regexp.MustCompile(ipv4)},
+		DetectorIPv6:       {re: regexp.MustCompile(ipv6), valid: isIPv6},
+		DetectorIP:         {re: regexp.MustCompile(ipv4 + `|` + ipv6), valid: isIP},
+		// The scheme is kept so that the redacted value still reads as a
+		// credential.
+		DetectorBearerToken: {re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)},
+		DetectorJWT:         {re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)},
+	}
+)
+
+// Redactor applies redaction rules to log records.
+type Redactor struct {
+	rules []rule
+}
+
+type rule struct {
+	name  string
+	mode  Mode
+	keys  []string
+	re    *regexp.Regexp // nil redacts whole values
+	valid func(string) bool
+
+	replacement string
+	key         []byte
+}
+
+// NewRedactor compiles the rules of cfg.
+func NewRedactor(cfg Config) (*Redactor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	rules := make([]rule, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		compiled := rule{
+			name:        r.name(),
+			mode:        r.Mode,
+			keys:        r.Keys,
+			replacement: r.Replacement,
+			key:         []byte(cfg.HashKey),
+		}
+		if compiled.replacement == "" {
+			compiled.replacement = defaultReplacement
+		}
+		if d, ok := detectors[r.Detector]; ok {
+			compiled.re, compiled.valid = d.re, d.valid
+		}
+		if r.Pattern != "" {
+			compiled.re = regexp.MustCompile(r.Pattern)
+		}
+		rules[i] = compiled
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Redact applies the rules to the bodies, attribute values and resource
+// attribute values of logs in place and returns the records that were not
+// dropped, reusing the array of logs.
+func (r *Redactor) Redact(logs []telemetrytypes.LogRecord) []telemetrytypes.LogRecord {
+	if len(r.rules) == 0 {
+		return logs
+	}
+	kept := logs[:0]
+	for i := range logs {
+		if r.redact(&logs[i]) {
+			kept = append(kept, logs[i])
+		}
+	}
+	return kept
+}
+
+// redact applies the rules to rec and reports whether it is kept.
+func (r *Redactor) redact(rec *telemetrytypes.LogRecord) bool {
+	for i := range r.rules {
+		rl := &r.rules[i]
+
+		var n, found int
+		if len(rl.keys) == 0 {
+			rec.Body, n = rl.apply(rec.Body)
+			found += n
+		}
+		found += rl.applyAttrs(rec.Attributes)
+		found += rl.applyAttrs(rec.Resource.Attributes)
+
+		if found == 0 {
+			continue
+		}
+		metrics.Redactions.WithLabelValues(rl.name).Add(float64(found))
+		if rl.mode == ModeDrop {
+			return false
+		}
+	}
+	return true
+}
+
+// applyAttrs redacts the values of attrs the rule applies to and returns
+// the number of matches.
+func (rl *rule) applyAttrs(attrs []telemetrytypes.KeyValue) int {
+	found := 0
+	for i := range attrs {
+		kv := &attrs[i]
+		if len(rl.keys) > 0 && !slices.Contains(rl.keys, kv.Key) {
+			continue
+		}
+		// Values are text, except in self telemetry, whose numbers and
+		// booleans are not personal data.
+		if s, ok := kv.Value.(string); ok {
+			var n int
+			kv.Value, n = rl.apply(s)
+			found += n
+		}
+	}
+	return found
+}
+
+// apply redacts what the rule matches in text and returns the result and
+// the number of matches.
+func (rl *rule) apply(text string) (string, int) {
+	if rl.re == nil {
+		if text == "" {
+			return text, 0
+		}
+		return rl.replace(text), 1
+	}
+
+	var (
+		b    strings.Builder
+		last int
+		n    int
+	)
+	for _, m := range rl.re.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		if len(m) > 2 {
+			if m[2] < 0 {
+				continue
+			}
+			start, end = m[2], m[3]
+		}
+		if start == end || (rl.valid != nil && !rl.valid(text[start:end])) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(rl.replace(text[start:end]))
+		last = end
+		n++
+	}
+	if n == 0 {
+		return text, 0
+	}
+	b.WriteString(text[last:])
+	return b.String(), n
+}
+
+func (rl *rule) replace(match string) string {
+	switch rl.mode {
+	case ModeHash:
+		mac := hmac.New(sha256.New, rl.key)
+		mac.Write([]byte(match))
+		return "[" + rl.name + ":" + hex.EncodeToString(mac.Sum(nil)[:8]) + "]"
+	case ModeDrop:
+		// The record is dropped, so the text does not matter.
+		return match
+	default:
+		return rl.replacement
+	}
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum of card
+// numbers.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// isIPv6 accepts addresses with at least three groups, so that names
+// such as Foo::Bar are not taken for ::Ba.
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6() && strings.Count(s, ":") >= 3
+}
+
+// isIP accepts the IPv4 matches as they are and checks IPv6 ones.
+func isIP(s string) bool {
+	return !strings.Contains(s, ":") || isIPv6(s)
+}
@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+)
+
+type Config struct {
+	// LogLevel is the minimum level logged: debug, info, warn or error.
+	LogLevel string `mapstructure:"log_level"`
+
+	// LogFormat is "text" or "json".
+	LogFormat string `mapstructure:"log_format"`
+
+	// TracesEndpoint is an OTLP/gRPC endpoint, such as the collector, to
+	// send spans to. Empty disables the export.
+	TracesEndpoint string `mapstructure:"traces_endpoint"`
+
+	// TracesInsecure sends spans without TLS.
+	TracesInsecure bool `mapstructure:"traces_insecure"`
+
+	// SampleRatio is the fraction of traces recorded.
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+
+	// SelfIngest stores watchdata's own logs and spans in watchdata, in the
+	// default tenant under ReservedServiceName.
+	SelfIngest bool `mapstructure:"self_ingest"`
+}
+
+func NewConfigFactory() factory.Factory {
+	return factory.NewFactory(factory.MustNewId("telemetry"), newConfig)
+}
+
+func newConfig() factory.Configurable {
+	return Config{
+		LogLevel:       envOr("WATCHDATA_LOG_LEVEL", "info"),
+		LogFormat:      envOr("WATCHDATA_LOG_FORMAT", "text"),
+		TracesEndpoint: os.Getenv("WATCHDATA_TRACES_ENDPOINT"),
+		TracesInsecure: envBool("WATCHDATA_TRACES_INSECURE", false),
+		SampleRatio:    envFloat("WATCHDATA_TRACES_SAMPLE_RATIO", 1),
+		SelfIngest:     envBool("WATCHDATA_SELF_TELEMETRY", false),
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func envFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+func (c Config) Validate() error {
+	if _, err := c.level(); err != nil {
+		return err
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("unknown log format %q", c.LogFormat)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sample ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+func (c Config) level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", c.LogLevel)
+	}
+	return level, nil
+}
+
+// tracing reports whether spans are recorded at all.
+func (c Config) tracing() bool {
+	return c.TracesEndpoint != "" || c.SelfIngest
+}
+
+func LoadConfig() (Config, error) {
+	cfg := newConfig().(Config)
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,269 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxBuffered bounds the self telemetry waiting to be written. More is
+// dropped, e.g. while ClickHouse is down.
+const maxBuffered = 10000
+
+// Sink stores self telemetry.
+type Sink interface {
+	InsertLogs(ctx context.Context, logs []telemetrytypes.LogRecord) error
+}
+
+// selfIngest buffers watchdata's own logs and ended spans as log records.
+type selfIngest struct {
+	mu      sync.Mutex
+	records []telemetrytypes.LogRecord
+	dropped int
+}
+
+func newSelfIngest() *selfIngest {
+	return &selfIngest{}
+}
+
+func (s *selfIngest) add(rec telemetrytypes.LogRecord) {
+	rec.TenantID = tenanttypes.DefaultTenant
+	rec.ObservedTime = time.Now()
+	rec.Resource = telemetrytypes.Resource{Attributes: []telemetrytypes.KeyValue{
+		{Key: "service.name", Value: ReservedServiceName},
+	}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.records) >= maxBuffered {
+		s.dropped++
+		return
+	}
+	s.records = append(s.records, rec)
+}
+
+func (s *selfIngest) flush(ctx context.Context, sink Sink) error {
+	s.mu.Lock()
+	records, dropped := s.records, s.dropped
+	s.records, s.dropped = nil, 0
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		slog.WarnContext(ctx, "dropped self telemetry", "records", dropped)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return sink.InsertLogs(ctx, records)
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (s *selfIngest) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd stores an ended span as a log record named after the span.
+func (s *selfIngest) OnEnd(span sdktrace.ReadOnlySpan) {
+	severity, text := int8(9), "INFO"
+	if span.Status().Code == codes.Error {
+		severity, text = 17, "ERROR"
+	}
+
+	attrs := []telemetrytypes.KeyValue{
+		{Key: "span.kind", Value: span.SpanKind().String()},
+		{Key: "duration_ms", Value: float64(span.EndTime().Sub(span.StartTime())) / float64(time.Millisecond)},
+	}
+	if span.Parent().IsValid() {
+		attrs = append(attrs, telemetrytypes.KeyValue{Key: "parent_span_id", Value: span.Parent().SpanID().String()})
+	}
+	if msg := span.Status().Description; msg != "" {
+		attrs = append(attrs, telemetrytypes.KeyValue{Key: "status_message", Value: msg})
+	}
+	for _, kv := range span.Attributes() {
+		attrs = append(attrs, telemetrytypes.KeyValue{Key: string(kv.Key), Value: kv.Value.Emit()})
+	}
+
+	sc := span.SpanContext()
+	s.add(telemetrytypes.LogRecord{
+		Timestamp:      span.EndTime(),
+		SeverityNumber: severity,
+		SeverityText:   text,
+		Body:           span.Name(),
+		Attributes:     attrs,
+		TraceID:        sc.TraceID().String(),
+		SpanID:         sc.SpanID().String(),
+		TraceFlags:     uint8(sc.TraceFlags()),
+	})
+}
+
+// Shutdown implements sdktrace.SpanProcessor.
+func (s *selfIngest) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements sdktrace.SpanProcessor. Records are written by
+// Telemetry.Service.
+func (s *selfIngest) ForceFlush(context.Context) error { return nil }
+
+func (s *selfIngest) handler(level slog.Level) slog.Handler {
+	return &recordHandler{self: s, level: level}
+}
+
+// recordHandler turns slog records into log records for self-ingest.
+type recordHandler struct {
+	self   *selfIngest
+	level  slog.Level
+	attrs  []telemetrytypes.KeyValue
+	prefix string
+}
+
+func (h *recordHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && !suppressed(ctx)
+}
+
+func (h *recordHandler) Handle(ctx context.Context, r slog.Record) error {
+	if suppressed(ctx) {
+		return nil
+	}
+
+	attrs := append([]telemetrytypes.KeyValue(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendAttr(attrs, h.prefix, a)
+		return true
+	})
+
+	rec := telemetrytypes.LogRecord{
+		Timestamp:      r.Time,
+		SeverityNumber: severityNumber(r.Level),
+		SeverityText:   r.Level.String(),
+		Body:           r.Message,
+		Attributes:     attrs,
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		rec.TraceID, rec.SpanID, rec.TraceFlags = sc.TraceID().String(), sc.SpanID().String(), uint8(sc.TraceFlags())
+	}
+	h.self.add(rec)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]telemetrytypes.KeyValue(nil), h.attrs...)
+	for _, a := range as {
+		next.attrs = appendAttr(next.attrs, h.prefix, a)
+	}
+	return &next
+}
+
+func (h *recordHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// appendAttr flattens groups into dotted keys.
+func appendAttr(attrs []telemetrytypes.KeyValue, prefix string, a slog.Attr) []telemetrytypes.KeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			attrs = appendAttr(attrs, prefix, ga)
+		}
+		return attrs
+	}
+	if a.Key == "" {
+		return attrs
+	}
+
+	var v any
+	switch a.Value.Kind() {
+	case slog.KindString:
+		v = a.Value.String()
+	case slog.KindInt64:
+		v = a.Value.Int64()
+	case slog.KindUint64:
+		v = a.Value.Uint64()
+	case slog.KindFloat64:
+		v = a.Value.Float64()
+	case slog.KindBool:
+		v = a.Value.Bool()
+	case slog.KindTime:
+		v = a.Value.Time().Format(time.RFC3339Nano)
+	default:
+		v = fmt.Sprint(a.Value.Any())
+	}
+	return append(attrs, telemetrytypes.KeyValue{Key: prefix + a.Key, Value: v})
+}
+
+// severityNumber maps slog levels to OpenTelemetry severity numbers.
+func severityNumber(level slog.Level) int8 {
+	switch {
+	case level >= slog.LevelError:
+		return 17
+	case level >= slog.LevelWarn:
+		return 13
+	case level >= slog.LevelInfo:
+		return 9
+	default:
+		return 5
+	}
+}
+
+// contextHandler adds the trace and span of the context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(as)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// teeHandler sends records to two handlers.
+type teeHandler struct {
+	a, b slog.Handler
+}
+
+func (h teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.a.Enabled(ctx, level) || h.b.Enabled(ctx, level)
+}
+
+func (h teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.a.Enabled(ctx, r.Level) {
+		err = h.a.Handle(ctx, r.Clone())
+	}
+	if h.b.Enabled(ctx, r.Level) {
+		if bErr := h.b.Handle(ctx, r); bErr != nil && err == nil {
+			err = bErr
+		}
+	}
+	return err
+}
+
+func (h teeHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return teeHandler{h.a.WithAttrs(as), h.b.WithAttrs(as)}
+}
+
+func (h teeHandler) WithGroup(name string) slog.Handler {
+	return teeHandler{h.a.WithGroup(name), h.b.WithGroup(name)}
+}
@@ -0,0 +1,163 @@
+// Package telemetry sets up watchdata's own logs and traces: structured
+// logging through slog, OpenTelemetry spans and, optionally, ingesting both
+// into watchdata itself.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/factory"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReservedServiceName is the service.name of watchdata's own logs and spans
+// when they are ingested into watchdata. Other services should not use it.
+const ReservedServiceName = "watchdata-self"
+
+// serviceName is the service.name of spans exported over OTLP.
+const serviceName = "watchdata"
+
+const instrumentationName = "github.com/Ricky004/watchdata"
+
+// flushInterval is how often self telemetry is written to the store.
+const flushInterval = time.Second
+
+type suppressKey struct{}
+
+// Suppress marks ctx so no spans or self-ingested logs are recorded under
+// it. Writing self telemetry uses it so the write is not recorded in turn.
+func Suppress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, suppressKey{}, true)
+}
+
+func suppressed(ctx context.Context) bool {
+	s, _ := ctx.Value(suppressKey{}).(bool)
+	return s
+}
+
+// Start starts a span with the global tracer provider, unless ctx is
+// suppressed.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if suppressed(ctx) {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// Telemetry owns the tracer provider and the self-ingest buffer.
+type Telemetry struct {
+	tp   *sdktrace.TracerProvider
+	self *selfIngest
+}
+
+// Setup installs the default slog logger, which also receives the output
+// of the log package, and the global tracer provider.
+func Setup(ctx context.Context, cfg Config) (*Telemetry, error) {
+	level, err := cfg.level()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Telemetry{}
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	handler = contextHandler{handler}
+	if cfg.SelfIngest {
+		t.self = newSelfIngest()
+		handler = teeHandler{handler, t.self.handler(level)}
+	}
+	slog.SetDefault(slog.New(handler))
+
+	if !cfg.tracing() {
+		return t, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+	if cfg.TracesEndpoint != "" {
+		expOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.TracesEndpoint)}
+		if cfg.TracesInsecure {
+			expOpts = append(expOpts, otlptracegrpc.WithInsecure())
+		}
+		exp, err := otlptracegrpc.New(ctx, expOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create span exporter: %w", err)
+		}
+		tpOpts = append(tpOpts, sdktrace.WithBatcher(exp))
+	}
+	if t.self != nil {
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(t.self))
+	}
+
+	t.tp = sdktrace.NewTracerProvider(tpOpts...)
+	otel.SetTracerProvider(t.tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return t, nil
+}
+
+// Service writes self telemetry to sink until it is stopped, then flushes
+// and shuts down the tracer provider. Register it right after the store so
+// it is stopped right before it.
+func (t *Telemetry) Service(sink Sink) factory.IdxService {
+	return factory.NewRunService(factory.MustNewId("telemetry"), func(ctx context.Context) error {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				t.flush(ctx, sink)
+			case <-ctx.Done():
+				return t.shutdown(sink)
+			}
+		}
+	})
+}
+
+func (t *Telemetry) shutdown(sink Sink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	if t.tp != nil {
+		err = t.tp.ForceFlush(ctx)
+	}
+	t.flush(ctx, sink)
+	if t.tp != nil {
+		if shutdownErr := t.tp.Shutdown(ctx); shutdownErr != nil {
+			err = shutdownErr
+		}
+	}
+	return err
+}
+
+func (t *Telemetry) flush(ctx context.Context, sink Sink) {
+	if t.self == nil || sink == nil {
+		return
+	}
+	ctx = Suppress(ctx)
+	if err := t.self.flush(ctx, sink); err != nil {
+		slog.ErrorContext(ctx, "failed to store self telemetry", "error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package telemetry_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/errors"
+	"github.com/Ricky004/watchdata/pkg/telemetry"
+	"github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sink struct {
+	mu   sync.Mutex
+	logs []telemetrytypes.LogRecord
+}
+
+func (s *sink) InsertLogs(ctx context.Context, logs []telemetrytypes.LogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, logs...)
+	return nil
+}
+
+func attr(rec telemetrytypes.LogRecord, key string) any {
+	for _, kv := range rec.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+func TestSelfIngest(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	tel, err := telemetry.Setup(context.Background(), telemetry.Config{
+		LogLevel: "info", LogFormat: "json", SampleRatio: 1, SelfIngest: true,
+	})
+	require.NoError(t, err)
+
+	ctx, span := telemetry.Start(context.Background(), "work")
+	slog.InfoContext(ctx, "inside", "error", errors.New(errors.CodeInvalidRequest, "bad input", errors.SeverityInfo))
+	span.End()
+	slog.InfoContext(telemetry.Suppress(context.Background()), "suppressed")
+	slog.Debug("below level")
+
+	out := &sink{}
+	svc := tel.Service(out)
+	go func() { _ = svc.Start(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, svc.Stop(context.Background()))
+
+	require.Len(t, out.logs, 2)
+	logRec, spanRec := out.logs[0], out.logs[1]
+
+	assert.Equal(t, "inside", logRec.Body)
+	assert.Equal(t, "INFO", logRec.SeverityText)
+	assert.Equal(t, string(errors.CodeInvalidRequest), attr(logRec, "error.code"))
+	assert.Equal(t, span.SpanContext().TraceID().String(), logRec.TraceID)
+
+	assert.Equal(t, "work", spanRec.Body)
+	assert.Equal(t, logRec.TraceID, spanRec.TraceID)
+	assert.NotNil(t, attr(spanRec, "duration_ms"))
+
+	for _, rec := range out.logs {
+		assert.Equal(t, tenanttypes.DefaultTenant, rec.TenantID)
+		assert.Equal(t, telemetry.ReservedServiceName, rec.Resource.Attributes[0].Value)
+	}
+}
+
+func TestStartSuppressed(t *testing.T) {
+	_, span := telemetry.Start(telemetry.Suppress(context.Background()), "quiet")
+	assert.False(t, span.SpanContext().IsValid())
+}
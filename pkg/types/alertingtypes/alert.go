@@ -0,0 +1,70 @@
+package alertingtypes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+type State string
+
+const (
+	StateInactive State = "inactive"
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// TenantLabel is added to every alert so that notification routes can
+// match on the tenant that owns the rule.
+const TenantLabel = "tenant"
+
+// Alert is the state of one rule for one group of label values.
+type Alert struct {
+	TenantID    string            `json:"tenant_id"`
+	RuleID      string            `json:"rule_id"`
+	RuleName    string            `json:"rule_name"`
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	State       State             `json:"state"`
+	Value       float64           `json:"value"`
+
+	ActiveAt        time.Time `json:"active_at"`
+	FiredAt         time.Time `json:"fired_at,omitzero"`
+	ResolvedAt      time.Time `json:"resolved_at,omitzero"`
+	LastEvaluatedAt time.Time `json:"last_evaluated_at"`
+}
+
+// Transition records an alert moving from one state to another.
+type Transition struct {
+	TenantID    string            `json:"tenant_id"`
+	RuleID      string            `json:"rule_id"`
+	RuleName    string            `json:"rule_name"`
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	From        State             `json:"from"`
+	To          State             `json:"to"`
+	Value       float64           `json:"value"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+// Fingerprint returns a stable identifier for a rule and a set of labels.
+func Fingerprint(ruleID string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write([]byte(ruleID))
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(labels[k]))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
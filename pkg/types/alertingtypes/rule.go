@@ -0,0 +1,176 @@
+package alertingtypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+	"github.com/Ricky004/watchdata/pkg/types/anomalytypes"
+)
+
+type Aggregation string
+
+const (
+	// AggregationCount is the number of matching records in the window.
+	AggregationCount Aggregation = "count"
+	// AggregationRate is the number of matching records per second in the window.
+	AggregationRate Aggregation = "rate"
+)
+
+// Source is what a rule counts.
+type Source string
+
+const (
+	// SourceLogs counts log records. It is the default.
+	SourceLogs Source = "logs"
+	// SourceAnomalies counts anomalies found by the detector, see
+	// anomalytypes.Finding.
+	SourceAnomalies Source = "anomalies"
+)
+
+type Comparator string
+
+const (
+	ComparatorGt  Comparator = "gt"
+	ComparatorGte Comparator = "gte"
+	ComparatorLt  Comparator = "lt"
+	ComparatorLte Comparator = "lte"
+)
+
+// Compare reports whether value satisfies the comparator against threshold.
+func (c Comparator) Compare(value, threshold float64) bool {
+	switch c {
+	case ComparatorGt:
+		return value > threshold
+	case ComparatorGte:
+		return value >= threshold
+	case ComparatorLt:
+		return value < threshold
+	case ComparatorLte:
+		return value <= threshold
+	}
+	return false
+}
+
+// Duration is a time.Duration that marshals to and from strings like "5m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Rule is a log-based alert rule.
+type Rule struct {
+	ID          string `json:"id"`
+	TenantID    string `json:"tenant_id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// Source is what the rule counts, log records by default.
+	Source Source `json:"source,omitempty"`
+	// AnomalyKinds limits an anomalies rule to some kinds of finding.
+	AnomalyKinds []anomalytypes.Kind `json:"anomaly_kinds,omitempty"`
+
+	// Filter selects the log records the rule looks at, see pkg/filter.
+	// Anomalies rules may only filter on the fields findings carry, see
+	// anomalytypes.Column.
+	Filter      string      `json:"filter"`
+	Aggregation Aggregation `json:"aggregation"`
+	Comparator  Comparator  `json:"comparator"`
+	Threshold   float64     `json:"threshold"`
+
+	// Window is the lookback window the aggregation is computed over.
+	Window Duration `json:"window"`
+	// Interval is how often the rule is evaluated.
+	Interval Duration `json:"interval"`
+	// For is how long the condition must hold before the alert fires.
+	For Duration `json:"for"`
+	// GroupBy splits the evaluation into one alert per distinct value set.
+	GroupBy []string `json:"group_by,omitempty"`
+
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Enabled     bool              `json:"enabled"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultInterval is used when a rule does not set an evaluation interval.
+const DefaultInterval = Duration(time.Minute)
+
+// Validate checks that the rule can be evaluated.
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	expr, err := filter.Parse(r.Filter)
+	if err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	switch r.Source {
+	case "", SourceLogs:
+		if len(r.AnomalyKinds) > 0 {
+			return fmt.Errorf("anomaly_kinds requires the anomalies source")
+		}
+	case SourceAnomalies:
+		if _, err := anomalytypes.MapFilter(expr); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+		for _, k := range r.AnomalyKinds {
+			if _, err := anomalytypes.ParseKind(string(k)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported source %q", r.Source)
+	}
+	switch r.Aggregation {
+	case AggregationCount, AggregationRate:
+	default:
+		return fmt.Errorf("unsupported aggregation %q", r.Aggregation)
+	}
+	switch r.Comparator {
+	case ComparatorGt, ComparatorGte, ComparatorLt, ComparatorLte:
+	default:
+		return fmt.Errorf("unsupported comparator %q", r.Comparator)
+	}
+	if r.Window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	if r.Interval < 0 || r.For < 0 {
+		return fmt.Errorf("interval and for must not be negative")
+	}
+	for _, key := range r.GroupBy {
+		if r.Source == SourceAnomalies {
+			if _, err := anomalytypes.GroupColumn(key); err != nil {
+				return fmt.Errorf("invalid group_by key: %w", err)
+			}
+		} else if _, err := filter.ParseField(key); err != nil {
+			return fmt.Errorf("invalid group_by key: %w", err)
+		}
+	}
+	return nil
+}
+
+// EvalInterval returns the evaluation interval, falling back to DefaultInterval.
+func (r *Rule) EvalInterval() time.Duration {
+	if r.Interval <= 0 {
+		return time.Duration(DefaultInterval)
+	}
+	return time.Duration(r.Interval)
+}
@@ -0,0 +1,113 @@
+package alertingtypes
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Matcher matches an alert label against a value or a regular expression.
+type Matcher struct {
+	Name    string `json:"name" yaml:"name"`
+	Value   string `json:"value" yaml:"value"`
+	IsRegex bool   `json:"is_regex,omitempty" yaml:"is_regex"`
+
+	// re is the compiled Value of regex matchers, set by Compile.
+	re *regexp.Regexp
+}
+
+// Validate checks that the matcher is well formed and compiles it.
+func (m *Matcher) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("matcher name is required")
+	}
+	return m.Compile()
+}
+
+// Compile compiles the regular expression of regex matchers, so that
+// Matches does not compile it on every call.
+func (m *Matcher) Compile() error {
+	if !m.IsRegex || m.re != nil {
+		return nil
+	}
+	re, err := regexp.Compile("^(?:" + m.Value + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid matcher regex for %q: %w", m.Name, err)
+	}
+	m.re = re
+	return nil
+}
+
+// Matches reports whether the labels satisfy the matcher. Regex matchers
+// that were not compiled are compiled for the call.
+func (m Matcher) Matches(labels map[string]string) bool {
+	v := labels[m.Name]
+	if !m.IsRegex {
+		return v == m.Value
+	}
+	if m.re == nil && m.Compile() != nil {
+		return false
+	}
+	return m.re.MatchString(v)
+}
+
+// MatchAll reports whether every matcher matches the labels.
+func MatchAll(matchers []Matcher, labels map[string]string) bool {
+	for _, m := range matchers {
+		if !m.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Silence mutes notifications for alerts whose labels match all matchers
+// between StartsAt and EndsAt.
+type Silence struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks that the silence can be applied.
+func (s *Silence) Validate() error {
+	if len(s.Matchers) == 0 {
+		return fmt.Errorf("at least one matcher is required")
+	}
+	for i := range s.Matchers {
+		if err := s.Matchers[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if s.EndsAt.IsZero() {
+		return fmt.Errorf("ends_at is required")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// Active reports whether the silence applies at the given time.
+func (s *Silence) Active(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// NotificationAttempt records one delivery of a notification to a channel.
+type NotificationAttempt struct {
+	Timestamp  time.Time `json:"timestamp"`
+	TenantID   string    `json:"tenant_id"`
+	Channel    string    `json:"channel"`
+	GroupKey   string    `json:"group_key"`
+	Status     State     `json:"status"`
+	AlertCount int       `json:"alert_count"`
+	Success    bool      `json:"success"`
+	ErrorCode  string    `json:"error_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
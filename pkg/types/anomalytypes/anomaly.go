@@ -0,0 +1,117 @@
+package anomalytypes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/Ricky004/watchdata/pkg/filter"
+)
+
+type Kind string
+
+const (
+	// KindVolumeSpike is a service logging far more at a severity than it
+	// usually does at this hour of the week.
+	KindVolumeSpike Kind = "volume_spike"
+	// KindVolumeDrop is a service logging far less than usual, including
+	// not at all.
+	KindVolumeDrop Kind = "volume_drop"
+	// KindNewPattern is a log pattern that had never been seen before.
+	KindNewPattern Kind = "new_pattern"
+	// KindNewError is a new pattern with records of severity ERROR or
+	// above.
+	KindNewError Kind = "new_error"
+)
+
+// Kinds lists every kind of finding.
+var Kinds = []Kind{KindVolumeSpike, KindVolumeDrop, KindNewPattern, KindNewError}
+
+// ParseKind validates a kind name.
+func ParseKind(s string) (Kind, error) {
+	for _, k := range Kinds {
+		if string(k) == s {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown anomaly kind %q", s)
+}
+
+// Finding is an anomaly detected in one window of a tenant's logs.
+type Finding struct {
+	ID           string `json:"id"`
+	TenantID     string `json:"tenant_id"`
+	Kind         Kind   `json:"kind"`
+	Service      string `json:"service"`
+	SeverityText string `json:"severity_text,omitempty"`
+
+	// PatternID, Template and Sample describe new patterns.
+	PatternID string `json:"pattern_id,omitempty"`
+	Template  string `json:"template,omitempty"`
+	Sample    string `json:"sample,omitempty"`
+
+	// Value is the number of records in the window, Expected the
+	// baseline for it and Score how many standard deviations apart they
+	// are. Expected and Score are zero for new patterns.
+	Value    float64 `json:"value"`
+	Expected float64 `json:"expected"`
+	Score    float64 `json:"score"`
+
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// FindingID derives the ID of a finding from what it is about, so that
+// evaluating a window twice yields the same findings.
+func FindingID(tenantID string, kind Kind, service, severity, patternID string, windowStart time.Time) string {
+	h := sha256.New()
+	for _, s := range []string{tenantID, string(kind), service, severity, patternID, windowStart.UTC().Format(time.RFC3339Nano)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Column returns the finding column a log field refers to in anomaly
+// queries and alert rules. Findings only carry the service, severity text
+// and pattern of the records.
+func Column(f filter.Field) (string, bool) {
+	switch {
+	case f.Kind == filter.FieldResource && f.Name == "service.name":
+		return "service", true
+	case f.Kind == filter.FieldColumn && (f.Name == "severity_text" || f.Name == "pattern_id"):
+		return f.Name, true
+	}
+	return "", false
+}
+
+// GroupColumn returns the finding column of a group-by key, which is a
+// log field Column accepts or "kind".
+func GroupColumn(key string) (string, error) {
+	if key == "kind" {
+		return "kind", nil
+	}
+	f, err := filter.ParseField(key)
+	if err != nil {
+		return "", err
+	}
+	col, ok := Column(f)
+	if !ok {
+		return "", fmt.Errorf("anomalies cannot be grouped by %q", key)
+	}
+	return col, nil
+}
+
+// MapFilter rewrites a log filter to the finding columns, failing on
+// fields findings do not carry.
+func MapFilter(expr filter.Expr) (filter.Expr, error) {
+	return filter.MapFields(expr, func(f filter.Field) (filter.Field, error) {
+		col, ok := Column(f)
+		if !ok {
+			return filter.Field{}, fmt.Errorf("anomalies cannot be filtered on %q", f)
+		}
+		return filter.Field{Kind: filter.FieldColumn, Name: col}, nil
+	})
+}
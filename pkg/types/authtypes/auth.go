@@ -0,0 +1,117 @@
+package authtypes
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned by stores when an API key does not exist.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrSessionNotFound is returned by stores when a session does not exist.
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+type Role string
+
+const (
+	// RoleViewer can read logs, alerts and settings.
+	RoleViewer Role = "viewer"
+	// RoleEditor can additionally ingest logs and manage alert rules.
+	RoleEditor Role = "editor"
+	// RoleAdmin can additionally manage API keys.
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Allows reports whether r grants at least the permissions of required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required] && r.Valid()
+}
+
+// APIKey is a stored API key. Only the hash of the secret is kept.
+type APIKey struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	Hash      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Revoked   bool      `json:"revoked"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Expired reports whether the key has an expiry that has passed.
+func (k APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// Session is a browser login created by single sign-on. The ID is the hash
+// of the session cookie value.
+type Session struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Groups    []string  `json:"groups"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Revoked   bool      `json:"revoked"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Expired reports whether the session has ended.
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string `json:"subject"`
+	Role    Role   `json:"role"`
+
+	// TenantID is the tenant the request acts on.
+	TenantID string `json:"tenant_id"`
+	// Global is set for callers that are not bound to a tenant (the
+	// bootstrap token, or any caller when auth is disabled). They choose
+	// the tenant per request and may manage tenants.
+	Global bool `json:"global,omitempty"`
+
+	// KeyID is set when the caller authenticated with an API key.
+	KeyID string `json:"key_id,omitempty"`
+	// Method is how the caller authenticated, e.g. "api_key" or "session".
+	Method string `json:"method"`
+
+	// Email, Name and Groups are set for single sign-on sessions.
+	Email  string   `json:"email,omitempty"`
+	Name   string   `json:"name,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,69 @@
+package opamptypes
+
+import "time"
+
+// Transports agents connect over.
+const (
+	TransportWebSocket = "websocket"
+	TransportHTTP      = "http"
+)
+
+// Remote config statuses an agent reports.
+const (
+	RemoteConfigUnset    = "unset"
+	RemoteConfigApplying = "applying"
+	RemoteConfigApplied  = "applied"
+	RemoteConfigFailed   = "failed"
+)
+
+// Agent is the last known state of a collector managed over OpAMP.
+type Agent struct {
+	// ID is the agent's instance UID.
+	ID        string `json:"id"`
+	Connected bool   `json:"connected"`
+	Transport string `json:"transport"`
+
+	// ConnectedAt is when the agent last connected and LastSeen when it
+	// last sent a message.
+	ConnectedAt time.Time `json:"connected_at"`
+	LastSeen    time.Time `json:"last_seen"`
+
+	// IdentifyingAttributes identify the agent, such as service.name and
+	// host.name; NonIdentifyingAttributes describe it further, such as
+	// os.type.
+	IdentifyingAttributes    map[string]string `json:"identifying_attributes"`
+	NonIdentifyingAttributes map[string]string `json:"non_identifying_attributes"`
+
+	Capabilities []string `json:"capabilities"`
+
+	// Health is nil until the agent reports it.
+	Health *ComponentHealth `json:"health,omitempty"`
+
+	// EffectiveConfig is the config the agent runs, by file name. Agents
+	// with a single file use the empty name.
+	EffectiveConfig map[string]string `json:"effective_config,omitempty"`
+
+	RemoteConfig RemoteConfigStatus `json:"remote_config"`
+}
+
+// ComponentHealth is the health of an agent or, in Components, of its
+// pipelines and their components.
+type ComponentHealth struct {
+	Healthy    bool                       `json:"healthy"`
+	Status     string                     `json:"status,omitempty"`
+	LastError  string                     `json:"last_error,omitempty"`
+	StartTime  *time.Time                 `json:"start_time,omitempty"`
+	StatusTime *time.Time                 `json:"status_time,omitempty"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// RemoteConfigStatus is how far an agent got applying the config it was
+// sent.
+type RemoteConfigStatus struct {
+	Status string `json:"status"`
+	// Hash identifies the config the status is of.
+	Hash  string `json:"hash,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Current reports whether Hash is of the config the server offers.
+	Current bool `json:"current"`
+}
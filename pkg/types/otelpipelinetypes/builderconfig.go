@@ -1,17 +1,20 @@
 package otelpipelinetypes
 
 type BuilderConfigs struct {
-	Dist struct {
-		Name        string `yaml:"name"`
-		Description string `yaml:"description"`
-		OutputPath  string `yaml:"output_path"`
-	} `yaml:"dist"`
+	Dist Dist `yaml:"dist"`
 
 	Receivers  []ModuleEntry `yaml:"receivers"`
 	Processors []ModuleEntry `yaml:"processors"`
 	Exporters  []ModuleEntry `yaml:"exporters"`
 }
 
+// Dist describes the collector binary the builder produces.
+type Dist struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	OutputPath  string `yaml:"output_path" json:"output_path"`
+}
+
 type ModuleEntry struct {
 	Gomod  string `yaml:"gomod"`
 	Import string `yaml:"import,omitempty"`
@@ -0,0 +1,43 @@
+package otelpipelinetypes
+
+import (
+	"fmt"
+	"time"
+)
+
+// Definition is a collector pipeline managed through the API. The stored
+// definitions together make up the deployed collector config.
+type Definition struct {
+	ID string `json:"id"`
+	// Name is the pipeline's ID in the collector config, such as "logs" or
+	// "logs/app". It is unique among definitions.
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Pipeline    Pipeline `json:"pipeline"`
+
+	// Params fill in and Receivers, Processors and Exporters override the
+	// templates of the components the pipeline uses, as in a Selection.
+	// Definitions that share a component must agree on both.
+	Params     ComponentValues                   `json:"params"`
+	Receivers  map[string]map[string]interface{} `json:"receivers,omitempty"`
+	Processors map[string]map[string]interface{} `json:"processors,omitempty"`
+	Exporters  map[string]map[string]interface{} `json:"exporters,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the fields that do not depend on other definitions.
+// Components and overrides are checked when the definitions are rendered.
+func (d *Definition) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(d.Pipeline.Receivers) == 0 {
+		return fmt.Errorf("pipeline needs at least one receiver")
+	}
+	if len(d.Pipeline.Exporters) == 0 {
+		return fmt.Errorf("pipeline needs at least one exporter")
+	}
+	return nil
+}
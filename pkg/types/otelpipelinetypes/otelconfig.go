@@ -1,18 +1,59 @@
 package otelpipelinetypes
 
+import "strings"
+
+// OTelConfig is a collector config. Sections and settings it does not
+// model are kept by Document when a config file is edited.
 type OTelConfig struct {
-	Receivers  map[string]interface{} `yaml:"receivers"`
-	Processors map[string]interface{} `yaml:"processors"`
-	Exporters  map[string]interface{} `yaml:"exporters"`
-	Service    ServiceConfig           `yaml:"service"`
+	Receivers  map[string]interface{} `yaml:"receivers,omitempty"`
+	Processors map[string]interface{} `yaml:"processors,omitempty"`
+	Exporters  map[string]interface{} `yaml:"exporters,omitempty"`
+	Extensions map[string]interface{} `yaml:"extensions,omitempty"`
+	// Connectors are exporters of one pipeline and receivers of another.
+	Connectors map[string]interface{} `yaml:"connectors,omitempty"`
+	Service    ServiceConfig          `yaml:"service,omitempty"`
 }
 
 type ServiceConfig struct {
-	Pipelines map[string]Pipeline `yaml:"pipelines"`
+	// Extensions are the extensions to start.
+	Extensions []string               `yaml:"extensions,omitempty"`
+	Telemetry  map[string]interface{} `yaml:"telemetry,omitempty"`
+	Pipelines  map[string]Pipeline    `yaml:"pipelines,omitempty"`
 }
 
 type Pipeline struct {
-	Receivers  []string `yaml:"receivers"`
-	Processors []string `yaml:"processors"`
-	Exporters  []string `yaml:"exporters"`
+	Receivers  []string `yaml:"receivers" json:"receivers"`
+	Processors []string `yaml:"processors,omitempty" json:"processors"`
+	Exporters  []string `yaml:"exporters" json:"exporters"`
+}
+
+// ComponentType returns the type of a component name: "otlp" for both
+// "otlp" and "otlp/2".
+func ComponentType(name string) string {
+	typ, _, _ := strings.Cut(name, "/")
+	return typ
+}
+
+// Selection declares a collector config: the pipelines to run, the params
+// each component's template is filled in with and, per component name,
+// values that override the rendered template. Nested maps are merged; a
+// null value removes the key from the template.
+type Selection struct {
+	Pipelines  map[string]Pipeline               `yaml:"pipelines" json:"pipelines"`
+	Params     ComponentValues                   `yaml:"params,omitempty" json:"params,omitempty"`
+	Receivers  map[string]map[string]interface{} `yaml:"receivers,omitempty" json:"receivers,omitempty"`
+	Processors map[string]map[string]interface{} `yaml:"processors,omitempty" json:"processors,omitempty"`
+	Exporters  map[string]map[string]interface{} `yaml:"exporters,omitempty" json:"exporters,omitempty"`
+	Dist       *Dist                             `yaml:"dist,omitempty" json:"dist,omitempty"`
+	// Release is the collector release, such as "0.127.0", the builder
+	// config pins its modules to.
+	Release string `yaml:"release,omitempty" json:"release,omitempty"`
+}
+
+// ComponentValues holds values per component name, for each kind of
+// component.
+type ComponentValues struct {
+	Receivers  map[string]map[string]interface{} `yaml:"receivers,omitempty" json:"receivers,omitempty"`
+	Processors map[string]map[string]interface{} `yaml:"processors,omitempty" json:"processors,omitempty"`
+	Exporters  map[string]map[string]interface{} `yaml:"exporters,omitempty" json:"exporters,omitempty"`
 }
@@ -0,0 +1,258 @@
+package otelpipelinetypes
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldType is the type of a component config value.
+type FieldType string
+
+const (
+	TypeString   FieldType = "string"
+	TypeInt      FieldType = "int"
+	TypeBool     FieldType = "bool"
+	TypeDuration FieldType = "duration"
+	TypeList     FieldType = "list"
+	TypeObject   FieldType = "object"
+	// TypeAny accepts any value, for parts of a config that are not
+	// described.
+	TypeAny FieldType = "any"
+)
+
+// Schema describes a component config value.
+type Schema struct {
+	Type        FieldType `json:"type"`
+	Description string    `json:"description,omitempty"`
+	Required    bool      `json:"required,omitempty"`
+	// Enum lists the allowed values of a string.
+	Enum []string `json:"enum,omitempty"`
+	// Default is the value the component uses when the field is unset.
+	Default interface{} `json:"default,omitempty"`
+	// Fields are the keys of an object. Other keys are an error unless
+	// the object is Open.
+	Fields map[string]*Schema `json:"fields,omitempty"`
+	Open   bool               `json:"open,omitempty"`
+	// Items describes the elements of a list.
+	Items *Schema `json:"items,omitempty"`
+}
+
+// Issue is a problem found at a path of a config, such as
+// "receivers.filelog.include[0]".
+type Issue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// ValidationError reports every issue found in a config.
+type ValidationError struct {
+	Issues []Issue `json:"issues"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.String()
+	}
+	return "invalid config:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// Validate returns the issues of v, whose path is path. A nil value is an
+// empty object, as YAML writes "batch:".
+func (s *Schema) Validate(path string, v interface{}) []Issue {
+	if s == nil || s.Type == TypeAny {
+		return nil
+	}
+	issue := func(format string, args ...interface{}) []Issue {
+		return []Issue{{Path: path, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	switch s.Type {
+	case TypeString:
+		str, ok := v.(string)
+		if !ok {
+			return issue("expected a string, got %s", describe(v))
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return issue("%q is not one of %s", str, strings.Join(s.Enum, ", "))
+		}
+	case TypeInt:
+		if _, ok := toInt(v); !ok {
+			return issue("expected an integer, got %s", describe(v))
+		}
+	case TypeBool:
+		if _, ok := v.(bool); !ok {
+			return issue("expected true or false, got %s", describe(v))
+		}
+	case TypeDuration:
+		str, ok := v.(string)
+		if !ok {
+			return issue("expected a duration such as \"10s\", got %s", describe(v))
+		}
+		if _, err := time.ParseDuration(str); err != nil {
+			return issue("invalid duration %q", str)
+		}
+	case TypeList:
+		items, ok := toList(v)
+		if !ok {
+			return issue("expected a list, got %s", describe(v))
+		}
+		var issues []Issue
+		for i, item := range items {
+			issues = append(issues, s.Items.Validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+		return issues
+	case TypeObject:
+		if v == nil {
+			v = map[string]interface{}{}
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return issue("expected an object, got %s", describe(v))
+		}
+		return s.validateObject(path, m)
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(path string, m map[string]interface{}) []Issue {
+	var issues []Issue
+	keys := make([]string, 0, len(m)+len(s.Fields))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for k, f := range s.Fields {
+		if _, ok := m[k]; !ok && f.Required {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fieldPath := JoinPath(path, k)
+		f, known := s.Fields[k]
+		v, set := m[k]
+		switch {
+		case !known && !s.Open:
+			issues = append(issues, Issue{Path: fieldPath, Message: "unknown field" + suggest(k, s.Fields)})
+		case !known:
+		case !set:
+			issues = append(issues, Issue{Path: fieldPath, Message: "required field is missing"})
+		default:
+			issues = append(issues, f.Validate(fieldPath, v)...)
+		}
+	}
+	return issues
+}
+
+// JoinPath appends key to a YAML path.
+func JoinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// suggest names a known field close to a misspelled one.
+func suggest(key string, fields map[string]*Schema) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case float64:
+		return int64(n), n == math.Trunc(n)
+	}
+	return 0, false
+}
+
+func toList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case []string:
+		items := make([]interface{}, len(l))
+		for i, s := range l {
+			items[i] = s
+		}
+		return items, true
+	case []map[string]interface{}:
+		items := make([]interface{}, len(l))
+		for i, m := range l {
+			items[i] = m
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+func describe(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int, int64, uint64, float64:
+		return "a number"
+	case map[string]interface{}:
+		return "an object"
+	}
+	if _, ok := toList(v); ok {
+		return "a list"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,187 @@
+package otelpipelinetypes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Signals a pipeline can carry.
+const (
+	SignalLogs    = "logs"
+	SignalMetrics = "metrics"
+	SignalTraces  = "traces"
+)
+
+// Param is a value a template is filled in with, referenced in its config
+// as "{{name}}". A string that is only a reference takes the value as is,
+// lists and objects included; references inside longer strings are
+// replaced by the value's text.
+type Param struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	// Example is a typical value of a param without a default.
+	Example interface{} `json:"example,omitempty"`
+}
+
+// Positions a processor can be pinned to in a pipeline.
+const (
+	PositionFirst = "first"
+	PositionLast  = "last"
+)
+
+// Template is the starting config of a component.
+type Template struct {
+	Description string   `json:"description"`
+	Signals     []string `json:"signals"`
+	// Position pins a processor to the start or end of its pipelines.
+	Position string                 `json:"position,omitempty"`
+	Params   []Param                `json:"params,omitempty"`
+	Config   map[string]interface{} `json:"config"`
+}
+
+var paramRef = regexp.MustCompile(`\{\{\s*([a-z0-9_]+)\s*\}\}`)
+
+// Render returns a copy of the template's config with its params filled
+// in from values, or their defaults. Missing required params and values
+// for params the template does not have are errors.
+func (t Template) Render(values map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(t.Params))
+	var errs []string
+	for _, p := range t.Params {
+		v, ok := values[p.Name]
+		switch {
+		case ok && v != nil:
+			resolved[p.Name] = v
+		case p.Required:
+			errs = append(errs, fmt.Sprintf("param %q is required", p.Name))
+		default:
+			resolved[p.Name] = p.Default
+		}
+	}
+	for _, name := range sortedNames(values) {
+		if !t.hasParam(name) {
+			errs = append(errs, fmt.Sprintf("unknown param %q", name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+
+	cfg, err := fill(t.Config, resolved)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return map[string]interface{}{}, nil
+	}
+	return cfg.(map[string]interface{}), nil
+}
+
+func (t Template) hasParam(name string) bool {
+	for _, p := range t.Params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fill deep-copies v, replacing param references.
+func fill(v interface{}, params map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			filled, err := fill(e, params)
+			if err != nil {
+				return nil, err
+			}
+			// Fields set to an unset param are left out, as are objects
+			// left empty by that.
+			if _, ref := e.(string); ref && filled == nil {
+				continue
+			}
+			if src, ok := e.(map[string]interface{}); ok && len(src) > 0 && len(filled.(map[string]interface{})) == 0 {
+				continue
+			}
+			m[k] = filled
+		}
+		return m, nil
+	case []interface{}, []string, []map[string]interface{}:
+		items, _ := toList(v)
+		s := make([]interface{}, len(items))
+		for i, e := range items {
+			filled, err := fill(e, params)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = filled
+		}
+		return s, nil
+	case string:
+		if params == nil {
+			return v, nil
+		}
+		return fillString(v, params)
+	}
+	return v, nil
+}
+
+func fillString(s string, params map[string]interface{}) (interface{}, error) {
+	if m := paramRef.FindStringSubmatch(s); m != nil && m[0] == s {
+		v, ok := params[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("template references undeclared param %q", m[1])
+		}
+		// Copy the value without filling it in.
+		return fill(v, nil)
+	}
+
+	var err error
+	out := paramRef.ReplaceAllStringFunc(s, func(ref string) string {
+		name := paramRef.FindStringSubmatch(ref)[1]
+		v, ok := params[name]
+		switch {
+		case !ok:
+			err = fmt.Errorf("template references undeclared param %q", name)
+		case v == nil:
+		default:
+			if _, isList := toList(v); isList {
+				err = fmt.Errorf("param %q must be a single value", name)
+			} else if _, isMap := v.(map[string]interface{}); isMap {
+				err = fmt.Errorf("param %q must be a single value", name)
+			} else {
+				return fmt.Sprint(v)
+			}
+		}
+		return ""
+	})
+	return out, err
+}
+
+func sortedNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTemplate returns the template of a component name: the preset of
+// exactly that name, such as "filelog/nginx", or else the template of its
+// type.
+func LookupTemplate(templates map[string]Template, name string) (Template, bool) {
+	if t, ok := templates[name]; ok {
+		return t, true
+	}
+	t, ok := templates[ComponentType(name)]
+	return t, ok
+}
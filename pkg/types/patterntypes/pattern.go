@@ -0,0 +1,28 @@
+package patterntypes
+
+import "time"
+
+// Wildcard stands for the variable parts of a template.
+const Wildcard = "<*>"
+
+// Pattern is the template shared by a group of log bodies, such as
+// "user <*> logged in from <*>".
+type Pattern struct {
+	TenantID string `json:"tenant_id"`
+	ID       string `json:"id"`
+	Template string `json:"template"`
+	// CreatedAt is when the pattern was first seen.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Summary describes the records of one pattern in a time range.
+type Summary struct {
+	ID        string    `json:"id"`
+	Template  string    `json:"template"`
+	Count     uint64    `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Samples are a few bodies of the pattern.
+	Samples []string `json:"samples"`
+}
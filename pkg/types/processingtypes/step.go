@@ -0,0 +1,85 @@
+package processingtypes
+
+import "github.com/Ricky004/watchdata/pkg/types/telemetrytypes"
+
+type StepType string
+
+const (
+	// StepJSON parses a JSON object into attributes. Nested objects are
+	// flattened into dotted keys.
+	StepJSON StepType = "json"
+	// StepLogfmt parses key=value pairs into attributes.
+	StepLogfmt StepType = "logfmt"
+	// StepRegex extracts the named groups of a regular expression into
+	// attributes.
+	StepRegex StepType = "regex"
+	// StepGrok extracts the %{PATTERN:key} references of a grok expression
+	// into attributes.
+	StepGrok StepType = "grok"
+	// StepTimestamp sets the timestamp from a field.
+	StepTimestamp StepType = "timestamp"
+	// StepSeverity infers the severity of records that have none.
+	StepSeverity StepType = "severity"
+	// StepRename renames attributes.
+	StepRename StepType = "rename"
+	// StepDrop removes attributes.
+	StepDrop StepType = "drop"
+	// StepRoute sends records through the steps of the first route whose
+	// condition they match.
+	StepRoute StepType = "route"
+)
+
+// Step is one step of an ingest processing chain. Steps that cannot parse
+// a record, such as a json step given a plain string, leave it as it is.
+type Step struct {
+	Type StepType `json:"type" yaml:"type"`
+	// When is a filter expression. The step only applies to the records
+	// it matches.
+	When string `json:"when,omitempty" yaml:"when"`
+
+	// Field is the field the parser, timestamp and severity steps read, as
+	// written in filter expressions: body or attributes.<key>. Parsers
+	// read the body by default; severity steps read the severity text,
+	// then the body.
+	Field string `json:"field,omitempty" yaml:"field"`
+	// Prefix is prepended to the keys of the attributes parsers add.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix"`
+	// Body is a key a parser finds whose value replaces the body instead of
+	// becoming an attribute, such as the message of JSON logs.
+	Body string `json:"body,omitempty" yaml:"body"`
+
+	// Pattern is the expression of regex and grok steps.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern"`
+	// Layout is the format of timestamp steps: rfc3339, unix, unix_ms,
+	// unix_us, unix_ns or a Go time layout.
+	Layout string `json:"layout,omitempty" yaml:"layout"`
+
+	// Rename maps attribute keys to their new keys.
+	Rename map[string]string `json:"rename,omitempty" yaml:"rename"`
+	// Keys are the attribute keys drop steps remove.
+	Keys []string `json:"keys,omitempty" yaml:"keys"`
+
+	Routes []Route `json:"routes,omitempty" yaml:"routes"`
+}
+
+// Route is a branch of a route step. A route without a condition matches
+// every record.
+type Route struct {
+	When string `json:"when,omitempty" yaml:"when"`
+	// Drop discards the records of the route instead of storing them.
+	Drop  bool   `json:"drop,omitempty" yaml:"drop"`
+	Steps []Step `json:"steps,omitempty" yaml:"steps"`
+}
+
+// TestRequest runs sample records through a processing chain without
+// storing them. Without steps, the server's chain is used.
+type TestRequest struct {
+	Steps   []Step                     `json:"steps,omitempty"`
+	Records []telemetrytypes.LogRecord `json:"records"`
+}
+
+// TestResult is what a processing chain made of a sample record.
+type TestResult struct {
+	Record  telemetrytypes.LogRecord `json:"record"`
+	Dropped bool                     `json:"dropped,omitempty"`
+}
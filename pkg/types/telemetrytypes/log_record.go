@@ -2,6 +2,8 @@ package telemetrytypes
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Resource struct {
@@ -9,6 +11,13 @@ type Resource struct {
 }
 
 type LogRecord struct {
+	// ID identifies the record. It is assigned at ingest and sorts by
+	// ingest time.
+	ID       string `json:"id,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	// PatternID is the pattern the body belongs to, if it was assigned
+	// one at ingest.
+	PatternID        string     `json:"pattern_id,omitempty"`
 	Timestamp        time.Time  `json:"timestamp"`
 	ObservedTime     time.Time  `json:"observed_time"`
 	SeverityNumber   int8       `json:"severity_number"`
@@ -22,3 +31,15 @@ type LogRecord struct {
 	Flags            uint32     `json:"flags,omitempty"`
 	DroppedAttrCount uint32     `json:"dropped_attributes_count,omitempty"`
 }
+
+// NewLogID returns a new record ID, a version 7 UUID.
+func NewLogID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// LogContext is a record with the records around it from the same source.
+type LogContext struct {
+	Log    LogRecord   `json:"log"`
+	Before []LogRecord `json:"before"`
+	After  []LogRecord `json:"after"`
+}
@@ -0,0 +1,68 @@
+package tenanttypes
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// DefaultTenant owns data ingested without a tenant, including
+	// everything stored before multi-tenancy was introduced.
+	DefaultTenant = "default"
+
+	// AllTenants is accepted by store queries run by background components
+	// (the alert engine, the dispatcher, the live tail poller) that work
+	// across tenants. It can never be a tenant id.
+	AllTenants = "*"
+
+	// DefaultRetentionDays matches the retention of the original logs table.
+	DefaultRetentionDays = 30
+
+	// MaxRetentionDays bounds per-tenant retention.
+	MaxRetentionDays = 3650
+)
+
+// ErrTenantNotFound is returned by stores when a tenant does not exist.
+var ErrTenantNotFound = errors.New("tenant not found")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidID reports whether id may be used as a tenant id.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Tenant holds the settings of one tenant.
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// RetentionDays is how long newly ingested logs are kept. Changing it
+	// does not affect logs that are already stored.
+	RetentionDays uint16 `json:"retention_days"`
+
+	// DailyQuotaBytes caps how much the compressed size of the tenant's
+	// stored logs may grow per UTC day. Zero means unlimited.
+	DailyQuotaBytes uint64 `json:"daily_quota_bytes"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Default returns the settings used for tenants that have not been
+// configured.
+func Default(id string) Tenant {
+	return Tenant{ID: id, Name: id, RetentionDays: DefaultRetentionDays}
+}
+
+func (t Tenant) Validate() error {
+	if !ValidID(t.ID) {
+		return fmt.Errorf("invalid tenant id %q: use lowercase letters, digits, '-' and '_'", t.ID)
+	}
+	if t.RetentionDays == 0 || t.RetentionDays > MaxRetentionDays {
+		return fmt.Errorf("retention_days must be between 1 and %d", MaxRetentionDays)
+	}
+	return nil
+}
@@ -9,4 +9,6 @@ type Config struct {
 	component.Config `mapstructure:",squash"`
 	DSN              string `mapstructure:"dsn"`
 	TLSInsecure      bool   `mapstructure:"insecure"`
+	// TenantID is the tenant the exported logs are stored under.
+	TenantID string `mapstructure:"tenant_id"`
 }
@@ -1,6 +1,7 @@
 package watchdataexporter
 
 import (
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/exporter"
 )
@@ -21,5 +22,6 @@ func CreateDefaultConfig() component.Config {
 	return &Config{
 		DSN: "",
 		TLSInsecure:   true,
+		TenantID:      tenanttypes.DefaultTenant,
 	}
 }
@@ -50,6 +50,7 @@ func convertToLogRecords(ld plog.Logs) []telemetrytypes.LogRecord {
 				}
 				
 				records = append(records, telemetrytypes.LogRecord{
+					ID:               telemetrytypes.NewLogID(),
 					Timestamp:        log.Timestamp().AsTime(),
 					ObservedTime:     log.ObservedTimestamp().AsTime(),
 					SeverityNumber:   int8(log.SeverityNumber()),
@@ -6,30 +6,63 @@ import (
 	"time"
 
 	"github.com/Ricky004/watchdata/pkg/clickhousestore"
+	"github.com/Ricky004/watchdata/pkg/patterns"
+	"github.com/Ricky004/watchdata/pkg/redaction"
+	"github.com/Ricky004/watchdata/pkg/types/tenanttypes"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+const instrumentationName = "github.com/Ricky004/watchdata/pkg/watchdataexporter"
+
 type watchdataExporter struct {
 	dsn         string
 	tlsInsecure bool
+	tenantID    string
 	logger      *zap.Logger
+	tracer      trace.Tracer
 	ch          *clickhousestore.ClickHouseProvider
+	patterns    *patterns.Miner
+	redactor    *redaction.Redactor
 }
 
 func newLogsExporter(cfg *Config, set exporter.Settings, ch *clickhousestore.ClickHouseProvider) (*watchdataExporter, error) {
 	if cfg.DSN == "" {
 		return nil, fmt.Errorf("DSN must be provided for watchdataExporter")
 	}
+	if cfg.TenantID != "" && !tenanttypes.ValidID(cfg.TenantID) {
+		return nil, fmt.Errorf("invalid tenant_id %q for watchdataExporter", cfg.TenantID)
+	}
+	patternsCfg, err := patterns.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load patterns config: %w", err)
+	}
+	// The collector reads the same redaction rules as the server, so that
+	// records stored by either are redacted alike.
+	redactionCfg, err := redaction.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := redaction.NewRedactor(redactionCfg)
+	if err != nil {
+		return nil, err
+	}
 
 	return &watchdataExporter{
 		dsn:         cfg.DSN,
 		tlsInsecure: cfg.TLSInsecure,
+		tenantID:    cfg.TenantID,
 		logger:      set.Logger,
+		tracer:      set.TracerProvider.Tracer(instrumentationName),
 		ch:          ch,
+		patterns:    patterns.NewMiner(patternsCfg, ch),
+		redactor:    redactor,
 	}, nil
 }
 
@@ -77,6 +110,16 @@ func (e *watchdataExporter) Start(ctx context.Context, host component.Host) erro
 // Shutdown is a lifecycle function for the exporter.
 func (e *watchdataExporter) Shutdown(ctx context.Context) error {
 	e.logger.Info("Stopping watchdataExporter with DSN", zap.String("dsn", e.dsn))
+	// The collector shuts exporters down after receivers and processors,
+	// so batches flushed on shutdown have already been inserted.
+	if e.patterns != nil {
+		if err := e.patterns.Flush(ctx); err != nil {
+			e.logger.Warn("Failed to store log patterns", zap.Error(err))
+		}
+	}
+	if e.ch != nil {
+		return e.ch.Close()
+	}
 	return nil
 }
 
@@ -85,13 +128,35 @@ func (e *watchdataExporter) Capabilities() consumer.Capabilities {
 	return consumer.Capabilities{MutatesData: false}
 }
 
-// ConsumeLogs is the method that receives log data.
+// ConsumeLogs is the method that receives log data. Every batch is
+// recorded as a span with the collector's tracer provider.
 func (e *watchdataExporter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
-	records := convertToLogRecords(ld)
+	ctx, span := e.tracer.Start(ctx, "watchdataexporter/ConsumeLogs",
+		trace.WithAttributes(attribute.Int("records", ld.LogRecordCount())))
+	defer span.End()
+
+	records := e.redactor.Redact(convertToLogRecords(ld))
+	if dropped := ld.LogRecordCount() - len(records); dropped > 0 {
+		span.SetAttributes(attribute.Int("redaction.dropped", dropped))
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	for i := range records {
+		records[i].TenantID = e.tenantID
+	}
+	e.patterns.Assign(ctx, records)
 	err := e.ch.InsertLogs(ctx, records)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to insert logs")
+		e.logger.Error("Failed to insert logs", zap.Int("records", len(records)), zap.Error(err))
 		return fmt.Errorf("failed to insert logs: %w", err)
 	}
+	if err := e.patterns.Flush(ctx); err != nil {
+		e.logger.Warn("Failed to store log patterns", zap.Error(err))
+	}
+	e.logger.Debug("Inserted logs", zap.Int("records", len(records)))
 	return nil
 }
 